@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"rag/internal/bench"
+	"rag/internal/config"
+	"rag/internal/embedding"
+	"rag/internal/i18n"
+)
+
+// benchQueryTerms is the number of words of a keyword query.
+const benchQueryTerms = 3
+
+// runBenchModels compares embedders on a sample of the corpus: each indexes
+// the same chunks and answers the same generated queries, and the models
+// are reported by how often the chunk a query came from is found.
+func runBenchModels(args []string) {
+	fs := flag.NewFlagSet("bench-models", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	models := fs.String("models", "", "Comma-separated embedders to compare: names under embedders in the config, tfidf, or models of the configured OpenAI-compatible endpoint (default: the configured embedder and the named ones)")
+	sample := fs.Int("sample", 300, "Number of chunks to sample from the corpus")
+	queries := fs.Int("queries", 50, "Number of queries to generate")
+	topK := fs.Int("top-k", 10, "Number of results searched for each query's answer")
+	generate := fs.String("generate", "", "How to generate queries: keywords (distinctive terms of a chunk) or llm (questions written by the configured llm; default when one is configured)")
+	seed := fs.Int64("seed", 1, "Seed of the sample and the queries, to repeat a run")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Println(i18n.T("Usage: rag bench-models [--config=config.yaml] [--models=a,b,c] [--sample=300] [--queries=50] [--top-k=10] [--generate=keywords|llm] [--seed=1] file1.txt [file2.txt ...]"))
+		os.Exit(1)
+	}
+	cfg := loadConfig(*cfgPath)
+	if *generate == "" {
+		*generate = "keywords"
+		if cfg.LLM != nil {
+			*generate = "llm"
+		}
+	}
+	names, embedders := benchModels(cfg, *models)
+
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+	ctx := context.Background()
+	chunks, err := svc.ChunkSources(ctx, corpusSources(cfg, fs.Args()))
+	if err != nil {
+		log.Fatal(i18n.Sprintf("load failed: %v", err))
+	}
+	if len(chunks) == 0 {
+		log.Fatal(i18n.T("no chunks to sample; check the file arguments"))
+	}
+	chunks = bench.Sample(chunks, *sample, *seed)
+	var pairs []bench.Pair
+	switch *generate {
+	case "keywords":
+		pairs = bench.KeywordQueries(chunks, *queries, benchQueryTerms, *seed)
+	case "llm":
+		if cfg.LLM == nil {
+			log.Fatal(i18n.T("generating queries with an llm needs an llm section in the config"))
+		}
+		pairs, err = bench.GeneratedQueries(ctx, chunks, *queries, *seed, newLLM(cfg.LLM).Question)
+		if err != nil {
+			log.Fatal(i18n.Sprintf("query generation failed: %v", err))
+		}
+	default:
+		log.Fatal(i18n.Sprintf("unknown query generator: %s", *generate))
+	}
+	if len(pairs) == 0 {
+		log.Fatal(i18n.T("no queries could be generated from the sample"))
+	}
+	fmt.Println(i18n.Sprintf("Sample: %d chunks, %d queries (%s), answers searched in the top %d.", len(chunks), len(pairs), *generate, *topK))
+
+	width := utf8.RuneCountInString(i18n.T("model"))
+	for _, n := range names {
+		width = max(width, len(n))
+	}
+	fmt.Printf("%-*s  %6s  %6s  %6s  %10s  %10s\n", width, i18n.T("model"), "hit@1", fmt.Sprintf("hit@%d", *topK), "MRR", i18n.T("index"), i18n.T("query"))
+	var results []bench.Result
+	for i, name := range names {
+		res, err := bench.Evaluate(ctx, name, embedders[i], chunks, pairs, *topK)
+		if err != nil {
+			fmt.Printf("%-*s  %s\n", width, name, i18n.Sprintf("failed: %v", err))
+			continue
+		}
+		results = append(results, res)
+		q := float64(res.Queries)
+		fmt.Printf("%-*s  %6.3f  %6.3f  %6.3f  %10s  %10s\n", width, name, float64(res.Top1)/q, float64(res.TopK)/q, res.MRR, res.Index.Round(time.Millisecond), res.Query.Round(10*time.Microsecond))
+	}
+	if len(results) == 0 {
+		os.Exit(1)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].MRR > results[j].MRR })
+	fmt.Println(i18n.Sprintf("Best: %s (MRR %.3f).", results[0].Model, results[0].MRR))
+	if *generate == "keywords" {
+		fmt.Println(i18n.T("Keyword queries share words with their answers, which favors tfidf; configure an llm for natural-language questions."))
+	}
+}
+
+// benchModels resolves the --models list into names and embedders. A name
+// is an embedder under embedders in the config, tfidf, or else a model of
+// the configured OpenAI-compatible endpoint. With no list, the configured
+// embedder is compared with the named ones.
+func benchModels(cfg *config.AppConfig, list string) ([]string, []embedding.Embedder) {
+	var names []string
+	var configs []config.EmbedderConfig
+	if list == "" {
+		names = append(names, embedderLabel(cfg.Embedder))
+		configs = append(configs, cfg.Embedder)
+		var named []string
+		for name := range cfg.Embedders {
+			named = append(named, name)
+		}
+		sort.Strings(named)
+		for _, name := range named {
+			names = append(names, name)
+			configs = append(configs, cfg.Embedders[name])
+		}
+	} else {
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+				configs = append(configs, benchEmbedder(cfg, name))
+			}
+		}
+	}
+	if len(names) == 0 {
+		log.Fatal(i18n.T("no models to compare"))
+	}
+	// Create every embedder up front, so that a misconfigured one is
+	// reported before any is run.
+	embedders := make([]embedding.Embedder, len(configs))
+	for i, c := range configs {
+		embedders[i] = newEmbedder(c)
+	}
+	return names, embedders
+}
+
+// benchEmbedder resolves one name of the --models list.
+func benchEmbedder(cfg *config.AppConfig, name string) config.EmbedderConfig {
+	if e, ok := cfg.Embedders[name]; ok {
+		return e
+	}
+	if name == "tfidf" {
+		return config.EmbedderConfig{Type: "tfidf"}
+	}
+	oc := config.OpenAIEmbedderConfig{APIKeyEnv: "OPENAI_API_KEY"}
+	if cfg.Embedder.OpenAI != nil {
+		oc = *cfg.Embedder.OpenAI
+	}
+	oc.Model = name
+	return config.EmbedderConfig{Type: "openai", OpenAI: &oc}
+}
+
+// embedderLabel names the configured embedder in the comparison.
+func embedderLabel(e config.EmbedderConfig) string {
+	if e.Type == "openai" && e.OpenAI != nil && e.OpenAI.Model != "" {
+		return e.OpenAI.Model
+	}
+	if e.Type == "" {
+		return "tfidf"
+	}
+	return e.Type
+}
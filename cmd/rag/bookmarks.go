@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"rag/internal/i18n"
+	"rag/internal/snippet"
+)
+
+// runBookmarks lists the results bookmarked in the TUI for the index of the
+// given files.
+func runBookmarks(args []string) {
+	fs := flag.NewFlagSet("bookmarks", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	_ = fs.Parse(args)
+	inputs := fs.Args()
+	cfg := loadConfig(*cfgPath)
+	if len(inputs) == 0 && !readOnlyStore(cfg) {
+		fmt.Println(i18n.T("Usage: rag bookmarks [--config=config.yaml] file1.txt [file2.txt ...]"))
+		os.Exit(1)
+	}
+	list, err := bookmarks(cfg, inputs).List()
+	if err != nil {
+		log.Fatal(i18n.Sprintf("failed to load bookmarks: %v", err))
+	}
+	if len(list) == 0 {
+		fmt.Println(i18n.T("No bookmarks. Press Enter on a result in the TUI and choose Bookmark."))
+		return
+	}
+	for _, b := range list {
+		fmt.Printf("%s  %s#%d", b.Created.Format("2006-01-02"), b.Path, b.Index)
+		if b.Query != "" {
+			fmt.Printf("  (%s)", b.Query)
+		}
+		fmt.Printf("\n    %s\n", snippet.Generate(b.Text, "", nil, snippet.DefaultWidth))
+	}
+}
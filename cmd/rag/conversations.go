@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"rag/internal/conversation"
+	"rag/internal/i18n"
+)
+
+// runConversations lists the conversations of the plain interactive mode
+// for the index of the given files, exports one to Markdown or deletes one.
+func runConversations(args []string) {
+	fs := flag.NewFlagSet("conversations", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	export := fs.String("export", "", "Write the conversation with this ID as Markdown")
+	out := fs.String("out", "", "Output file of --export (default: stdout)")
+	del := fs.String("delete", "", "Delete the conversation with this ID")
+	_ = fs.Parse(args)
+	inputs := fs.Args()
+	cfg := loadConfig(*cfgPath)
+	if len(inputs) == 0 && !readOnlyStore(cfg) {
+		fmt.Println(i18n.T("Usage: rag conversations [--config=config.yaml] [--export=ID [--out=notes.md]] [--delete=ID] file1.txt [file2.txt ...]"))
+		os.Exit(1)
+	}
+	store := conversations(cfg, inputs)
+	switch {
+	case *del != "":
+		if err := store.Delete(*del); err != nil {
+			log.Fatal(i18n.Sprintf("failed to delete the conversation: %v", err))
+		}
+		return
+	case *export != "":
+		c, err := store.Get(*export)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var w io.Writer = os.Stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := conversation.Markdown(w, c); err != nil {
+			log.Fatal(i18n.Sprintf("export failed: %v", err))
+		}
+		return
+	}
+	list, err := store.List()
+	if err != nil {
+		log.Fatal(i18n.Sprintf("failed to load conversations: %v", err))
+	}
+	if len(list) == 0 {
+		fmt.Println(i18n.T("No conversations. Queries and answers of rag --no-tui are kept here."))
+		return
+	}
+	for _, c := range list {
+		fmt.Printf("%s  %s  %s\n", c.ID, c.Started.Format("2006-01-02 15:04"), i18n.Sprintf("%d queries, first: %s", len(c.Turns), c.Turns[0].Query))
+	}
+}
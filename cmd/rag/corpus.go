@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"rag/internal/config"
+	"rag/internal/corpus"
+	"rag/internal/domain"
+	"rag/internal/i18n"
+	"rag/internal/service"
+)
+
+// corpusSources turns file arguments into ingest sources. Arguments naming
+// a corpus manifest (corpus.yaml) contribute the sources it lists.
+func corpusSources(cfg *config.AppConfig, args []string) []service.Source {
+	var out []service.Source
+	for _, arg := range args {
+		if !corpus.IsManifest(arg) {
+			out = append(out, service.Source{Pattern: arg})
+			continue
+		}
+		m, err := corpus.Load(arg)
+		if err != nil {
+			log.Fatal(i18n.Sprintf("failed to load corpus manifest: %v", err))
+		}
+		for _, src := range m.Sources {
+			s := service.Source{Pattern: src.Path, URL: src.URL, Tags: src.Tags, Loader: src.Loader, Language: src.Lang}
+			if c := src.Chunker; c != nil {
+				cc := cfg.Chunker
+				if c.Type != "" {
+					cc.Type = c.Type
+				}
+				if c.LinesPerChunk != nil {
+					cc.LinesPerChunk = *c.LinesPerChunk
+				}
+				if c.CharsPerChunk != nil {
+					cc.CharsPerChunk = *c.CharsPerChunk
+				}
+				if c.SentencesPerChunk != nil {
+					cc.SentencesPerChunk = *c.SentencesPerChunk
+				}
+				if c.OverlapSentences != nil {
+					cc.OverlapSentences = *c.OverlapSentences
+				}
+				s.Chunker = newChunker(cc)
+			}
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// ingestCorpus indexes the corpus of the file arguments for a command that
+// prints its output once, logging the ingest warnings and exiting when the
+// ingest fails.
+func ingestCorpus(svc *service.RAGServiceImpl, cfg *config.AppConfig, args []string) {
+	report, err := svc.IngestSources(context.Background(), corpusSources(cfg, args), nil)
+	checkIngest(report, err)
+}
+
+// checkIngest exits on a failed ingest and logs the warnings of one that
+// went through.
+func checkIngest(report domain.IngestReport, err error) {
+	switch {
+	case errors.Is(err, service.ErrNoDocuments):
+		log.Fatal(i18n.Sprintf("ingest failed: %v; check the file arguments", err))
+	case errors.Is(err, service.ErrEmbedderUnavailable):
+		log.Fatal(i18n.Sprintf("ingest failed: %v; check the embedder settings", err))
+	case err != nil:
+		log.Fatal(i18n.Sprintf("ingest failed: %v", err))
+	}
+	for _, w := range report.Warnings {
+		log.Print(w)
+	}
+	if report.Skipped > 0 {
+		log.Print(i18n.Sprintf("%d chunks failed to embed and are left out", report.Skipped))
+	}
+}
+
+// watchPatterns lists the files to poll for changes: file arguments,
+// manifests and the file sources they list. URL sources are not polled.
+func watchPatterns(args []string) []string {
+	var out []string
+	for _, arg := range args {
+		out = append(out, arg)
+		if !corpus.IsManifest(arg) {
+			continue
+		}
+		m, err := corpus.Load(arg)
+		if err != nil {
+			log.Fatal(i18n.Sprintf("failed to load corpus manifest: %v", err))
+		}
+		for _, src := range m.Sources {
+			if src.Path != "" {
+				out = append(out, src.Path)
+			}
+		}
+	}
+	return out
+}
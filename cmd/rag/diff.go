@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"rag/internal/domain"
+	"rag/internal/i18n"
+	"rag/internal/loader"
+	"rag/internal/service"
+	"rag/internal/snippet"
+)
+
+// runDiff ingests two versions of a corpus, such as two checkouts of a
+// documentation directory, and reports the chunks that changed, were added
+// or were removed between them.
+func runDiff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	cfgPath := flags.String("config", "", "Path to YAML config file")
+	threshold := flags.Float64("threshold", 0.8, "Minimum similarity for a chunk to count as changed rather than removed and added (0..1)")
+	_ = flags.Parse(args)
+	if flags.NArg() != 2 {
+		fmt.Println(i18n.T("Usage: rag diff [--config=config.yaml] [--threshold=0.8] OLD NEW  (directories, files or glob patterns)"))
+		os.Exit(1)
+	}
+	cfg := loadConfig(*cfgPath)
+	if readOnlyStore(cfg) {
+		log.Fatal(i18n.T("the Qdrant collection is read-only; diff needs a store it can index both versions into"))
+	}
+	oldFiles, newFiles := versionFiles(flags.Arg(0)), versionFiles(flags.Arg(1))
+	// documents maps the files of the old version to their paths within it,
+	// and those of the new version likewise.
+	oldDocuments, newDocuments := make(map[string]string), make(map[string]string)
+	for _, f := range oldFiles {
+		oldDocuments[f.path] = f.document
+	}
+	for _, f := range newFiles {
+		newDocuments[f.path] = f.document
+	}
+
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+	// Both versions go into one index, so that their vectors are
+	// comparable (TF-IDF, for one, is fitted to the corpus).
+	var paths []string
+	for _, f := range append(oldFiles, newFiles...) {
+		paths = append(paths, f.path)
+	}
+	ingestCorpus(svc, cfg, paths)
+	changes, err := svc.CompareVersions(func(ch domain.Chunk) (bool, string) {
+		if doc, ok := oldDocuments[ch.Path]; ok {
+			return true, doc
+		}
+		return false, newDocuments[ch.Path]
+	}, *threshold)
+	if err != nil {
+		log.Fatal(i18n.Sprintf("diff failed: %v", err))
+	}
+	counts := make(map[string]int)
+	for _, c := range changes {
+		counts[c.Kind]++
+		switch c.Kind {
+		case service.ChunkChanged:
+			fmt.Println(i18n.Sprintf("~ %.3f  %s#%d  (was %s#%d)", c.Similarity, c.New.Path, c.New.Index, c.Old.Path, c.Old.Index))
+			fmt.Printf("    - %s\n", snippet.Generate(c.Old.Text, "", nil, snippet.DefaultWidth))
+			fmt.Printf("    + %s\n", snippet.Generate(c.New.Text, "", nil, snippet.DefaultWidth))
+		case service.ChunkAdded:
+			fmt.Printf("+ %s#%d\n", c.New.Path, c.New.Index)
+			fmt.Printf("    %s\n", snippet.Generate(c.New.Text, "", nil, snippet.DefaultWidth))
+		case service.ChunkRemoved:
+			fmt.Printf("- %s#%d\n", c.Old.Path, c.Old.Index)
+			fmt.Printf("    %s\n", snippet.Generate(c.Old.Text, "", nil, snippet.DefaultWidth))
+		}
+	}
+	fmt.Println(i18n.Sprintf("Changed: %d, added: %d, removed: %d, unchanged: %d.", counts[service.ChunkChanged], counts[service.ChunkAdded], counts[service.ChunkRemoved], counts[service.ChunkUnchanged]))
+}
+
+// versionFile is a file of one version of a corpus, with its path relative
+// to the directory given for the version (or its base name).
+type versionFile struct {
+	path, document string
+}
+
+// versionFiles lists the files of one version: the files under a directory,
+// skipping hidden ones, or the files a path or glob pattern matches.
+func versionFiles(arg string) []versionFile {
+	var files []versionFile
+	for _, path := range loader.Expand(arg) {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			files = append(files, versionFile{path, filepath.Base(path)})
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if p != path && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				rel, err := filepath.Rel(path, p)
+				if err != nil {
+					return err
+				}
+				files = append(files, versionFile{p, rel})
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal(i18n.Sprintf("failed to list %s: %v", path, err))
+		}
+	}
+	return files
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"rag/internal/i18n"
+	"rag/internal/service"
+	"rag/internal/snippet"
+)
+
+// runDupes ingests the given files and reports near-duplicate chunks or
+// documents.
+func runDupes(args []string) {
+	fs := flag.NewFlagSet("dupes", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	method := fs.String("method", service.DupMinHash, "Similarity method: minhash or embedding")
+	threshold := fs.Float64("threshold", 0.9, "Minimum similarity to report (0..1)")
+	byDocument := fs.Bool("documents", false, "Compare whole documents instead of chunks")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Println(i18n.T("Usage: rag dupes [--config=config.yaml] [--method=minhash|embedding] [--threshold=0.9] [--documents] file1.txt [file2.txt ...]"))
+		os.Exit(1)
+	}
+
+	cfg := loadConfig(*cfgPath)
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+	ingestCorpus(svc, cfg, fs.Args())
+	dupes, err := svc.Duplicates(*method, *threshold, *byDocument)
+	if err != nil {
+		log.Fatal(i18n.Sprintf("duplicate detection failed: %v", err))
+	}
+	if len(dupes) == 0 {
+		fmt.Println(i18n.T("No duplicates found."))
+		return
+	}
+	for _, d := range dupes {
+		if *byDocument {
+			fmt.Printf("%.3f  %s  <->  %s\n", d.Similarity, d.PathA, d.PathB)
+			continue
+		}
+		fmt.Printf("%.3f  %s#%d  <->  %s#%d\n", d.Similarity, d.PathA, d.ChunkA.Index, d.PathB, d.ChunkB.Index)
+		fmt.Printf("       %s\n", snippet.Generate(d.ChunkA.Text, "", nil, 100))
+		fmt.Printf("       %s\n", snippet.Generate(d.ChunkB.Text, "", nil, 100))
+	}
+	fmt.Println(i18n.Sprintf("%d duplicate pairs.", len(dupes)))
+}
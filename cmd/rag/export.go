@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"rag/internal/i18n"
+	"rag/internal/langchain"
+)
+
+// runExport chunks the given files and writes the chunks as JSON Lines of
+// LangChain Documents, for use in Python RAG stacks. Nothing is embedded.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	out := fs.String("out", "", "Output file (default: stdout)")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Println(i18n.T("Usage: rag export [--config=config.yaml] [--out=corpus.jsonl] file1.txt [file2.txt ...]"))
+		os.Exit(1)
+	}
+
+	cfg := loadConfig(*cfgPath)
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+	chunks, err := svc.ChunkSources(context.Background(), corpusSources(cfg, fs.Args()))
+	if err != nil {
+		log.Fatal(i18n.Sprintf("load failed: %v", err))
+	}
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := langchain.Write(w, chunks); err != nil {
+		log.Fatal(i18n.Sprintf("export failed: %v", err))
+	}
+	if *out != "" {
+		log.Print(i18n.Sprintf("exported %d chunks to %s", len(chunks), *out))
+	}
+}
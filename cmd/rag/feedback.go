@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"rag/internal/feedback"
+	"rag/internal/i18n"
+)
+
+// runFeedback exports the relevance judgments made in the TUI for the index
+// of the given files as JSON Lines, one query per line with the passages
+// judged relevant and not relevant, for evaluating or tuning the ranking.
+func runFeedback(args []string) {
+	fs := flag.NewFlagSet("feedback", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	out := fs.String("out", "", "Output file (default: stdout)")
+	_ = fs.Parse(args)
+	inputs := fs.Args()
+	cfg := loadConfig(*cfgPath)
+	if len(inputs) == 0 && !readOnlyStore(cfg) {
+		fmt.Println(i18n.T("Usage: rag feedback [--config=config.yaml] [--out=feedback.jsonl] file1.txt [file2.txt ...]"))
+		os.Exit(1)
+	}
+	judgments, err := feedbackStore(cfg, inputs).List()
+	if err != nil {
+		log.Fatal(i18n.Sprintf("failed to load feedback: %v", err))
+	}
+	if len(judgments) == 0 {
+		log.Print(i18n.T("No feedback. Press Enter on a result in the TUI and mark it relevant (+) or not (-)."))
+		return
+	}
+	examples := feedback.Examples(judgments)
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := feedback.WriteExamples(w, examples); err != nil {
+		log.Fatal(i18n.Sprintf("export failed: %v", err))
+	}
+	if *out != "" {
+		log.Print(i18n.Sprintf("exported %d judgments of %d queries to %s", len(judgments), len(examples), *out))
+	}
+}
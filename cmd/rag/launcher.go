@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"rag/internal/domain"
+	"rag/internal/i18n"
+	"rag/internal/queryparse"
+	"rag/internal/snippet"
+)
+
+// launcherOutputs are the --output formats of rag query for desktop
+// launchers, which run it once per query and list what it prints.
+var launcherOutputs = map[string]bool{"alfred": true, "rofi": true}
+
+// alfredItem is a result as an Alfred Script Filter lists it: the snippet
+// as title, the location and score as subtitle, and the file to open as
+// arg.
+type alfredItem struct {
+	Title        string            `json:"title"`
+	Subtitle     string            `json:"subtitle"`
+	Arg          string            `json:"arg"`
+	Valid        *bool             `json:"valid,omitempty"`
+	Type         string            `json:"type,omitempty"`
+	QuicklookURL string            `json:"quicklookurl,omitempty"`
+	Text         *alfredText       `json:"text,omitempty"`
+	Variables    map[string]string `json:"variables,omitempty"`
+}
+
+// alfredText is what Alfred copies (⌘C) and shows in large type (⌘L).
+type alfredText struct {
+	Copy      string `json:"copy"`
+	LargeType string `json:"largetype"`
+}
+
+// writeLauncher writes results in a launcher's format: alfred for the JSON
+// of an Alfred Script Filter, which Raycast script commands and other
+// Alfred-compatible launchers read too, or rofi for lines of tab-separated
+// snippet, location, file and line, of which rofi -dmenu shows the first two.
+func writeLauncher(w io.Writer, format string, results []domain.SearchResult, query string) {
+	terms := queryparse.Terms(query)
+	switch format {
+	case "alfred":
+		items := make([]alfredItem, 0, len(results))
+		for _, r := range results {
+			ch := r.Chunk
+			target := launcherTarget(ch.Path)
+			item := alfredItem{
+				Title:     launcherLine(snippet.Generate(ch.Text, terms, nil, snippet.DefaultWidth)),
+				Subtitle:  fmt.Sprintf("%s  %.3f", ch.Location(), r.Score),
+				Arg:       target,
+				Text:      &alfredText{Copy: ch.Location(), LargeType: ch.Text},
+				Variables: map[string]string{"path": target},
+			}
+			if ch.StartLine > 0 {
+				item.Variables["line"] = strconv.Itoa(ch.StartLine)
+			}
+			if !isURL(ch.Path) {
+				item.Type = "file"
+				item.QuicklookURL = target
+			}
+			items = append(items, item)
+		}
+		if len(items) == 0 {
+			// Alfred shows its fallback searches for an empty list, as if
+			// the script had failed.
+			invalid := false
+			items = append(items, alfredItem{Title: i18n.T("No results."), Subtitle: query, Valid: &invalid})
+		}
+		encodeJSON(w, map[string]any{"items": items})
+	case "rofi":
+		bw := bufio.NewWriter(w)
+		for _, r := range results {
+			ch := r.Chunk
+			line := ""
+			if ch.StartLine > 0 {
+				line = strconv.Itoa(ch.StartLine)
+			}
+			fmt.Fprintf(bw, "%s\t%s\t%s\t%s\n", launcherLine(snippet.Generate(ch.Text, terms, nil, snippet.DefaultWidth)), ch.Location(), launcherTarget(ch.Path), line)
+		}
+		if err := bw.Flush(); err != nil {
+			log.Fatal(i18n.Sprintf("write failed: %v", err))
+		}
+	}
+}
+
+// launcherLine puts text on one line without tabs, as launchers list it.
+func launcherLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// launcherTarget is what a launcher opens for a result at path: the
+// absolute path of a file, since launchers do not run in the directory the
+// corpus was indexed from, or the URL of a fetched page.
+func launcherTarget(path string) string {
+	if isURL(path) {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
+
+// isURL reports whether a document's path is the URL it was fetched from.
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
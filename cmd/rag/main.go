@@ -1,117 +1,686 @@
 package main
 
 import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/joho/godotenv"
 
+	"rag/internal/bookmark"
 	"rag/internal/chunker"
 	"rag/internal/config"
+	"rag/internal/conversation"
+	"rag/internal/decompose"
 	"rag/internal/domain"
 	"rag/internal/embedding"
 	"rag/internal/embedding/openai"
 	"rag/internal/embedding/tfidf"
+	"rag/internal/enrich"
+	"rag/internal/feedback"
+	"rag/internal/generator"
+	"rag/internal/grounding"
+	"rag/internal/i18n"
+	"rag/internal/llm"
+	"rag/internal/loader"
+	"rag/internal/opener"
+	"rag/internal/ordering"
+	"rag/internal/paths"
+	"rag/internal/retriever/bm25"
+	"rag/internal/roots"
+	"rag/internal/savedsearch"
 	"rag/internal/service"
 	"rag/internal/summarizer"
+	"rag/internal/textlog"
+	"rag/internal/textutil"
 	"rag/internal/tui"
 	"rag/internal/vectorstore"
+	"rag/internal/vectorstore/disk"
 	"rag/internal/vectorstore/memory"
 	"rag/internal/vectorstore/qdrant"
 )
 
+// commands maps subcommand names to their entrypoints. Anything else on the
+// command line is treated as input files for the interactive search.
+var commands = map[string]func(args []string){
+	"retry-failed":   runRetryFailed,
+	"bench-models":   runBenchModels,
+	"bookmarks":      runBookmarks,
+	"conversations":  runConversations,
+	"feedback":       runFeedback,
+	"diff":           runDiff,
+	"dupes":          runDupes,
+	"export":         runExport,
+	"open":           runOpen,
+	"profile-ingest": runProfileIngest,
+	"purge":          runPurge,
+	"query":          runQuery,
+	"rpc":            runRPC,
+	"similar":        runSimilar,
+	"sweep":          runSweep,
+	"tune":           runTune,
+	"verify":         runVerify,
+	"watch":          runWatch,
+}
+
 func main() {
 	_ = godotenv.Load()
 
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			run(os.Args[2:])
+			return
+		}
+	}
+
 	var cfgPath string
-	flag.StringVar(&cfgPath, "config", "", "Path to YAML config file (optional; uses ~/.config/rag/config.yaml if not provided)")
+	flag.StringVar(&cfgPath, "config", "", "Path to YAML config file (optional; uses ~/.config/rag/config.yaml, or %APPDATA%\\rag\\config.yaml on Windows, if not provided)")
+	pprofAddr := flag.String("pprof", "", "Serve net/http/pprof on this address (e.g. :6060) while rag runs")
+	noTUI := flag.Bool("no-tui", false, "Plain prompt-and-print search without the full-screen interface, for screen readers and dumb terminals (default when TERM=dumb)")
 	flag.Parse()
 	inputs := flag.Args()
-	if len(inputs) == 0 {
-		fmt.Println("Usage: rag [--config=config.yaml] file1.txt [file2.txt ...]")
+	cfg := loadConfig(cfgPath)
+	if len(inputs) == 0 && !readOnlyStore(cfg) {
+		fmt.Println(i18n.T(`Usage: rag [--config=config.yaml] [--no-tui] [--pprof=:6060] file1.txt [file2.txt ...]
+       rag retry-failed [--config=config.yaml] files...
+       rag dupes [--method=minhash|embedding] [--threshold=0.9] [--documents] files...
+       rag query [--q=text | --saved=name] [--top-k=10] files...
+       rag similar [--passage=passage.txt] [--top-k=10] [--output=text|json] files...
+       rag diff [--threshold=0.8] OLD NEW
+       rag export [--out=corpus.jsonl] files...
+       rag rpc [--pprof=:6060] files...
+       rag open FILE [LINE]
+       rag bookmarks files...
+       rag conversations [--export=ID [--out=notes.md]] [--delete=ID] files...
+       rag feedback [--out=feedback.jsonl] files...
+       rag tune [--top-k=10] [--dry-run] files...
+       rag verify [--repair] files...
+       rag purge [--older-than=720h] [--dry-run]
+       rag watch [--interval=5s] [--threshold=0.3] [--notify] [--webhook=URL] [--pprof=:6060] files...
+       rag bench-models [--models=a,b,c] [--sample=300] [--queries=50] files...
+       rag sweep [--sentences=3,5,8] [--overlap=0,1,2] [--queries=feedback.jsonl] [--parallel=4] files...
+       rag profile-ingest [--cpu=cpu.pprof] [--heap=heap.pprof] files...`))
 		os.Exit(1)
 	}
+	checkReadOnlyInputs(cfg, inputs)
+	servePprof(*pprofAddr)
+
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+	sources := corpusSources(cfg, inputs)
+	// The ingest runs behind the TUI's progress screen, where it can be
+	// canceled; it must not log while the TUI owns the terminal. A read-only
+	// collection is searched as it is.
+	var ingest tui.IngestFunc
+	if !readOnlyStore(cfg) {
+		ingest = func(ctx context.Context, progress func(domain.IngestProgress)) (domain.IngestReport, error) {
+			report, err := svc.IngestSources(ctx, sources, progress)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				return report, err
+			}
+			if serr := service.SaveFailedChunks(failedChunksPath(cfg, inputs), svc.FailedChunks()); serr != nil {
+				report.Warnings = append(report.Warnings, i18n.Sprintf("failed chunks not recorded: %v", serr))
+			}
+			if report.Skipped > 0 {
+				report.Warnings = append(report.Warnings, i18n.Sprintf("%d chunks failed to embed (rag retry-failed)", report.Skipped))
+			}
+			return report, err
+		}
+	}
+
+	if err := opener.Check(cfg.OpenCommand); err != nil {
+		log.Fatal(i18n.Sprintf("invalid config: %v", err))
+	}
+	if *noTUI || os.Getenv("TERM") == "dumb" {
+		runREPL(svc, cfg, ingest, conversations(cfg, inputs), os.Stdin, os.Stdout)
+		return
+	}
+
+	saved := savedSearches(cfg, inputs)
+	var answer tui.AnswerFunc
+	if gen := answerer(cfg, svc); gen != nil {
+		answer = gen.Stream
+	}
+	m := tui.New(svc, "", tui.Config{
+		Ingest:          ingest,
+		Saved:           saved,
+		GroupByDocument: cfg.Search.GroupByDocument,
+		Order:           resultOrder(cfg.Search.Order),
+		TerminalBidi:    cfg.TUI.TerminalBidi,
+		WrapColumn:      cfg.TUI.WrapColumn,
+		HangingIndent:   cfg.TUI.HangingIndent,
+		Backend:         backendName(cfg),
+		TopK:            cfg.Search.TopK,
+		Bookmarks:       bookmarks(cfg, inputs),
+		Feedback:        feedbackStore(cfg, inputs),
+		Translate:       translator(cfg, cfg.Search.TranslateTo),
+		TranslateTo:     cfg.Search.TranslateTo,
+		Answer:          answer,
+		OpenCommand:     cfg.OpenCommand,
+	})
+	if _, err := tea.NewProgram(m).Run(); err != nil {
+		log.Fatal(err)
+	}
+}
 
+func loadConfig(path string) *config.AppConfig {
 	var cfg *config.AppConfig
 	var err error
-	if cfgPath == "" {
+	if path == "" {
 		cfg, _, err = config.LoadDefault()
 	} else {
-		cfg, err = config.Load(cfgPath)
+		cfg, err = config.Load(path)
 	}
 	if err != nil {
-		log.Fatalf("failed to load config: %v", err)
+		log.Fatal(i18n.Sprintf("failed to load config: %v", err))
+	}
+	if err := i18n.SetLanguage(cfg.TUI.Language); err != nil {
+		log.Print(i18n.Sprintf("warning: %v", err))
 	}
+	return cfg
+}
 
-	// Assemble components
-	var emb embedding.Embedder
-	switch cfg.Embedder.Type {
-	case "tfidf", "":
-		emb = tfidf.NewEmbedder()
-	case "openai":
-		if cfg.Embedder.OpenAI == nil {
-			log.Fatalf("openai embedder config missing")
+// buildService assembles the configured components into a RAG service.
+// The returned cleanup function releases on-disk resources.
+func buildService(cfg *config.AppConfig) (*service.RAGServiceImpl, func()) {
+	textutil.SetNormalization(textutil.Normalization{NFKC: cfg.Normalize.NFKC, FoldDiacritics: cfg.Normalize.FoldDiacritics})
+	var closers []io.Closer
+	cleanup := func() {
+		for _, c := range closers {
+			_ = c.Close()
 		}
-		client, err := openai.NewClient(openai.Config{
-			BaseURL:   cfg.Embedder.OpenAI.BaseURL,
-			APIKeyEnv: cfg.Embedder.OpenAI.APIKeyEnv,
-			Model:     cfg.Embedder.OpenAI.Model,
-			Timeout:   time.Duration(cfg.Embedder.OpenAI.TimeoutSecs) * time.Second,
-		})
+	}
+	newTextLog := func() *textlog.Log {
+		dir, err := paths.TextLogDir()
 		if err != nil {
-			log.Fatalf("openai embedder init failed: %v", err)
+			log.Fatal(i18n.Sprintf("failed to resolve cache directory: %v", err))
 		}
-		emb = client
-	default:
-		log.Fatalf("unknown embedder: %s", cfg.Embedder.Type)
+		l, err := textlog.Create(dir, cfg.VectorStore.CompressText)
+		if err != nil {
+			log.Fatal(i18n.Sprintf("failed to create text log: %v", err))
+		}
+		closers = append(closers, l)
+		return l
 	}
 
-	var ch domain.Chunker
-	switch cfg.Chunker.Type {
-	case "sentence", "":
-		ch = chunker.NewSentenceChunker(cfg.Chunker.SentencesPerChunk, cfg.Chunker.OverlapSentences)
-	default:
-		log.Fatalf("unknown chunker: %s", cfg.Chunker.Type)
+	emb := newEmbedder(cfg.Embedder)
+	ch := newChunker(cfg.Chunker)
+	loaders := loader.Default(loader.Config{
+		ChatWindow:         time.Duration(cfg.Loaders.ChatWindowMinutes) * time.Minute,
+		ChatWindowMessages: cfg.Loaders.ChatWindowMessages,
+	})
+	for _, o := range cfg.Chunker.Overrides {
+		loaders.SetChunker(o.Pattern, newChunker(o.Apply(cfg.Chunker)))
+	}
+
+	// memoryConfig configures the memory store, which the disk store
+	// searches in too.
+	memoryConfig := func() memory.Config {
+		mcfg := memory.Config{
+			IVFLists:  cfg.VectorStore.IVF.Lists,
+			IVFProbes: cfg.VectorStore.IVF.Probes,
+			Distance:  cfg.VectorStore.Distance,
+		}
+		switch cfg.VectorStore.Index {
+		case "flat", "":
+		case "ivf":
+			mcfg.IVF = true
+		default:
+			log.Fatal(i18n.Sprintf("unknown vector index: %s", cfg.VectorStore.Index))
+		}
+		if cfg.VectorStore.TextOnDisk {
+			mcfg.TextLog = newTextLog()
+		}
+		return mcfg
 	}
 
 	var st vectorstore.Storage
 	switch cfg.VectorStore.Type {
 	case "memory", "":
-		st = memory.NewStorage()
+		ms, err := memory.NewStorageWithConfig(memoryConfig())
+		if err != nil {
+			log.Fatal(i18n.Sprintf("memory store init failed: %v", err))
+		}
+		st = ms
+	case "disk":
+		dcfg := disk.Config{CompressText: cfg.VectorStore.CompressText, Memory: memoryConfig()}
+		if cfg.VectorStore.Disk != nil {
+			dcfg.Path = cfg.VectorStore.Disk.Path
+		}
+		if dcfg.Path == "" {
+			path, err := paths.VectorDB()
+			if err != nil {
+				log.Fatal(i18n.Sprintf("failed to resolve data directory: %v", err))
+			}
+			dcfg.Path = path
+		}
+		ds, err := disk.Open(dcfg)
+		if err != nil {
+			log.Fatal(i18n.Sprintf("disk store init failed: %v", err))
+		}
+		closers = append(closers, ds)
+		st = ds
 	case "qdrant":
 		if cfg.VectorStore.Qdrant == nil {
-			log.Fatalf("qdrant config missing")
+			log.Fatal(i18n.T("qdrant config missing"))
 		}
 		qcfg := qdrant.Config{
-			URL:        cfg.VectorStore.Qdrant.URL,
-			APIKey:     cfg.VectorStore.Qdrant.APIKey,
-			Collection: cfg.VectorStore.Qdrant.Collection,
+			URL:            cfg.VectorStore.Qdrant.URL,
+			APIKey:         cfg.VectorStore.Qdrant.APIKey,
+			Collection:     cfg.VectorStore.Qdrant.Collection,
+			CompressText:   cfg.VectorStore.CompressText,
+			PayloadMapping: cfg.VectorStore.Qdrant.PayloadMapping,
+			ReadOnly:       cfg.VectorStore.Qdrant.ReadOnly,
+			Distance:       cfg.VectorStore.Distance,
 		}
-		st = qdrant.NewStorage(qcfg)
+		qs, err := qdrant.NewStorage(qcfg)
+		if err != nil {
+			log.Fatal(i18n.Sprintf("qdrant init failed: %v", err))
+		}
+		st = qs
 	default:
-		log.Fatalf("unknown vector store: %s", cfg.VectorStore.Type)
+		log.Fatal(i18n.Sprintf("unknown vector store: %s", cfg.VectorStore.Type))
 	}
 
 	var sum domain.Summarizer
 	switch cfg.Summarizer.Type {
 	case "frequency", "":
-		sum = summarizer.NewFrequencySummarizer()
+		sum = summarizer.NewFrequencySummarizer(summarizer.Config{Language: cfg.Summarizer.Language})
 	default:
-		log.Fatalf("unknown summarizer: %s", cfg.Summarizer.Type)
+		log.Fatal(i18n.Sprintf("unknown summarizer: %s", cfg.Summarizer.Type))
+	}
+	switch cfg.Summarizer.Strategy {
+	case "hierarchical":
+		sum = summarizer.NewHierarchical(sum, cfg.Summarizer.Workers)
+	case "flat":
+	default:
+		log.Fatal(i18n.Sprintf("unknown summarizer strategy: %s", cfg.Summarizer.Strategy))
 	}
 
-	svc := service.NewRAGService(ch, emb, st, sum, cfg.Summarizer.MaxSentences)
-	summary, err := svc.IngestDocuments(inputs)
+	svcCfg := service.Config{
+		SummaryBudget:       summaryBudget(cfg.Summarizer),
+		FailureThreshold:    cfg.Ingest.FailureThreshold,
+		SoftDelete:          cfg.Ingest.SoftDelete,
+		HydrateFromSource:   cfg.VectorStore.HydrateFromSource,
+		KeywordsPerDocument: cfg.Keywords.PerDocument,
+		KeywordPayloads:     cfg.Keywords.Payloads,
+		TopicCount:          cfg.Topics.Count,
+		MaxPerDocument:      cfg.Search.MaxPerDocument,
+		LinkBoost:           cfg.Search.LinkBoost,
+		DetectLanguage:      cfg.Loaders.DetectLanguage,
+		EmbedWorkers:        cfg.Embedder.Concurrency,
+		Scorer:              scorer(cfg.Search),
+		Loaders:             loaders,
+	}
+	allowed, err := roots.New(cfg.Ingest.AllowedRoots)
 	if err != nil {
-		log.Fatalf("ingest failed: %v", err)
+		log.Fatal(i18n.Sprintf("invalid ingest.allowed_roots: %v", err))
+	}
+	svcCfg.Roots = allowed
+	switch cfg.Search.LexicalScoring {
+	case "ochiai", "":
+	case "bm25":
+		svcCfg.LexicalBM25 = true
+	default:
+		log.Fatal(i18n.Sprintf("unknown lexical scoring: %s", cfg.Search.LexicalScoring))
+	}
+	// Queries may ask for HyDE with hyde:on whenever a model is configured.
+	switch {
+	case cfg.LLM != nil:
+		svcCfg.HyDE = cfg.Search.HyDE
+		svcCfg.Hypothesize = newLLM(cfg.LLM).Hypothesize
+	case cfg.Search.HyDE:
+		log.Fatal(i18n.T("search.hyde needs an llm section in the config"))
+	}
+	switch cfg.Search.Strategy {
+	case "single", "":
+	case "decompose":
+		svcCfg.Decompose = decomposer(cfg)
+	default:
+		log.Fatal(i18n.Sprintf("unknown search strategy: %s", cfg.Search.Strategy))
 	}
+	if cfg.Enrich.Template != "" {
+		svcCfg.Enricher = newEnricher(cfg)
+		svcCfg.EnrichWorkers = cfg.Enrich.Workers
+	}
+	switch cfg.Retrieval.Mode {
+	case "vector", "":
+	case "hybrid":
+		svcCfg.Hybrid = true
+		svcCfg.RRFK = cfg.Retrieval.RRFK
+	case "bm25":
+		svcCfg.BM25Only = true
+	default:
+		log.Fatal(i18n.Sprintf("unknown retrieval mode: %s", cfg.Retrieval.Mode))
+	}
+	svcCfg.BM25 = bm25Params(cfg.Retrieval.BM25)
+	if cfg.VectorStore.TextOnDisk && !cfg.VectorStore.HydrateFromSource {
+		svcCfg.TextLog = newTextLog()
+	}
+	return service.NewRAGService(ch, emb, st, sum, svcCfg), cleanup
+}
 
-	m := tui.New(svc, summary)
-	if _, err := tea.NewProgram(m).Run(); err != nil {
+// bm25Params returns the configured BM25 parameters, with defaults for
+// those unset.
+func bm25Params(c config.BM25Config) *bm25.Params {
+	p := bm25.DefaultParams()
+	if c.K1 != nil {
+		p.K1 = *c.K1
+	}
+	if c.B != nil {
+		p.B = *c.B
+	}
+	if err := p.Validate(); err != nil {
+		log.Fatal(i18n.Sprintf("invalid retrieval.bm25 config: %v", err))
+	}
+	return &p
+}
+
+// decomposer returns a function splitting queries into the questions they
+// ask, with the llm model when one is configured and by rules otherwise.
+func decomposer(cfg *config.AppConfig) func(ctx context.Context, query string) ([]string, error) {
+	if cfg.LLM == nil {
+		return func(_ context.Context, query string) ([]string, error) {
+			return decompose.Split(query), nil
+		}
+	}
+	client := newLLM(cfg.LLM)
+	return func(ctx context.Context, query string) ([]string, error) {
+		return client.Decompose(ctx, query, decompose.MaxQuestions)
+	}
+}
+
+// newEnricher creates the configured chunk enricher, with the llm model
+// and the context cache when the template has {context}.
+func newEnricher(cfg *config.AppConfig) *enrich.Enricher {
+	ecfg := enrich.Config{Template: cfg.Enrich.Template, MaxDocumentChars: cfg.Enrich.MaxDocumentChars}
+	if strings.Contains(cfg.Enrich.Template, "{"+enrich.Context+"}") {
+		if cfg.LLM == nil {
+			log.Fatal(i18n.T("{context} in enrich.template needs an llm section in the config"))
+		}
+		client := newLLM(cfg.LLM)
+		ecfg.Generate, ecfg.Model = client.Situate, client.Model()
+		path, err := paths.ChunkContexts()
+		if err != nil {
+			log.Fatal(i18n.Sprintf("failed to resolve cache directory: %v", err))
+		}
+		if ecfg.Cache, err = enrich.OpenCache(path); err != nil {
+			log.Fatal(i18n.Sprintf("failed to read the chunk context cache: %v", err))
+		}
+	}
+	e, err := enrich.New(ecfg)
+	if err != nil {
+		log.Fatal(i18n.Sprintf("invalid enrich config: %v", err))
+	}
+	return e
+}
+
+// answerer returns the generator of answers with the configured LLM, or nil
+// when none is configured. Similarity grounding embeds with svc.
+func answerer(cfg *config.AppConfig, svc *service.RAGServiceImpl) *generator.Generator {
+	if cfg.LLM == nil {
+		return nil
+	}
+	client := newLLM(cfg.LLM)
+	gcfg := generator.Config{
+		Sources:        cfg.Generator.Sources,
+		MaxSourceChars: cfg.Generator.MaxSourceChars,
+		Options: llm.Options{
+			Model:       cfg.Generator.Model,
+			Temperature: cfg.Generator.Temperature,
+			MaxTokens:   cfg.Generator.MaxTokens,
+		},
+	}
+	if err := gcfg.Options.Validate(); err != nil {
+		log.Fatal(i18n.Sprintf("invalid generator config: %v", err))
+	}
+	switch cfg.Generator.Grounding {
+	case "similarity", "":
+		gcfg.Checker = grounding.Similarity{Embed: svc.Embed, Threshold: cfg.Generator.GroundingThreshold}
+	case "llm":
+		gcfg.Checker = grounding.Model{Chat: client.Chat}
+	case "off":
+	default:
+		log.Fatal(i18n.Sprintf("unknown grounding check: %s", cfg.Generator.Grounding))
+	}
+	return generator.New(client, gcfg)
+}
+
+// translator returns a function translating text into lang with the
+// configured LLM, or nil when lang is empty. It exits when no LLM is
+// configured.
+func translator(cfg *config.AppConfig, lang string) func(ctx context.Context, text string) (string, error) {
+	if lang == "" {
+		return nil
+	}
+	if cfg.LLM == nil {
+		log.Fatal(i18n.T("translating results needs an llm section in the config"))
+	}
+	client := newLLM(cfg.LLM)
+	return func(ctx context.Context, text string) (string, error) {
+		return client.Translate(ctx, text, lang)
+	}
+}
+
+// newLLM creates the configured chat model client.
+func newLLM(cfg *config.LLMConfig) *llm.Client {
+	client, err := llm.NewClient(llm.Config{
+		BaseURL:    cfg.BaseURL,
+		APIKeyEnv:  cfg.APIKeyEnv,
+		Model:      cfg.Model,
+		Timeout:    time.Duration(cfg.TimeoutSecs) * time.Second,
+		MaxRetries: cfg.MaxRetries,
+	})
+	if err != nil {
+		log.Fatal(i18n.Sprintf("llm init failed: %v", err))
+	}
+	return client
+}
+
+// resultOrder parses the order of query results, exiting on unknown ones.
+func resultOrder(name string) ordering.Order {
+	order, err := ordering.Parse(name)
+	if err != nil {
 		log.Fatal(err)
 	}
+	return order
+}
+
+// scorer builds the ranking scorer from the search weights.
+func scorer(cfg config.SearchConfig) service.Scorer {
+	w := service.WeightedScorer{
+		Vector:  1,
+		Lexical: cfg.Weights.Lexical,
+		Recency: cfg.Weights.Recency,
+		Links:   cfg.LinkBoost,
+	}
+	if cfg.Weights.Vector != nil {
+		w.Vector = *cfg.Weights.Vector
+	}
+	return w
+}
+
+// readOnlyStore reports whether the configured store is a Qdrant collection
+// populated by another pipeline, which rag searches without ingesting.
+func readOnlyStore(cfg *config.AppConfig) bool {
+	return cfg.VectorStore.Type == "qdrant" && cfg.VectorStore.Qdrant != nil && cfg.VectorStore.Qdrant.ReadOnly
+}
+
+// checkReadOnlyInputs exits when input files are given for a read-only
+// collection, since they could not be indexed.
+func checkReadOnlyInputs(cfg *config.AppConfig, inputs []string) {
+	if len(inputs) > 0 && readOnlyStore(cfg) {
+		log.Fatal(i18n.T("the Qdrant collection is read-only; run without input files to search it"))
+	}
+}
+
+// backendName describes the configured embedder and vector store.
+func backendName(cfg *config.AppConfig) string {
+	embedder := cfg.Embedder.Type
+	if embedder == "" {
+		embedder = "tfidf"
+	}
+	if embedder == "openai" && cfg.Embedder.OpenAI != nil && cfg.Embedder.OpenAI.Model != "" {
+		embedder += "/" + cfg.Embedder.OpenAI.Model
+	}
+	store := cfg.VectorStore.Type
+	if store == "" {
+		store = "memory"
+	}
+	if store == "qdrant" && cfg.VectorStore.Qdrant != nil && cfg.VectorStore.Qdrant.Collection != "" {
+		store += "/" + cfg.VectorStore.Qdrant.Collection
+	}
+	return embedder + " · " + store
+}
+
+// newEmbedder creates the configured embedder.
+func newEmbedder(cfg config.EmbedderConfig) embedding.Embedder {
+	switch cfg.Type {
+	case "tfidf", "":
+		return tfidf.NewEmbedder()
+	case "openai":
+		if cfg.OpenAI == nil {
+			log.Fatal(i18n.T("openai embedder config missing"))
+		}
+		client, err := openai.NewClient(openai.Config{
+			BaseURL:           cfg.OpenAI.BaseURL,
+			APIKeyEnv:         cfg.OpenAI.APIKeyEnv,
+			Model:             cfg.OpenAI.Model,
+			Timeout:           time.Duration(cfg.OpenAI.TimeoutSecs) * time.Second,
+			MaxInputTokens:    cfg.OpenAI.MaxInputTokens,
+			Overflow:          cfg.OpenAI.Overflow,
+			MaxRetries:        cfg.OpenAI.MaxRetries,
+			BatchSize:         cfg.OpenAI.BatchSize,
+			RequestsPerMinute: cfg.OpenAI.RequestsPerMinute,
+		})
+		if err != nil {
+			log.Fatal(i18n.Sprintf("openai embedder init failed: %v", err))
+		}
+		return client
+	default:
+		log.Fatal(i18n.Sprintf("unknown embedder: %s", cfg.Type))
+		return nil
+	}
+}
+
+// newChunker creates the configured chunker.
+func newChunker(cfg config.ChunkerConfig) domain.Chunker {
+	switch cfg.Type {
+	case "sentence", "":
+		return chunker.NewSentenceChunker(cfg.SentencesPerChunk, cfg.OverlapSentences)
+	case "code":
+		return chunker.NewCodeChunker(cfg.LinesPerChunk)
+	case "markdown":
+		return chunker.NewMarkdownChunker(cfg.CharsPerChunk)
+	default:
+		log.Fatal(i18n.Sprintf("unknown chunker: %s", cfg.Type))
+		return nil
+	}
+}
+
+// summaryBudget converts the summarizer length settings into a budget.
+func summaryBudget(cfg config.SummarizerConfig) domain.Budget {
+	switch unit := domain.BudgetUnit(cfg.BudgetUnit); unit {
+	case "", domain.BudgetSentences:
+		size := cfg.Budget
+		if size == 0 {
+			size = cfg.MaxSentences
+		}
+		return domain.Budget{Unit: domain.BudgetSentences, Size: size}
+	case domain.BudgetWords, domain.BudgetTokens:
+		return domain.Budget{Unit: unit, Size: cfg.Budget}
+	default:
+		log.Fatal(i18n.Sprintf("unknown summary budget unit: %s", cfg.BudgetUnit))
+		return domain.Budget{}
+	}
+}
+
+// indexKey names the index for per-index state such as saved searches.
+// Qdrant indexes are keyed by collection, in-memory indexes by the set of
+// input paths.
+func indexKey(cfg *config.AppConfig, inputs []string) string {
+	if cfg.VectorStore.Type == "qdrant" && cfg.VectorStore.Qdrant != nil {
+		return "qdrant-" + cfg.VectorStore.Qdrant.Collection
+	}
+	abs := make([]string, len(inputs))
+	for i, in := range inputs {
+		if a, err := filepath.Abs(in); err == nil {
+			abs[i] = a
+		} else {
+			abs[i] = in
+		}
+	}
+	sort.Strings(abs)
+	h := sha1.Sum([]byte(strings.Join(abs, "\n")))
+	return "files-" + hex.EncodeToString(h[:8])
+}
+
+// savedSearches opens the saved searches of the index.
+func savedSearches(cfg *config.AppConfig, inputs []string) *savedsearch.Store {
+	path, err := paths.SavedSearches(indexKey(cfg, inputs))
+	if err != nil {
+		log.Fatal(i18n.Sprintf("failed to resolve data directory: %v", err))
+	}
+	return savedsearch.NewStore(path)
+}
+
+// bookmarks opens the bookmarks of the index.
+func bookmarks(cfg *config.AppConfig, inputs []string) *bookmark.Store {
+	path, err := paths.Bookmarks(indexKey(cfg, inputs))
+	if err != nil {
+		log.Fatal(i18n.Sprintf("failed to resolve data directory: %v", err))
+	}
+	return bookmark.NewStore(path)
+}
+
+// conversations opens the conversations of the index.
+func conversations(cfg *config.AppConfig, inputs []string) *conversation.Store {
+	path, err := paths.Conversations(indexKey(cfg, inputs))
+	if err != nil {
+		log.Fatal(i18n.Sprintf("failed to resolve data directory: %v", err))
+	}
+	return conversation.NewStore(path)
+}
+
+// feedbackStore opens the result judgments of the index.
+func feedbackStore(cfg *config.AppConfig, inputs []string) *feedback.Store {
+	path, err := paths.Feedback(indexKey(cfg, inputs))
+	if err != nil {
+		log.Fatal(i18n.Sprintf("failed to resolve data directory: %v", err))
+	}
+	return feedback.NewStore(path)
+}
+
+// failedChunksPath returns the record of the chunks of the index that
+// failed to embed.
+func failedChunksPath(cfg *config.AppConfig, inputs []string) string {
+	path, err := paths.FailedChunks(indexKey(cfg, inputs))
+	if err != nil {
+		log.Fatal(i18n.Sprintf("failed to resolve data directory: %v", err))
+	}
+	return path
+}
+
+// recordFailures persists chunks of the index that could not be embedded
+// so that `rag retry-failed` can pick them up later.
+func recordFailures(cfg *config.AppConfig, inputs []string, failed []service.FailedChunk) {
+	path := failedChunksPath(cfg, inputs)
+	if err := service.SaveFailedChunks(path, failed); err != nil {
+		log.Print(i18n.Sprintf("failed to record failed chunks: %v", err))
+		return
+	}
+	if len(failed) > 0 {
+		log.Print(i18n.Sprintf("%d chunks failed to embed and were skipped; run `rag retry-failed` to re-attempt them", len(failed)))
+	}
 }
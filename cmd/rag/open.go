@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"rag/internal/i18n"
+	"rag/internal/opener"
+	"rag/internal/roots"
+)
+
+// runOpen opens a file at a line with the configured open_command, or in
+// $VISUAL or $EDITOR, for launchers acting on a result of rag query
+// --output=alfred or rofi.
+func runOpen(args []string) {
+	fs := flag.NewFlagSet("open", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 || fs.NArg() > 2 {
+		fmt.Println(i18n.T("Usage: rag open [--config=config.yaml] FILE [LINE]"))
+		os.Exit(1)
+	}
+	path, line := fs.Arg(0), 1
+	// Launchers pass an empty line for results without lines in the file.
+	if fs.NArg() == 2 && fs.Arg(1) != "" {
+		n, err := strconv.Atoi(fs.Arg(1))
+		if err != nil || n < 1 {
+			log.Fatal(i18n.Sprintf("invalid line: %s", fs.Arg(1)))
+		}
+		line = n
+	}
+	if isURL(path) {
+		log.Fatal(i18n.Sprintf("%s is not a local file", path))
+	}
+	cfg := loadConfig(*cfgPath)
+	allowed, err := roots.New(cfg.Ingest.AllowedRoots)
+	if err != nil {
+		log.Fatal(i18n.Sprintf("invalid ingest.allowed_roots: %v", err))
+	}
+	if err := allowed.Check(path); err != nil {
+		log.Fatalf("%s: %v", path, err)
+	}
+	cmdArgs, err := opener.Command(cfg.OpenCommand, path, line)
+	if err != nil {
+		log.Fatal(i18n.Sprintf("invalid config: %v", err))
+	}
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		log.Fatal(i18n.Sprintf("open failed: %v", err))
+	}
+}
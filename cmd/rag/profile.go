@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"rag/internal/i18n"
+)
+
+// servePprof serves the net/http/pprof handlers on addr (e.g. ":6060") for
+// the rest of the run. The address is bound before returning, so a port in
+// use is reported before the TUI takes over the terminal.
+func servePprof(addr string) {
+	if addr == "" {
+		return
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("pprof: %v", err)
+	}
+	go func() { _ = http.Serve(ln, nil) }()
+}
+
+// runProfileIngest ingests the given files once, writing a CPU profile of
+// the ingest and a heap profile taken when it ends, for diagnosing slow
+// ingests of big corpora with go tool pprof.
+func runProfileIngest(args []string) {
+	fs := flag.NewFlagSet("profile-ingest", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	cpuPath := fs.String("cpu", "cpu.pprof", "Write the CPU profile of the ingest to this file")
+	heapPath := fs.String("heap", "heap.pprof", "Write a heap profile taken after the ingest to this file")
+	_ = fs.Parse(args)
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		fmt.Println(i18n.T("Usage: rag profile-ingest [--config=config.yaml] [--cpu=cpu.pprof] [--heap=heap.pprof] file1.txt [file2.txt ...]"))
+		os.Exit(1)
+	}
+	cfg := loadConfig(*cfgPath)
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+
+	cpu, err := os.Create(*cpuPath)
+	if err != nil {
+		log.Fatal(i18n.Sprintf("failed to create CPU profile: %v", err))
+	}
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		log.Fatal(i18n.Sprintf("failed to start CPU profile: %v", err))
+	}
+	start := time.Now()
+	report, err := svc.IngestSources(context.Background(), corpusSources(cfg, inputs), nil)
+	elapsed := time.Since(start)
+	pprof.StopCPUProfile()
+	if cerr := cpu.Close(); cerr != nil {
+		log.Fatal(i18n.Sprintf("failed to write CPU profile: %v", cerr))
+	}
+	if err != nil {
+		log.Fatal(i18n.Sprintf("ingest failed: %v", err))
+	}
+
+	heap, err := os.Create(*heapPath)
+	if err != nil {
+		log.Fatal(i18n.Sprintf("failed to create heap profile: %v", err))
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(heap); err != nil {
+		log.Fatal(i18n.Sprintf("failed to write heap profile: %v", err))
+	}
+	if err := heap.Close(); err != nil {
+		log.Fatal(i18n.Sprintf("failed to write heap profile: %v", err))
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	// The index must still be live when the heap is profiled.
+	runtime.KeepAlive(svc)
+	fmt.Println(i18n.Sprintf("Indexed %d documents (%d chunks) in %s.", report.Documents, report.Chunks, elapsed.Round(time.Millisecond)))
+	fmt.Println(i18n.Sprintf("Heap in use %d MiB, %d GC cycles, %s total GC pause.", mem.HeapInuse>>20, mem.NumGC, time.Duration(mem.PauseTotalNs).Round(time.Microsecond)))
+	fmt.Println(i18n.Sprintf("Profiles: %s (CPU), %s (heap). Inspect with: go tool pprof %s", *cpuPath, *heapPath, *cpuPath))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"rag/internal/i18n"
+	"rag/internal/service"
+)
+
+// runPurge drops soft-deleted documents from the configured vector store for
+// good: all of them, or those deleted longer ago than --older-than. With
+// --dry-run, it only lists them.
+func runPurge(args []string) {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	olderThan := fs.Duration("older-than", 0, "Only purge documents deleted longer ago than this, e.g. 720h")
+	dryRun := fs.Bool("dry-run", false, "List the soft-deleted documents without purging them")
+	_ = fs.Parse(args)
+	cfg := loadConfig(*cfgPath)
+	if cfg.VectorStore.Type == "memory" || cfg.VectorStore.Type == "" {
+		fmt.Println(i18n.T("The memory vector store keeps nothing between runs; there is nothing to purge."))
+		return
+	}
+	if readOnlyStore(cfg) {
+		log.Fatal(i18n.T("the Qdrant collection is read-only; it cannot be purged"))
+	}
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+	cutoff := time.Now().Add(-*olderThan)
+	var docs []service.DeletedDocument
+	var err error
+	if *dryRun {
+		docs, err = svc.DeletedDocuments()
+	} else {
+		docs, err = svc.Purge(cutoff)
+	}
+	if err != nil {
+		log.Fatal(i18n.Sprintf("purge failed: %v", err))
+	}
+	shown := 0
+	for _, d := range docs {
+		if *dryRun && !d.Deleted.Before(cutoff) {
+			continue
+		}
+		shown++
+		fmt.Printf("%s  %s  %s\n", d.Deleted.Local().Format("2006-01-02 15:04"), d.Path, i18n.Sprintf("%d chunks", d.Chunks))
+	}
+	switch {
+	case shown == 0:
+		fmt.Println(i18n.T("No soft-deleted documents to purge."))
+	case *dryRun:
+		fmt.Println(i18n.Sprintf("%d soft-deleted documents would be purged.", shown))
+	default:
+		fmt.Println(i18n.Sprintf("Purged %d soft-deleted documents.", shown))
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"rag/internal/domain"
+	"rag/internal/generator"
+	"rag/internal/grounding"
+	"rag/internal/grouping"
+	"rag/internal/i18n"
+	"rag/internal/ordering"
+	"rag/internal/queryparse"
+	"rag/internal/service"
+	"rag/internal/snippet"
+)
+
+// runQuery ingests the given files (none for a read-only collection), runs a single query (given inline or by
+// saved-search name) and prints the results.
+func runQuery(args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	q := fs.String("q", "", "Query text")
+	savedName := fs.String("saved", "", "Run the saved search with this name")
+	topK := fs.Int("top-k", 0, "Number of results (default from search.top_k)")
+	group := fs.Bool("group", false, "Group results by document (default from search.group_by_document)")
+	order := fs.String("order", "", "Order results by score, document or recency (default from search.order)")
+	translateTo := fs.String("translate", "", "Translate the snippets into this language with the configured LLM (default from search.translate_to)")
+	output := fs.String("output", "text", "Output format: text, json for a JSON array of the results, or alfred or rofi for launchers")
+	answer := fs.Bool("answer", false, "Write an answer from the top results with the configured LLM, citing them")
+	temperature := fs.Float64("temperature", 0, "Temperature of the answer, from 0 to 2 (default from generator.temperature)")
+	maxTokens := fs.Int("max-tokens", 0, "Bound on the length of the answer in tokens (default from generator.max_tokens)")
+	answerModel := fs.String("answer-model", "", "Model writing the answer (default from generator.model, else llm.model)")
+	_ = fs.Parse(args)
+	inputs := fs.Args()
+	if *q == "" && *savedName == "" {
+		queryUsage()
+	}
+	cfg := loadConfig(*cfgPath)
+	if len(inputs) == 0 && !readOnlyStore(cfg) {
+		queryUsage()
+	}
+	if *order == "" {
+		*order = cfg.Search.Order
+	}
+	resultsOrder := resultOrder(*order)
+	if !launcherOutputs[*output] {
+		checkOutput(*output)
+	} else if *answer {
+		log.Fatal(i18n.Sprintf("--answer writes text or json, not %s", *output))
+	}
+	if *translateTo == "" {
+		*translateTo = cfg.Search.TranslateTo
+	}
+	translate := translator(cfg, *translateTo)
+	if *answer && cfg.LLM == nil {
+		log.Fatal(i18n.T("answering needs an llm section in the config"))
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "temperature":
+			cfg.Generator.Temperature = temperature
+		case "max-tokens":
+			cfg.Generator.MaxTokens = *maxTokens
+		case "answer-model":
+			cfg.Generator.Model = *answerModel
+		}
+	})
+	checkReadOnlyInputs(cfg, inputs)
+	query, k := *q, *topK
+	if k <= 0 {
+		k = cfg.Search.TopK
+	}
+	if *savedName != "" {
+		store := savedSearches(cfg, inputs)
+		s, err := store.Get(*savedName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		query = s.Query
+		if s.TopK > 0 {
+			k = s.TopK
+		}
+	}
+
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+	var gen *generator.Generator
+	if *answer {
+		gen = answerer(cfg, svc)
+	}
+	if !readOnlyStore(cfg) {
+		ingestCorpus(svc, cfg, inputs)
+	}
+	if parsed, err := queryparse.Parse(query); err == nil && parsed.Limit > 0 {
+		k = parsed.Limit
+	}
+	results, err := svc.Query(query, min(k, queryparse.MaxLimit))
+	if err != nil {
+		log.Fatal(i18n.Sprintf("query failed: %v", err))
+	}
+	if gen != nil {
+		// Answers are written from the best results, whatever the order.
+		a, err := gen.Answer(context.Background(), queryparse.Terms(query), results)
+		if err != nil {
+			log.Fatal(i18n.Sprintf("answer failed: %v", err))
+		}
+		if *output == "json" {
+			encodeJSON(os.Stdout, newJSONAnswer(svc, a, query, translate))
+			return
+		}
+		printAnswer(os.Stdout, a, query)
+		return
+	}
+	ordering.Sort(results, resultsOrder)
+	if launcherOutputs[*output] {
+		writeLauncher(os.Stdout, *output, results, query)
+		return
+	}
+	if *output == "json" {
+		if *group || cfg.Search.GroupByDocument {
+			// Documents in turn, each with its hits.
+			var grouped []domain.SearchResult
+			for _, g := range grouping.ByDocument(results) {
+				for _, h := range g.Hits {
+					grouped = append(grouped, results[h])
+				}
+			}
+			results = grouped
+		}
+		writeJSON(os.Stdout, svc, results, query, translate)
+		return
+	}
+	if *group || cfg.Search.GroupByDocument {
+		for i, g := range grouping.ByDocument(results) {
+			fmt.Println(i18n.Sprintf("%2d. %.3f  %s  (%d hits)", i+1, g.Score, g.Path, len(g.Hits)))
+			for _, h := range g.Hits {
+				r := results[h]
+				fmt.Printf("    %.3f  #%d  %s\n", r.Score, r.Chunk.Index, snippet.Generate(r.Chunk.Text, queryparse.Terms(query), nil, snippet.DefaultWidth))
+				printTranslation(translate, r.Chunk.Text, query, "      ")
+			}
+		}
+		return
+	}
+	if translate == nil {
+		printResults(os.Stdout, results, query, 1)
+		return
+	}
+	for i, r := range results {
+		printResults(os.Stdout, results[i:i+1], query, i+1)
+		printTranslation(translate, r.Chunk.Text, query, "    ")
+	}
+}
+
+// printTranslation prints the translation of the snippet of text for query,
+// or why it failed, when translate is set.
+func printTranslation(translate func(ctx context.Context, text string) (string, error), text, query, indent string) {
+	if translate == nil {
+		return
+	}
+	out, err := translate(context.Background(), snippet.Generate(text, queryparse.Terms(query), nil, snippet.DefaultWidth))
+	if err != nil {
+		out = i18n.Sprintf("Translation failed: %v", err)
+	}
+	fmt.Printf("%s→ %s\n", indent, out)
+}
+
+// printResults prints results as a numbered list starting at from, each
+// with a plain-text snippet around the query terms.
+func printResults(w io.Writer, results []domain.SearchResult, query string, from int) {
+	for i, r := range results {
+		fmt.Fprintf(w, "%2d. %.3f  %s\n", from+i, r.Score, r.Chunk.Location())
+		fmt.Fprintf(w, "    %s\n", snippet.Generate(r.Chunk.Text, queryparse.Terms(query), nil, snippet.DefaultWidth))
+		if len(r.Via) > 0 {
+			fmt.Fprintf(w, "    %s\n", i18n.Sprintf("found for: %s", strings.Join(r.Via, " | ")))
+		}
+	}
+}
+
+// jsonResult is a search result as --output=json writes it.
+type jsonResult struct {
+	Path    string `json:"path"`
+	ChunkID string `json:"chunk_id"`
+	Index   int    `json:"index"`
+	// StartLine and EndLine are the lines of the result in its file, from
+	// 1, when it can be located there.
+	StartLine int     `json:"start_line,omitempty"`
+	EndLine   int     `json:"end_line,omitempty"`
+	Score     float64 `json:"score"`
+	Text      string  `json:"text"`
+	// Highlight is the sentence of the text that best matches the query,
+	// or the two best when they are about as good.
+	Highlight   string            `json:"highlight"`
+	Date        string            `json:"date,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Translation string            `json:"translation,omitempty"`
+	// Via lists the questions of a decomposed query that found the result.
+	Via []string `json:"via,omitempty"`
+}
+
+// jsonAnswer is an answer as --answer --output=json writes it.
+type jsonAnswer struct {
+	Answer string `json:"answer"`
+	// Cited lists the numbers of the sources cited, from 1.
+	Cited   []int        `json:"cited"`
+	Sources []jsonResult `json:"sources"`
+	// Claims are the checked sentences of the answer.
+	Claims     []jsonClaim `json:"claims,omitempty"`
+	CheckError string      `json:"check_error,omitempty"`
+}
+
+// jsonClaim is a checked sentence of an answer.
+type jsonClaim struct {
+	Text      string  `json:"text"`
+	Supported bool    `json:"supported"`
+	Source    int     `json:"source,omitempty"`
+	Score     float64 `json:"score"`
+}
+
+// newJSONAnswer converts a as --answer --output=json writes it.
+func newJSONAnswer(svc *service.RAGServiceImpl, a generator.Answer, query string, translate func(ctx context.Context, text string) (string, error)) jsonAnswer {
+	out := jsonAnswer{Answer: a.Text, Cited: a.Cited, Sources: jsonResults(svc, a.Sources, query, translate)}
+	for _, c := range a.Claims {
+		out.Claims = append(out.Claims, jsonClaim{Text: a.Text[c.Start:c.End], Supported: c.Supported, Source: c.Source, Score: c.Score})
+	}
+	if a.CheckErr != nil {
+		out.CheckError = a.CheckErr.Error()
+	}
+	return out
+}
+
+// printAnswer prints an answer and the sentences of it no source supports,
+// then the sources it cites numbered as cited, or all of them when it cites
+// none.
+func printAnswer(w io.Writer, a generator.Answer, query string) {
+	fmt.Fprintln(w, a.Text)
+	fmt.Fprintln(w)
+	if a.CheckErr != nil {
+		fmt.Fprintln(w, i18n.Sprintf("Claims not checked: %v", a.CheckErr))
+		fmt.Fprintln(w)
+	}
+	if unsupported := grounding.Unsupported(a.Claims); len(unsupported) > 0 {
+		fmt.Fprintln(w, i18n.T("Not supported by the sources:"))
+		for _, c := range unsupported {
+			fmt.Fprintf(w, "  ! %s\n", strings.Join(strings.Fields(a.Text[c.Start:c.End]), " "))
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, i18n.T("Sources:"))
+	cited := a.Cited
+	if len(cited) == 0 {
+		for i := range a.Sources {
+			cited = append(cited, i+1)
+		}
+	}
+	for _, n := range cited {
+		r := a.Sources[n-1]
+		fmt.Fprintf(w, "[%d] %s\n", n, r.Chunk.Location())
+		fmt.Fprintf(w, "    %s\n", snippet.Generate(r.Chunk.Text, queryparse.Terms(query), nil, snippet.DefaultWidth))
+	}
+}
+
+// checkOutput exits on an unknown --output format.
+func checkOutput(format string) {
+	switch format {
+	case "text", "json":
+	default:
+		log.Fatal(i18n.Sprintf("unknown output format: %s", format))
+	}
+}
+
+// writeJSON writes results as a JSON array, with the sentences highlighted
+// for query and, when translate is set, the translated snippets.
+func writeJSON(w io.Writer, svc *service.RAGServiceImpl, results []domain.SearchResult, query string, translate func(ctx context.Context, text string) (string, error)) {
+	encodeJSON(w, jsonResults(svc, results, query, translate))
+}
+
+// jsonResults converts results as writeJSON writes them.
+func jsonResults(svc *service.RAGServiceImpl, results []domain.SearchResult, query string, translate func(ctx context.Context, text string) (string, error)) []jsonResult {
+	out := make([]jsonResult, len(results))
+	for i, r := range results {
+		ch := r.Chunk
+		jr := jsonResult{Path: ch.Path, ChunkID: ch.ChunkID, Index: ch.Index, StartLine: ch.StartLine, EndLine: ch.EndLine, Score: r.Score, Text: ch.Text, Metadata: ch.Metadata, Via: r.Via}
+		if !ch.Time.IsZero() {
+			jr.Date = ch.Time.Format("2006-01-02")
+		}
+		// Without a highlight, e.g. when the embedder fails, the result is
+		// written all the same.
+		spans, _ := svc.Highlight(query, ch.Text)
+		var sentences []string
+		for _, sp := range spans {
+			sentences = append(sentences, ch.Text[sp[0]:sp[1]])
+		}
+		jr.Highlight = strings.Join(sentences, " ")
+		if translate != nil {
+			t, err := translate(context.Background(), snippet.Generate(ch.Text, queryparse.Terms(query), nil, snippet.DefaultWidth))
+			if err != nil {
+				log.Print(i18n.Sprintf("translation failed: %v", err))
+			}
+			jr.Translation = t
+		}
+		out[i] = jr
+	}
+	return out
+}
+
+// encodeJSON writes v as indented JSON.
+func encodeJSON(w io.Writer, v any) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Fatal(i18n.Sprintf("write failed: %v", err))
+	}
+}
+
+func queryUsage() {
+	fmt.Println(i18n.T("Usage: rag query [--config=config.yaml] [--q=text | --saved=name] [--top-k=10] [--group] [--order=score|document|recency] [--translate=LANG] [--output=text|json|alfred|rofi] [--answer [--temperature=T] [--max-tokens=N] [--answer-model=NAME]] file1.txt [file2.txt ...]"))
+	os.Exit(1)
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"rag/internal/config"
+	"rag/internal/conversation"
+	"rag/internal/domain"
+	"rag/internal/i18n"
+	"rag/internal/llm"
+	"rag/internal/queryparse"
+	"rag/internal/service"
+	"rag/internal/tui"
+)
+
+// replHelp lists the commands of the plain interactive mode.
+const replHelp = `Type a query and press Enter to search.
+Commands:
+  more    show the next results of the last query
+  N       show the full text of result N
+  answer  answer the last query from its results (needs llm)
+  :set    show the parameters of answers; :set temp 0, :set max_tokens 500
+          or :set model NAME sets one, :set temp default resets it
+  export  write this conversation as Markdown (export FILE writes to FILE)
+  docs    list the indexed documents
+  help    show this help
+  quit    exit (or end the input)`
+
+// runREPL is the interactive search without the full-screen interface
+// (--no-tui, or TERM=dumb): a prompt and plain lines of output on stdin and
+// stdout, with no alternate screen, colors or cursor movement, for screen
+// readers and dumb terminals. ingest is the TUI's ingest, nil for a
+// read-only collection; Ctrl+C cancels it, keeping what is indexed so far.
+// The queries, their results and answers are kept in convs.
+func runREPL(svc *service.RAGServiceImpl, cfg *config.AppConfig, ingest tui.IngestFunc, convs *conversation.Store, in io.Reader, out io.Writer) {
+	if ingest != nil {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		stage := ""
+		report, err := ingest(ctx, func(p domain.IngestProgress) {
+			if p.Stage != stage {
+				stage = p.Stage
+				fmt.Fprintf(out, "%s…\n", replStage(p))
+			}
+		})
+		stop()
+		switch {
+		case errors.Is(err, context.Canceled):
+			fmt.Fprintln(out, i18n.T("Indexing canceled; searching what is indexed so far."))
+		case err != nil:
+			fmt.Fprintln(out, i18n.Sprintf("Indexing failed: %v", err))
+			os.Exit(1)
+		default:
+			fmt.Fprintln(out, i18n.Sprintf("Indexed %d documents (%d chunks).", report.Documents, report.Chunks))
+		}
+		for _, w := range report.Warnings {
+			fmt.Fprintln(out, i18n.Sprintf("Warning: %s", w))
+		}
+		if report.Summary != "" {
+			fmt.Fprintln(out, i18n.Sprintf("Summary: %s", report.Summary))
+		}
+	}
+	fmt.Fprintln(out, i18n.T(`Type a query, or "help".`))
+	gen := answerer(cfg, svc)
+
+	var (
+		query   string
+		k       int
+		offset  int
+		results []domain.SearchResult
+		// opts are the generation parameters set with :set, over the
+		// config's.
+		opts llm.Options
+		conv = conversation.New(time.Now())
+	)
+	save := func() {
+		if err := convs.Save(conv); err != nil {
+			fmt.Fprintln(out, i18n.Sprintf("Conversation not saved: %v", err))
+		}
+	}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for {
+		fmt.Fprint(out, "query> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == "export" && len(fields) <= 2 {
+			if err := exportConversation(out, conv, fields[1:]); err != nil {
+				fmt.Fprintln(out, i18n.Sprintf("Export failed: %v", err))
+			}
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == ":set" {
+			if gen == nil {
+				fmt.Fprintln(out, i18n.T("Answers need an llm section in the config."))
+				continue
+			}
+			if err := replSet(&opts, fields[1:]); err != nil {
+				fmt.Fprintln(out, err)
+				continue
+			}
+			fmt.Fprintln(out, describeOptions(gen.With(opts).Options(), cfg.LLM.Model))
+			continue
+		}
+		switch line {
+		case "":
+			continue
+		case "quit", "exit", "q":
+			return
+		case "help", "?":
+			fmt.Fprintln(out, i18n.T(replHelp))
+			continue
+		case "docs":
+			for _, d := range svc.Documents() {
+				fmt.Fprintf(out, "%s  %s\n", d.Path, i18n.Sprintf("(%d chunks)", d.Chunks))
+			}
+			continue
+		case "answer":
+			switch {
+			case gen == nil:
+				fmt.Fprintln(out, i18n.T("Answers need an llm section in the config."))
+			case len(results) == 0:
+				fmt.Fprintln(out, i18n.T("No query yet."))
+			default:
+				a, err := gen.With(opts).Answer(context.Background(), queryparse.Terms(query), results)
+				if err != nil {
+					fmt.Fprintln(out, i18n.Sprintf("Answer failed: %v", err))
+					continue
+				}
+				printAnswer(out, a, query)
+				turn := &conv.Turns[len(conv.Turns)-1]
+				turn.Answer, turn.Sources, turn.Cited = a.Text, sources(a.Sources), a.Cited
+				save()
+			}
+			continue
+		case "more":
+			if query == "" {
+				fmt.Fprintln(out, i18n.T("No query yet."))
+				continue
+			}
+		default:
+			if n, err := strconv.Atoi(line); err == nil {
+				if n < 1 || n > len(results) {
+					fmt.Fprintln(out, i18n.Sprintf("No result %d.", n))
+					continue
+				}
+				r := results[n-1]
+				fmt.Fprintf(out, "%s  %s\n%s\n", r.Chunk.Location(), i18n.Sprintf("score %.3f", r.Score), r.Chunk.Text)
+				continue
+			}
+			query, offset, results = line, 0, nil
+			k = cfg.Search.TopK
+			if parsed, err := queryparse.Parse(query); err == nil && parsed.Limit > 0 {
+				k = parsed.Limit
+			}
+		}
+		page, err := svc.QueryPage(query, offset, k)
+		if err != nil {
+			fmt.Fprintln(out, i18n.Sprintf("Query failed: %v", err))
+			continue
+		}
+		if len(page) == 0 {
+			if offset == 0 {
+				fmt.Fprintln(out, i18n.T("No results."))
+			} else {
+				fmt.Fprintln(out, i18n.T("No more results."))
+			}
+			continue
+		}
+		printResults(out, page, query, offset+1)
+		if offset == 0 {
+			conv.Turns = append(conv.Turns, conversation.Turn{Query: query, Time: time.Now()})
+		}
+		turn := &conv.Turns[len(conv.Turns)-1]
+		turn.Results = append(turn.Results, sources(page)...)
+		save()
+		offset += len(page)
+		results = append(results, page...)
+	}
+}
+
+// sources lists results as conversation sources.
+func sources(results []domain.SearchResult) []conversation.Source {
+	out := make([]conversation.Source, len(results))
+	for i, r := range results {
+		out[i] = conversation.Source{ChunkID: r.Chunk.ChunkID, Path: r.Chunk.Path, Index: r.Chunk.Index}
+	}
+	return out
+}
+
+// exportConversation writes conv as Markdown to the file named in args, or
+// to out.
+func exportConversation(out io.Writer, conv conversation.Conversation, args []string) error {
+	if len(conv.Turns) == 0 {
+		return errors.New(i18n.T("No query yet."))
+	}
+	if len(args) == 0 {
+		return conversation.Markdown(out, conv)
+	}
+	f, err := os.Create(args[0])
+	if err != nil {
+		return err
+	}
+	if err := conversation.Markdown(f, conv); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, i18n.Sprintf("Wrote %s.", args[0]))
+	return nil
+}
+
+// replSet applies the arguments of :set, a parameter and its value, to
+// opts; the value "default" returns the parameter to the config's.
+func replSet(opts *llm.Options, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	if len(args) != 2 {
+		return errors.New(i18n.T("Usage: :set temp|max_tokens|model VALUE"))
+	}
+	name, value := args[0], args[1]
+	reset := value == "default"
+	next := *opts
+	switch name {
+	case "temp", "temperature":
+		next.Temperature = nil
+		if !reset {
+			t, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return errors.New(i18n.Sprintf("Not a number: %s", value))
+			}
+			next.Temperature = &t
+		}
+	case "max_tokens", "tokens":
+		next.MaxTokens = 0
+		if !reset {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return errors.New(i18n.Sprintf("Not a positive number: %s", value))
+			}
+			next.MaxTokens = n
+		}
+	case "model":
+		next.Model = ""
+		if !reset {
+			next.Model = value
+		}
+	default:
+		return errors.New(i18n.T("Usage: :set temp|max_tokens|model VALUE"))
+	}
+	if err := next.Validate(); err != nil {
+		return err
+	}
+	*opts = next
+	return nil
+}
+
+// describeOptions shows the generation parameters of answers, with model,
+// the llm model, when they set none, and the server's defaults for the
+// others left unset.
+func describeOptions(o llm.Options, model string) string {
+	temp, tokens := i18n.T("default"), i18n.T("default")
+	if o.Model != "" {
+		model = o.Model
+	}
+	if model == "" {
+		model = i18n.T("default")
+	}
+	if o.Temperature != nil {
+		temp = strconv.FormatFloat(*o.Temperature, 'g', -1, 64)
+	}
+	if o.MaxTokens > 0 {
+		tokens = strconv.Itoa(o.MaxTokens)
+	}
+	return fmt.Sprintf("model=%s temp=%s max_tokens=%s", model, temp, tokens)
+}
+
+// replStage describes an ingest stage as a line of its own.
+func replStage(p domain.IngestProgress) string {
+	switch p.Stage {
+	case domain.StageEnriching:
+		return i18n.Sprintf("Enriching %d chunks", p.Total)
+	case domain.StageEmbedding:
+		return i18n.Sprintf("Embedding %d chunks", p.Total)
+	case domain.StageSummarizing:
+		return i18n.T("Summarizing the corpus")
+	default:
+		return i18n.T("Loading documents")
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"rag/internal/i18n"
+	"rag/internal/service"
+)
+
+// runRetryFailed re-embeds chunks recorded as failed by a previous ingest of
+// the given files (none for a read-only collection) and upserts them into
+// the configured vector store.
+func runRetryFailed(args []string) {
+	fs := flag.NewFlagSet("retry-failed", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	_ = fs.Parse(args)
+	inputs := fs.Args()
+	cfg := loadConfig(*cfgPath)
+	if len(inputs) == 0 && !readOnlyStore(cfg) {
+		fmt.Println(i18n.T("Usage: rag retry-failed [--config=config.yaml] file1.txt [file2.txt ...]"))
+		os.Exit(1)
+	}
+	checkReadOnlyInputs(cfg, inputs)
+	if cfg.VectorStore.Type == "memory" || cfg.VectorStore.Type == "" {
+		log.Fatal(i18n.T("retry-failed needs a persistent vector store: the memory store would discard the retried chunks on exit"))
+	}
+	path := failedChunksPath(cfg, inputs)
+	failed, err := service.LoadFailedChunks(path)
+	if err != nil {
+		log.Fatal(i18n.Sprintf("failed to read %s: %v", path, err))
+	}
+	if len(failed) == 0 {
+		fmt.Println(i18n.T("No failed chunks recorded."))
+		return
+	}
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+	remaining, err := svc.RetryFailed(context.Background(), corpusSources(cfg, inputs), failed)
+	if err != nil {
+		log.Fatal(i18n.Sprintf("retry failed: %v", err))
+	}
+	if err := service.SaveFailedChunks(path, remaining); err != nil {
+		log.Fatal(i18n.Sprintf("failed to update %s: %v", path, err))
+	}
+	fmt.Println(i18n.Sprintf("Retried %d chunks: %d succeeded, %d still failing.", len(failed), len(failed)-len(remaining), len(remaining)))
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"rag/internal/i18n"
+	"rag/internal/jsonrpc"
+	"rag/internal/queryparse"
+	"rag/internal/service"
+)
+
+// runRPC indexes the given files and answers JSON-RPC 2.0 requests on
+// stdin and stdout, for editor plugins searching the index for the word or
+// selection under the cursor. Logs go to stderr.
+func runRPC(args []string) {
+	fs := flag.NewFlagSet("rpc", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	pprofAddr := fs.String("pprof", "", "Serve net/http/pprof on this address (e.g. :6060)")
+	_ = fs.Parse(args)
+	inputs := fs.Args()
+	cfg := loadConfig(*cfgPath)
+	if len(inputs) == 0 && !readOnlyStore(cfg) {
+		fmt.Fprintln(os.Stderr, i18n.T("Usage: rag rpc [--config=config.yaml] [--pprof=:6060] file1.txt [file2.txt ...]"))
+		os.Exit(1)
+	}
+	checkReadOnlyInputs(cfg, inputs)
+	servePprof(*pprofAddr)
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+	if !readOnlyStore(cfg) {
+		ingestCorpus(svc, cfg, inputs)
+	}
+	if err := rpcServer(svc, cfg.Search.TopK).Serve(os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("rpc: %v", err)
+	}
+}
+
+// rpcMethods are the methods rpcServer answers.
+var rpcMethods = []string{"initialize", "search", "similar", "documents", "shutdown"}
+
+// rpcServer returns the server answering editor requests on svc, with
+// topK results unless a request asks for another number.
+func rpcServer(svc *service.RAGServiceImpl, topK int) *jsonrpc.Server {
+	s := jsonrpc.NewServer()
+	s.Handle("initialize", func(json.RawMessage) (any, error) {
+		return map[string]any{"serverInfo": map[string]string{"name": "rag"}, "methods": rpcMethods}, nil
+	})
+	// search ranks passages for a query, such as the word under the
+	// cursor, with the query operators of the TUI.
+	s.Handle("search", func(raw json.RawMessage) (any, error) {
+		var p struct {
+			Query  string `json:"query"`
+			TopK   *int   `json:"top_k"`
+			Offset int    `json:"offset"`
+		}
+		if err := decodeParams(raw, &p); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(p.Query) == "" {
+			return nil, jsonrpc.InvalidParams("query is empty")
+		}
+		k, err := rpcTopK(p.TopK, topK)
+		if err != nil {
+			return nil, err
+		}
+		results, err := svc.QueryPage(p.Query, max(p.Offset, 0), k)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"results": jsonResults(svc, results, p.Query, nil)}, nil
+	})
+	// similar ranks passages by their similarity to a text, such as a
+	// selected paragraph, taken as a whole rather than as a query.
+	s.Handle("similar", func(raw json.RawMessage) (any, error) {
+		var p struct {
+			Text string `json:"text"`
+			TopK *int   `json:"top_k"`
+		}
+		if err := decodeParams(raw, &p); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(p.Text) == "" {
+			return nil, jsonrpc.InvalidParams("text is empty")
+		}
+		k, err := rpcTopK(p.TopK, topK)
+		if err != nil {
+			return nil, err
+		}
+		results, err := svc.Similar(p.Text, 0, k)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"results": jsonResults(svc, results, p.Text, nil)}, nil
+	})
+	s.Handle("documents", func(json.RawMessage) (any, error) {
+		type document struct {
+			Path   string `json:"path"`
+			Title  string `json:"title,omitempty"`
+			Chunks int    `json:"chunks"`
+		}
+		docs := svc.Documents()
+		out := make([]document, len(docs))
+		for i, d := range docs {
+			out[i] = document{Path: d.Path, Title: d.Title, Chunks: d.Chunks}
+		}
+		return map[string]any{"documents": out}, nil
+	})
+	s.Handle("shutdown", func(json.RawMessage) (any, error) {
+		return nil, jsonrpc.ErrShutdown
+	})
+	return s
+}
+
+// decodeParams decodes the params of a request into v; absent params leave
+// v as it is.
+func decodeParams(raw json.RawMessage, v any) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return jsonrpc.InvalidParams("params: %v", err)
+	}
+	return nil
+}
+
+// rpcTopK is the number of results a request asks for, up to the limit of
+// k:N in queries, or def when it asks for none.
+func rpcTopK(asked *int, def int) (int, error) {
+	if asked == nil {
+		return def, nil
+	}
+	if *asked <= 0 {
+		return 0, jsonrpc.InvalidParams("top_k must be positive, got %d", *asked)
+	}
+	return min(*asked, queryparse.MaxLimit), nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"rag/internal/i18n"
+	"rag/internal/snippet"
+)
+
+// runSimilar ingests the given files and lists the chunks most similar to a
+// passage, e.g. to check whether it was copied or paraphrased from the corpus.
+func runSimilar(args []string) {
+	fs := flag.NewFlagSet("similar", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	passageFile := fs.String("passage", "", "File holding the passage (default: read stdin)")
+	topK := fs.Int("top-k", 10, "Number of results")
+	output := fs.String("output", "text", "Output format: text, or json for a JSON array of the results")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		fmt.Println(i18n.T("Usage: rag similar [--config=config.yaml] [--passage=passage.txt] [--top-k=10] [--output=text|json] file1.txt [file2.txt ...] < passage.txt"))
+		os.Exit(1)
+	}
+	checkOutput(*output)
+
+	var data []byte
+	var err error
+	if *passageFile != "" {
+		data, err = os.ReadFile(*passageFile)
+	} else {
+		data, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		log.Fatal(i18n.Sprintf("read passage: %v", err))
+	}
+	passage := strings.TrimSpace(string(data))
+	if passage == "" {
+		log.Fatal(i18n.T("empty passage"))
+	}
+
+	cfg := loadConfig(*cfgPath)
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+	ingestCorpus(svc, cfg, fs.Args())
+	results, err := svc.Similar(passage, 0, *topK)
+	if err != nil {
+		log.Fatal(i18n.Sprintf("search failed: %v", err))
+	}
+	if *output == "json" {
+		writeJSON(os.Stdout, svc, results, passage, nil)
+		return
+	}
+	if len(results) == 0 {
+		fmt.Println(i18n.T("No similar passages found."))
+		return
+	}
+	for i, r := range results {
+		fmt.Printf("%2d. %.3f  %s\n", i+1, r.Score, r.Chunk.Location())
+		fmt.Printf("    %s\n", snippet.Generate(r.Chunk.Text, "", nil, snippet.DefaultWidth))
+	}
+}
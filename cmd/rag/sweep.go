@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"rag/internal/config"
+	"rag/internal/feedback"
+	"rag/internal/i18n"
+	"rag/internal/service"
+	"rag/internal/sweep"
+)
+
+// runSweep builds an index of the given files for every pairing of the
+// --sentences and --overlap lists, several at once in memory stores, and
+// reports how well each ranks the passages judged relevant to a query set:
+// the relevance feedback of the index by default, or the --queries file in
+// the layout rag feedback exports.
+func runSweep(args []string) {
+	fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	sentences := fs.String("sentences", "3,5,8", "Comma-separated values of chunker.sentences_per_chunk to try")
+	overlaps := fs.String("overlap", "0,1,2", "Comma-separated values of chunker.overlap_sentences to try")
+	queriesPath := fs.String("queries", "", "JSON Lines file of queries with their relevant passages, as written by rag feedback (default: the relevance feedback of the index)")
+	topK := fs.Int("top-k", 0, "Number of results the relevant passages are sought in (default from search.top_k)")
+	parallel := fs.Int("parallel", 4, "Number of indexes built at once")
+	_ = fs.Parse(args)
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		fmt.Println(i18n.T("Usage: rag sweep [--config=config.yaml] [--sentences=3,5,8] [--overlap=0,1,2] [--queries=feedback.jsonl] [--top-k=10] [--parallel=4] file1.txt [file2.txt ...]"))
+		os.Exit(1)
+	}
+	cfg := loadConfig(*cfgPath)
+	if cfg.Chunker.Type != "sentence" && cfg.Chunker.Type != "" {
+		log.Fatal(i18n.Sprintf("sweeping chunk sizes needs the sentence chunker, not %s", cfg.Chunker.Type))
+	}
+	k := *topK
+	if k <= 0 {
+		k = cfg.Search.TopK
+	}
+	grid := sweep.Grid(parseInts("sentences", *sentences), parseInts("overlap", *overlaps))
+	if len(grid) == 0 {
+		log.Fatal(i18n.T("no chunker parameters to try; the overlap must be less than the chunk size"))
+	}
+	examples := sweepQueries(cfg, inputs, *queriesPath)
+	if len(examples) == 0 {
+		return
+	}
+
+	// Services are built one at a time, as building sets process-wide
+	// state; they are then filled concurrently.
+	*parallel = max(*parallel, 1)
+	services := make(map[sweep.Params]*service.RAGServiceImpl, len(grid))
+	for _, p := range grid {
+		svc, cleanup := buildService(sweepConfig(cfg, p, *parallel))
+		defer cleanup()
+		services[p] = svc
+	}
+	fmt.Println(i18n.Sprintf("%d indexes, %d queries, relevant passages searched in the top %d.", len(grid), len(examples), k))
+	results := sweep.Run(grid, *parallel, func(p sweep.Params) sweep.Result {
+		res := sweep.Result{Params: p}
+		svc := services[p]
+		start := time.Now()
+		// A copy of the config per index keeps manifest chunker settings
+		// based on the parameters tried.
+		if _, res.Err = svc.IngestSources(context.Background(), corpusSources(sweepConfig(cfg, p, *parallel), inputs), nil); res.Err != nil {
+			return res
+		}
+		res.Index = time.Since(start)
+		res.Chunks = len(svc.Chunks())
+		res.Err = sweep.Score(&res, examples, k, svc.Query)
+		return res
+	})
+
+	fmt.Printf("%9s  %7s  %7s  %6s  %6s  %6s  %10s\n", i18n.T("sentences"), i18n.T("overlap"), i18n.T("chunks"), "hit@1", fmt.Sprintf("hit@%d", k), "MRR", i18n.T("index"))
+	var ok []sweep.Result
+	for _, r := range results {
+		current := ""
+		if r.SentencesPerChunk == cfg.Chunker.SentencesPerChunk && r.OverlapSentences == cfg.Chunker.OverlapSentences {
+			current = "  " + i18n.T("current")
+		}
+		if r.Err != nil {
+			fmt.Printf("%9d  %7d  %s%s\n", r.SentencesPerChunk, r.OverlapSentences, i18n.Sprintf("failed: %v", r.Err), current)
+			continue
+		}
+		ok = append(ok, r)
+		q := float64(max(r.Queries, 1))
+		fmt.Printf("%9d  %7d  %7d  %6.3f  %6.3f  %6.3f  %10s%s\n", r.SentencesPerChunk, r.OverlapSentences, r.Chunks, float64(r.Top1)/q, float64(r.TopK)/q, r.MRR, r.Index.Round(time.Millisecond), current)
+	}
+	if len(ok) == 0 {
+		os.Exit(1)
+	}
+	// Smaller indexes win ties.
+	sort.SliceStable(ok, func(i, j int) bool {
+		if ok[i].MRR != ok[j].MRR {
+			return ok[i].MRR > ok[j].MRR
+		}
+		if ok[i].TopK != ok[j].TopK {
+			return ok[i].TopK > ok[j].TopK
+		}
+		return ok[i].Chunks < ok[j].Chunks
+	})
+	best := ok[0]
+	fmt.Println(i18n.Sprintf("Best: sentences_per_chunk %d, overlap_sentences %d (MRR %.3f).", best.SentencesPerChunk, best.OverlapSentences, best.MRR))
+}
+
+// sweepQueries loads the query set from path, or else the relevance
+// feedback of the index of inputs. Only queries with a relevant passage
+// count.
+func sweepQueries(cfg *config.AppConfig, inputs []string, path string) []feedback.Example {
+	var examples []feedback.Example
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(i18n.Sprintf("failed to load queries: %v", err))
+		}
+		defer f.Close()
+		if examples, err = feedback.ReadExamples(f); err != nil {
+			log.Fatal(i18n.Sprintf("failed to load queries: %v", err))
+		}
+	} else {
+		judgments, err := feedbackStore(cfg, inputs).List()
+		if err != nil {
+			log.Fatal(i18n.Sprintf("failed to load feedback: %v", err))
+		}
+		examples = feedback.Examples(judgments)
+	}
+	var out []feedback.Example
+	for _, e := range examples {
+		if len(e.Positives) > 0 {
+			out = append(out, e)
+		}
+	}
+	if len(out) == 0 {
+		if path != "" {
+			log.Print(i18n.Sprintf("no queries with a relevant passage in %s", path))
+		} else {
+			log.Print(i18n.T("No feedback. Press Enter on a result in the TUI and mark it relevant (+) or not (-)."))
+		}
+	}
+	return out
+}
+
+// sweepConfig returns cfg with the chunker parameters p and a memory store.
+// A remote embedder's request rate is shared among the parallel builds.
+func sweepConfig(cfg *config.AppConfig, p sweep.Params, parallel int) *config.AppConfig {
+	c := *cfg
+	c.Chunker.SentencesPerChunk = p.SentencesPerChunk
+	c.Chunker.OverlapSentences = p.OverlapSentences
+	c.VectorStore.Type = "memory"
+	c.VectorStore.TextOnDisk = false
+	if oc := cfg.Embedder.OpenAI; oc != nil && oc.RequestsPerMinute > 0 {
+		shared := *oc
+		shared.RequestsPerMinute = max(oc.RequestsPerMinute/parallel, 1)
+		c.Embedder.OpenAI = &shared
+	}
+	return &c
+}
+
+// parseInts parses the comma-separated integers of flag name.
+func parseInts(name, list string) []int {
+	var out []int
+	for _, f := range strings.Split(list, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			log.Fatal(i18n.Sprintf("invalid --%s value %q", name, f))
+		}
+		out = append(out, n)
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"rag/internal/config"
+	"rag/internal/feedback"
+	"rag/internal/i18n"
+	"rag/internal/tune"
+)
+
+// runTune fits search.weights to the relevance judgments made in the TUI
+// for the index of the given files: the candidates of each judged query are
+// ranked under a grid of weights, refined around the best, and the weights
+// that put the most relevant results in the top k are written back to the
+// config file.
+func runTune(args []string) {
+	fs := flag.NewFlagSet("tune", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	topK := fs.Int("top-k", 0, "Number of results the judged results are sought in (default from search.top_k)")
+	dryRun := fs.Bool("dry-run", false, "Report the tuned weights without writing them to the config")
+	_ = fs.Parse(args)
+	inputs := fs.Args()
+	cfg := loadConfig(*cfgPath)
+	if len(inputs) == 0 && !readOnlyStore(cfg) {
+		fmt.Println(i18n.T("Usage: rag tune [--config=config.yaml] [--top-k=10] [--dry-run] file1.txt [file2.txt ...]"))
+		os.Exit(1)
+	}
+	checkReadOnlyInputs(cfg, inputs)
+	k := *topK
+	if k <= 0 {
+		k = cfg.Search.TopK
+	}
+	judgments, err := feedbackStore(cfg, inputs).List()
+	if err != nil {
+		log.Fatal(i18n.Sprintf("failed to load feedback: %v", err))
+	}
+	if len(judgments) == 0 {
+		log.Print(i18n.T("No feedback. Press Enter on a result in the TUI and mark it relevant (+) or not (-)."))
+		return
+	}
+
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+	if !readOnlyStore(cfg) {
+		ingestCorpus(svc, cfg, inputs)
+	}
+	examples := feedback.Examples(judgments)
+	queries := make([]tune.Query, 0, len(examples))
+	unreachable := 0
+	for _, e := range examples {
+		candidates, err := svc.Candidates(e.Query, k)
+		if err != nil {
+			log.Fatal(i18n.Sprintf("query failed: %v", err))
+		}
+		q := tune.Query{Candidates: candidates, Judgments: make(map[string]bool)}
+		for _, p := range e.Positives {
+			q.Judgments[p.ChunkID] = true
+		}
+		for _, p := range e.Negatives {
+			q.Judgments[p.ChunkID] = false
+		}
+		found := make(map[string]bool)
+		for _, c := range candidates {
+			found[c.Chunk.ChunkID] = true
+		}
+		for _, p := range e.Positives {
+			if !found[p.ChunkID] {
+				unreachable++
+			}
+		}
+		queries = append(queries, q)
+	}
+	if unreachable > 0 {
+		log.Print(i18n.Sprintf("%d results judged relevant are not among the candidates of their query, so no weights can rank them (edited or removed since?)", unreachable))
+	}
+
+	current := tune.Weights{Vector: 1, Lexical: cfg.Search.Weights.Lexical, Recency: cfg.Search.Weights.Recency}
+	if cfg.Search.Weights.Vector != nil {
+		current.Vector = *cfg.Search.Weights.Vector
+	}
+	before := tune.Evaluate(queries, current, cfg.Search.LinkBoost, k)
+	best, after := tune.Fit(queries, current, cfg.Search.LinkBoost, k)
+	fmt.Println(i18n.Sprintf("%d judgments of %d queries, measured in the top %d.", len(judgments), len(queries), k))
+	fmt.Printf("%-8s  %7s  %7s  %7s  %9s  %7s  %9s\n", "", i18n.T("vector"), i18n.T("lexical"), i18n.T("recency"), fmt.Sprintf("recall@%d", k), "MRR", i18n.T("negatives"))
+	for _, row := range []struct {
+		name string
+		w    tune.Weights
+		m    tune.Metrics
+	}{{i18n.T("current"), current, before}, {i18n.T("tuned"), best, after}} {
+		fmt.Printf("%-8s  %7g  %7g  %7g  %9.3f  %7.3f  %9.2f\n", row.name, row.w.Vector, row.w.Lexical, row.w.Recency, row.m.Recall, row.m.MRR, row.m.Negatives)
+	}
+	if best == current {
+		fmt.Println(i18n.T("The current weights rank the judged results best; nothing to change."))
+		return
+	}
+	if *dryRun {
+		return
+	}
+	path := *cfgPath
+	if path == "" {
+		if _, path, err = config.LoadDefault(); err != nil {
+			log.Fatal(i18n.Sprintf("failed to load config: %v", err))
+		}
+	}
+	if err := config.SaveScoreWeights(path, config.ScoreWeights{Vector: &best.Vector, Lexical: best.Lexical, Recency: best.Recency}); err != nil {
+		log.Fatal(i18n.Sprintf("failed to write config: %v", err))
+	}
+	fmt.Println(i18n.Sprintf("Wrote the tuned weights to search.weights in %s.", path))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"rag/internal/i18n"
+	"rag/internal/service"
+)
+
+// verifyExamples is the number of chunks listed for each kind of issue.
+const verifyExamples = 5
+
+// verifyKinds lists the kinds of issue in the order they are reported, with
+// what they mean.
+var verifyKinds = []struct{ kind, meaning string }{
+	{service.IssueMissing, "chunks of the corpus not in the store"},
+	{service.IssueOrphan, "stored points of no chunk of the corpus"},
+	{service.IssueStale, "stored points whose text has changed"},
+	{service.IssueDuplicate, "chunks stored more than once"},
+	{service.IssueDimension, "vectors of the wrong dimension"},
+	{service.IssueSource, "stored points whose source file is gone"},
+	{service.IssueStamp, "store stamp does not match the embedder or the corpus"},
+}
+
+// runVerify checks the configured vector store against the corpus of the
+// given files (none for a read-only collection) and reports what does not
+// match. With --repair, the store is rebuilt from the corpus, reusing the
+// vectors that are still good, and checked again. It exits with status 1
+// while issues remain.
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	repair := fs.Bool("repair", false, "Rebuild the store from the corpus when issues are found")
+	_ = fs.Parse(args)
+	inputs := fs.Args()
+	cfg := loadConfig(*cfgPath)
+	if len(inputs) == 0 && !readOnlyStore(cfg) {
+		fmt.Println(i18n.T("Usage: rag verify [--config=config.yaml] [--repair] file1.txt [file2.txt ...]"))
+		os.Exit(1)
+	}
+	checkReadOnlyInputs(cfg, inputs)
+	if cfg.VectorStore.Type == "memory" || cfg.VectorStore.Type == "" {
+		fmt.Println(i18n.T("The memory vector store keeps nothing between runs; there is no index to verify."))
+		return
+	}
+	if *repair && readOnlyStore(cfg) {
+		log.Fatal(i18n.T("the Qdrant collection is read-only; it cannot be repaired"))
+	}
+
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+	ctx := context.Background()
+	sources := corpusSources(cfg, inputs)
+	report := verifyStore(ctx, svc, sources)
+	if len(report.Issues) > 0 && *repair {
+		fmt.Println(i18n.T("Repairing: rebuilding the store from the corpus..."))
+		checkIngest(svc.Repair(ctx, sources, nil))
+		report = verifyStore(ctx, svc, sources)
+	}
+	if len(report.Issues) > 0 {
+		if !*repair && !readOnlyStore(cfg) {
+			fmt.Println(i18n.T("Run rag verify --repair to rebuild the store from the corpus."))
+		}
+		os.Exit(1)
+	}
+}
+
+// verifyStore verifies the store and prints the report.
+func verifyStore(ctx context.Context, svc *service.RAGServiceImpl, sources []service.Source) service.VerifyReport {
+	report, err := svc.Verify(ctx, sources)
+	switch {
+	case errors.Is(err, service.ErrEmbedderUnavailable):
+		log.Fatal(i18n.Sprintf("verify failed: %v; check the embedder settings", err))
+	case err != nil:
+		log.Fatal(i18n.Sprintf("verify failed: %v", err))
+	}
+	fmt.Println(i18n.Sprintf("Corpus: %d chunks; store: %d points of dimension %d.", report.Chunks, report.Points, report.Dimension))
+	if report.Deleted > 0 {
+		fmt.Println(i18n.Sprintf("%d soft-deleted points are left out of searches; rag purge drops them.", report.Deleted))
+	}
+	for _, k := range verifyKinds {
+		n := report.Count(k.kind)
+		if n == 0 {
+			continue
+		}
+		fmt.Printf("%-10s %6d  %s\n", k.kind, n, i18n.T(k.meaning))
+		shown := 0
+		for _, is := range report.Issues {
+			if is.Kind != k.kind || shown == verifyExamples {
+				continue
+			}
+			shown++
+			var parts []string
+			for _, p := range []string{is.Path, is.ChunkID, is.Detail} {
+				if p != "" {
+					parts = append(parts, p)
+				}
+			}
+			fmt.Println("  " + strings.Join(parts, "  "))
+		}
+		if n > shown {
+			fmt.Println("  " + i18n.Sprintf("... and %d more", n-shown))
+		}
+	}
+	if len(report.Issues) == 0 {
+		fmt.Println(i18n.T("The index is consistent with the corpus."))
+	} else {
+		fmt.Println(i18n.Sprintf("%d issues found.", len(report.Issues)))
+	}
+	return report
+}
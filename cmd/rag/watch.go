@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"rag/internal/alert"
+	"rag/internal/domain"
+	"rag/internal/i18n"
+	"rag/internal/queryparse"
+	"rag/internal/savedsearch"
+	"rag/internal/service"
+	"rag/internal/snippet"
+	"rag/internal/watch"
+)
+
+// runWatch re-ingests the given files whenever they change and raises alerts
+// when new content matches one of the index's saved searches (standing
+// queries) with at least the given score.
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to YAML config file")
+	interval := fs.Duration("interval", 5*time.Second, "Polling interval")
+	threshold := fs.Float64("threshold", 0.3, "Minimum score for an alert")
+	notify := fs.Bool("notify", false, "Show desktop notifications")
+	webhook := fs.String("webhook", "", "POST alerts as JSON to this URL")
+	pprofAddr := fs.String("pprof", "", "Serve net/http/pprof on this address (e.g. :6060)")
+	_ = fs.Parse(args)
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		fmt.Println(i18n.T("Usage: rag watch [--config=config.yaml] [--interval=5s] [--threshold=0.3] [--notify] [--webhook=URL] [--pprof=:6060] file1.txt [file2.txt ...]"))
+		os.Exit(1)
+	}
+
+	cfg := loadConfig(*cfgPath)
+	servePprof(*pprofAddr)
+	saved := savedSearches(cfg, inputs)
+	notifiers := []alert.Notifier{alert.Writer{W: os.Stdout}}
+	if *notify {
+		notifiers = append(notifiers, alert.Desktop{})
+	}
+	if *webhook != "" {
+		notifiers = append(notifiers, alert.Webhook{URL: *webhook})
+	}
+
+	svc, cleanup := buildService(cfg)
+	defer cleanup()
+	ingestCorpus(svc, cfg, inputs)
+	known := fingerprints(svc.Chunks())
+	log.Print(i18n.Sprintf("watching %d chunks; standing queries are the saved searches of this index", len(known)))
+
+	poller := watch.NewPoller(watchPatterns(inputs))
+	stop := make(chan struct{})
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		close(stop)
+	}()
+	poller.Run(*interval, stop, func() {
+		report, err := svc.IngestSources(context.Background(), corpusSources(cfg, inputs), nil)
+		if err != nil {
+			log.Print(i18n.Sprintf("re-ingest failed: %v", err))
+			return
+		}
+		recordFailures(cfg, inputs, svc.FailedChunks())
+		current := fingerprints(svc.Chunks())
+		fresh := make(map[[20]byte]struct{})
+		for fp := range current {
+			if _, ok := known[fp]; !ok {
+				fresh[fp] = struct{}{}
+			}
+		}
+		known = current
+		log.Print(i18n.Sprintf("re-ingested: %d new chunks", len(fresh)))
+		if report.Deleted > 0 {
+			log.Print(i18n.Sprintf("%d chunks of files gone from the corpus are kept soft-deleted (rag purge drops them)", report.Deleted))
+		}
+		if len(fresh) > 0 {
+			checkStandingQueries(svc, saved, fresh, *threshold, notifiers)
+		}
+	})
+}
+
+// checkStandingQueries runs every saved search and alerts on results that
+// belong to freshly ingested chunks.
+func checkStandingQueries(svc *service.RAGServiceImpl, saved *savedsearch.Store, fresh map[[20]byte]struct{}, threshold float64, notifiers []alert.Notifier) {
+	searches, err := saved.List()
+	if err != nil {
+		log.Print(i18n.Sprintf("failed to load saved searches: %v", err))
+		return
+	}
+	for _, s := range searches {
+		results, err := svc.Query(s.Query, max(s.TopK, 10))
+		if err != nil {
+			log.Print(i18n.Sprintf("standing query %q failed: %v", s.Name, err))
+			continue
+		}
+		for _, r := range results {
+			if r.Score < threshold {
+				continue
+			}
+			if _, ok := fresh[sha1.Sum([]byte(r.Chunk.Text))]; !ok {
+				continue
+			}
+			a := alert.Alert{
+				Name:       s.Name,
+				Query:      s.Query,
+				Score:      r.Score,
+				Path:       r.Chunk.Path,
+				ChunkIndex: r.Chunk.Index,
+				Text:       snippet.Generate(r.Chunk.Text, queryparse.Terms(s.Query), nil, snippet.DefaultWidth),
+			}
+			for _, n := range notifiers {
+				if err := n.Notify(a); err != nil {
+					log.Print(i18n.Sprintf("notification failed: %v", err))
+				}
+			}
+		}
+	}
+}
+
+func fingerprints(chunks []domain.Chunk) map[[20]byte]struct{} {
+	out := make(map[[20]byte]struct{}, len(chunks))
+	for _, ch := range chunks {
+		out[sha1.Sum([]byte(ch.Text))] = struct{}{}
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// Alert describes new content matching a standing query.
+type Alert struct {
+	Name       string  `json:"name"`
+	Query      string  `json:"query"`
+	Score      float64 `json:"score"`
+	Path       string  `json:"path"`
+	ChunkIndex int     `json:"chunk_index"`
+	Text       string  `json:"text"`
+}
+
+// Notifier delivers alerts somewhere.
+type Notifier interface {
+	Notify(a Alert) error
+}
+
+// Writer prints alerts as plain text lines.
+type Writer struct{ W io.Writer }
+
+// Notify writes a one-line description of the alert.
+func (w Writer) Notify(a Alert) error {
+	_, err := fmt.Fprintf(w.W, "[%s] %.3f %s#%d: %s\n", a.Name, a.Score, a.Path, a.ChunkIndex, a.Text)
+	return err
+}
+
+// Desktop shows alerts as desktop notifications using notify-send on
+// Linux/BSD, osascript on macOS and a toast via PowerShell on Windows.
+type Desktop struct{}
+
+// Notify raises a desktop notification for the alert.
+func (Desktop) Notify(a Alert) error {
+	title := fmt.Sprintf("rag: %s (%.2f)", a.Name, a.Score)
+	body := a.Path + ": " + a.Text
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %q with title %q", body, title)
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		script := fmt.Sprintf("[reflection.assembly]::loadwithpartialname('System.Windows.Forms') | Out-Null; "+
+			"$n = New-Object System.Windows.Forms.NotifyIcon; $n.Icon = [System.Drawing.SystemIcons]::Information; "+
+			"$n.Visible = $true; $n.ShowBalloonTip(5000, '%s', '%s', 'Info')", psQuote(title), psQuote(body))
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", script)
+	default:
+		cmd = exec.Command("notify-send", title, body)
+	}
+	return cmd.Run()
+}
+
+// Webhook posts alerts as JSON to a URL.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// Notify sends the alert as a JSON POST request.
+func (w Webhook) Notify(a Alert) error {
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s failed: %s", w.URL, resp.Status)
+	}
+	return nil
+}
+
+func psQuote(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		if r == '\'' {
+			out = append(out, '\'')
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
@@ -0,0 +1,190 @@
+// Package bench measures how well embedders retrieve the passages of a
+// corpus, so that a model can be chosen on the user's own documents rather
+// than on public leaderboards. Queries are generated from sampled chunks,
+// each answered by the chunk it came from, and every embedder indexes the
+// same sample and answers the same queries.
+package bench
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+
+	"rag/internal/domain"
+	"rag/internal/embedding"
+	"rag/internal/keywords"
+	"rag/internal/vectorstore"
+	"rag/internal/vectorstore/memory"
+)
+
+// Pair is a query and the chunk of the sample that answers it.
+type Pair struct {
+	Query  string
+	Answer int
+}
+
+// Result is how well one embedder answered the queries.
+type Result struct {
+	Model   string
+	Queries int
+	// Top1 and TopK count the queries whose answer was the first result,
+	// and among the first k.
+	Top1, TopK int
+	// MRR is the mean reciprocal rank of the answers, counting answers
+	// beyond the first k as 0.
+	MRR float64
+	// Index is the time taken to embed and index the sample, Query the
+	// mean time per query.
+	Index, Query time.Duration
+}
+
+// minQueryTerms is the fewest distinctive terms a chunk needs to make a
+// keyword query of.
+const minQueryTerms = 2
+
+// Sample picks up to n chunks at random, the same ones for the same seed,
+// keeping their corpus order.
+func Sample(chunks []domain.Chunk, n int, seed int64) []domain.Chunk {
+	if n <= 0 || n >= len(chunks) {
+		return chunks
+	}
+	picked := rand.New(rand.NewSource(seed)).Perm(len(chunks))[:n]
+	sort.Ints(picked)
+	out := make([]domain.Chunk, n)
+	for i, j := range picked {
+		out[i] = chunks[j]
+	}
+	return out
+}
+
+// KeywordQueries makes up to n queries of terms words each from chunks of
+// the sample chosen at random. The words are drawn from the chunk's most
+// distinctive terms within the sample, so that a query names its chunk's
+// subject without quoting it. Such queries share words with their answer,
+// which favors lexical embedders such as TF-IDF.
+func KeywordQueries(chunks []domain.Chunk, n, terms int, seed int64) []Pair {
+	texts := make([]string, len(chunks))
+	for i, ch := range chunks {
+		texts[i] = ch.Text
+	}
+	kw := keywords.NewExtractor(texts)
+	rng := rand.New(rand.NewSource(seed))
+	var pairs []Pair
+	for _, i := range rng.Perm(len(chunks)) {
+		if len(pairs) == n {
+			break
+		}
+		top := kw.Document(i, 2*terms)
+		if len(top) < minQueryTerms {
+			continue
+		}
+		rng.Shuffle(len(top), func(a, b int) { top[a], top[b] = top[b], top[a] })
+		query := top[0]
+		for _, t := range top[1:min(terms, len(top))] {
+			query += " " + t
+		}
+		pairs = append(pairs, Pair{Query: query, Answer: i})
+	}
+	return pairs
+}
+
+// GeneratedQueries asks generate, such as a language model, for a query
+// answered by each of up to n chunks of the sample chosen at random. Chunks
+// it returns no query for are skipped.
+func GeneratedQueries(ctx context.Context, chunks []domain.Chunk, n int, seed int64, generate func(ctx context.Context, passage string) (string, error)) ([]Pair, error) {
+	var pairs []Pair
+	for _, i := range rand.New(rand.NewSource(seed)).Perm(len(chunks)) {
+		if len(pairs) == n {
+			break
+		}
+		query, err := generate(ctx, chunks[i].Text)
+		if err != nil {
+			return pairs, err
+		}
+		if query != "" {
+			pairs = append(pairs, Pair{Query: query, Answer: i})
+		}
+	}
+	return pairs, nil
+}
+
+// Evaluate indexes the sample with emb in a memory store and searches the
+// first k results for each query.
+func Evaluate(ctx context.Context, model string, emb embedding.Embedder, chunks []domain.Chunk, pairs []Pair, k int) (Result, error) {
+	res := Result{Model: model, Queries: len(pairs)}
+	if len(chunks) == 0 {
+		return res, errors.New("no chunks to index")
+	}
+	start := time.Now()
+	texts := make([]string, len(chunks))
+	for i, ch := range chunks {
+		texts[i] = ch.Text
+	}
+	if err := emb.Prepare(texts); err != nil {
+		return res, err
+	}
+	var vectors [][]float64
+	if b, ok := emb.(embedding.BatchEmbedder); ok {
+		v, err := b.EmbedBatchContext(ctx, texts)
+		if err != nil {
+			return res, err
+		}
+		vectors = v
+	} else {
+		vectors = make([][]float64, len(chunks))
+		for i, text := range texts {
+			v, err := embed(ctx, emb, text)
+			if err != nil {
+				return res, err
+			}
+			vectors[i] = v
+		}
+	}
+	store := memory.NewStorage()
+	if err := store.Init(len(vectors[0])); err != nil {
+		return res, err
+	}
+	if err := store.Upsert(chunks, vectors); err != nil {
+		return res, err
+	}
+	res.Index = time.Since(start)
+
+	start = time.Now()
+	for _, p := range pairs {
+		v, err := embed(ctx, emb, p.Query)
+		if err != nil {
+			return res, err
+		}
+		results, err := store.Search(v, 0, k, vectorstore.Filter{})
+		if err != nil {
+			return res, err
+		}
+		answer := chunks[p.Answer]
+		for rank, r := range results {
+			// Chunks with the same text answer equally well.
+			if r.Chunk.ChunkID == answer.ChunkID || r.Chunk.Text == answer.Text {
+				if rank == 0 {
+					res.Top1++
+				}
+				res.TopK++
+				res.MRR += 1 / float64(rank+1)
+				break
+			}
+		}
+	}
+	if len(pairs) > 0 {
+		res.MRR /= float64(len(pairs))
+		res.Query = time.Since(start) / time.Duration(len(pairs))
+	}
+	return res, nil
+}
+
+// embed embeds text, abandoning remote requests when ctx is canceled.
+func embed(ctx context.Context, emb embedding.Embedder, text string) ([]float64, error) {
+	if ce, ok := emb.(embedding.ContextEmbedder); ok {
+		return ce.EmbedContext(ctx, text)
+	}
+	return emb.Embed(text)
+}
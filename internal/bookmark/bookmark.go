@@ -0,0 +1,64 @@
+// Package bookmark keeps the results a user marked for later, per index.
+package bookmark
+
+import (
+	"errors"
+	"time"
+
+	"rag/internal/jsonstore"
+)
+
+// Bookmark is a marked result: where its chunk is and what it said.
+type Bookmark struct {
+	ChunkID string `json:"chunk_id"`
+	Path    string `json:"path"`
+	Index   int    `json:"index"`
+	Text    string `json:"text"`
+	// Query is the query that found the result.
+	Query   string    `json:"query,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// Store persists bookmarks as a JSON file.
+type Store struct {
+	file *jsonstore.Store[Bookmark]
+}
+
+// NewStore returns a store backed by the file at path. The file is created
+// on first save.
+func NewStore(path string) *Store {
+	return &Store{file: jsonstore.New(path, func(b Bookmark) string { return b.ChunkID }, older)}
+}
+
+// List returns all bookmarks, oldest first.
+func (s *Store) List() ([]Bookmark, error) {
+	return s.file.List()
+}
+
+// Has reports whether the chunk with the given ID is bookmarked.
+func (s *Store) Has(chunkID string) (bool, error) {
+	_, ok, err := s.file.Get(chunkID)
+	return ok, err
+}
+
+// Add stores b, replacing any bookmark of the same chunk.
+func (s *Store) Add(b Bookmark) error {
+	if b.ChunkID == "" {
+		return errors.New("bookmark needs a chunk ID")
+	}
+	return s.file.Put(b)
+}
+
+// Delete removes the bookmark of the chunk with the given ID.
+func (s *Store) Delete(chunkID string) error {
+	_, err := s.file.Delete(chunkID)
+	return err
+}
+
+// older orders bookmarks by creation, then by chunk.
+func older(a, b Bookmark) bool {
+	if !a.Created.Equal(b.Created) {
+		return a.Created.Before(b.Created)
+	}
+	return a.ChunkID < b.ChunkID
+}
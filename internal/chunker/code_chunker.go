@@ -0,0 +1,114 @@
+package chunker
+
+import (
+	"strconv"
+	"strings"
+
+	"rag/internal/domain"
+)
+
+// CodeChunker splits source code into chunks of whole top-level blocks: a
+// block starts after a blank line at a line without indentation, which in
+// most languages is where functions, types and comments on them begin.
+// Blocks are packed into chunks of up to linesPerChunk lines; longer blocks
+// are cut at line boundaries into pieces of about that size.
+type CodeChunker struct {
+	linesPerChunk int
+}
+
+// NewCodeChunker creates a code chunker; linesPerChunk <= 0 selects 40.
+func NewCodeChunker(linesPerChunk int) *CodeChunker {
+	if linesPerChunk <= 0 {
+		linesPerChunk = 40
+	}
+	return &CodeChunker{linesPerChunk: linesPerChunk}
+}
+
+// line is a line of source with its byte range, newline excluded.
+type line struct {
+	start, end int
+	blank      bool
+	indented   bool
+}
+
+// Chunk splits the provided document into chunks of top-level blocks.
+func (c *CodeChunker) Chunk(document domain.Document) ([]domain.Chunk, error) {
+	text := document.Content
+	var lines []line
+	for pos := 0; pos < len(text); {
+		end := strings.IndexByte(text[pos:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += pos
+		}
+		s := strings.TrimRight(text[pos:end], "\r")
+		lines = append(lines, line{
+			start:    pos,
+			end:      pos + len(s),
+			blank:    strings.TrimSpace(s) == "",
+			indented: s != "" && (s[0] == ' ' || s[0] == '\t'),
+		})
+		pos = end + 1
+	}
+	// Block boundaries: indices of lines that start a top-level block.
+	var blocks []int
+	for i, l := range lines {
+		if l.blank {
+			continue
+		}
+		if len(blocks) == 0 || (!l.indented && lines[i-1].blank) {
+			blocks = append(blocks, i)
+		}
+	}
+	var chunks []domain.Chunk
+	emit := func(from, to int) {
+		// Trim blank lines at either end.
+		for from < to && lines[from].blank {
+			from++
+		}
+		for to > from && lines[to-1].blank {
+			to--
+		}
+		if from == to {
+			return
+		}
+		start, end := lines[from].start, lines[to-1].end
+		idx := len(chunks)
+		chunks = append(chunks, domain.Chunk{
+			DocumentID: document.ID,
+			ChunkID:    document.ID + ":" + strconv.Itoa(idx),
+			Text:       text[start:end],
+			Index:      idx,
+			Path:       document.Path,
+			Time:       document.Time,
+			Metadata:   document.Metadata,
+			Start:      start,
+			End:        end,
+		})
+	}
+	from := -1
+	for b, first := range blocks {
+		last := len(lines)
+		if b+1 < len(blocks) {
+			last = blocks[b+1]
+		}
+		if from >= 0 && last-from > c.linesPerChunk {
+			emit(from, first)
+			from = -1
+		}
+		if from < 0 {
+			from = first
+		}
+		// A block longer than a chunk is cut into chunk-sized pieces; a
+		// last piece of a few lines stays with the one before.
+		for last-from > c.linesPerChunk+c.linesPerChunk/4 {
+			emit(from, from+c.linesPerChunk)
+			from += c.linesPerChunk
+		}
+	}
+	if from >= 0 {
+		emit(from, len(lines))
+	}
+	return chunks, nil
+}
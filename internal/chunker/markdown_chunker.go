@@ -0,0 +1,228 @@
+package chunker
+
+import (
+	"maps"
+	"strconv"
+	"strings"
+
+	"rag/internal/domain"
+	"rag/internal/textutil"
+)
+
+// MarkdownChunker splits Markdown along its structure: every heading starts
+// a new chunk, and sections longer than charsPerChunk are cut between
+// paragraphs, lists and fenced code blocks rather than inside them. Headings
+// inside code fences are code. A heading directly followed by a subheading
+// stays with it, so no chunk is a bare title. Each chunk's "heading"
+// metadata holds the headings it falls under, such as "Install > Linux".
+type MarkdownChunker struct {
+	charsPerChunk int
+}
+
+// NewMarkdownChunker creates a Markdown chunker; charsPerChunk <= 0 selects
+// 1500.
+func NewMarkdownChunker(charsPerChunk int) *MarkdownChunker {
+	if charsPerChunk <= 0 {
+		charsPerChunk = 1500
+	}
+	return &MarkdownChunker{charsPerChunk: charsPerChunk}
+}
+
+// mdBlock is a heading, paragraph or fenced code block with its byte range.
+type mdBlock struct {
+	start, end int
+	// level is 1-6 for headings and 0 otherwise.
+	level int
+	title string
+	fence bool
+}
+
+// Chunk splits the provided document into chunks of sections.
+func (c *MarkdownChunker) Chunk(document domain.Document) ([]domain.Chunk, error) {
+	text := document.Content
+	var chunks []domain.Chunk
+	// headings[i] is the current heading of level i+1.
+	var headings [6]string
+	from, to := -1, -1
+	body := false
+	emit := func() {
+		if from < 0 {
+			return
+		}
+		idx := len(chunks)
+		ch := domain.Chunk{
+			DocumentID: document.ID,
+			ChunkID:    document.ID + ":" + strconv.Itoa(idx),
+			Text:       text[from:to],
+			Index:      idx,
+			Path:       document.Path,
+			Time:       document.Time,
+			Metadata:   document.Metadata,
+			Start:      from,
+			End:        to,
+		}
+		var path []string
+		for _, h := range headings {
+			if h != "" {
+				path = append(path, h)
+			}
+		}
+		if len(path) > 0 {
+			ch.Metadata = maps.Clone(document.Metadata)
+			if ch.Metadata == nil {
+				ch.Metadata = make(map[string]string)
+			}
+			ch.Metadata["heading"] = strings.Join(path, " > ")
+		}
+		chunks = append(chunks, ch)
+		from, to, body = -1, -1, false
+	}
+	for _, b := range c.split(text, markdownBlocks(text)) {
+		if b.level > 0 {
+			if body {
+				emit()
+			}
+			headings[b.level-1] = b.title
+			for i := b.level; i < len(headings); i++ {
+				headings[i] = ""
+			}
+		} else {
+			if body && b.end-from > c.charsPerChunk {
+				emit()
+			}
+			body = true
+		}
+		if from < 0 {
+			from = b.start
+		}
+		to = b.end
+	}
+	emit()
+	return chunks, nil
+}
+
+// split cuts blocks longer than a chunk: code blocks at line ends and
+// other blocks at sentence ends, into pieces of up to charsPerChunk bytes
+// where possible.
+func (c *MarkdownChunker) split(text string, blocks []mdBlock) []mdBlock {
+	var out []mdBlock
+	for _, b := range blocks {
+		if b.level > 0 || b.end-b.start <= c.charsPerChunk {
+			out = append(out, b)
+			continue
+		}
+		// Cut points are the ends of the lines or sentences of the block.
+		var cuts []int
+		if b.fence {
+			for pos := b.start; pos < b.end; {
+				nl := strings.IndexByte(text[pos:b.end], '\n')
+				if nl < 0 {
+					break
+				}
+				pos += nl + 1
+				cuts = append(cuts, pos-1)
+			}
+		} else {
+			for _, sp := range textutil.SentenceSpans(text[b.start:b.end]) {
+				cuts = append(cuts, b.start+sp[1])
+			}
+		}
+		cuts = append(cuts, b.end)
+		start, last := b.start, b.start
+		for _, cut := range cuts {
+			if cut-start > c.charsPerChunk && last > start {
+				out = append(out, mdBlock{start: start, end: last, fence: b.fence})
+				start = skipSpace(text, last, b.end)
+			}
+			last = cut
+		}
+		if start < b.end {
+			out = append(out, mdBlock{start: start, end: b.end, fence: b.fence})
+		}
+	}
+	return out
+}
+
+// skipSpace returns the position of the first non-space byte of
+// text[pos:end], or end.
+func skipSpace(text string, pos, end int) int {
+	for pos < end && strings.ContainsRune(" \t\r\n", rune(text[pos])) {
+		pos++
+	}
+	return pos
+}
+
+// markdownBlocks lists the headings, paragraphs and fenced code blocks of
+// text in order. Paragraphs run to the next blank line, heading or fence;
+// a paragraph underlined with === or --- is a heading (setext style).
+func markdownBlocks(text string) []mdBlock {
+	var blocks []mdBlock
+	// para is the index in blocks of the open paragraph, or -1.
+	para := -1
+	// fence is the opening fence of the open code block ("```" or "~~~"
+	// runs), or "".
+	fence := ""
+	for pos := 0; pos < len(text); {
+		end := strings.IndexByte(text[pos:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += pos
+		}
+		line := strings.TrimRight(text[pos:end], "\r")
+		lineEnd := pos + len(line)
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		switch {
+		case fence != "":
+			blocks[len(blocks)-1].end = lineEnd
+			if indent < 4 && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+		case trimmed == "":
+			para = -1
+		case indent < 4 && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+			blocks = append(blocks, mdBlock{start: pos + indent, end: lineEnd, fence: true})
+			para = -1
+		case indent < 4 && atxLevel(trimmed) > 0:
+			level := atxLevel(trimmed)
+			title := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(trimmed[level:]), "#"))
+			blocks = append(blocks, mdBlock{start: pos + indent, end: lineEnd, level: level, title: title})
+			para = -1
+		case para >= 0 && indent < 4 && setextLevel(trimmed) > 0:
+			b := &blocks[para]
+			b.level = setextLevel(trimmed)
+			b.title = strings.Join(strings.Fields(text[b.start:b.end]), " ")
+			b.end = lineEnd
+			para = -1
+		case para >= 0:
+			blocks[para].end = lineEnd
+		default:
+			para = len(blocks)
+			blocks = append(blocks, mdBlock{start: pos + indent, end: lineEnd})
+		}
+		pos = end + 1
+	}
+	return blocks
+}
+
+// atxLevel returns the level of a "# Title" heading line, or 0.
+func atxLevel(line string) int {
+	n := len(line) - len(strings.TrimLeft(line, "#"))
+	if n == 0 || n > 6 || (n < len(line) && line[n] != ' ' && line[n] != '\t') {
+		return 0
+	}
+	return n
+}
+
+// setextLevel returns 1 for a line of '=' and 2 for a line of '-', or 0.
+func setextLevel(line string) int {
+	switch {
+	case strings.Trim(line, "=") == "":
+		return 1
+	case strings.Trim(line, "-") == "":
+		return 2
+	}
+	return 0
+}
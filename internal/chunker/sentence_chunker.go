@@ -1,18 +1,16 @@
 package chunker
 
 import (
-	"regexp"
 	"strconv"
-	"strings"
 
 	"rag/internal/domain"
+	"rag/internal/textutil"
 )
 
 // SentenceChunker splits text into sentence-based chunks with overlap.
 type SentenceChunker struct {
 	sentencesPerChunk int
 	overlapSentences  int
-	splitter          *regexp.Regexp
 }
 
 // NewSentenceChunker creates a sentence-based chunker with optional overlap.
@@ -23,26 +21,21 @@ func NewSentenceChunker(sentencesPerChunk, overlapSentences int) *SentenceChunke
 	if overlapSentences < 0 {
 		overlapSentences = 0
 	}
+	// Chunks must advance by at least one sentence.
+	if overlapSentences >= sentencesPerChunk {
+		overlapSentences = sentencesPerChunk - 1
+	}
 	return &SentenceChunker{
 		sentencesPerChunk: sentencesPerChunk,
 		overlapSentences:  overlapSentences,
-		splitter:          regexp.MustCompile(`(?m)(?U)([^.!?]+[.!?])`),
 	}
 }
 
 // Chunk splits the provided document into sentence-based chunks.
 func (c *SentenceChunker) Chunk(document domain.Document) ([]domain.Chunk, error) {
-	sentences := c.splitter.FindAllString(document.Content, -1)
+	sentences := textutil.SentenceSpans(document.Content)
 	if len(sentences) == 0 {
-		trimmed := strings.TrimSpace(document.Content)
-		if trimmed == "" {
-			return nil, nil
-		}
-		sentences = []string{trimmed}
-	}
-	// Trim spaces
-	for i := range sentences {
-		sentences[i] = strings.TrimSpace(sentences[i])
+		return nil, nil
 	}
 	var chunks []domain.Chunk
 	i := 0
@@ -52,12 +45,18 @@ func (c *SentenceChunker) Chunk(document domain.Document) ([]domain.Chunk, error
 		if end > len(sentences) {
 			end = len(sentences)
 		}
-		text := strings.Join(sentences[i:end], " ")
+		// Keep the source text between the sentences, so paragraph breaks
+		// survive as they do when chunks are re-read from the source.
 		chunk := domain.Chunk{
 			DocumentID: document.ID,
 			ChunkID:    document.ID + ":" + strconv.Itoa(idx),
-			Text:       text,
+			Text:       document.Content[sentences[i][0]:sentences[end-1][1]],
 			Index:      idx,
+			Path:       document.Path,
+			Time:       document.Time,
+			Metadata:   document.Metadata,
+			Start:      sentences[i][0],
+			End:        sentences[end-1][1],
 		}
 		chunks = append(chunks, chunk)
 		if end == len(sentences) {
@@ -0,0 +1,62 @@
+package chunker
+
+import (
+	"strconv"
+	"testing"
+
+	"rag/internal/domain"
+	"rag/internal/textutil"
+)
+
+func FuzzSentenceChunker(f *testing.F) {
+	f.Add("One. Two. Three. Four. Five. Six.", uint8(2), uint8(1))
+	f.Add("A single sentence without an end", uint8(5), uint8(0))
+	f.Add("", uint8(3), uint8(1))
+	f.Add("Pi is 3.14. Really?! \"Yes.\"\n\nこれはペンです。はい！", uint8(1), uint8(4))
+	f.Add("x. y. z.", uint8(0), uint8(0))
+	f.Fuzz(func(t *testing.T, content string, per, overlap uint8) {
+		c := NewSentenceChunker(int(per), int(overlap))
+		doc := domain.Document{ID: "doc", Path: "doc.txt", Content: content}
+		chunks, err := c.Chunk(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sentences := textutil.SentenceSpans(content)
+		if len(sentences) == 0 {
+			if len(chunks) != 0 {
+				t.Fatalf("%d chunks of a text without sentences", len(chunks))
+			}
+			return
+		}
+		if len(chunks) == 0 {
+			t.Fatalf("no chunks of %d sentences", len(sentences))
+		}
+		if chunks[0].Start != sentences[0][0] || chunks[len(chunks)-1].End != sentences[len(sentences)-1][1] {
+			t.Fatalf("chunks cover [%d, %d), sentences [%d, %d)", chunks[0].Start, chunks[len(chunks)-1].End, sentences[0][0], sentences[len(sentences)-1][1])
+		}
+		for i, ch := range chunks {
+			if ch.Index != i || ch.ChunkID != "doc:"+strconv.Itoa(i) || ch.DocumentID != "doc" || ch.Path != "doc.txt" {
+				t.Fatalf("chunk %d is numbered %d (%s) of %s at %s", i, ch.Index, ch.ChunkID, ch.DocumentID, ch.Path)
+			}
+			if ch.Start < 0 || ch.Start >= ch.End || ch.End > len(content) || ch.Text != content[ch.Start:ch.End] {
+				t.Fatalf("chunk %d [%d, %d) does not match its text %q", i, ch.Start, ch.End, ch.Text)
+			}
+			if i > 0 && (ch.Start <= chunks[i-1].Start || ch.End <= chunks[i-1].End) {
+				t.Fatalf("chunk %d [%d, %d) does not advance past chunk %d [%d, %d)", i, ch.Start, ch.End, i-1, chunks[i-1].Start, chunks[i-1].End)
+			}
+		}
+		// Every sentence is in a chunk.
+		for _, sp := range sentences {
+			covered := false
+			for _, ch := range chunks {
+				if ch.Start <= sp[0] && sp[1] <= ch.End {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				t.Fatalf("sentence %q is in no chunk", content[sp[0]:sp[1]])
+			}
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package cluster
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Result holds the outcome of a k-means run.
+type Result struct {
+	// Assignments maps each input vector to its cluster index.
+	Assignments []int
+	Centroids   [][]float64
+}
+
+// KMeans groups vectors into k clusters using k-means++ seeding and Lloyd
+// iterations. The seed makes runs reproducible.
+func KMeans(vectors [][]float64, k, maxIter int, seed int64) Result {
+	n := len(vectors)
+	if n == 0 || k <= 0 {
+		return Result{}
+	}
+	if k > n {
+		k = n
+	}
+	if maxIter <= 0 {
+		maxIter = 50
+	}
+	rng := rand.New(rand.NewSource(seed))
+	centroids := seedPlusPlus(vectors, k, rng)
+	assign := make([]int, n)
+	for i := range assign {
+		assign[i] = -1
+	}
+	dim := len(vectors[0])
+	for iter := 0; iter < maxIter; iter++ {
+		changed := false
+		for i, v := range vectors {
+			best, bestDist := 0, math.Inf(1)
+			for c, centroid := range centroids {
+				if d := sqDist(v, centroid); d < bestDist {
+					best, bestDist = c, d
+				}
+			}
+			if assign[i] != best {
+				assign[i] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+		sums := make([][]float64, k)
+		counts := make([]int, k)
+		for c := range sums {
+			sums[c] = make([]float64, dim)
+		}
+		for i, v := range vectors {
+			c := assign[i]
+			counts[c]++
+			for j := range v {
+				sums[c][j] += v[j]
+			}
+		}
+		for c := range centroids {
+			if counts[c] == 0 {
+				// Re-seed empty clusters with a random point.
+				centroids[c] = append([]float64(nil), vectors[rng.Intn(n)]...)
+				continue
+			}
+			for j := range sums[c] {
+				sums[c][j] /= float64(counts[c])
+			}
+			centroids[c] = sums[c]
+		}
+	}
+	return Result{Assignments: assign, Centroids: centroids}
+}
+
+// seedPlusPlus picks initial centroids spread out proportionally to the
+// squared distance from already chosen ones.
+func seedPlusPlus(vectors [][]float64, k int, rng *rand.Rand) [][]float64 {
+	centroids := make([][]float64, 0, k)
+	centroids = append(centroids, append([]float64(nil), vectors[rng.Intn(len(vectors))]...))
+	dists := make([]float64, len(vectors))
+	for len(centroids) < k {
+		total := 0.0
+		for i, v := range vectors {
+			d := sqDist(v, centroids[len(centroids)-1])
+			if len(centroids) == 1 || d < dists[i] {
+				dists[i] = d
+			}
+			total += dists[i]
+		}
+		if total == 0 {
+			break
+		}
+		target := rng.Float64() * total
+		pick := len(vectors) - 1
+		for i, d := range dists {
+			target -= d
+			if target <= 0 {
+				pick = i
+				break
+			}
+		}
+		centroids = append(centroids, append([]float64(nil), vectors[pick]...))
+	}
+	return centroids
+}
+
+func sqDist(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
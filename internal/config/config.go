@@ -1,11 +1,17 @@
 package config
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"rag/internal/paths"
 )
 
 // OpenAIEmbedderConfig holds configuration for the OpenAI-compatible embedder.
@@ -15,25 +21,115 @@ type OpenAIEmbedderConfig struct {
 	Model       string `yaml:"model"`
 	TimeoutSecs int    `yaml:"timeout_secs"`
 	BatchSize   int    `yaml:"batch_size"`
+	// MaxInputTokens overrides the model's known context length (0 = auto).
+	MaxInputTokens int `yaml:"max_input_tokens"`
+	// Overflow is "split" (embed windows and average) or "truncate".
+	Overflow string `yaml:"overflow"`
+	// MaxRetries bounds retries per request (0 = default of 5, -1 = none).
+	MaxRetries int `yaml:"max_retries"`
+	// RequestsPerMinute caps the request rate across concurrent requests
+	// (0 = no limit).
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+}
+
+// LLMConfig configures the OpenAI-compatible chat model used to translate
+// results and to write rag bench-models queries. Unlike the embedder's, the API key is optional: an empty
+// api_key_env sends none, as local servers such as Ollama need none.
+type LLMConfig struct {
+	BaseURL     string `yaml:"base_url"`
+	APIKeyEnv   string `yaml:"api_key_env"`
+	Model       string `yaml:"model"`
+	TimeoutSecs int    `yaml:"timeout_secs"`
+	// MaxRetries bounds retries per request (0 = default of 3, -1 = none).
+	MaxRetries int `yaml:"max_retries"`
 }
 
 // EmbedderConfig selects and configures the text embedder implementation.
 type EmbedderConfig struct {
-	Type   string                `yaml:"type"`
-	OpenAI *OpenAIEmbedderConfig `yaml:"openai,omitempty"`
+	Type string `yaml:"type"`
+	// Concurrency is how many embedding requests an ingest makes at once
+	// to a remote embedder (0 or 1 = one at a time).
+	Concurrency int                   `yaml:"concurrency"`
+	OpenAI      *OpenAIEmbedderConfig `yaml:"openai,omitempty"`
 }
 
 // ChunkerConfig configures how documents are split into chunks.
 type ChunkerConfig struct {
+	// Type is "sentence" (default), "code" or "markdown".
 	Type              string `yaml:"type"`
 	SentencesPerChunk int    `yaml:"sentences_per_chunk"`
 	OverlapSentences  int    `yaml:"overlap_sentences"`
+	// LinesPerChunk bounds code chunks (default 40).
+	LinesPerChunk int `yaml:"lines_per_chunk,omitempty"`
+	// CharsPerChunk bounds Markdown chunks in bytes (default 1500).
+	CharsPerChunk int `yaml:"chars_per_chunk,omitempty"`
+	// Overrides select other chunkers or sizes for files matching a
+	// pattern; the first matching override applies.
+	Overrides []ChunkerOverride `yaml:"overrides,omitempty"`
+}
+
+// ChunkerOverride configures the chunker of files matching Pattern, such as
+// "*.go" or "docs/*.md" (matched against the end of the path). Unset fields
+// keep the values of the main chunker config.
+type ChunkerOverride struct {
+	Pattern           string `yaml:"pattern"`
+	Type              string `yaml:"type,omitempty"`
+	SentencesPerChunk int    `yaml:"sentences_per_chunk,omitempty"`
+	OverlapSentences  *int   `yaml:"overlap_sentences,omitempty"`
+	LinesPerChunk     int    `yaml:"lines_per_chunk,omitempty"`
+	CharsPerChunk     int    `yaml:"chars_per_chunk,omitempty"`
+}
+
+// Apply returns base with the fields set in o replaced.
+func (o ChunkerOverride) Apply(base ChunkerConfig) ChunkerConfig {
+	base.Overrides = nil
+	if o.Type != "" {
+		base.Type = o.Type
+	}
+	if o.SentencesPerChunk != 0 {
+		base.SentencesPerChunk = o.SentencesPerChunk
+	}
+	if o.OverlapSentences != nil {
+		base.OverlapSentences = *o.OverlapSentences
+	}
+	if o.LinesPerChunk != 0 {
+		base.LinesPerChunk = o.LinesPerChunk
+	}
+	if o.CharsPerChunk != 0 {
+		base.CharsPerChunk = o.CharsPerChunk
+	}
+	return base
 }
 
 // VectorStoreConfig selects and configures the vector store implementation.
 type VectorStoreConfig struct {
 	Type   string        `yaml:"type"`
 	Qdrant *QdrantConfig `yaml:"qdrant,omitempty"`
+	Disk   *DiskConfig   `yaml:"disk,omitempty"`
+	// TextOnDisk keeps chunk texts in a memory-mapped log under the cache
+	// directory instead of RAM (memory store and lexical fallback).
+	TextOnDisk bool `yaml:"text_on_disk"`
+	// CompressText zstd-compresses chunk texts in the on-disk text log, the
+	// disk store and Qdrant payloads.
+	CompressText bool `yaml:"compress_text"`
+	// HydrateFromSource stores only source paths and byte offsets; result
+	// texts are re-read from the files at display time.
+	HydrateFromSource bool `yaml:"hydrate_from_source"`
+	// Distance is "cosine" (default), for which vectors are normalized to
+	// unit length, or "dot" for raw dot products.
+	Distance string `yaml:"distance"`
+	// Index is the memory store's search structure: "flat" (default, exact
+	// scan) or "ivf" (approximate, for corpora of millions of chunks).
+	Index string    `yaml:"index"`
+	IVF   IVFConfig `yaml:"ivf"`
+}
+
+// IVFConfig tunes the IVF-Flat index.
+type IVFConfig struct {
+	// Lists is the number of k-means clusters (0 = √chunks).
+	Lists int `yaml:"lists"`
+	// Probes is how many clusters a query searches (0 = 8).
+	Probes int `yaml:"probes"`
 }
 
 // QdrantConfig contains connection details for a Qdrant vector store.
@@ -41,14 +137,216 @@ type QdrantConfig struct {
 	URL         string `yaml:"url"`
 	APIKey      string `yaml:"api_key"`
 	Collection  string `yaml:"collection"`
-	Distance    string `yaml:"distance"`
+	Distance    string `yaml:"distance"` // sets vector_store.distance when that is empty
 	TimeoutSecs int    `yaml:"timeout_secs"`
+	// PayloadMapping maps chunk fields (text, document_id, path, ...) to the
+	// payload keys of a collection populated by another pipeline, e.g.
+	// {text: page_content, path: metadata.source} for LangChain.
+	PayloadMapping map[string]string `yaml:"payload_mapping,omitempty"`
+	// ReadOnly queries the collection as it is, without input files: rag
+	// never writes to or drops it.
+	ReadOnly bool `yaml:"read_only"`
+}
+
+// DiskConfig configures the on-disk vector store.
+type DiskConfig struct {
+	// Path is the database file; empty selects vectors.db under the data
+	// directory.
+	Path string `yaml:"path"`
 }
 
 // SummarizerConfig selects and configures the summarizer.
 type SummarizerConfig struct {
 	Type         string `yaml:"type"`
 	MaxSentences int    `yaml:"max_sentences"`
+	// BudgetUnit measures the summary length in "sentences" (default,
+	// bounded by MaxSentences), "words" or "tokens" (bounded by Budget).
+	BudgetUnit string `yaml:"budget_unit"`
+	Budget     int    `yaml:"budget"`
+	// Strategy is "hierarchical" (summarize each document, then the
+	// summaries) or "flat" (one pass over the concatenated corpus).
+	Strategy string `yaml:"strategy"`
+	// Workers bounds concurrent document summaries (0 = one per CPU).
+	Workers int `yaml:"workers"`
+	// Language is the ISO 639-1 code of the summary language, or "auto"
+	// for the corpus's dominant language.
+	Language string `yaml:"language"`
+}
+
+// IngestConfig controls how ingestion reacts to partial failures.
+type IngestConfig struct {
+	// FailureThreshold is the fraction of chunks (0..1) allowed to fail
+	// embedding before the whole ingest is aborted. Zero selects the default
+	// of 0.1; a negative value aborts on the first failure.
+	FailureThreshold float64 `yaml:"failure_threshold"`
+	// SoftDelete keeps the chunks of files gone from the corpus in the
+	// store, left out of searches, until they return or rag purge drops
+	// them.
+	SoftDelete bool `yaml:"soft_delete"`
+	// AllowedRoots confines the files indexed, and read back for results,
+	// to these directories, and rules out URL sources; empty allows any
+	// file. It keeps instances indexing what others ask for, such as
+	// rag rpc or rag watch on a shared manifest, from reading other files.
+	AllowedRoots []string `yaml:"allowed_roots,omitempty"`
+}
+
+// KeywordsConfig configures keyword extraction.
+type KeywordsConfig struct {
+	PerDocument int `yaml:"per_document"`
+	// Payloads stores document keywords with every chunk for filtering.
+	Payloads bool `yaml:"payloads"`
+}
+
+// TopicsConfig configures corpus topic clustering.
+type TopicsConfig struct {
+	// Count is the number of k-means clusters (0 = derived from corpus size).
+	Count int `yaml:"count"`
+}
+
+// SearchConfig controls how query results are presented.
+type SearchConfig struct {
+	// TopK is the number of results a query returns unless it asks for
+	// another with k:N (default 10).
+	TopK int `yaml:"top_k"`
+	// GroupByDocument groups results by source document by default.
+	GroupByDocument bool `yaml:"group_by_document"`
+	// Order sorts the results of a query: "score" (default), "document"
+	// (by path, then position in the file) or "recency" (newest first).
+	Order string `yaml:"order"`
+	// MaxPerDocument caps the hits returned from a single document so that
+	// results cover more sources (0 = no cap).
+	MaxPerDocument int `yaml:"max_per_document"`
+	// LinkBoost raises the scores of notes with many wiki-link backlinks
+	// (0 = off).
+	LinkBoost float64 `yaml:"link_boost"`
+	// LexicalScoring ranks the lexical fallback: "ochiai" (default), the
+	// weighted share of query terms in a chunk, or "bm25".
+	LexicalScoring string `yaml:"lexical_scoring"`
+	// Weights combine the signals of each hit into its score.
+	Weights ScoreWeights `yaml:"weights"`
+	// Strategy is "single" (default), searching each query whole, or
+	// "decompose", searching each question of a query asking several on
+	// its own, split by the llm model when one is configured and by rules
+	// otherwise.
+	Strategy string `yaml:"strategy"`
+	// HyDE embeds, for every query, an answer drafted by the llm model
+	// instead of the query; hyde:on and hyde:off override it per query.
+	HyDE bool `yaml:"hyde"`
+	// TranslateTo is the language results are translated into on request
+	// (TUI actions menu, rag query --translate), by the llm model.
+	TranslateTo string `yaml:"translate_to"`
+}
+
+// ScoreWeights weigh the ranking signals: vector and lexical scores are
+// blended, recency (and link_boost) raise scores by up to weight·100%.
+type ScoreWeights struct {
+	// Vector defaults to 1 when unset.
+	Vector  *float64 `yaml:"vector,omitempty"`
+	Lexical float64  `yaml:"lexical"`
+	Recency float64  `yaml:"recency"`
+}
+
+// RetrievalConfig selects how the first-stage ranking of a query is made.
+type RetrievalConfig struct {
+	// Mode is "vector" (default), vector search with the lexical ranking
+	// as fallback for queries without a vector signal, "hybrid", which
+	// always runs BM25 and vector search and merges them by reciprocal
+	// rank fusion, or "bm25", which ranks by BM25 alone.
+	Mode string `yaml:"mode"`
+	// RRFK damps the fusion's preference for top ranks: a hit at rank r
+	// of a ranking adds 1/(rrf_k+r) (default 60).
+	RRFK int `yaml:"rrf_k"`
+	// BM25 tunes the BM25 ranking, wherever it is used.
+	BM25 BM25Config `yaml:"bm25"`
+}
+
+// BM25Config holds the BM25 parameters; unset ones keep their defaults.
+type BM25Config struct {
+	// K1 sets how fast repeated terms stop counting (default 1.2).
+	K1 *float64 `yaml:"k1,omitempty"`
+	// B sets how much longer chunks are penalized, from 0 to 1 (default
+	// 0.75).
+	B *float64 `yaml:"b,omitempty"`
+}
+
+// EnrichConfig configures the rewriting of chunks before they are embedded.
+type EnrichConfig struct {
+	// Template is the text embedded for each chunk, with the placeholders
+	// {text}, {title}, {path} and {context}, a sentence situating the
+	// chunk in its document written by the llm model; empty embeds the
+	// chunks as they are.
+	Template string `yaml:"template"`
+	// MaxDocumentChars bounds the document sent with each chunk to the
+	// llm model; longer ones are cut around the chunk (default 20000).
+	MaxDocumentChars int `yaml:"max_document_chars"`
+	// Workers is how many contexts are written at once (default 4).
+	Workers int `yaml:"workers"`
+}
+
+// GeneratorConfig configures the answers written by the llm model from the
+// results of a query.
+type GeneratorConfig struct {
+	// Sources is the number of top results an answer is written from
+	// (default 5).
+	Sources int `yaml:"sources"`
+	// MaxSourceChars bounds the text of each result sent to the model
+	// (default 2000).
+	MaxSourceChars int `yaml:"max_source_chars"`
+	// Grounding checks each sentence of an answer against its sources:
+	// "similarity" (default) by embedding similarity with the configured
+	// embedder, "llm" by asking the llm model, or "off".
+	Grounding string `yaml:"grounding"`
+	// GroundingThreshold is the similarity to a source a sentence needs to
+	// count as supported (default 0.5).
+	GroundingThreshold float64 `yaml:"grounding_threshold"`
+	// Model writes the answers in place of the llm model when set.
+	Model string `yaml:"model,omitempty"`
+	// Temperature of the answers, from 0 to 2 (unset = the server's
+	// default).
+	Temperature *float64 `yaml:"temperature,omitempty"`
+	// MaxTokens bounds the length of the answers (0 = the server's
+	// default).
+	MaxTokens int `yaml:"max_tokens,omitempty"`
+}
+
+// LoadersConfig tunes file loaders.
+type LoadersConfig struct {
+	// ChatWindowMinutes is the silence that splits chat exports into
+	// separate conversation windows (default 30).
+	ChatWindowMinutes int `yaml:"chat_window_minutes"`
+	// ChatWindowMessages caps the messages per conversation window (default 30).
+	ChatWindowMessages int `yaml:"chat_window_messages"`
+	// DetectLanguage tags documents with their language (ISO 639-1) in the
+	// "lang" metadata, for lang: filters, unless their source sets one.
+	DetectLanguage bool `yaml:"detect_language"`
+}
+
+// NormalizeConfig controls how words are normalized before they are
+// compared, in TF-IDF vectors, lexical search and highlighting. Changing it
+// requires re-ingesting, as queries must be tokenized like the index.
+type NormalizeConfig struct {
+	// NFKC applies Unicode compatibility normalization (composed accents,
+	// ligatures, full-width letters).
+	NFKC bool `yaml:"nfkc"`
+	// FoldDiacritics ignores accents, so "café" matches "cafe".
+	FoldDiacritics bool `yaml:"fold_diacritics"`
+}
+
+// TUIConfig tunes the interactive interface.
+type TUIConfig struct {
+	// TerminalBidi leaves right-to-left text (Arabic, Hebrew) in logical
+	// order for terminals with their own bidi support; by default it is
+	// reordered for display.
+	TerminalBidi bool `yaml:"terminal_bidi"`
+	// WrapColumn is where reading mode wraps result text (0 = window width).
+	WrapColumn int `yaml:"wrap_column"`
+	// HangingIndent indents the wrapped lines of a paragraph in reading
+	// mode (0 = 2 columns, negative = none).
+	HangingIndent int `yaml:"hanging_indent"`
+	// Language is the ISO 639-1 code of the language of the TUI and of
+	// command-line messages ("en" or "ru"), or "auto" for the one set by
+	// LC_ALL, LC_MESSAGES or LANG.
+	Language string `yaml:"language"`
 }
 
 // AppConfig is the root application configuration structure.
@@ -57,6 +355,24 @@ type AppConfig struct {
 	Chunker     ChunkerConfig     `yaml:"chunker"`
 	VectorStore VectorStoreConfig `yaml:"vector_store"`
 	Summarizer  SummarizerConfig  `yaml:"summarizer"`
+	Ingest      IngestConfig      `yaml:"ingest"`
+	Keywords    KeywordsConfig    `yaml:"keywords"`
+	Topics      TopicsConfig      `yaml:"topics"`
+	Search      SearchConfig      `yaml:"search"`
+	Retrieval   RetrievalConfig   `yaml:"retrieval"`
+	Enrich      EnrichConfig      `yaml:"enrich"`
+	Generator   GeneratorConfig   `yaml:"generator"`
+	Loaders     LoadersConfig     `yaml:"loaders"`
+	Normalize   NormalizeConfig   `yaml:"normalize"`
+	TUI         TUIConfig         `yaml:"tui"`
+	LLM         *LLMConfig        `yaml:"llm,omitempty"`
+	// OpenCommand opens a result's file from the TUI and rag open, as a
+	// command template such as "code -g {path}:{line}"; empty opens it in
+	// $VISUAL or $EDITOR.
+	OpenCommand string `yaml:"open_command,omitempty"`
+	// Embedders are further embedders by name, for rag bench-models to
+	// compare with the configured one.
+	Embedders map[string]EmbedderConfig `yaml:"embedders,omitempty"`
 }
 
 // Load reads a config from a specified path. If the file does not exist, returns defaults.
@@ -77,15 +393,16 @@ func Load(path string) (*AppConfig, error) {
 	return &cfg, nil
 }
 
-// LoadDefault tries ./config.yaml first, then ~/.config/rag/config.yaml.
-// If neither exists, it writes defaults to ~/.config/rag/config.yaml and returns them.
+// LoadDefault tries ./config.yaml first, then the user config file (see
+// paths.ConfigFile). If neither exists, it writes defaults to the user config file
+// and returns them.
 func LoadDefault() (*AppConfig, string, error) {
 	cwdPath := "config.yaml"
 	if _, err := os.Stat(cwdPath); err == nil {
 		cfg, err := Load(cwdPath)
 		return cfg, cwdPath, err
 	}
-	userPath, err := defaultUserConfigPath()
+	userPath, err := paths.ConfigFile()
 	if err != nil {
 		return nil, "", err
 	}
@@ -112,12 +429,80 @@ func Save(path string, cfg *AppConfig) error {
 	return os.WriteFile(path, data, 0o644)
 }
 
-func defaultUserConfigPath() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
+// SaveScoreWeights sets search.weights in the config file at path, leaving
+// the rest of the file as it is, comments included. The file is created if
+// it does not exist.
+func SaveScoreWeights(path string, w ScoreWeights) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: not a mapping", path)
+	}
+	weights := mappingKey(mappingKey(root, "search"), "weights")
+	vector := 1.0
+	if w.Vector != nil {
+		vector = *w.Vector
+	}
+	setScalar(weights, "vector", vector)
+	setScalar(weights, "lexical", w.Lexical)
+	setScalar(weights, "recency", w.Recency)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
 	}
-	return filepath.Join(home, ".config", "rag", "config.yaml"), nil
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// mappingKey returns the mapping under key in m, adding it if missing.
+func mappingKey(m *yaml.Node, key string) *yaml.Node {
+	if v := valueOf(m, key); v != nil {
+		if v.Kind != yaml.MappingNode {
+			// An empty or null value, such as "weights:" alone.
+			*v = yaml.Node{Kind: yaml.MappingNode, HeadComment: v.HeadComment, LineComment: v.LineComment}
+		}
+		return v
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, v)
+	return v
+}
+
+// setScalar sets key in m to x, keeping the comments of an existing value.
+func setScalar(m *yaml.Node, key string, x float64) {
+	value := strconv.FormatFloat(x, 'g', -1, 64)
+	if v := valueOf(m, key); v != nil {
+		*v = yaml.Node{Kind: yaml.ScalarNode, Value: value, HeadComment: v.HeadComment, LineComment: v.LineComment, FootComment: v.FootComment}
+		return
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &yaml.Node{Kind: yaml.ScalarNode, Value: value})
+}
+
+// valueOf returns the value of key in the mapping m, or nil.
+func valueOf(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
 }
 
 func defaultConfig() *AppConfig {
@@ -125,7 +510,14 @@ func defaultConfig() *AppConfig {
 		Embedder:    EmbedderConfig{Type: "tfidf"},
 		Chunker:     ChunkerConfig{Type: "sentence", SentencesPerChunk: 5, OverlapSentences: 1},
 		VectorStore: VectorStoreConfig{Type: "memory"},
-		Summarizer:  SummarizerConfig{Type: "frequency", MaxSentences: 5},
+		Summarizer:  SummarizerConfig{Type: "frequency", MaxSentences: 5, Strategy: "hierarchical", Language: "auto"},
+		Ingest:      IngestConfig{FailureThreshold: 0.1},
+		Keywords:    KeywordsConfig{PerDocument: 10},
+		Search:      SearchConfig{TopK: 10},
+		Retrieval:   RetrievalConfig{Mode: "vector", RRFK: 60},
+		Enrich:      EnrichConfig{Workers: 4},
+		Generator:   GeneratorConfig{Grounding: "similarity"},
+		TUI:         TUIConfig{Language: "auto"},
 	}
 	return cfg
 }
@@ -134,21 +526,63 @@ func applyConfigDefaults(cfg *AppConfig) {
 	if cfg.Chunker.SentencesPerChunk == 0 {
 		cfg.Chunker.SentencesPerChunk = 5
 	}
-	if cfg.Embedder.Type == "openai" && cfg.Embedder.OpenAI != nil {
-		if cfg.Embedder.OpenAI.BaseURL == "" {
-			cfg.Embedder.OpenAI.BaseURL = "https://api.openai.com/v1"
-		}
-		if cfg.Embedder.OpenAI.APIKeyEnv == "" {
-			cfg.Embedder.OpenAI.APIKeyEnv = "OPENAI_API_KEY"
-		}
-		if cfg.Embedder.OpenAI.Model == "" {
-			cfg.Embedder.OpenAI.Model = "text-embedding-3-small"
-		}
-		if cfg.Embedder.OpenAI.TimeoutSecs == 0 {
-			cfg.Embedder.OpenAI.TimeoutSecs = 30
-		}
-		if cfg.Embedder.OpenAI.BatchSize == 0 {
-			cfg.Embedder.OpenAI.BatchSize = 32
-		}
+	if cfg.Ingest.FailureThreshold == 0 {
+		cfg.Ingest.FailureThreshold = 0.1
+	}
+	if cfg.Search.TopK <= 0 {
+		cfg.Search.TopK = 10
+	}
+	if cfg.Keywords.PerDocument == 0 {
+		cfg.Keywords.PerDocument = 10
+	}
+	if cfg.Retrieval.RRFK <= 0 {
+		cfg.Retrieval.RRFK = 60
+	}
+	if cfg.Enrich.Workers <= 0 {
+		cfg.Enrich.Workers = 4
+	}
+	if cfg.Generator.Grounding == "" {
+		cfg.Generator.Grounding = "similarity"
+	}
+	if cfg.Summarizer.Strategy == "" {
+		cfg.Summarizer.Strategy = "hierarchical"
+	}
+	if cfg.Summarizer.Language == "" {
+		cfg.Summarizer.Language = "auto"
+	}
+	if cfg.TUI.Language == "" {
+		cfg.TUI.Language = "auto"
+	}
+	if cfg.VectorStore.Distance == "" && cfg.VectorStore.Qdrant != nil {
+		cfg.VectorStore.Distance = strings.ToLower(cfg.VectorStore.Qdrant.Distance)
+	}
+	applyEmbedderDefaults(&cfg.Embedder)
+	for name, e := range cfg.Embedders {
+		applyEmbedderDefaults(&e)
+		cfg.Embedders[name] = e
+	}
+}
+
+func applyEmbedderDefaults(e *EmbedderConfig) {
+	if e.Type != "openai" || e.OpenAI == nil {
+		return
+	}
+	if e.OpenAI.BaseURL == "" {
+		e.OpenAI.BaseURL = "https://api.openai.com/v1"
+	}
+	if e.OpenAI.APIKeyEnv == "" {
+		e.OpenAI.APIKeyEnv = "OPENAI_API_KEY"
+	}
+	if e.OpenAI.Model == "" {
+		e.OpenAI.Model = "text-embedding-3-small"
+	}
+	if e.OpenAI.TimeoutSecs == 0 {
+		e.OpenAI.TimeoutSecs = 30
+	}
+	if e.OpenAI.BatchSize == 0 {
+		e.OpenAI.BatchSize = 32
+	}
+	if e.OpenAI.Overflow == "" {
+		e.OpenAI.Overflow = "split"
 	}
 }
@@ -0,0 +1,140 @@
+// Package conversation keeps the conversations of the plain interactive
+// mode, per index: the queries asked, the chunks retrieved for them and the
+// answers written from them, to be listed later or exported to Markdown for
+// notes.
+package conversation
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"rag/internal/jsonstore"
+)
+
+// Source is a retrieved chunk, by ID and by where it is.
+type Source struct {
+	ChunkID string `json:"chunk_id"`
+	Path    string `json:"path"`
+	Index   int    `json:"index"`
+}
+
+// Turn is a query with what was retrieved and answered for it.
+type Turn struct {
+	Query   string    `json:"query"`
+	Time    time.Time `json:"time"`
+	Results []Source  `json:"results"`
+	// Answer is the answer written, if any, from Sources; source n (from
+	// 1) is cited in it as [n], and Cited lists those it cites.
+	Answer  string   `json:"answer,omitempty"`
+	Sources []Source `json:"sources,omitempty"`
+	Cited   []int    `json:"cited,omitempty"`
+}
+
+// Conversation is the turns of one interactive session.
+type Conversation struct {
+	ID      string    `json:"id"`
+	Started time.Time `json:"started"`
+	Turns   []Turn    `json:"turns"`
+}
+
+// New returns an empty conversation started at t, named after it.
+func New(t time.Time) Conversation {
+	return Conversation{ID: t.Format("20060102-150405"), Started: t}
+}
+
+// Store persists conversations as a JSON file.
+type Store struct {
+	file *jsonstore.Store[Conversation]
+}
+
+// NewStore returns a store backed by the file at path. The file is created
+// on first save.
+func NewStore(path string) *Store {
+	return &Store{file: jsonstore.New(path, func(c Conversation) string { return c.ID }, older)}
+}
+
+// List returns all conversations, oldest first.
+func (s *Store) List() ([]Conversation, error) {
+	return s.file.List()
+}
+
+// Get returns the conversation with the given ID.
+func (s *Store) Get(id string) (Conversation, error) {
+	c, ok, err := s.file.Get(id)
+	if err != nil {
+		return Conversation{}, err
+	}
+	if !ok {
+		return Conversation{}, fmt.Errorf("no conversation %q", id)
+	}
+	return c, nil
+}
+
+// Save stores c, replacing the conversation of the same ID. Conversations
+// without turns are not stored.
+func (s *Store) Save(c Conversation) error {
+	if len(c.Turns) == 0 {
+		return nil
+	}
+	return s.file.Put(c)
+}
+
+// Delete removes the conversation with the given ID.
+func (s *Store) Delete(id string) error {
+	ok, err := s.file.Delete(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no conversation %q", id)
+	}
+	return nil
+}
+
+// older orders conversations by start, then by ID.
+func older(a, b Conversation) bool {
+	if !a.Started.Equal(b.Started) {
+		return a.Started.Before(b.Started)
+	}
+	return a.ID < b.ID
+}
+
+// Markdown writes c as a Markdown note: a section per query, with its
+// answer and the sources it cites as a numbered list matching the [n] of
+// the answer, or the results retrieved when it was not answered.
+func Markdown(w io.Writer, c Conversation) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Conversation of %s\n", c.Started.Format("2006-01-02 15:04"))
+	for _, t := range c.Turns {
+		fmt.Fprintf(&b, "\n## %s\n\n", t.Query)
+		if t.Answer == "" {
+			for _, r := range t.Results {
+				fmt.Fprintf(&b, "- %s\n", sourceRef(r))
+			}
+			continue
+		}
+		b.WriteString(t.Answer + "\n\n")
+		cited := make(map[int]bool, len(t.Cited))
+		for _, n := range t.Cited {
+			cited[n] = true
+		}
+		for i, s := range t.Sources {
+			// A list item starting with [n] would read as a link reference.
+			line := fmt.Sprintf("%d. %s", i+1, sourceRef(s))
+			if len(t.Cited) > 0 && !cited[i+1] {
+				line += " (not cited)"
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// sourceRef names a source as path#index, in code so that Markdown leaves
+// the path as it is.
+func sourceRef(s Source) string {
+	return fmt.Sprintf("`%s#%d`", s.Path, s.Index)
+}
@@ -0,0 +1,109 @@
+// Package corpus reads corpus manifests: YAML files listing the sources of
+// a corpus with per-source settings, so a project's corpus can be indexed
+// the same way every time.
+//
+//	name: handbook
+//	sources:
+//	  - path: docs/*.md
+//	    tags: [docs]
+//	  - path: notes/*.txt
+//	    loader: .md
+//	    chunker: {sentences_per_chunk: 3, overlap_sentences: 0}
+//	  - url: https://example.com/faq.txt
+//	    tags: [faq, web]
+//	  - path: docs/de/*.md
+//	    lang: de
+package corpus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is a parsed corpus manifest.
+type Manifest struct {
+	Name    string   `yaml:"name"`
+	Sources []Source `yaml:"sources"`
+}
+
+// Source is one entry of a manifest: a file path or glob, or a URL.
+type Source struct {
+	// Path is a file or glob pattern, relative to the manifest's directory
+	// unless absolute.
+	Path string `yaml:"path"`
+	// URL is fetched over HTTP(S) instead of reading a file.
+	URL string `yaml:"url"`
+	// Tags are added to the documents' tags, so `tag:` filters select them.
+	Tags []string `yaml:"tags"`
+	// Loader is the extension whose loader parses the source (e.g. ".md"),
+	// overriding the file extension.
+	Loader string `yaml:"loader"`
+	// Lang is the language of the documents (ISO 639-1), stored in their
+	// "lang" metadata for lang: filters.
+	Lang string `yaml:"lang"`
+	// Chunker overrides the configured chunk sizes for this source.
+	Chunker *Chunker `yaml:"chunker,omitempty"`
+}
+
+// Chunker holds per-source chunker settings; unset fields keep the
+// configured values.
+type Chunker struct {
+	// Type is "sentence", "code" or "markdown".
+	Type              string `yaml:"type"`
+	SentencesPerChunk *int   `yaml:"sentences_per_chunk"`
+	OverlapSentences  *int   `yaml:"overlap_sentences"`
+	LinesPerChunk     *int   `yaml:"lines_per_chunk"`
+	CharsPerChunk     *int   `yaml:"chars_per_chunk"`
+}
+
+// IsManifest reports whether a command-line argument names a manifest
+// rather than a document: manifests are .yaml or .yml files.
+func IsManifest(arg string) bool {
+	ext := strings.ToLower(filepath.Ext(arg))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// Load reads and validates the manifest at path, resolving source paths
+// against its directory.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(m.Sources) == 0 {
+		return nil, fmt.Errorf("%s: no sources", path)
+	}
+	dir := filepath.Dir(path)
+	for i := range m.Sources {
+		src := &m.Sources[i]
+		if (src.Path == "") == (src.URL == "") {
+			return nil, fmt.Errorf("%s: source %d: set exactly one of path and url", path, i+1)
+		}
+		if src.URL != "" && !strings.HasPrefix(src.URL, "http://") && !strings.HasPrefix(src.URL, "https://") {
+			return nil, fmt.Errorf("%s: source %d: url must be http or https", path, i+1)
+		}
+		if src.Loader != "" && !strings.HasPrefix(src.Loader, ".") {
+			src.Loader = "." + src.Loader
+		}
+		if src.Path != "" && !filepath.IsAbs(src.Path) && !strings.HasPrefix(src.Path, "~") {
+			src.Path = filepath.Join(dir, filepath.FromSlash(src.Path))
+		}
+		if c := src.Chunker; c != nil && (notPositive(c.SentencesPerChunk) || notPositive(c.LinesPerChunk) || notPositive(c.CharsPerChunk) || (c.OverlapSentences != nil && *c.OverlapSentences < 0)) {
+			return nil, fmt.Errorf("%s: source %d: chunk sizes must be positive and overlap_sentences not negative", path, i+1)
+		}
+	}
+	return &m, nil
+}
+
+// notPositive reports whether an optional size is set but not positive.
+func notPositive(n *int) bool {
+	return n != nil && *n <= 0
+}
@@ -0,0 +1,102 @@
+// Package decompose splits a query that asks several things, such as "how
+// does watch mode work and what happens to saved searches?", into the
+// questions it asks, to be searched one by one: a single embedding of the
+// whole query tends to land between the passages answering each part and
+// find neither.
+package decompose
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaxQuestions bounds the questions a query is split into; further ones are
+// dropped.
+const MaxQuestions = 4
+
+// interrogatives are the words that start a new question after a
+// conjunction, so that "cats and dogs" stays whole while "how X works and
+// why Y fails" is split.
+var interrogatives = map[string]bool{
+	"what": true, "how": true, "why": true, "when": true, "where": true, "who": true,
+	"whom": true, "whose": true, "which": true, "is": true, "are": true, "was": true,
+	"were": true, "does": true, "do": true, "did": true, "can": true, "could": true,
+	"should": true, "will": true, "would": true, "has": true, "have": true,
+	"что": true, "как": true, "почему": true, "зачем": true, "когда": true, "где": true,
+	"куда": true, "откуда": true, "кто": true, "какой": true, "какая": true,
+	"какое": true, "какие": true, "сколько": true,
+}
+
+// conjunctions join two questions when an interrogative follows them.
+var conjunctions = map[string]bool{"and": true, "or": true, "и": true, "а": true, "или": true}
+
+// Split returns the questions query asks, by rules: it is cut at question
+// marks, semicolons, line breaks and sentence ends, and at a conjunction
+// followed by a question word. A query asking one thing is returned alone.
+func Split(query string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, sentence := range sentences(query) {
+		for _, q := range atConjunctions(sentence) {
+			key := strings.ToLower(q)
+			if seen[key] || !hasLetter(q) {
+				continue
+			}
+			seen[key] = true
+			out = append(out, q)
+		}
+	}
+	if len(out) > MaxQuestions {
+		out = out[:MaxQuestions]
+	}
+	if len(out) == 0 {
+		return []string{strings.TrimSpace(query)}
+	}
+	return out
+}
+
+// sentences cuts s at question and exclamation marks, semicolons, line
+// breaks, and periods followed by a space or the end, keeping the marks.
+func sentences(s string) []string {
+	var out []string
+	start := 0
+	for i, r := range s {
+		end := false
+		switch r {
+		case '?', '!', ';', '\n':
+			end = true
+		case '.':
+			next := i + 1
+			end = next == len(s) || s[next] == ' ' || s[next] == '\n'
+		}
+		if end {
+			out = append(out, strings.TrimSpace(strings.TrimRight(s[start:i+1], ";\n")))
+			start = i + 1
+		}
+	}
+	return append(out, strings.TrimSpace(s[start:]))
+}
+
+// atConjunctions cuts s before each conjunction that is followed by an
+// interrogative, dropping the conjunctions.
+func atConjunctions(s string) []string {
+	words := strings.Fields(s)
+	var out []string
+	start := 0
+	for i := 1; i+1 < len(words); i++ {
+		if conjunctions[bare(words[i])] && interrogatives[bare(words[i+1])] {
+			out = append(out, strings.TrimRight(strings.Join(words[start:i], " "), ","))
+			start = i + 1
+		}
+	}
+	return append(out, strings.Join(words[start:], " "))
+}
+
+// bare lowercases w without the punctuation around it.
+func bare(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, func(r rune) bool { return !unicode.IsLetter(r) }))
+}
+
+func hasLetter(s string) bool {
+	return strings.IndexFunc(s, unicode.IsLetter) >= 0
+}
@@ -0,0 +1,125 @@
+package dedup
+
+import (
+	"hash/fnv"
+	"strings"
+
+	"rag/internal/textutil"
+)
+
+const (
+	numHashes = 128
+	bands     = 32
+	rows      = numHashes / bands
+	// shingleSize is the number of consecutive words per shingle.
+	shingleSize = 3
+)
+
+// Signature is a MinHash sketch of a text's word shingles.
+type Signature [numHashes]uint64
+
+// seeds are fixed so signatures are comparable across runs.
+var seeds = func() [numHashes]uint64 {
+	var s [numHashes]uint64
+	x := uint64(0x9e3779b97f4a7c15)
+	for i := range s {
+		x = splitmix(x)
+		s[i] = x
+	}
+	return s
+}()
+
+// Sign computes the MinHash signature of text. Texts shorter than a single
+// shingle are hashed as one shingle.
+func Sign(text string) Signature {
+	var sig Signature
+	for i := range sig {
+		sig[i] = ^uint64(0)
+	}
+	for _, sh := range shingles(textutil.Tokens(text)) {
+		h := hashString(sh)
+		for i := range sig {
+			if v := splitmix(h ^ seeds[i]); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	return sig
+}
+
+// Similarity estimates the Jaccard similarity of the underlying shingle sets.
+func (s Signature) Similarity(o Signature) float64 {
+	eq := 0
+	for i := range s {
+		if s[i] == o[i] {
+			eq++
+		}
+	}
+	return float64(eq) / numHashes
+}
+
+// Pair is a pair of item indexes with their similarity.
+type Pair struct {
+	A, B       int
+	Similarity float64
+}
+
+// NearDuplicates returns all pairs whose estimated Jaccard similarity is at
+// least threshold. Locality-sensitive hashing over signature bands limits the
+// comparisons to likely candidates.
+func NearDuplicates(sigs []Signature, threshold float64) []Pair {
+	seen := make(map[[2]int]struct{})
+	var out []Pair
+	for b := 0; b < bands; b++ {
+		buckets := make(map[uint64][]int)
+		for i, sig := range sigs {
+			h := uint64(b)
+			for _, v := range sig[b*rows : (b+1)*rows] {
+				h = splitmix(h ^ v)
+			}
+			buckets[h] = append(buckets[h], i)
+		}
+		for _, members := range buckets {
+			for x := 0; x < len(members); x++ {
+				for y := x + 1; y < len(members); y++ {
+					key := [2]int{members[x], members[y]}
+					if _, ok := seen[key]; ok {
+						continue
+					}
+					seen[key] = struct{}{}
+					if sim := sigs[key[0]].Similarity(sigs[key[1]]); sim >= threshold {
+						out = append(out, Pair{A: key[0], B: key[1], Similarity: sim})
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+func shingles(tokens []string) []string {
+	if len(tokens) == 0 {
+		return nil
+	}
+	if len(tokens) < shingleSize {
+		return []string{strings.Join(tokens, " ")}
+	}
+	out := make([]string, 0, len(tokens)-shingleSize+1)
+	for i := 0; i+shingleSize <= len(tokens); i++ {
+		out = append(out, strings.Join(tokens[i:i+shingleSize], " "))
+	}
+	return out
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum64()
+}
+
+func splitmix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,60 @@
+// Package docdate detects the date a document was written from its header
+// lines or file name.
+package docdate
+
+import (
+	"net/mail"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// headerLines is how many leading lines are searched for a date header.
+const headerLines = 20
+
+var (
+	// dateHeaderRe matches email headers ("Date: ...") and front matter
+	// fields ("date: 2024-01-31").
+	dateHeaderRe = regexp.MustCompile(`(?i)^date:\s*(.+?)\s*$`)
+	isoDateRe    = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+	layouts      = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+)
+
+// Detect returns the date of a document: a Date header among its first
+// lines, otherwise an ISO date in the file name. It returns the zero time
+// when the document is undated.
+func Detect(path, content string) time.Time {
+	for i, line := range strings.SplitN(content, "\n", headerLines+1) {
+		if i == headerLines {
+			break
+		}
+		m := dateHeaderRe.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+		if t, ok := Parse(strings.Trim(m[1], `"'`)); ok {
+			return t
+		}
+	}
+	if m := isoDateRe.FindString(filepath.Base(path)); m != "" {
+		if t, err := time.Parse("2006-01-02", m); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// Parse reads a date in RFC 5322 (email), RFC 3339 or ISO "2006-01-02"
+// form, optionally with a time of day.
+func Parse(s string) (time.Time, bool) {
+	if t, err := mail.ParseDate(s); err == nil {
+		return t, true
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -1,41 +1,171 @@
 package domain
 
+import (
+	"strconv"
+	"time"
+)
+
 // Document represents a single text file loaded into the system.
 type Document struct {
 	ID      string
 	Path    string
 	Content string
+	// Time is when the document was written; zero if undated.
+	Time time.Time
+	// Title names a document that is one part of its file, such as a chat
+	// conversation window.
+	Title string
+	// Metadata holds loader-provided fields (e.g. sender, channel) that
+	// queries can filter on. Multiple values are separated by ", ".
+	Metadata map[string]string
+	// Offset is the byte offset of Content within the file at Path, e.g.
+	// past a front matter block, and LineOffset the number of lines before
+	// it.
+	Offset     int
+	LineOffset int
+	// Transformed is set when Content was produced by a loader rather than
+	// read verbatim, so chunks cannot be re-read from Path by offset.
+	Transformed bool
+	// Atomic documents are indexed as a single chunk.
+	Atomic bool
+	// Links are the wiki-link targets in the document, as written.
+	Links []string
 }
 
 // Chunk is a semantically meaningful part of a document used for indexing.
+// Start and End are byte offsets of the chunk within the source document,
+// and StartLine and EndLine the lines, from 1, of its first and last
+// characters; all are 0 when the chunk cannot be located in the file.
 type Chunk struct {
 	DocumentID string
 	ChunkID    string
 	Text       string
 	Index      int
+	Path       string
+	Start      int
+	End        int
+	StartLine  int
+	EndLine    int
+	Keywords   []string
+	// Time is the date of the chunk's document; zero if undated.
+	Time     time.Time
+	Metadata map[string]string
+	// Embedded is a digest of the text embedded for the chunk when an
+	// enricher rewrote it; empty when Text was embedded as it is.
+	Embedded string
+}
+
+// Location names where the chunk is in its file: by lines, as in
+// "notes/chapter3.txt:120–160", when they are known, else by index, as in
+// "notes/chapter3.txt#4".
+func (c Chunk) Location() string {
+	switch {
+	case c.StartLine == 0:
+		return c.Path + "#" + strconv.Itoa(c.Index)
+	case c.EndLine <= c.StartLine:
+		return c.Path + ":" + strconv.Itoa(c.StartLine)
+	}
+	return c.Path + ":" + strconv.Itoa(c.StartLine) + "–" + strconv.Itoa(c.EndLine)
+}
+
+// DocumentInfo describes an ingested document for browsing.
+type DocumentInfo struct {
+	ID       string
+	Path     string
+	Title    string
+	Chunks   int
+	Keywords []string
+}
+
+// Topic is a cluster of semantically related chunks.
+type Topic struct {
+	Label    string
+	Keywords []string
+	// Chunks are ordered from most to least representative.
+	Chunks []Chunk
 }
 
 // SearchResult represents a matching chunk with a relevance score.
 type SearchResult struct {
 	Chunk Chunk
 	Score float64
+	// Via lists the questions of a decomposed query whose searches found
+	// the chunk; it is nil for queries searched whole.
+	Via []string
 }
 
-
 // Chunker splits documents into chunks suitable for retrieval indexing.
 type Chunker interface {
 	Chunk(document Document) ([]Chunk, error)
 }
 
+// BudgetUnit is the unit a summary length is measured in.
+type BudgetUnit string
 
+const (
+	BudgetSentences BudgetUnit = "sentences"
+	BudgetWords     BudgetUnit = "words"
+	// BudgetTokens counts estimated model tokens, for summaries that go
+	// into LLM prompts.
+	BudgetTokens BudgetUnit = "tokens"
+)
+
+// Budget bounds the length of a summary.
+type Budget struct {
+	Unit BudgetUnit
+	Size int
+}
 
 // Summarizer produces a brief summary of the provided text.
 type Summarizer interface {
-	Summarize(text string, maxSentences int) (string, error)
+	Summarize(text string, budget Budget) (string, error)
+}
+
+// CorpusSummarizer is implemented by summarizers that summarize a corpus
+// document by document instead of as one concatenated text.
+type CorpusSummarizer interface {
+	SummarizeCorpus(documents []string, budget Budget) (string, error)
+}
+
+// Ingest stages reported through IngestProgress.
+const (
+	StageLoading     = "loading"
+	StageEnriching   = "enriching"
+	StageEmbedding   = "embedding"
+	StageSummarizing = "summarizing"
+)
+
+// IngestProgress reports how far an ingest has got: Done of Total items of
+// the current stage (documents while loading, chunks while enriching and
+// embedding).
+// Total is 0 when not known yet.
+type IngestProgress struct {
+	Stage string
+	Done  int
+	Total int
+}
+
+// IngestReport describes the outcome of an ingest, for callers to show.
+type IngestReport struct {
+	// Summary is the corpus summary; empty when the ingest was canceled.
+	Summary string
+	// Documents and Chunks count what was indexed.
+	Documents int
+	Chunks    int
+	// Skipped counts chunks left out of the index because embedding them
+	// failed.
+	Skipped int
+	// Deleted counts the chunks of documents gone from the corpus that the
+	// store keeps soft-deleted.
+	Deleted int
+	// Warnings describe problems that did not stop the ingest, such as
+	// files no loader reads.
+	Warnings []string
+	Duration time.Duration
 }
 
 // RAGService defines the operations exposed by the application core.
 type RAGService interface {
-	IngestDocuments(paths []string) (summary string, err error)
+	IngestDocuments(paths []string) (IngestReport, error)
 	Query(query string, topK int) ([]SearchResult, error)
 }
@@ -1,5 +1,7 @@
 package embedding
 
+import "context"
+
 // Embedder converts free text into a numeric vector representation.
 // Implementations may require a preparation phase over the corpus.
 type Embedder interface {
@@ -8,3 +10,34 @@ type Embedder interface {
 	Dimension() int
 	Embed(text string) ([]float64, error)
 }
+
+// IntoEmbedder is implemented by embedders that can write an embedding into
+// a buffer the caller owns, so that an ingest fills one backing array per
+// batch instead of allocating a vector per chunk.
+type IntoEmbedder interface {
+	// EmbedInto writes the embedding of text into dst, which has
+	// Dimension() elements.
+	EmbedInto(text string, dst []float64) error
+}
+
+// ContextEmbedder is implemented by embedders that can abandon a request
+// when its context is canceled, such as remote APIs.
+type ContextEmbedder interface {
+	EmbedContext(ctx context.Context, text string) ([]float64, error)
+}
+
+// Fingerprinter is implemented by embedders that can identify the function
+// they compute: equal fingerprints mean equal vectors for equal texts, so
+// that vectors stored by an earlier run can be reused. Embedders fitted to
+// a corpus include what they learned from it.
+type Fingerprinter interface {
+	Fingerprint() string
+}
+
+// BatchEmbedder is implemented by embedders that embed several texts per
+// request, such as remote APIs, so that an ingest makes far fewer round
+// trips. The vectors are in the order of the texts.
+type BatchEmbedder interface {
+	EmbedBatch(texts []string) ([][]float64, error)
+	EmbedBatchContext(ctx context.Context, texts []string) ([][]float64, error)
+}
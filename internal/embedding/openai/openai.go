@@ -2,14 +2,22 @@ package openai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
+
+	"rag/internal/textutil"
 )
 
 // Client is an OpenAI-compatible embeddings client implementing the Embedder interface.
@@ -18,9 +26,16 @@ type Client struct {
 	apiKey     string
 	model      string
 	timeout    time.Duration
-	dimension  int
+	dimension  atomic.Int64
 	client     *http.Client
 	maxRetries int
+	maxTokens  int
+	overflow   string
+	batchSize  int
+	limiter    *limiter
+	// singleInputs is set once the server is found to take one input per
+	// request only.
+	singleInputs atomic.Bool
 }
 
 // Config configures the OpenAI-compatible embeddings client.
@@ -29,8 +44,43 @@ type Config struct {
 	APIKeyEnv string
 	Model     string
 	Timeout   time.Duration
+	// MaxInputTokens caps the estimated token count of a single input.
+	// Zero selects a limit based on the model name.
+	MaxInputTokens int
+	// Overflow controls how over-long inputs are handled: "split" (default)
+	// embeds fixed-size windows and averages them, "truncate" keeps the head.
+	Overflow string
+	// MaxRetries bounds retries per request on network errors, 429 and 5xx.
+	// Zero uses the default of 5; a negative value disables retries.
+	MaxRetries int
+	// BatchSize caps the inputs per request of EmbedBatch (default 32).
+	BatchSize int
+	// RequestsPerMinute spaces requests, across concurrent callers, to stay
+	// within the API's rate limit (0 = no limit).
+	RequestsPerMinute int
+}
+
+// Overflow strategies for inputs exceeding the model's context length.
+const (
+	OverflowSplit    = "split"
+	OverflowTruncate = "truncate"
+)
+
+// modelMaxTokens lists known input limits of popular embedding models.
+var modelMaxTokens = map[string]int{
+	"text-embedding-3-small": 8191,
+	"text-embedding-3-large": 8191,
+	"text-embedding-ada-002": 8191,
+	"nomic-embed-text":       8192,
+	"mxbai-embed-large":      512,
+	"all-minilm":             256,
+	"snowflake-arctic-embed": 512,
+	"bge-m3":                 8192,
 }
 
+// defaultMaxTokens is used for models missing from modelMaxTokens.
+const defaultMaxTokens = 512
+
 // NewClient creates a new embeddings client using the provided configuration.
 func NewClient(cfg Config) (*Client, error) {
 	key := os.Getenv(cfg.APIKeyEnv)
@@ -47,81 +97,225 @@ func NewClient(cfg Config) (*Client, error) {
 	if t == 0 {
 		t = 30 * time.Second
 	}
+	maxTokens := cfg.MaxInputTokens
+	if maxTokens <= 0 {
+		maxTokens = lookupMaxTokens(cfg.Model)
+	}
+	retries := cfg.MaxRetries
+	if retries == 0 {
+		retries = 5
+	} else if retries < 0 {
+		retries = 0
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 32
+	}
+	switch cfg.Overflow {
+	case "":
+		cfg.Overflow = OverflowSplit
+	case OverflowSplit, OverflowTruncate:
+	default:
+		return nil, fmt.Errorf("unknown overflow strategy %q", cfg.Overflow)
+	}
 	return &Client{
 		baseURL:    cfg.BaseURL,
 		apiKey:     key,
 		model:      cfg.Model,
 		timeout:    t,
 		client:     &http.Client{Timeout: t},
-		maxRetries: 5,
+		maxRetries: retries,
+		maxTokens:  maxTokens,
+		overflow:   cfg.Overflow,
+		batchSize:  batchSize,
+		limiter:    newLimiter(cfg.RequestsPerMinute),
 	}, nil
 }
 
 // Name returns the identifier of this embedder implementation.
 func (c *Client) Name() string { return "openai" }
 
-// Prepare is not required for remote embedding. We will lazily set dimension on first embed.
+// Fingerprint identifies the server, model and input limits, which
+// together determine the vectors.
+func (c *Client) Fingerprint() string {
+	return fmt.Sprintf("openai %s %s %d %s", c.baseURL, c.model, c.maxTokens, c.overflow)
+}
+
+// Prepare is not required for remote embedding.
 func (c *Client) Prepare(corpus []string) error { return nil }
 
 // Dimension returns the dimensionality of the produced embedding vectors.
-func (c *Client) Dimension() int { return c.dimension }
+// It is 0 until the first successful embed, as the API reports it only
+// with a vector; it is safe to call concurrently with Embed.
+func (c *Client) Dimension() int { return int(c.dimension.Load()) }
 
 // Embed returns an embedding vector for the given text.
+// Inputs longer than the model's context are truncated or split and averaged.
 func (c *Client) Embed(text string) ([]float64, error) {
-	type reqBody struct {
-		Input  string `json:"input,omitempty"`
-		Prompt string `json:"prompt,omitempty"`
-		Model  string `json:"model"`
+	return c.EmbedContext(context.Background(), text)
+}
+
+// EmbedContext is Embed with a context that cancels pending requests and
+// retry waits.
+func (c *Client) EmbedContext(ctx context.Context, text string) ([]float64, error) {
+	windows := c.inputs(text)
+	vectors := make([][]float64, len(windows))
+	for i, w := range windows {
+		v, err := c.embedRequest(ctx, w)
+		if err != nil {
+			return nil, err
+		}
+		vectors[i] = v
 	}
-	url := fmt.Sprintf("%s/embeddings", c.baseURL)
-	for attempt := 0; attempt <= c.maxRetries; attempt++ {
-		body := reqBody{Input: text, Prompt: text, Model: c.model}
-		data, _ := json.Marshal(body)
-		req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	return combine(windows, vectors)
+}
 
-		resp, err := c.client.Do(req)
+// EmbedBatch returns embedding vectors for texts, sending up to BatchSize
+// inputs per request. Over-long texts are truncated or split and averaged
+// as by Embed.
+func (c *Client) EmbedBatch(texts []string) ([][]float64, error) {
+	return c.EmbedBatchContext(context.Background(), texts)
+}
+
+// EmbedBatchContext is EmbedBatch with a context that cancels pending
+// requests and retry waits.
+func (c *Client) EmbedBatchContext(ctx context.Context, texts []string) ([][]float64, error) {
+	// owners maps each input to the text it is (a window of).
+	var inputs []string
+	var owners []int
+	for i, text := range texts {
+		for _, w := range c.inputs(text) {
+			inputs = append(inputs, w)
+			owners = append(owners, i)
+		}
+	}
+	vectors := make([][]float64, 0, len(inputs))
+	for start := 0; start < len(inputs); start += c.batchSize {
+		vs, err := c.embedInputs(ctx, inputs[start:min(start+c.batchSize, len(inputs))])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, vs...)
+	}
+	out := make([][]float64, len(texts))
+	for first := 0; first < len(inputs); {
+		end := first + 1
+		for end < len(inputs) && owners[end] == owners[first] {
+			end++
+		}
+		v, err := combine(inputs[first:end], vectors[first:end])
 		if err != nil {
-			if attempt < c.maxRetries {
-				time.Sleep(retryDelay(attempt))
-				continue
-			}
 			return nil, err
 		}
+		out[owners[first]] = v
+		first = end
+	}
+	return out, nil
+}
 
-		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
-			// Respect Retry-After if provided
-			if ra := resp.Header.Get("Retry-After"); ra != "" {
-				if secs, err := strconv.Atoi(ra); err == nil {
-					_ = resp.Body.Close()
-					time.Sleep(time.Duration(secs) * time.Second)
-				} else {
-					_ = resp.Body.Close()
-					time.Sleep(retryDelay(attempt))
-				}
-			} else {
-				_ = resp.Body.Close()
-				time.Sleep(retryDelay(attempt))
-			}
-			if attempt < c.maxRetries {
-				continue
-			}
-			return nil, fmt.Errorf("openai embeddings failed: %s", resp.Status)
+// inputs cuts text into the inputs embedded for it: the text itself if it
+// fits the model's context, else its first window or all of them,
+// depending on the overflow strategy.
+func (c *Client) inputs(text string) []string {
+	if textutil.EstimateTokens(text) <= c.maxTokens {
+		return []string{text}
+	}
+	windows := splitByTokens(text, c.maxTokens)
+	if c.overflow == OverflowTruncate {
+		return windows[:1]
+	}
+	return windows
+}
+
+// combine averages the vectors of the windows of a text, weighted by their
+// length.
+func combine(windows []string, vectors [][]float64) ([]float64, error) {
+	if len(vectors) == 1 {
+		return vectors[0], nil
+	}
+	var sum []float64
+	totalWeight := 0.0
+	for i, v := range vectors {
+		if sum == nil {
+			sum = make([]float64, len(v))
 		}
+		if len(v) != len(sum) {
+			return nil, errors.New("inconsistent embedding dimension across windows")
+		}
+		weight := float64(utf8.RuneCountInString(windows[i]))
+		for j := range v {
+			sum[j] += v[j] * weight
+		}
+		totalWeight += weight
+	}
+	return normalize(sum, totalWeight), nil
+}
 
-		if resp.StatusCode >= 300 {
-			defer resp.Body.Close()
-			return nil, fmt.Errorf("openai embeddings failed: %s", resp.Status)
+// errSingleInputs reports a server that takes one input per request only.
+var errSingleInputs = errors.New("batch embeddings not supported")
+
+// embedInputs embeds inputs in one request, or one input per request for
+// servers that do not take a list of inputs, such as Ollama's native API.
+func (c *Client) embedInputs(ctx context.Context, inputs []string) ([][]float64, error) {
+	if len(inputs) > 1 && !c.singleInputs.Load() {
+		vectors, err := c.embedBatchRequest(ctx, inputs)
+		if !errors.Is(err, errSingleInputs) {
+			return vectors, err
+		}
+	}
+	vectors := make([][]float64, len(inputs))
+	for i, in := range inputs {
+		v, err := c.embedRequest(ctx, in)
+		if err != nil {
+			return nil, err
 		}
+		vectors[i] = v
+	}
+	return vectors, nil
+}
 
-		payload, err := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
+// embedBatchRequest embeds inputs in one request. A response that does not
+// hold a vector for each input marks the server as taking single inputs
+// only.
+func (c *Client) embedBatchRequest(ctx context.Context, inputs []string) ([][]float64, error) {
+	payload, err := c.post(ctx, struct {
+		Input []string `json:"input"`
+		Model string   `json:"model"`
+	}{inputs, c.model})
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &out); err != nil || len(out.Data) != len(inputs) {
+		c.singleInputs.Store(true)
+		return nil, errSingleInputs
+	}
+	vectors := make([][]float64, len(inputs))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(inputs) || vectors[d.Index] != nil || len(d.Embedding) == 0 {
+			c.singleInputs.Store(true)
+			return nil, errSingleInputs
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	c.dimension.CompareAndSwap(0, int64(len(vectors[0])))
+	return vectors, nil
+}
+
+func (c *Client) embedRequest(ctx context.Context, text string) ([]float64, error) {
+	type reqBody struct {
+		Input  string `json:"input,omitempty"`
+		Prompt string `json:"prompt,omitempty"`
+		Model  string `json:"model"`
+	}
+	for attempt := 0; ; attempt++ {
+		payload, err := c.post(ctx, reqBody{Input: text, Prompt: text, Model: c.model})
 		if err != nil {
-			if attempt < c.maxRetries {
-				time.Sleep(retryDelay(attempt))
-				continue
-			}
 			return nil, err
 		}
 		// Try OpenAI-compatible response first
@@ -133,9 +327,7 @@ func (c *Client) Embed(text string) ([]float64, error) {
 		if err := json.Unmarshal(payload, &openaiOut); err == nil {
 			if len(openaiOut.Data) > 0 && len(openaiOut.Data[0].Embedding) > 0 {
 				v := openaiOut.Data[0].Embedding
-				if c.dimension == 0 {
-					c.dimension = len(v)
-				}
+				c.dimension.CompareAndSwap(0, int64(len(v)))
 				return v, nil
 			}
 		}
@@ -146,20 +338,131 @@ func (c *Client) Embed(text string) ([]float64, error) {
 		if err := json.Unmarshal(payload, &ollamaOut); err == nil {
 			if len(ollamaOut.Embedding) > 0 {
 				v := ollamaOut.Embedding
-				if c.dimension == 0 {
-					c.dimension = len(v)
-				}
+				c.dimension.CompareAndSwap(0, int64(len(v)))
 				return v, nil
 			}
 		}
 		// If decoding failed, and retries remain, backoff and retry
-		if attempt < c.maxRetries {
-			time.Sleep(retryDelay(attempt))
+		if attempt >= c.maxRetries {
+			return nil, errors.New("no embedding returned")
+		}
+		if err := sleep(ctx, retryDelay(attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// post sends an embeddings request and returns the response body,
+// retrying network errors, 429 and 5xx responses and failed reads.
+func (c *Client) post(ctx context.Context, body any) ([]byte, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/embeddings", c.baseURL)
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if attempt >= c.maxRetries {
+				return nil, err
+			}
+			if err := sleep(ctx, retryDelay(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			_ = resp.Body.Close()
+			if attempt >= c.maxRetries {
+				return nil, fmt.Errorf("openai embeddings failed: %s", resp.Status)
+			}
+			// Respect Retry-After if provided
+			delay := retryDelay(attempt)
+			if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				delay = time.Duration(secs) * time.Second
+			}
+			if err := sleep(ctx, delay); err != nil {
+				return nil, err
+			}
 			continue
 		}
-		return nil, errors.New("no embedding returned")
+
+		if resp.StatusCode >= 300 {
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("openai embeddings failed: %s", resp.Status)
+		}
+
+		payload, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			if attempt >= c.maxRetries {
+				return nil, err
+			}
+			if err := sleep(ctx, retryDelay(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return payload, nil
+	}
+}
+
+// limiter spaces requests evenly to at most a number per minute.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	// next is the earliest time of the next request.
+	next time.Time
+}
+
+// newLimiter returns a limiter of perMinute requests, or nil (no limit)
+// for zero.
+func newLimiter(perMinute int) *limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &limiter{interval: time.Minute / time.Duration(perMinute)}
+}
+
+// wait waits for the turn of a request, or until ctx is canceled.
+func (l *limiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	at := time.Now()
+	if l.next.After(at) {
+		at = l.next
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+	return sleep(ctx, time.Until(at))
+}
+
+// sleep waits for d or until ctx is canceled.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	return nil, errors.New("no embedding returned")
 }
 
 func retryDelay(attempt int) time.Duration {
@@ -174,3 +477,80 @@ func retryDelay(attempt int) time.Duration {
 	}
 	return d
 }
+
+func lookupMaxTokens(model string) int {
+	if n, ok := modelMaxTokens[model]; ok {
+		return n
+	}
+	// Ollama-style tags such as "nomic-embed-text:latest"
+	if i := strings.IndexByte(model, ':'); i > 0 {
+		if n, ok := modelMaxTokens[model[:i]]; ok {
+			return n
+		}
+	}
+	return defaultMaxTokens
+}
+
+// splitByTokens cuts text into consecutive windows whose estimated token
+// count fits within limit, preferring to break on whitespace.
+func splitByTokens(text string, limit int) []string {
+	var windows []string
+	for text != "" {
+		if textutil.EstimateTokens(text) <= limit {
+			windows = append(windows, text)
+			break
+		}
+		cut, lastSpace, tokens, ascii := 0, -1, 0, 0
+		for i, r := range text {
+			if r < utf8.RuneSelf {
+				ascii++
+				if ascii == 4 {
+					tokens++
+					ascii = 0
+				}
+			} else {
+				tokens++
+			}
+			if tokens >= limit {
+				break
+			}
+			if r == ' ' || r == '\n' || r == '\t' {
+				lastSpace = i
+			}
+			cut = i + utf8.RuneLen(r)
+		}
+		if lastSpace > 0 {
+			cut = lastSpace
+		}
+		if cut == 0 {
+			_, cut = utf8.DecodeRuneInString(text)
+		}
+		if w := strings.TrimSpace(text[:cut]); w != "" {
+			windows = append(windows, w)
+		}
+		text = strings.TrimLeft(text[cut:], " \n\t")
+	}
+	if len(windows) == 0 {
+		windows = []string{""}
+	}
+	return windows
+}
+
+// normalize divides by the total weight and rescales to unit length.
+func normalize(v []float64, weight float64) []float64 {
+	if weight <= 0 {
+		return v
+	}
+	norm := 0.0
+	for i := range v {
+		v[i] /= weight
+		norm += v[i] * v[i]
+	}
+	norm = math.Sqrt(norm)
+	if norm > 0 {
+		for i := range v {
+			v[i] /= norm
+		}
+	}
+	return v
+}
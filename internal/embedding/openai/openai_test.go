@@ -0,0 +1,293 @@
+package openai
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reply is a canned response of the test server.
+type reply struct {
+	status int
+	header map[string]string
+	// file is the body, from testdata.
+	file string
+}
+
+// server is an embeddings endpoint that records the requests it gets and
+// answers them with replies in turn, repeating the last one.
+type server struct {
+	*httptest.Server
+	mu       sync.Mutex
+	replies  []reply
+	requests []map[string]any
+}
+
+func newServer(t *testing.T, replies ...reply) *server {
+	t.Helper()
+	s := &server{replies: replies}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/embeddings" {
+			t.Errorf("%s %s, want POST /v1/embeddings", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization: %q", got)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		s.mu.Lock()
+		rep := s.replies[min(len(s.requests), len(s.replies)-1)]
+		s.requests = append(s.requests, body)
+		s.mu.Unlock()
+		for k, v := range rep.header {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(rep.status)
+		if rep.file != "" {
+			w.Write(readFile(t, rep.file))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *server) received() []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func readFile(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func newTestClient(t *testing.T, url string, cfg Config) *Client {
+	t.Helper()
+	t.Setenv("RAG_TEST_OPENAI_KEY", "sk-test")
+	cfg.BaseURL = url + "/v1"
+	cfg.APIKeyEnv = "RAG_TEST_OPENAI_KEY"
+	c, err := NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestEmbedBatch(t *testing.T) {
+	srv := newServer(t, reply{status: http.StatusOK, file: "batch_response.json"})
+	c := newTestClient(t, srv.URL, Config{})
+	vectors, err := c.EmbedBatch([]string{"Raft elects a leader.", "Paxos agrees on a value.", "Feed the starter daily."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The response lists the vectors out of order; their index places them.
+	want := [][]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	if !reflect.DeepEqual(vectors, want) {
+		t.Errorf("vectors %v, want %v", vectors, want)
+	}
+	if c.Dimension() != 3 {
+		t.Errorf("Dimension() = %d, want 3", c.Dimension())
+	}
+	var request map[string]any
+	if err := json.Unmarshal(readFile(t, "batch_request.json"), &request); err != nil {
+		t.Fatal(err)
+	}
+	if got := srv.received(); len(got) != 1 || !reflect.DeepEqual(got[0], request) {
+		t.Errorf("requests %v, want %v", got, request)
+	}
+}
+
+func TestEmbedBatchSize(t *testing.T) {
+	// The server answers every input with a vector of its length. A single
+	// input goes alone, not as a list.
+	var (
+		mu    sync.Mutex
+		sizes []int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input json.RawMessage `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		var inputs []string
+		if err := json.Unmarshal(body.Input, &inputs); err != nil {
+			var input string
+			if err := json.Unmarshal(body.Input, &input); err != nil {
+				t.Errorf("input %s", body.Input)
+			}
+			inputs = []string{input}
+		}
+		mu.Lock()
+		sizes = append(sizes, len(inputs))
+		mu.Unlock()
+		type datum struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		}
+		var out struct {
+			Data []datum `json:"data"`
+		}
+		for i, in := range inputs {
+			out.Data = append(out.Data, datum{i, []float64{float64(len(in)), 1}})
+		}
+		json.NewEncoder(w).Encode(out)
+	}))
+	defer srv.Close()
+	c := newTestClient(t, srv.URL, Config{BatchSize: 2})
+	texts := []string{"a", "bb", "ccc", "dddd", "eeeee"}
+	vectors, err := c.EmbedBatch(texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(sizes, []int{2, 2, 1}) {
+		t.Errorf("requests of %v inputs, want [2 2 1]", sizes)
+	}
+	for i, v := range vectors {
+		if v[0] != float64(len(texts[i])) {
+			t.Errorf("vector %d is %v, of another input", i, v)
+		}
+	}
+}
+
+func TestEmbedBatchSingleInputs(t *testing.T) {
+	// Ollama's native API answers a list of inputs with a single vector.
+	srv := newServer(t, reply{status: http.StatusOK, file: "ollama_response.json"})
+	c := newTestClient(t, srv.URL, Config{Model: "nomic-embed-text"})
+	texts := []string{"Raft elects a leader.", "Paxos agrees on a value."}
+	for range 2 {
+		vectors, err := c.EmbedBatch(texts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(vectors, [][]float64{{0.6, 0, 0.8}, {0.6, 0, 0.8}}) {
+			t.Errorf("vectors %v", vectors)
+		}
+	}
+	// The first batch is tried as a list, then sent one input at a time,
+	// as is the second batch straight away.
+	got := srv.received()
+	if len(got) != 5 {
+		t.Fatalf("%d requests, want 5", len(got))
+	}
+	if _, ok := got[0]["input"].([]any); !ok {
+		t.Errorf("first request %v does not send a list of inputs", got[0])
+	}
+	for i, req := range got[1:] {
+		text := texts[i%2]
+		if req["input"] != text || req["prompt"] != text || req["model"] != "nomic-embed-text" {
+			t.Errorf("request %d is %v, want input and prompt %q", i+1, req, text)
+		}
+	}
+}
+
+func TestEmbedRetryAfter(t *testing.T) {
+	srv := newServer(t,
+		reply{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "1"}, file: "rate_limited.json"},
+		reply{status: http.StatusOK, file: "single_response.json"},
+	)
+	c := newTestClient(t, srv.URL, Config{})
+	start := time.Now()
+	v, err := c.Embed("Raft elects a leader.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, before the Retry-After of 1s", elapsed)
+	}
+	if !reflect.DeepEqual(v, []float64{0.6, 0.8, 0}) {
+		t.Errorf("vector %v", v)
+	}
+	if n := len(srv.received()); n != 2 {
+		t.Errorf("%d requests, want 2", n)
+	}
+}
+
+func TestEmbedServerErrorRetry(t *testing.T) {
+	srv := newServer(t,
+		reply{status: http.StatusServiceUnavailable},
+		reply{status: http.StatusBadGateway},
+		reply{status: http.StatusOK, file: "single_response.json"},
+	)
+	c := newTestClient(t, srv.URL, Config{})
+	v, err := c.Embed("Raft elects a leader.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, []float64{0.6, 0.8, 0}) {
+		t.Errorf("vector %v", v)
+	}
+	if n := len(srv.received()); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+}
+
+func TestEmbedRetriesExhausted(t *testing.T) {
+	srv := newServer(t, reply{status: http.StatusInternalServerError})
+	c := newTestClient(t, srv.URL, Config{MaxRetries: 1})
+	_, err := c.Embed("Raft elects a leader.")
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("error %v, want the 500 status", err)
+	}
+	if n := len(srv.received()); n != 2 {
+		t.Errorf("%d requests, want 2", n)
+	}
+}
+
+func TestEmbedClientErrorNotRetried(t *testing.T) {
+	srv := newServer(t, reply{status: http.StatusBadRequest})
+	c := newTestClient(t, srv.URL, Config{})
+	if _, err := c.Embed("Raft elects a leader."); err == nil {
+		t.Error("400 response embedded")
+	}
+	if n := len(srv.received()); n != 1 {
+		t.Errorf("%d requests, want 1", n)
+	}
+}
+
+func TestEmbedSplitsLongInput(t *testing.T) {
+	srv := newServer(t, reply{status: http.StatusOK, file: "single_response.json"})
+	c := newTestClient(t, srv.URL, Config{MaxInputTokens: 8})
+	text := strings.TrimSpace(strings.Repeat("leader ", 20))
+	v, err := c.Embed(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := srv.received()
+	if len(got) < 2 {
+		t.Fatalf("%d requests for an input of 4 windows or more", len(got))
+	}
+	var joined []string
+	for _, req := range got {
+		in, _ := req["input"].(string)
+		joined = append(joined, in)
+	}
+	if strings.Join(joined, " ") != text {
+		t.Errorf("windows %q do not make up the input", joined)
+	}
+	// Every window embeds alike, so the average is that vector.
+	for i, want := range []float64{0.6, 0.8, 0} {
+		if math.Abs(v[i]-want) > 1e-9 {
+			t.Fatalf("vector %v, want [0.6 0.8 0]", v)
+		}
+	}
+}
@@ -1,30 +1,32 @@
 package tfidf
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"math"
-	"regexp"
 	"sort"
-	"strings"
+
+	"rag/internal/textutil"
 )
 
 // Embedder implements a simple TF-IDF vectorizer.
 // It builds a vocabulary from the corpus and computes IDF values.
 type Embedder struct {
-	vocabulary   map[string]int
-	idf          []float64
-	dimension    int
-	prepared     bool
-	tokenPattern *regexp.Regexp
-	stopwords    map[string]struct{}
+	vocabulary map[string]int
+	idf        []float64
+	dimension  int
+	prepared   bool
+	stopwords  map[string]struct{}
 }
 
 // NewEmbedder creates an unprepared TF-IDF embedder.
 func NewEmbedder() *Embedder {
 	return &Embedder{
-		vocabulary:   make(map[string]int),
-		tokenPattern: regexp.MustCompile(`\p{L}+(?:['’]\p{L}+)*`),
-		stopwords:    defaultStopwords(),
+		vocabulary: make(map[string]int),
+		stopwords:  defaultStopwords(),
 	}
 }
 
@@ -74,6 +76,24 @@ func (e *Embedder) Prepare(corpus []string) error {
 	return nil
 }
 
+// Fingerprint digests the vocabulary and IDF values, so it changes with
+// the corpus the embedder was prepared on.
+func (e *Embedder) Fingerprint() string {
+	terms := make([]string, e.dimension)
+	for term, i := range e.vocabulary {
+		terms[i] = term
+	}
+	h := sha256.New()
+	var buf [8]byte
+	for i, term := range terms {
+		h.Write([]byte(term))
+		h.Write([]byte{0})
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(e.idf[i]))
+		h.Write(buf[:])
+	}
+	return "tfidf " + hex.EncodeToString(h.Sum(nil))
+}
+
 // Dimension returns the dimensionality of the produced embedding vectors.
 func (e *Embedder) Dimension() int { return e.dimension }
 
@@ -83,42 +103,52 @@ func (e *Embedder) Embed(text string) ([]float64, error) {
 		return nil, errors.New("tfidf embedder not prepared")
 	}
 	vec := make([]float64, e.dimension)
-	tokens := e.tokenize(text)
-	tf := make(map[int]int)
+	if err := e.EmbedInto(text, vec); err != nil {
+		return nil, err
+	}
+	return vec, nil
+}
+
+// EmbedInto computes the TF-IDF embedding of text into dst, counting term
+// frequencies in dst itself.
+func (e *Embedder) EmbedInto(text string, dst []float64) error {
+	if !e.prepared {
+		return errors.New("tfidf embedder not prepared")
+	}
+	if len(dst) != e.dimension {
+		return fmt.Errorf("tfidf: buffer of %d dims, vocabulary has %d", len(dst), e.dimension)
+	}
+	clear(dst)
 	total := 0
-	for _, tok := range tokens {
-		if _, isStop := e.stopwords[tok]; isStop {
-			continue
-		}
+	for _, tok := range e.tokenize(text) {
 		if idx, ok := e.vocabulary[tok]; ok {
-			tf[idx]++
+			dst[idx]++
 			total++
 		}
 	}
 	if total == 0 {
-		return vec, nil
-	}
-	for idx, count := range tf {
-		tfv := float64(count) / float64(total)
-		vec[idx] = tfv * e.idf[idx]
+		return nil
 	}
-	// L2 normalize
+	// Weight and L2 normalize
 	norm := 0.0
-	for _, v := range vec {
-		norm += v * v
+	for i, count := range dst {
+		if count == 0 {
+			continue
+		}
+		dst[i] = count / float64(total) * e.idf[i]
+		norm += dst[i] * dst[i]
 	}
 	norm = math.Sqrt(norm)
 	if norm > 0 {
-		for i := range vec {
-			vec[i] /= norm
+		for i := range dst {
+			dst[i] /= norm
 		}
 	}
-	return vec, nil
+	return nil
 }
 
 func (e *Embedder) tokenize(text string) []string {
-	lower := strings.ToLower(text)
-	raw := e.tokenPattern.FindAllString(lower, -1)
+	raw := textutil.Tokens(text)
 	if len(raw) == 0 {
 		return nil
 	}
@@ -0,0 +1,257 @@
+// Package enrich rewrites chunks before they are embedded, so that their
+// vectors carry what a chunk leaves implicit: the document it comes from,
+// and, with a language model, a sentence situating it in the document
+// ("contextual retrieval"). A chunk about "the second quarter" is then found
+// by a query about ACME's revenue. Only the embedded text changes; results
+// show the chunks as they are.
+package enrich
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"rag/internal/domain"
+)
+
+// Template placeholders.
+const (
+	// Text is the chunk's text.
+	Text = "text"
+	// Title is the document's title, or its file name without extension.
+	Title = "title"
+	// Path is the document's path.
+	Path = "path"
+	// Context is a sentence situating the chunk in its document, written
+	// by the language model.
+	Context = "context"
+)
+
+// DefaultMaxDocumentChars bounds the document sent with each chunk to the
+// language model.
+const DefaultMaxDocumentChars = 20000
+
+var placeholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// Config configures an Enricher.
+type Config struct {
+	// Template is the text embedded for each chunk, with placeholders
+	// such as {title} and {text}.
+	Template string
+	// Generate writes the context of chunk within document; it is needed
+	// when the template has {context}.
+	Generate func(ctx context.Context, document, chunk string) (string, error)
+	// Model names what Generate asks, so that contexts of another model
+	// are neither reused nor cached for it.
+	Model string
+	// Cache keeps generated contexts across runs; nil keeps none.
+	Cache *Cache
+	// MaxDocumentChars bounds the document sent to Generate: longer ones
+	// are cut to a window around the chunk (0 = DefaultMaxDocumentChars).
+	MaxDocumentChars int
+}
+
+// Enricher renders the embedded text of chunks.
+type Enricher struct {
+	cfg     Config
+	context bool
+}
+
+// New checks the template and returns an Enricher.
+func New(cfg Config) (*Enricher, error) {
+	e := &Enricher{cfg: cfg}
+	for _, m := range placeholder.FindAllStringSubmatch(cfg.Template, -1) {
+		switch m[1] {
+		case Text, Title, Path:
+		case Context:
+			e.context = true
+		default:
+			return nil, fmt.Errorf("unknown placeholder {%s} in the enrichment template", m[1])
+		}
+	}
+	if !strings.Contains(cfg.Template, "{"+Text+"}") {
+		return nil, fmt.Errorf("the enrichment template must contain {%s}", Text)
+	}
+	if e.context && cfg.Generate == nil {
+		return nil, fmt.Errorf("{%s} in the enrichment template needs a language model", Context)
+	}
+	if e.cfg.MaxDocumentChars <= 0 {
+		e.cfg.MaxDocumentChars = DefaultMaxDocumentChars
+	}
+	return e, nil
+}
+
+// Fingerprint identifies the template and model, so that vectors embedded
+// under other ones are not taken for these.
+func (e *Enricher) Fingerprint() string {
+	id := e.cfg.Template
+	if e.context {
+		id += "\x00" + e.cfg.Model
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Enrich returns the text to embed for chunk of document.
+func (e *Enricher) Enrich(ctx context.Context, document domain.Document, chunk domain.Chunk) (string, error) {
+	var situated string
+	if e.context {
+		var err error
+		if situated, err = e.situate(ctx, document, chunk); err != nil {
+			return "", err
+		}
+	}
+	return placeholder.ReplaceAllStringFunc(e.cfg.Template, func(m string) string {
+		switch m[1 : len(m)-1] {
+		case Text:
+			return chunk.Text
+		case Title:
+			return title(document)
+		case Path:
+			return document.Path
+		default:
+			return situated
+		}
+	}), nil
+}
+
+// situate returns the context of chunk in document, from the cache when it
+// was written before.
+func (e *Enricher) situate(ctx context.Context, document domain.Document, chunk domain.Chunk) (string, error) {
+	doc := window(document.Content, chunk.Text, e.cfg.MaxDocumentChars)
+	sum := sha256.Sum256([]byte(e.cfg.Model + "\x00" + doc + "\x00" + chunk.Text))
+	key := hex.EncodeToString(sum[:])
+	if c, ok := e.cfg.Cache.Get(key); ok {
+		return c, nil
+	}
+	c, err := e.cfg.Generate(ctx, doc, chunk.Text)
+	if err != nil {
+		return "", err
+	}
+	c = strings.Join(strings.Fields(c), " ")
+	if err := e.cfg.Cache.Put(key, c); err != nil {
+		return "", err
+	}
+	return c, nil
+}
+
+// title returns the document's title, or its file name without extension.
+func title(d domain.Document) string {
+	if d.Title != "" {
+		return d.Title
+	}
+	base := filepath.Base(d.Path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// window cuts content to at most limit bytes centered on the first
+// occurrence of chunk, or to its beginning when the chunk is not found
+// verbatim, at rune boundaries.
+func window(content, chunk string, limit int) string {
+	if len(content) <= limit {
+		return content
+	}
+	start := 0
+	if i := strings.Index(content, chunk); i >= 0 {
+		start = max(0, min(i+len(chunk)/2-limit/2, len(content)-limit))
+	}
+	end := start + limit
+	for start > 0 && !utf8.RuneStart(content[start]) {
+		start++
+	}
+	for end < len(content) && !utf8.RuneStart(content[end]) {
+		end--
+	}
+	return content[start:end]
+}
+
+// Cache keeps generated contexts in a file of JSON lines, appended to as
+// they are written, so that contexts written before an ingest was canceled
+// are kept.
+type Cache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]string
+}
+
+type cacheEntry struct {
+	Key     string `json:"key"`
+	Context string `json:"context"`
+}
+
+// OpenCache reads the cache file at path; a missing file is an empty
+// cache, created on first write.
+func OpenCache(path string) (*Cache, error) {
+	c := &Cache{path: path, entries: make(map[string]string)}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var e cacheEntry
+		// A line cut short by a crash is skipped.
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			c.entries[e.Key] = e.Context
+		}
+	}
+	return c, sc.Err()
+}
+
+// Get returns the context cached under key.
+func (c *Cache) Get(key string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.entries[key]
+	return v, ok
+}
+
+// Put caches context under key.
+func (c *Cache) Put(key, context string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return nil
+	}
+	line, err := json.Marshal(cacheEntry{Key: key, Context: context})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(c.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	c.entries[key] = context
+	return nil
+}
@@ -0,0 +1,65 @@
+// Package facets counts search results by document, tag and directory.
+package facets
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"rag/internal/domain"
+)
+
+// Kind is what a facet counts results by.
+type Kind int
+
+// The kinds of facets.
+const (
+	Document Kind = iota
+	Tag
+	Directory
+)
+
+// Facet is a value shared by some of the results and how many have it.
+type Facet struct {
+	Kind  Kind
+	Value string
+	Count int
+}
+
+// Count returns the facets of results: documents, then tags, then
+// directories, each by descending count and then by value.
+func Count(results []domain.SearchResult) []Facet {
+	counts := map[Kind]map[string]int{Document: {}, Tag: {}, Directory: {}}
+	for _, r := range results {
+		if r.Chunk.Path != "" {
+			counts[Document][r.Chunk.Path]++
+			if dir := filepath.Dir(r.Chunk.Path); dir != "." {
+				counts[Directory][dir]++
+			}
+		}
+		seen := make(map[string]bool)
+		for _, t := range strings.Split(r.Chunk.Metadata["tags"], ", ") {
+			t = strings.TrimPrefix(strings.TrimSpace(t), "#")
+			if t == "" || seen[strings.ToLower(t)] {
+				continue
+			}
+			seen[strings.ToLower(t)] = true
+			counts[Tag][t]++
+		}
+	}
+	var out []Facet
+	for _, kind := range []Kind{Document, Tag, Directory} {
+		start := len(out)
+		for v, n := range counts[kind] {
+			out = append(out, Facet{Kind: kind, Value: v, Count: n})
+		}
+		group := out[start:]
+		sort.Slice(group, func(i, j int) bool {
+			if group[i].Count != group[j].Count {
+				return group[i].Count > group[j].Count
+			}
+			return group[i].Value < group[j].Value
+		})
+	}
+	return out
+}
@@ -0,0 +1,164 @@
+// Package feedback keeps the user's judgments of search results, per index:
+// whether a result was relevant to the query that found it. Results judged
+// not relevant are hard negatives, ranked high yet wrong, which is what
+// evaluating and tuning a ranking needs most.
+package feedback
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"rag/internal/jsonstore"
+)
+
+// Judgment is the user's verdict on one result of a query.
+type Judgment struct {
+	Query    string `json:"query"`
+	ChunkID  string `json:"chunk_id"`
+	Path     string `json:"path"`
+	Index    int    `json:"index"`
+	Text     string `json:"text"`
+	Relevant bool   `json:"relevant"`
+	// Rank (from 1) and Score are where the result was ranked when it was
+	// judged.
+	Rank    int       `json:"rank"`
+	Score   float64   `json:"score"`
+	Created time.Time `json:"created"`
+}
+
+// key identifies the judgment of a chunk for a query; queries differing
+// only in whitespace are the same.
+func key(query, chunkID string) string {
+	return strings.Join(strings.Fields(query), " ") + "\x00" + chunkID
+}
+
+// Store persists judgments as a JSON file.
+type Store struct {
+	file *jsonstore.Store[Judgment]
+}
+
+// NewStore returns a store backed by the file at path. The file is created
+// on first save.
+func NewStore(path string) *Store {
+	return &Store{file: jsonstore.New(path, func(j Judgment) string { return key(j.Query, j.ChunkID) }, older)}
+}
+
+// List returns all judgments, oldest first.
+func (s *Store) List() ([]Judgment, error) {
+	return s.file.List()
+}
+
+// Get returns the judgment of the chunk with the given ID for query, if
+// there is one.
+func (s *Store) Get(query, chunkID string) (Judgment, bool, error) {
+	return s.file.Get(key(query, chunkID))
+}
+
+// Set stores j, replacing an earlier judgment of the same chunk for the
+// same query.
+func (s *Store) Set(j Judgment) error {
+	if j.ChunkID == "" || strings.TrimSpace(j.Query) == "" {
+		return errors.New("a judgment needs a query and a chunk ID")
+	}
+	return s.file.Put(j)
+}
+
+// Delete removes the judgment of the chunk with the given ID for query.
+func (s *Store) Delete(query, chunkID string) error {
+	_, err := s.file.Delete(key(query, chunkID))
+	return err
+}
+
+// older orders judgments by creation, then by query and chunk.
+func older(a, b Judgment) bool {
+	if !a.Created.Equal(b.Created) {
+		return a.Created.Before(b.Created)
+	}
+	if a.Query != b.Query {
+		return a.Query < b.Query
+	}
+	return a.ChunkID < b.ChunkID
+}
+
+// Passage is a judged result in an exported example.
+type Passage struct {
+	ChunkID string  `json:"chunk_id"`
+	Path    string  `json:"path"`
+	Index   int     `json:"index"`
+	Text    string  `json:"text"`
+	Rank    int     `json:"rank"`
+	Score   float64 `json:"score"`
+}
+
+// Example gathers the judgments of one query: the passages judged relevant
+// (positives) and those judged not relevant (negatives).
+type Example struct {
+	Query     string    `json:"query"`
+	Positives []Passage `json:"positives"`
+	Negatives []Passage `json:"negatives"`
+}
+
+// Examples groups judgments by query, in order of each query's first
+// judgment, with passages in order of rank.
+func Examples(judgments []Judgment) []Example {
+	var out []Example
+	byQuery := make(map[string]int)
+	for _, j := range judgments {
+		q := strings.Join(strings.Fields(j.Query), " ")
+		i, ok := byQuery[q]
+		if !ok {
+			i = len(out)
+			byQuery[q] = i
+			out = append(out, Example{Query: q, Positives: []Passage{}, Negatives: []Passage{}})
+		}
+		p := Passage{ChunkID: j.ChunkID, Path: j.Path, Index: j.Index, Text: j.Text, Rank: j.Rank, Score: j.Score}
+		if j.Relevant {
+			out[i].Positives = append(out[i].Positives, p)
+		} else {
+			out[i].Negatives = append(out[i].Negatives, p)
+		}
+	}
+	for i := range out {
+		for _, ps := range [][]Passage{out[i].Positives, out[i].Negatives} {
+			sort.SliceStable(ps, func(a, b int) bool { return ps[a].Rank < ps[b].Rank })
+		}
+	}
+	return out
+}
+
+// WriteExamples writes examples as JSON Lines, one query per line, the
+// layout of query/positives/negatives datasets for training and evaluating
+// rerankers and embedders.
+func WriteExamples(w io.Writer, examples []Example) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, e := range examples {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadExamples reads examples written by WriteExamples, or written by hand
+// in the same layout, skipping blank lines.
+func ReadExamples(r io.Reader) ([]Example, error) {
+	var out []Example
+	dec := json.NewDecoder(r)
+	for {
+		var e Example
+		err := dec.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("example %d: %w", len(out)+1, err)
+		}
+		out = append(out, e)
+	}
+}
@@ -0,0 +1,176 @@
+// Package generator answers a query from the passages retrieved for it: the
+// top results are numbered into a prompt, a chat model writes an answer
+// citing them as [1], [2]…, and the citations are resolved back to the
+// results, so that every claim can be checked against its source.
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"rag/internal/domain"
+	"rag/internal/grounding"
+	"rag/internal/llm"
+)
+
+// Defaults of Config.
+const (
+	DefaultSources        = 5
+	DefaultMaxSourceChars = 2000
+)
+
+// ErrNoSources is returned when there are no results to answer from.
+var ErrNoSources = errors.New("no passages to answer from")
+
+// Model is the chat model answers are written by.
+type Model interface {
+	ChatWith(ctx context.Context, messages []llm.Message, opts llm.Options) (string, error)
+}
+
+// Streamer is a Model that can deliver its replies as they are written.
+type Streamer interface {
+	ChatStream(ctx context.Context, messages []llm.Message, opts llm.Options, onText func(string)) (string, error)
+}
+
+// Config configures a Generator.
+type Config struct {
+	// Sources is the number of top results the answer is written from
+	// (0 = DefaultSources).
+	Sources int
+	// MaxSourceChars bounds the text of each result in the prompt
+	// (0 = DefaultMaxSourceChars).
+	MaxSourceChars int
+	// Checker checks the sentences of each answer against its sources;
+	// nil leaves them unchecked.
+	Checker grounding.Checker
+	// Options are the generation parameters of answers.
+	Options llm.Options
+}
+
+// Generator writes answers with a chat model.
+type Generator struct {
+	model Model
+	cfg   Config
+}
+
+// New returns a Generator asking model.
+func New(model Model, cfg Config) *Generator {
+	if cfg.Sources <= 0 {
+		cfg.Sources = DefaultSources
+	}
+	if cfg.MaxSourceChars <= 0 {
+		cfg.MaxSourceChars = DefaultMaxSourceChars
+	}
+	return &Generator{model: model, cfg: cfg}
+}
+
+// Options returns the generation parameters of answers.
+func (g *Generator) Options() llm.Options { return g.cfg.Options }
+
+// With returns a generator writing answers with the parameters set in opts
+// in place of its own.
+func (g *Generator) With(opts llm.Options) *Generator {
+	cfg := g.cfg
+	cfg.Options = cfg.Options.Override(opts)
+	return &Generator{model: g.model, cfg: cfg}
+}
+
+// Answer is a generated answer with the results it was written from.
+type Answer struct {
+	Text string
+	// Sources are the results given to the model; source n (from 1) is
+	// cited as [n].
+	Sources []domain.SearchResult
+	// Cited lists the numbers of the sources the answer cites, in the
+	// order they are first cited.
+	Cited []int
+	// Claims are the sentences of Text checked against the sources, and
+	// CheckErr why they could not be; both are empty without a checker.
+	Claims   []grounding.Claim
+	CheckErr error
+}
+
+// Answer writes an answer to query from the top results.
+func (g *Generator) Answer(ctx context.Context, query string, results []domain.SearchResult) (Answer, error) {
+	return g.Stream(ctx, query, results, nil)
+}
+
+// Stream writes an answer like Answer, calling onText with the text written
+// so far as it arrives when the model is a Streamer; other models call it
+// once, with the whole text.
+func (g *Generator) Stream(ctx context.Context, query string, results []domain.SearchResult, onText func(string)) (Answer, error) {
+	if len(results) == 0 {
+		return Answer{}, ErrNoSources
+	}
+	sources := results[:min(len(results), g.cfg.Sources)]
+	messages := Prompt(query, sources, g.cfg.MaxSourceChars)
+	var text string
+	var err error
+	if s, ok := g.model.(Streamer); ok && onText != nil {
+		text, err = s.ChatStream(ctx, messages, g.cfg.Options, onText)
+	} else if text, err = g.model.ChatWith(ctx, messages, g.cfg.Options); err == nil && onText != nil {
+		onText(text)
+	}
+	if err != nil {
+		return Answer{}, err
+	}
+	a := Answer{Text: text, Sources: sources, Cited: Citations(text, len(sources))}
+	if g.cfg.Checker != nil {
+		// An answer that could not be checked is shown all the same.
+		a.Claims, a.CheckErr = g.cfg.Checker.Check(ctx, text, sources)
+	}
+	return a, nil
+}
+
+// systemPrompt asks for an answer grounded in the numbered sources.
+const systemPrompt = "You answer questions from the numbered sources you are given, in the question's language. Use only what the sources say; if they do not answer the question, say so. Cite the source of each claim with its number in square brackets, such as [1] or [2][3], right after the claim. Be concise."
+
+// Prompt returns the messages asking for an answer to query from sources,
+// each cut to maxChars bytes.
+func Prompt(query string, sources []domain.SearchResult, maxChars int) []llm.Message {
+	var b strings.Builder
+	for i, r := range sources {
+		text := r.Chunk.Text
+		if len(text) > maxChars {
+			text = strings.ToValidUTF8(text[:maxChars], "") + "…"
+		}
+		fmt.Fprintf(&b, "[%d] %s\n%s\n\n", i+1, sourceName(r.Chunk), text)
+	}
+	b.WriteString("Question: " + query)
+	return []llm.Message{
+		{Role: llm.RoleSystem, Content: systemPrompt},
+		{Role: llm.RoleUser, Content: b.String()},
+	}
+}
+
+// sourceName names the chunk in the prompt by its document.
+func sourceName(ch domain.Chunk) string {
+	if t := ch.Metadata["title"]; t != "" {
+		return t + " (" + ch.Path + ")"
+	}
+	return ch.Path
+}
+
+var citation = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// Citations returns the source numbers from 1 to n cited in text as [1],
+// [1, 2] or [1][2], each once, in order of first citation.
+func Citations(text string, n int) []int {
+	var out []int
+	seen := make(map[int]bool)
+	for _, m := range citation.FindAllStringSubmatch(text, -1) {
+		for _, f := range strings.Split(m[1], ",") {
+			i, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil || i < 1 || i > n || seen[i] {
+				continue
+			}
+			seen[i] = true
+			out = append(out, i)
+		}
+	}
+	return out
+}
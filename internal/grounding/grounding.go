@@ -0,0 +1,207 @@
+// Package grounding checks the sentences of a generated answer against the
+// passages it was written from, to flag the claims none of them supports:
+// a model answering from sources may still add what they do not say. A
+// sentence is checked either by its embedding similarity to the sources, or
+// by asking a language model whether the sources entail it.
+package grounding
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"rag/internal/domain"
+	"rag/internal/llm"
+	"rag/internal/textutil"
+)
+
+// DefaultThreshold is the similarity a sentence needs to a source to count
+// as supported by it.
+const DefaultThreshold = 0.5
+
+// minClaimTokens is the number of words below which a sentence, such as
+// "In short:", makes no claim worth checking.
+const minClaimTokens = 4
+
+// Claim is a sentence of an answer checked against the sources.
+type Claim struct {
+	// Start and End are the byte offsets of the sentence in the answer.
+	Start, End int
+	Supported  bool
+	// Source is the number, from 1, of the source supporting the claim
+	// best; 0 when none does.
+	Source int
+	// Score is how well Source supports the claim: a similarity, or 1 for
+	// a source the model says entails it.
+	Score float64
+}
+
+// Checker checks the claims of an answer against its sources.
+type Checker interface {
+	Check(ctx context.Context, answer string, sources []domain.SearchResult) ([]Claim, error)
+}
+
+// Unsupported returns the claims no source supports.
+func Unsupported(claims []Claim) []Claim {
+	var out []Claim
+	for _, c := range claims {
+		if !c.Supported {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+var citationMark = regexp.MustCompile(`\s*\[\d+(?:\s*,\s*\d+)*\]`)
+
+// sentences returns the spans of the sentences of answer that make a claim
+// worth checking, with their text without citation marks.
+func sentences(answer string) ([][2]int, []string) {
+	var spans [][2]int
+	var texts []string
+	for _, sp := range textutil.SentenceSpans(answer) {
+		text := strings.TrimSpace(citationMark.ReplaceAllString(answer[sp[0]:sp[1]], ""))
+		if len(textutil.Tokens(text)) < minClaimTokens {
+			continue
+		}
+		spans = append(spans, sp)
+		texts = append(texts, text)
+	}
+	return spans, texts
+}
+
+// Similarity supports a sentence by the source it is most similar to, in
+// whole or by one of its sentences, when the similarity reaches Threshold.
+type Similarity struct {
+	// Embed embeds text, as the index was embedded.
+	Embed func(text string) ([]float64, error)
+	// Threshold is the least similarity of a supported claim
+	// (0 = DefaultThreshold).
+	Threshold float64
+}
+
+// Check implements Checker.
+func (c Similarity) Check(_ context.Context, answer string, sources []domain.SearchResult) ([]Claim, error) {
+	threshold := c.Threshold
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	spans, texts := sentences(answer)
+	if len(spans) == 0 {
+		return nil, nil
+	}
+	// Each source is compared whole and sentence by sentence, so that
+	// claims summing up a passage are supported as well as those quoting
+	// one sentence of it.
+	var vectors [][]float64
+	var owner []int
+	for i, r := range sources {
+		parts := []string{r.Chunk.Text}
+		for _, sp := range textutil.SentenceSpans(r.Chunk.Text) {
+			parts = append(parts, r.Chunk.Text[sp[0]:sp[1]])
+		}
+		for _, p := range parts {
+			v, err := c.Embed(p)
+			if err != nil {
+				return nil, err
+			}
+			vectors = append(vectors, v)
+			owner = append(owner, i+1)
+		}
+	}
+	claims := make([]Claim, len(spans))
+	for i, text := range texts {
+		v, err := c.Embed(text)
+		if err != nil {
+			return nil, err
+		}
+		claim := Claim{Start: spans[i][0], End: spans[i][1]}
+		for j, sv := range vectors {
+			if s := cosine(v, sv); s > claim.Score {
+				claim.Score, claim.Source = s, owner[j]
+			}
+		}
+		claim.Supported = claim.Score >= threshold
+		if !claim.Supported {
+			claim.Source = 0
+		}
+		claims[i] = claim
+	}
+	return claims, nil
+}
+
+// Model asks a language model, for each sentence, which source entails it.
+type Model struct {
+	Chat func(ctx context.Context, messages []llm.Message) (string, error)
+}
+
+// modelPrompt asks for one verdict per numbered sentence.
+const modelPrompt = "You check answers against their sources. Given numbered sources and numbered sentences of an answer, decide for each sentence whether one of the sources states or clearly implies it. Reply with one line per sentence, in order, such as \"1: 2\" when source 2 supports sentence 1, or \"1: none\" when no source does. Reply with these lines only."
+
+var verdict = regexp.MustCompile(`^\s*(\d+)\s*[:.)-]\s*(.*)$`)
+
+// Check implements Checker. Sentences the model gives no verdict for count
+// as supported.
+func (c Model) Check(ctx context.Context, answer string, sources []domain.SearchResult) ([]Claim, error) {
+	spans, texts := sentences(answer)
+	if len(spans) == 0 {
+		return nil, nil
+	}
+	var b strings.Builder
+	b.WriteString("Sources:\n")
+	for i, r := range sources {
+		fmt.Fprintf(&b, "[%d] %s\n", i+1, r.Chunk.Text)
+	}
+	b.WriteString("\nSentences:\n")
+	for i, t := range texts {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, t)
+	}
+	reply, err := c.Chat(ctx, []llm.Message{
+		{Role: llm.RoleSystem, Content: modelPrompt},
+		{Role: llm.RoleUser, Content: b.String()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	claims := make([]Claim, len(spans))
+	for i, sp := range spans {
+		claims[i] = Claim{Start: sp[0], End: sp[1], Supported: true}
+	}
+	for _, line := range strings.Split(reply, "\n") {
+		m := verdict.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(m[1])
+		if n < 1 || n > len(claims) {
+			continue
+		}
+		source, err := strconv.Atoi(strings.Trim(strings.TrimSpace(m[2]), "[]"))
+		if err != nil || source < 1 || source > len(sources) {
+			claims[n-1].Supported, claims[n-1].Source, claims[n-1].Score = false, 0, 0
+			continue
+		}
+		claims[n-1].Supported, claims[n-1].Source, claims[n-1].Score = true, source, 1
+	}
+	return claims, nil
+}
+
+func cosine(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range min(len(a), len(b)) {
+		dot += a[i] * b[i]
+	}
+	for _, x := range a {
+		na += x * x
+	}
+	for _, x := range b {
+		nb += x * x
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,35 @@
+// Package grouping groups search results by their source document.
+package grouping
+
+import "rag/internal/domain"
+
+// Group is the set of hits from one document.
+type Group struct {
+	DocumentID string
+	Path       string
+	// Score is the best score among the hits.
+	Score float64
+	// Hits are indices into the grouped results, best first.
+	Hits []int
+}
+
+// ByDocument groups results by document. Groups are ordered by their best
+// hit and hits keep the order of results, which is assumed to be by score.
+func ByDocument(results []domain.SearchResult) []Group {
+	var groups []Group
+	byDoc := make(map[string]int)
+	for i, r := range results {
+		gi, ok := byDoc[r.Chunk.DocumentID]
+		if !ok {
+			gi = len(groups)
+			byDoc[r.Chunk.DocumentID] = gi
+			groups = append(groups, Group{DocumentID: r.Chunk.DocumentID, Path: r.Chunk.Path, Score: r.Score})
+		}
+		g := &groups[gi]
+		g.Hits = append(g.Hits, i)
+		if r.Score > g.Score {
+			g.Score = r.Score
+		}
+	}
+	return groups
+}
@@ -0,0 +1,77 @@
+// Package i18n translates the messages rag shows in the TUI and on the
+// command line. Messages are looked up by their English text, which is also
+// the fallback, so call sites read as before: T("Type to search.") or
+// Sprintf("Indexed %d documents", n). A language's catalog maps the English
+// format strings to translations that take the same verbs in the same order.
+//
+// The language comes from the config (tui.language) or, when that is "auto"
+// or empty, from the LC_ALL, LC_MESSAGES and LANG environment variables.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// catalogs holds the translations of each language other than English.
+var catalogs = map[string]map[string]string{
+	"ru": ru,
+}
+
+var (
+	mu      sync.RWMutex
+	catalog = catalogs[fromEnv()]
+)
+
+// SetLanguage selects the language of later messages: an ISO 639-1 code
+// such as "en" or "ru", or "auto" or "" for the environment's. Languages
+// without a catalog fall back to English, which is reported as an error.
+func SetLanguage(lang string) error {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" || lang == "auto" {
+		lang = fromEnv()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	catalog = catalogs[lang]
+	if catalog == nil && lang != "en" {
+		return fmt.Errorf("no translations for language %q; using English", lang)
+	}
+	return nil
+}
+
+// T returns the translation of the English message s.
+func T(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if t, ok := catalog[s]; ok {
+		return t
+	}
+	return s
+}
+
+// Sprintf formats the translation of the English format string.
+func Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// fromEnv returns the language of the first set locale variable, as
+// "ll" from a value such as "ll_CC.UTF-8". The C and POSIX locales are
+// English.
+func fromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		if v == "C" || v == "POSIX" || strings.HasPrefix(v, "C.") {
+			return "en"
+		}
+		lang, _, _ := strings.Cut(v, "_")
+		lang, _, _ = strings.Cut(lang, ".")
+		return strings.ToLower(lang)
+	}
+	return "en"
+}
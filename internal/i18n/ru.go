@@ -0,0 +1,414 @@
+package i18n
+
+// ru is the Russian catalog. Counts are phrased as "noun: N" so that the
+// translations need no plural forms.
+var ru = map[string]string{
+	// Search and results.
+	"Type query and press Enter":           "Введите запрос и нажмите Enter",
+	"Type to search.":                      "Введите запрос.",
+	"Loaded. Type to search.":              "Загружено. Введите запрос.",
+	"Loading...":                           "Загрузка...",
+	"No results yet.":                      "Результатов пока нет.",
+	"Results for %q":                       "Результаты по запросу %q",
+	"Passages similar to %q":               "Фрагменты, похожие на %q",
+	"All %d results for %q shown":          "Показаны все результаты по запросу %[2]q: %[1]d",
+	"Loaded %d more results for %q":        "Загружено ещё результатов по запросу %[2]q: %[1]d",
+	"Result %d/%d  score=%.3f":             "Результат %d/%d  оценка=%.3f",
+	"Error: %v":                            "Ошибка: %v",
+	"Editor: %v":                           "Редактор: %v",
+	"Query edited. Press Enter to search.": "Запрос изменён. Нажмите Enter для поиска.",
+	"Query mode.":                          "Режим запросов.",
+	"Find similar: paste a passage and press Enter (Ctrl+F for queries)":  "Поиск похожих: вставьте фрагмент и нажмите Enter (Ctrl+F — обычные запросы)",
+	"Grouped by document: Ctrl+X expands or collapses, Ctrl+G to ungroup": "Сгруппировано по документам: Ctrl+X раскрывает или сворачивает, Ctrl+G отменяет группировку",
+	"Showing all results.": "Показаны все результаты.",
+	"%s  (%d hits)":        "%s  (совпадений: %d)",
+	"No result to read.":   "Нет результата для чтения.",
+	"Reading: Up/Down scroll, Left/Right switch results, Esc returns": "Чтение: Вверх/Вниз — прокрутка, Влево/Вправо — другой результат, Esc — назад",
+	"%d chunks · %d docs":               "фрагментов: %d · документов: %d",
+	"(%d chunks)":                       "(фрагментов: %d)",
+	"%d more":                           "ещё %d",
+	"Ordered by score.":                 "Упорядочено по оценке.",
+	"Ordered by document and position.": "Упорядочено по документу и положению в нём.",
+	"Ordered by date, newest first.":    "Упорядочено по дате, сначала новые.",
+
+	// Filters.
+	"filter> ":           "фильтр> ",
+	"filter %s":          "фильтр %s",
+	"Filters: %s":        "Фильтры: %s",
+	"Filters cleared.":   "Фильтры сброшены.",
+	"Filters unchanged.": "Фильтры не изменены.",
+	"Filters: path:text tag:name score:0.3 (Enter applies, empty clears, Esc cancels)": "Фильтры: path:текст tag:имя score:0.3 (Enter применяет, пустая строка сбрасывает, Esc отменяет)",
+	"score: expects a number like 0.3, got %q":                                         "score: ожидается число вроде 0.3, получено %q",
+	"unknown filter %q (use path:, tag: or score:)":                                    "неизвестный фильтр %q (используйте path:, tag: или score:)",
+
+	// Facets.
+	"Documents":                      "Документы",
+	"Tags":                           "Теги",
+	"Directories":                    "Каталоги",
+	"Facets of %d results":           "Фасеты результатов: %d",
+	"Run a query to see its facets.": "Выполните запрос, чтобы увидеть его фасеты.",
+	"Facets: Enter toggles a facet as a filter, Esc or Ctrl+A to return": "Фасеты: Enter включает или выключает фасет как фильтр, Esc или Ctrl+A — назад",
+
+	// Refinements.
+	"refine> ":                           "уточнить> ",
+	"refine %s":                          "уточнение %s",
+	"Refined by %s":                      "Уточнено: %s",
+	"Refinement removed.":                "Уточнение удалено.",
+	"No refinements to remove.":          "Нет уточнений для удаления.",
+	"A refinement needs words to match.": "Уточнению нужны слова для поиска.",
+	"No result contains %q; the results are unchanged.":                                                      "Ни один результат не содержит %q; результаты не изменены.",
+	"Refine: words the results must contain (Enter applies, empty removes the last refinement, Esc cancels)": "Уточнение: слова, которые должны быть в результатах (Enter применяет, пустая строка удаляет последнее уточнение, Esc отменяет)",
+
+	// Result actions and bookmarks.
+	"Actions for %s#%d":                             "Действия для %s#%d",
+	"Actions: Enter or a key to run, Esc to return": "Действия: Enter или клавиша — выполнить, Esc — назад",
+	"Open in editor":                                "Открыть в редакторе",
+	"Copy text":                                     "Скопировать текст",
+	"Copy path":                                     "Скопировать путь",
+	"Show neighbors":                                "Показать соседние фрагменты",
+	"Find similar":                                  "Найти похожие",
+	"Bookmark":                                      "Добавить в закладки",
+	"Remove bookmark":                               "Удалить закладку",
+	"This result has no local file.":                "У этого результата нет локального файла.",
+	"Copy failed: %v":                               "Не удалось скопировать: %v",
+	"Copied the text of the result.":                "Текст результата скопирован.",
+	"Copied the path of the result.":                "Путь результата скопирован.",
+	"The chunks of this document are not available.": "Фрагменты этого документа недоступны.",
+	"Chunks around %s#%d":                            "Фрагменты рядом с %s#%d",
+	"Bookmarks are not available.":                   "Закладки недоступны.",
+	"Translate into %s":                              "Перевести на %s",
+	"Translating…":                                   "Перевод…",
+	"Translated.":                                    "Переведено.",
+	"Translation (%s):":                              "Перевод (%s):",
+	"Translation failed: %v":                         "Ошибка перевода: %v",
+	"Relevant to the query":                          "Релевантно запросу",
+	"Not relevant to the query":                      "Нерелевантно запросу",
+	"Withdraw: relevant":                             "Отменить оценку «релевантно»",
+	"Withdraw: not relevant":                         "Отменить оценку «нерелевантно»",
+	"Judgment withdrawn.":                            "Оценка отменена.",
+	"Marked %s#%d relevant to the query.":            "%s#%d отмечен как релевантный запросу.",
+	"Marked %s#%d not relevant to the query.":        "%s#%d отмечен как нерелевантный запросу.",
+	"No feedback. Press Enter on a result in the TUI and mark it relevant (+) or not (-).":                                           "Оценок нет. Нажмите Enter на результате в TUI и отметьте его как релевантный (+) или нет (-).",
+	"exported %d judgments of %d queries to %s":                                                                                      "экспортировано оценок: %d, запросов: %d, в %s",
+	"%d results judged relevant are not among the candidates of their query, so no weights can rank them (edited or removed since?)": "%d результатов, отмеченных как релевантные, нет среди кандидатов их запроса, и никакие веса не поднимут их (изменены или удалены?)",
+	"%d judgments of %d queries, measured in the top %d.":                                                                            "оценок: %d, запросов: %d, учитываются первые %d результатов.",
+	"current": "текущие",
+	"tuned":   "новые",
+	"The current weights rank the judged results best; nothing to change.":             "Текущие веса лучше всего ранжируют оценённые результаты; менять нечего.",
+	"Wrote the tuned weights to search.weights in %s.":                                 "Новые веса записаны в search.weights в %s.",
+	"chunks of the corpus not in the store":                                            "фрагменты корпуса, которых нет в хранилище",
+	"stored points of no chunk of the corpus":                                          "точки хранилища без фрагмента в корпусе",
+	"stored points whose text has changed":                                             "точки хранилища, текст которых изменился",
+	"chunks stored more than once":                                                     "фрагменты, сохранённые больше одного раза",
+	"vectors of the wrong dimension":                                                   "векторы неверной размерности",
+	"stored points whose source file is gone":                                          "точки хранилища, исходного файла которых больше нет",
+	"store stamp does not match the embedder or the corpus":                            "отметка хранилища не соответствует эмбеддеру или корпусу",
+	"embedded by another embedder or on another corpus":                                "векторы получены другим эмбеддером или на другом корпусе",
+	"indexed from other chunks":                                                        "проиндексированы другие фрагменты",
+	"The memory vector store keeps nothing between runs; there is no index to verify.": "Хранилище в памяти ничего не сохраняет между запусками; проверять нечего.",
+	"Repairing: rebuilding the store from the corpus...":                               "Восстановление: хранилище заново строится из корпуса...",
+	"verify failed: %v; check the embedder settings":                                   "проверка не удалась: %v; проверьте настройки эмбеддера",
+	"verify failed: %v":                                                                "проверка не удалась: %v",
+	"Corpus: %d chunks; store: %d points of dimension %d.":                             "Корпус: фрагментов: %d; хранилище: точек: %d, размерность %d.",
+	"... and %d more":                          "... и ещё %d",
+	"The index is consistent with the corpus.": "Индекс соответствует корпусу.",
+	"%d issues found.":                         "Найдено проблем: %d.",
+	"Run rag verify --repair to rebuild the store from the corpus.":                  "Запустите rag verify --repair, чтобы заново построить хранилище из корпуса.",
+	"%d soft-deleted points are left out of searches; rag purge drops them.":         "Мягко удалённых точек, исключённых из поиска: %d; rag purge удаляет их.",
+	"The memory vector store keeps nothing between runs; there is nothing to purge.": "Хранилище в памяти ничего не сохраняет между запусками; удалять нечего.",
+	"%d chunks":                                                         "фрагментов: %d",
+	"No soft-deleted documents to purge.":                               "Нет мягко удалённых документов для очистки.",
+	"%d soft-deleted documents would be purged.":                        "Будет удалено мягко удалённых документов: %d.",
+	"Purged %d soft-deleted documents.":                                 "Удалено мягко удалённых документов: %d.",
+	"%d indexes, %d queries, relevant passages searched in the top %d.": "Индексов: %d, запросов: %d; релевантные фрагменты ищутся среди первых %d результатов.",
+	"Best: sentences_per_chunk %d, overlap_sentences %d (MRR %.3f).":    "Лучшие параметры: sentences_per_chunk %d, overlap_sentences %d (MRR %.3f).",
+	"no queries with a relevant passage in %s":                          "в %s нет запросов с релевантными фрагментами",
+	"%d chunks could not be enriched and are embedded as they are: %v":  "Не удалось обогатить фрагментов: %d; они встроены как есть: %v",
+	"Enriching chunks %d/%d":                                            "Обогащение фрагментов %d/%d",
+	"Enriching %d chunks":                                               "Обогащение фрагментов: %d",
+	"found for: %s":                                                     "найдено по: %s",
+	"Sources:":                                                          "Источники:",
+	"Answers need an llm section in the config.":                        "Для ответов нужен раздел llm в конфигурации.",
+	"Answer: Up/Down scroll, Esc returns":                               "Ответ: Up/Down — прокрутка, Esc — назад",
+	"Writing an answer…":                                                "Пишется ответ…",
+	"Answer to %q":                                                      "Ответ на %q",
+	"Answer failed: %v":                                                 "Не удалось получить ответ: %v",
+	"Usage: :set temp|max_tokens|model VALUE":                           "Использование: :set temp|max_tokens|model ЗНАЧЕНИЕ",
+	"Not a number: %s":                                                  "Не число: %s",
+	"Not a positive number: %s":                                         "Не положительное число: %s",
+	"default":                                                           "по умолчанию",
+	"Conversation not saved: %v":                                        "Разговор не сохранён: %v",
+	"Export failed: %v":                                                 "Ошибка экспорта: %v",
+	"Wrote %s.":                                                         "Записан %s.",
+	"No conversations. Queries and answers of rag --no-tui are kept here.": "Разговоров нет. Здесь хранятся запросы и ответы rag --no-tui.",
+	"%d queries, first: %s":         "запросов: %d, первый: %s",
+	"(not cited)":                   "(не цитируется)",
+	"Claims not checked: %v":        "Утверждения не проверены: %v",
+	"Not supported by the sources:": "Не подтверждается источниками:",
+	"Sentences not supported by the sources (underlined): %d": "Предложения, не подтверждённые источниками (подчёркнуты): %d",
+	"Bookmark removed.": "Закладка удалена.",
+	"Bookmarked %s#%d.": "%s#%d добавлен в закладки.",
+	"No bookmarks. Press Enter on a result in the TUI and choose Bookmark.": "Закладок нет. Нажмите Enter на результате в TUI и выберите «Добавить в закладки».",
+
+	// Saved searches.
+	"name> ":                                     "имя> ",
+	"Saved searches are not available.":          "Сохранённые поиски недоступны.",
+	"Run a query before saving it.":              "Сначала выполните запрос, затем сохраните его.",
+	"Save %q as: (Enter to save, Esc to cancel)": "Сохранить %q как: (Enter — сохранить, Esc — отмена)",
+	"Saved search %q.":                           "Поиск %q сохранён.",
+	"Save cancelled.":                            "Сохранение отменено.",
+	"Saved searches: Enter to run, Delete to remove, Esc to return": "Сохранённые поиски: Enter — выполнить, Delete — удалить, Esc — назад",
+	"Deleted saved search %q.":                                      "Сохранённый поиск %q удалён.",
+	"No saved searches. Press Ctrl+S after a query to save it.":     "Сохранённых поисков нет. Нажмите Ctrl+S после запроса, чтобы сохранить его.",
+	"Saved searches %d/%d":                                          "Сохранённые поиски %d/%d",
+
+	// Documents, topics and links.
+	"Documents: Up/Down to browse, Esc or Ctrl+B to return": "Документы: Вверх/Вниз — листать, Esc или Ctrl+B — назад",
+	"No documents ingested.":                                "Нет проиндексированных документов.",
+	"Documents %d/%d":                                       "Документы %d/%d",
+	"Corpus keywords: %s":                                   "Ключевые слова корпуса: %s",
+	"Topics: Up/Down to browse, Esc or Ctrl+T to return":    "Темы: Вверх/Вниз — листать, Esc или Ctrl+T — назад",
+	"No topics found.":                                      "Темы не найдены.",
+	"Topic %d/%d":                                           "Тема %d/%d",
+	"Run a query to see the links of a result.":             "Выполните запрос, чтобы увидеть ссылки результата.",
+	"%s has no links or backlinks.":                         "В %s нет ссылок, и на него никто не ссылается.",
+	"Links: Enter to open a note, Esc or Ctrl+L to return":  "Ссылки: Enter — открыть заметку, Esc или Ctrl+L — назад",
+	"Note %s (%d chunks); Ctrl+L for its links":             "Заметка %s (фрагментов: %d); Ctrl+L — её ссылки",
+	"Links from %s":                                         "Ссылки из %s",
+	"Backlinks to %s":                                       "Обратные ссылки на %s",
+
+	// Ingest.
+	"Indexing…": "Индексирование…",
+	"Canceling ingest; keeping what is indexed…":                        "Индексирование отменяется; проиндексированное сохраняется…",
+	"The ingest was canceled; results cover the chunks indexed so far.": "Индексирование отменено; результаты охватывают уже проиндексированные фрагменты.",
+	"Ingest canceled; searching the partial index.":                     "Индексирование отменено; поиск по неполному индексу.",
+	"Ingest failed: %v (press any key to quit)":                         "Ошибка индексирования: %v (нажмите любую клавишу для выхода)",
+	"Indexed %d documents (%d chunks) in %s":                            "Проиндексировано документов: %d (фрагментов: %d) за %s",
+	", %d chunks skipped":                                               ", пропущено фрагментов: %d",
+	"Embedding chunks %d/%d":                                            "Вычисление эмбеддингов %d/%d",
+	"Summarizing the corpus…":                                           "Составление сводки корпуса…",
+	"Loading documents (%d so far)…":                                    "Загрузка документов (загружено: %d)…",
+	"Elapsed %s":                                                        "Прошло %s",
+	"Esc cancels, keeping what is indexed so far":                       "Esc отменяет, сохраняя уже проиндексированное",
+
+	// Command line and plain mode.
+	"ingest failed: %v":                              "ошибка индексирования: %v",
+	"ingest failed: %v; check the file arguments":    "ошибка индексирования: %v; проверьте аргументы с файлами",
+	"ingest failed: %v; check the embedder settings": "ошибка индексирования: %v; проверьте настройки эмбеддера",
+	"%d files skipped, no loader reads them: %s":     "пропущено файлов, которые не читает ни один загрузчик: %d — %s",
+	" and %d more":                                                        " и ещё %d",
+	"failed chunks not recorded: %v":                                      "неудавшиеся фрагменты не записаны: %v",
+	"%d chunks failed to embed (rag retry-failed)":                        "фрагментов без эмбеддинга: %d (rag retry-failed)",
+	"%d chunks failed to embed and are left out":                          "фрагментов без эмбеддинга, пропущено: %d",
+	"No failed chunks recorded.":                                          "Неудавшихся фрагментов не записано.",
+	"Retried %d chunks: %d succeeded, %d still failing.":                  "Повторено фрагментов: %d; успешно: %d, по-прежнему с ошибкой: %d.",
+	"No duplicates found.":                                                "Дубликаты не найдены.",
+	"%d duplicate pairs.":                                                 "Пар дубликатов: %d.",
+	"Changed: %d, added: %d, removed: %d, unchanged: %d.":                 "Изменено: %d, добавлено: %d, удалено: %d, без изменений: %d.",
+	"no chunks to sample; check the file arguments":                       "нет фрагментов для выборки; проверьте аргументы с файлами",
+	"Sample: %d chunks, %d queries (%s), answers searched in the top %d.": "Выборка: фрагментов — %d, запросов — %d (%s), ответ ищется среди первых %d.",
+	"failed: %v":           "ошибка: %v",
+	"Best: %s (MRR %.3f).": "Лучшая модель: %s (MRR %.3f).",
+	"Keyword queries share words with their answers, which favors tfidf; configure an llm for natural-language questions.": "Запросы из ключевых слов совпадают с ответами по словам, что даёт преимущество tfidf; настройте llm, чтобы получить вопросы на естественном языке.",
+	"No similar passages found.":                                    "Похожие фрагменты не найдены.",
+	"Loading documents":                                             "Загрузка документов",
+	"Embedding %d chunks":                                           "Вычисление эмбеддингов, фрагментов: %d",
+	"Summarizing the corpus":                                        "Составление сводки корпуса",
+	"Indexing canceled; searching what is indexed so far.":          "Индексирование отменено; поиск по уже проиндексированному.",
+	"Indexing failed: %v":                                           "Ошибка индексирования: %v",
+	"Indexed %d documents (%d chunks).":                             "Проиндексировано документов: %d (фрагментов: %d).",
+	"Indexed %d documents (%d chunks) in %s.":                       "Проиндексировано документов: %d (фрагментов: %d) за %s.",
+	"Heap in use %d MiB, %d GC cycles, %s total GC pause.":          "Куча: %d МиБ, циклов GC: %d, суммарная пауза GC: %s.",
+	"Profiles: %s (CPU), %s (heap). Inspect with: go tool pprof %s": "Профили: %s (CPU), %s (куча). Просмотр: go tool pprof %s",
+	"Warning: %s":                                                   "Предупреждение: %s",
+	"Summary: %s":                                                   "Сводка: %s",
+	`Type a query, or "help".`:                                      "Введите запрос или «help».",
+	"No query yet.":                                                 "Запроса ещё не было.",
+	"No result %d.":                                                 "Нет результата %d.",
+	"score %.3f":                                                    "оценка %.3f",
+	"Query failed: %v":                                              "Ошибка запроса: %v",
+	"No results.":                                                   "Ничего не найдено.",
+	"No more results.":                                              "Больше результатов нет.",
+	`Type a query and press Enter to search.
+Commands:
+  more    show the next results of the last query
+  N       show the full text of result N
+  answer  answer the last query from its results (needs llm)
+  :set    show the parameters of answers; :set temp 0, :set max_tokens 500
+          or :set model NAME sets one, :set temp default resets it
+  export  write this conversation as Markdown (export FILE writes to FILE)
+  docs    list the indexed documents
+  help    show this help
+  quit    exit (or end the input)`: `Введите запрос и нажмите Enter для поиска.
+Команды:
+  more    следующие результаты последнего запроса
+  N       полный текст результата N
+  answer  ответ на последний запрос по его результатам (нужна llm)
+  :set    параметры ответов; :set temp 0, :set max_tokens 500 или
+          :set model ИМЯ задаёт параметр, :set temp default сбрасывает его
+  export  записать этот разговор в Markdown (export ФАЙЛ — в ФАЙЛ)
+  docs    список проиндексированных документов
+  help    эта справка
+  quit    выход (или конец ввода)`,
+
+	// Command-line errors.
+	"load failed: %v": "ошибка загрузки: %v",
+	"generating queries with an llm needs an llm section in the config": "для генерации запросов моделью нужен раздел llm в конфигурации",
+	"query generation failed: %v":                                       "ошибка генерации запросов: %v",
+	"unknown query generator: %s":                                       "неизвестный генератор запросов: %s",
+	"no queries could be generated from the sample":                     "по выборке не удалось составить ни одного запроса",
+	"no models to compare":                                              "нет моделей для сравнения",
+	"failed to load bookmarks: %v":                                      "не удалось загрузить закладки: %v",
+	"failed to delete the conversation: %v":                             "не удалось удалить разговор: %v",
+	"export failed: %v":                                                 "ошибка экспорта: %v",
+	"failed to load conversations: %v":                                  "не удалось загрузить разговоры: %v",
+	"failed to load corpus manifest: %v":                                "не удалось загрузить манифест корпуса: %v",
+	"the Qdrant collection is read-only; diff needs a store it can index both versions into": "коллекция Qdrant доступна только для чтения; для diff нужно хранилище, в которое можно проиндексировать обе версии",
+	"diff failed: %v":                                                 "ошибка сравнения: %v",
+	"failed to list %s: %v":                                           "не удалось получить список %s: %v",
+	"duplicate detection failed: %v":                                  "ошибка поиска дубликатов: %v",
+	"exported %d chunks to %s":                                        "экспортировано фрагментов: %d в %s",
+	"failed to load feedback: %v":                                     "не удалось загрузить оценки: %v",
+	"write failed: %v":                                                "ошибка записи: %v",
+	"invalid config: %v":                                              "некорректная конфигурация: %v",
+	"failed to load config: %v":                                       "не удалось загрузить конфигурацию: %v",
+	"warning: %v":                                                     "предупреждение: %v",
+	"failed to resolve cache directory: %v":                           "не удалось определить каталог кэша: %v",
+	"failed to create text log: %v":                                   "не удалось создать журнал текстов: %v",
+	"unknown vector index: %s":                                        "неизвестный векторный индекс: %s",
+	"memory store init failed: %v":                                    "ошибка инициализации хранилища в памяти: %v",
+	"failed to resolve data directory: %v":                            "не удалось определить каталог данных: %v",
+	"disk store init failed: %v":                                      "ошибка инициализации дискового хранилища: %v",
+	"qdrant config missing":                                           "нет конфигурации qdrant",
+	"qdrant init failed: %v":                                          "ошибка инициализации qdrant: %v",
+	"unknown vector store: %s":                                        "неизвестное векторное хранилище: %s",
+	"unknown summarizer: %s":                                          "неизвестный суммаризатор: %s",
+	"unknown summarizer strategy: %s":                                 "неизвестная стратегия суммаризации: %s",
+	"invalid ingest.allowed_roots: %v":                                "некорректный ingest.allowed_roots: %v",
+	"unknown lexical scoring: %s":                                     "неизвестная лексическая оценка: %s",
+	"search.hyde needs an llm section in the config":                  "для search.hyde нужен раздел llm в конфигурации",
+	"unknown search strategy: %s":                                     "неизвестная стратегия поиска: %s",
+	"unknown retrieval mode: %s":                                      "неизвестный режим отбора: %s",
+	"invalid retrieval.bm25 config: %v":                               "некорректная конфигурация retrieval.bm25: %v",
+	"{context} in enrich.template needs an llm section in the config": "для {context} в enrich.template нужен раздел llm в конфигурации",
+	"failed to read the chunk context cache: %v":                      "не удалось прочитать кэш контекстов фрагментов: %v",
+	"invalid enrich config: %v":                                       "некорректная конфигурация enrich: %v",
+	"invalid generator config: %v":                                    "некорректная конфигурация generator: %v",
+	"unknown grounding check: %s":                                     "неизвестная проверка обоснованности: %s",
+	"translating results needs an llm section in the config":          "для перевода результатов нужен раздел llm в конфигурации",
+	"llm init failed: %v":                                             "ошибка инициализации llm: %v",
+	"the Qdrant collection is read-only; run without input files to search it": "коллекция Qdrant доступна только для чтения; запустите без входных файлов, чтобы искать по ней",
+	"openai embedder config missing":                                           "нет конфигурации эмбеддера openai",
+	"openai embedder init failed: %v":                                          "ошибка инициализации эмбеддера openai: %v",
+	"unknown embedder: %s":                                                     "неизвестный эмбеддер: %s",
+	"unknown chunker: %s":                                                      "неизвестный разбиватель на фрагменты: %s",
+	"unknown summary budget unit: %s":                                          "неизвестная единица объёма сводки: %s",
+	"failed to record failed chunks: %v":                                       "не удалось записать неудавшиеся фрагменты: %v",
+	"%d chunks failed to embed and were skipped; run `rag retry-failed` to re-attempt them": "фрагментов без эмбеддинга, пропущено: %d; повторите попытку с помощью `rag retry-failed`",
+	"invalid line: %s":                                        "некорректный номер строки: %s",
+	"%s is not a local file":                                  "%s — не локальный файл",
+	"open failed: %v":                                         "ошибка открытия: %v",
+	"failed to create CPU profile: %v":                        "не удалось создать профиль CPU: %v",
+	"failed to start CPU profile: %v":                         "не удалось запустить профилирование CPU: %v",
+	"failed to write CPU profile: %v":                         "не удалось записать профиль CPU: %v",
+	"failed to create heap profile: %v":                       "не удалось создать профиль кучи: %v",
+	"failed to write heap profile: %v":                        "не удалось записать профиль кучи: %v",
+	"the Qdrant collection is read-only; it cannot be purged": "коллекция Qdrant доступна только для чтения; очистить её нельзя",
+	"purge failed: %v":                                        "ошибка очистки: %v",
+	"--answer writes text or json, not %s":                    "--answer выводит text или json, а не %s",
+	"answering needs an llm section in the config":            "для ответов нужен раздел llm в конфигурации",
+	"query failed: %v":                                        "ошибка запроса: %v",
+	"answer failed: %v":                                       "ошибка ответа: %v",
+	"unknown output format: %s":                               "неизвестный формат вывода: %s",
+	"translation failed: %v":                                  "ошибка перевода: %v",
+	"failed to read %s: %v":                                   "не удалось прочитать %s: %v",
+	"retry-failed needs a persistent vector store: the memory store would discard the retried chunks on exit": "retry-failed нужно постоянное хранилище векторов: хранилище в памяти потеряет повторённые фрагменты при выходе",
+	"retry failed: %v":        "ошибка повтора: %v",
+	"failed to update %s: %v": "не удалось обновить %s: %v",
+	"read passage: %v":        "чтение фрагмента: %v",
+	"search failed: %v":       "ошибка поиска: %v",
+	"empty passage":           "пустой фрагмент",
+	"sweeping chunk sizes needs the sentence chunker, not %s":                              "для перебора размеров фрагментов нужен разбиватель sentence, а не %s",
+	"no chunker parameters to try; the overlap must be less than the chunk size":           "нет параметров для перебора; перекрытие должно быть меньше размера фрагмента",
+	"failed to load queries: %v":                                                           "не удалось загрузить запросы: %v",
+	"invalid --%s value %q":                                                                "некорректное значение --%s: %q",
+	"failed to write config: %v":                                                           "не удалось записать конфигурацию: %v",
+	"the Qdrant collection is read-only; it cannot be repaired":                            "коллекция Qdrant доступна только для чтения; исправить её нельзя",
+	"watching %d chunks; standing queries are the saved searches of this index":            "отслеживается фрагментов: %d; постоянные запросы — сохранённые поиски этого индекса",
+	"re-ingest failed: %v":                                                                 "ошибка переиндексации: %v",
+	"re-ingested: %d new chunks":                                                           "переиндексировано; новых фрагментов: %d",
+	"%d chunks of files gone from the corpus are kept soft-deleted (rag purge drops them)": "фрагменты файлов, исчезнувших из корпуса, помечены удалёнными: %d (rag purge удаляет их)",
+	"failed to load saved searches: %v":                                                    "не удалось загрузить сохранённые поиски: %v",
+	"standing query %q failed: %v":                                                         "ошибка постоянного запроса %q: %v",
+	"notification failed: %v":                                                              "ошибка уведомления: %v",
+	"~ %.3f  %s#%d  (was %s#%d)":                                                           "~ %.3f  %s#%d  (было %s#%d)",
+	"%2d. %.3f  %s  (%d hits)":                                                             "%2d. %.3f  %s  (совпадений: %d)",
+
+	// Table headers of rag bench-models, rag sweep and rag tune.
+	"model":     "модель",
+	"index":     "индекс",
+	"query":     "запрос",
+	"sentences": "предлож.",
+	"overlap":   "перекр.",
+	"chunks":    "фрагм.",
+	"vector":    "вектор",
+	"lexical":   "лексика",
+	"recency":   "новизна",
+	"negatives": "негативы",
+
+	// Usage.
+	"Usage: rag bench-models [--config=config.yaml] [--models=a,b,c] [--sample=300] [--queries=50] [--top-k=10] [--generate=keywords|llm] [--seed=1] file1.txt [file2.txt ...]": "Использование: rag bench-models [--config=config.yaml] [--models=a,b,c] [--sample=300] [--queries=50] [--top-k=10] [--generate=keywords|llm] [--seed=1] file1.txt [file2.txt ...]",
+	"Usage: rag bookmarks [--config=config.yaml] file1.txt [file2.txt ...]":                                                                                                     "Использование: rag bookmarks [--config=config.yaml] file1.txt [file2.txt ...]",
+	"Usage: rag conversations [--config=config.yaml] [--export=ID [--out=notes.md]] [--delete=ID] file1.txt [file2.txt ...]":                                                    "Использование: rag conversations [--config=config.yaml] [--export=ID [--out=notes.md]] [--delete=ID] file1.txt [file2.txt ...]",
+	"Usage: rag diff [--config=config.yaml] [--threshold=0.8] OLD NEW  (directories, files or glob patterns)":                                                                   "Использование: rag diff [--config=config.yaml] [--threshold=0.8] OLD NEW  (каталоги, файлы или шаблоны glob)",
+	"Usage: rag dupes [--config=config.yaml] [--method=minhash|embedding] [--threshold=0.9] [--documents] file1.txt [file2.txt ...]":                                            "Использование: rag dupes [--config=config.yaml] [--method=minhash|embedding] [--threshold=0.9] [--documents] file1.txt [file2.txt ...]",
+	"Usage: rag export [--config=config.yaml] [--out=corpus.jsonl] file1.txt [file2.txt ...]":                                                                                   "Использование: rag export [--config=config.yaml] [--out=corpus.jsonl] file1.txt [file2.txt ...]",
+	"Usage: rag feedback [--config=config.yaml] [--out=feedback.jsonl] file1.txt [file2.txt ...]":                                                                               "Использование: rag feedback [--config=config.yaml] [--out=feedback.jsonl] file1.txt [file2.txt ...]",
+	`Usage: rag [--config=config.yaml] [--no-tui] [--pprof=:6060] file1.txt [file2.txt ...]
+       rag retry-failed [--config=config.yaml] files...
+       rag dupes [--method=minhash|embedding] [--threshold=0.9] [--documents] files...
+       rag query [--q=text | --saved=name] [--top-k=10] files...
+       rag similar [--passage=passage.txt] [--top-k=10] [--output=text|json] files...
+       rag diff [--threshold=0.8] OLD NEW
+       rag export [--out=corpus.jsonl] files...
+       rag rpc [--pprof=:6060] files...
+       rag open FILE [LINE]
+       rag bookmarks files...
+       rag conversations [--export=ID [--out=notes.md]] [--delete=ID] files...
+       rag feedback [--out=feedback.jsonl] files...
+       rag tune [--top-k=10] [--dry-run] files...
+       rag verify [--repair] files...
+       rag purge [--older-than=720h] [--dry-run]
+       rag watch [--interval=5s] [--threshold=0.3] [--notify] [--webhook=URL] [--pprof=:6060] files...
+       rag bench-models [--models=a,b,c] [--sample=300] [--queries=50] files...
+       rag sweep [--sentences=3,5,8] [--overlap=0,1,2] [--queries=feedback.jsonl] [--parallel=4] files...
+       rag profile-ingest [--cpu=cpu.pprof] [--heap=heap.pprof] files...`: `Использование: rag [--config=config.yaml] [--no-tui] [--pprof=:6060] file1.txt [file2.txt ...]
+               rag retry-failed [--config=config.yaml] files...
+               rag dupes [--method=minhash|embedding] [--threshold=0.9] [--documents] files...
+               rag query [--q=text | --saved=name] [--top-k=10] files...
+               rag similar [--passage=passage.txt] [--top-k=10] [--output=text|json] files...
+               rag diff [--threshold=0.8] OLD NEW
+               rag export [--out=corpus.jsonl] files...
+               rag rpc [--pprof=:6060] files...
+               rag open FILE [LINE]
+               rag bookmarks files...
+               rag conversations [--export=ID [--out=notes.md]] [--delete=ID] files...
+               rag feedback [--out=feedback.jsonl] files...
+               rag tune [--top-k=10] [--dry-run] files...
+               rag verify [--repair] files...
+               rag purge [--older-than=720h] [--dry-run]
+               rag watch [--interval=5s] [--threshold=0.3] [--notify] [--webhook=URL] [--pprof=:6060] files...
+               rag bench-models [--models=a,b,c] [--sample=300] [--queries=50] files...
+               rag sweep [--sentences=3,5,8] [--overlap=0,1,2] [--queries=feedback.jsonl] [--parallel=4] files...
+               rag profile-ingest [--cpu=cpu.pprof] [--heap=heap.pprof] files...`,
+	"Usage: rag open [--config=config.yaml] FILE [LINE]":                                                               "Использование: rag open [--config=config.yaml] FILE [LINE]",
+	"Usage: rag profile-ingest [--config=config.yaml] [--cpu=cpu.pprof] [--heap=heap.pprof] file1.txt [file2.txt ...]": "Использование: rag profile-ingest [--config=config.yaml] [--cpu=cpu.pprof] [--heap=heap.pprof] file1.txt [file2.txt ...]",
+	"Usage: rag query [--config=config.yaml] [--q=text | --saved=name] [--top-k=10] [--group] [--order=score|document|recency] [--translate=LANG] [--output=text|json|alfred|rofi] [--answer [--temperature=T] [--max-tokens=N] [--answer-model=NAME]] file1.txt [file2.txt ...]": "Использование: rag query [--config=config.yaml] [--q=text | --saved=name] [--top-k=10] [--group] [--order=score|document|recency] [--translate=LANG] [--output=text|json|alfred|rofi] [--answer [--temperature=T] [--max-tokens=N] [--answer-model=NAME]] file1.txt [file2.txt ...]",
+	"Usage: rag retry-failed [--config=config.yaml] file1.txt [file2.txt ...]":                                                                                       "Использование: rag retry-failed [--config=config.yaml] file1.txt [file2.txt ...]",
+	"Usage: rag rpc [--config=config.yaml] [--pprof=:6060] file1.txt [file2.txt ...]":                                                                                "Использование: rag rpc [--config=config.yaml] [--pprof=:6060] file1.txt [file2.txt ...]",
+	"Usage: rag similar [--config=config.yaml] [--passage=passage.txt] [--top-k=10] [--output=text|json] file1.txt [file2.txt ...] < passage.txt":                    "Использование: rag similar [--config=config.yaml] [--passage=passage.txt] [--top-k=10] [--output=text|json] file1.txt [file2.txt ...] < passage.txt",
+	"Usage: rag sweep [--config=config.yaml] [--sentences=3,5,8] [--overlap=0,1,2] [--queries=feedback.jsonl] [--top-k=10] [--parallel=4] file1.txt [file2.txt ...]": "Использование: rag sweep [--config=config.yaml] [--sentences=3,5,8] [--overlap=0,1,2] [--queries=feedback.jsonl] [--top-k=10] [--parallel=4] file1.txt [file2.txt ...]",
+	"Usage: rag tune [--config=config.yaml] [--top-k=10] [--dry-run] file1.txt [file2.txt ...]":                                                                      "Использование: rag tune [--config=config.yaml] [--top-k=10] [--dry-run] file1.txt [file2.txt ...]",
+	"Usage: rag verify [--config=config.yaml] [--repair] file1.txt [file2.txt ...]":                                                                                  "Использование: rag verify [--config=config.yaml] [--repair] file1.txt [file2.txt ...]",
+	"Usage: rag watch [--config=config.yaml] [--interval=5s] [--threshold=0.3] [--notify] [--webhook=URL] [--pprof=:6060] file1.txt [file2.txt ...]":                 "Использование: rag watch [--config=config.yaml] [--interval=5s] [--threshold=0.3] [--notify] [--webhook=URL] [--pprof=:6060] file1.txt [file2.txt ...]",
+}
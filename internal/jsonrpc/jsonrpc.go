@@ -0,0 +1,218 @@
+// Package jsonrpc serves JSON-RPC 2.0 requests over a stream such as stdio,
+// for editor plugins. Messages are framed either as in the Language Server
+// Protocol, behind a Content-Length header, or one per line; each response
+// is framed as its request was, so that both LSP client libraries and plain
+// line-reading jobs can talk to the server.
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Error codes of the JSON-RPC 2.0 specification.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error is an error returned to the client. Handlers return it for invalid
+// params; any other error is reported as an internal error.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// InvalidParams returns an Error for params a handler cannot use.
+func InvalidParams(format string, args ...any) *Error {
+	return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// Handler answers a request with its params, as sent, and returns the
+// result to be encoded as JSON.
+type Handler func(params json.RawMessage) (any, error)
+
+// Server dispatches requests to the handlers of their methods.
+type Server struct {
+	handlers map[string]Handler
+}
+
+// NewServer returns a server with no methods.
+func NewServer() *Server {
+	return &Server{handlers: make(map[string]Handler)}
+}
+
+// Handle registers h for method.
+func (s *Server) Handle(method string, h Handler) {
+	s.handlers[method] = h
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// ErrShutdown is returned by a handler to answer its request and stop the
+// server.
+var ErrShutdown = errors.New("shutdown")
+
+// Serve reads requests from r and writes responses to w, one request at a
+// time, until r ends or a handler returns ErrShutdown. Notifications, the
+// requests without an ID, get no response.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	in := bufio.NewReader(r)
+	for {
+		body, framed, err := readMessage(in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(body)) == 0 {
+			continue
+		}
+		resp, stop := s.dispatch(body)
+		if resp != nil {
+			if err := write(w, resp, framed); err != nil {
+				return err
+			}
+		}
+		if stop {
+			return nil
+		}
+	}
+}
+
+// dispatch answers one message, returning nil for a notification, and
+// whether the server should stop.
+func (s *Server) dispatch(body []byte) (resp *response, stop bool) {
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		return &response{ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: err.Error()}}, false
+	}
+	notification := len(req.ID) == 0
+	resp = &response{ID: req.ID}
+	h, ok := s.handlers[req.Method]
+	switch {
+	case req.JSONRPC != "2.0" || req.Method == "":
+		resp.Error = &Error{Code: CodeInvalidRequest, Message: "not a JSON-RPC 2.0 request"}
+	case !ok:
+		resp.Error = &Error{Code: CodeMethodNotFound, Message: "unknown method " + strconv.Quote(req.Method)}
+	default:
+		result, err := h(req.Params)
+		var rpcErr *Error
+		switch {
+		case errors.Is(err, ErrShutdown):
+			stop = true
+			resp.Result = result
+		case errors.As(err, &rpcErr):
+			resp.Error = rpcErr
+		case err != nil:
+			resp.Error = &Error{Code: CodeInternalError, Message: err.Error()}
+		default:
+			resp.Result = result
+		}
+		if resp.Error == nil && resp.Result == nil {
+			// A null result is still a result.
+			resp.Result = json.RawMessage("null")
+		}
+	}
+	if notification {
+		return nil, stop
+	}
+	return resp, stop
+}
+
+// write sends resp, behind a Content-Length header when framed.
+func write(w io.Writer, resp *response, framed bool) error {
+	resp.JSONRPC = "2.0"
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	if framed {
+		_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	} else {
+		_, err = fmt.Fprintf(w, "%s\n", data)
+	}
+	return err
+}
+
+// maxMessageSize bounds the Content-Length of a message and the length of
+// a line, so that a bad header or a client that never sends a newline
+// cannot make the server allocate more memory than a request needs.
+const maxMessageSize = 64 << 20
+
+var errTooLarge = fmt.Errorf("message exceeds the limit of %d bytes", maxMessageSize)
+
+// readMessage reads the next message: the body behind LSP headers, with
+// framed set, or else the next line.
+func readMessage(in *bufio.Reader) ([]byte, bool, error) {
+	line, err := readLine(in)
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		return nil, false, err
+	}
+	name, value, ok := strings.Cut(string(line), ":")
+	if !ok || !strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+		return line, false, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 0 {
+		return nil, false, fmt.Errorf("bad Content-Length %q", strings.TrimSpace(value))
+	}
+	if n > maxMessageSize {
+		return nil, false, errTooLarge
+	}
+	// Further headers, such as Content-Type, end at a blank line.
+	for {
+		h, err := readLine(in)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(bytes.TrimSpace(h)) == 0 {
+			break
+		}
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(in, body); err != nil {
+		return nil, false, err
+	}
+	return body, true, nil
+}
+
+// readLine reads up to and including the next newline, like ReadBytes, but
+// fails with errTooLarge once the line outgrows maxMessageSize.
+func readLine(in *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		frag, err := in.ReadSlice('\n')
+		if len(line)+len(frag) > maxMessageSize {
+			return nil, errTooLarge
+		}
+		line = append(line, frag...)
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// endless is a reader of the same byte forever, a line without an end.
+type endless byte
+
+func (b endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(b)
+	}
+	return len(p), nil
+}
+
+func TestReadMessage(t *testing.T) {
+	const body = `{"jsonrpc":"2.0","id":1,"method":"query"}`
+	tests := []struct {
+		name   string
+		in     string
+		framed bool
+	}{
+		{"line", body + "\n", false},
+		{"last line", body, false},
+		{"framed", fmt.Sprintf("Content-Length: %d\r\nContent-Type: application/json\r\n\r\n%s", len(body), body), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, framed, err := readMessage(bufio.NewReader(strings.NewReader(tt.in)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.TrimSpace(string(got)) != body || framed != tt.framed {
+				t.Errorf("readMessage = %q, %v, want %q, %v", got, framed, body, tt.framed)
+			}
+		})
+	}
+}
+
+func TestReadMessageTooLarge(t *testing.T) {
+	tests := []struct {
+		name string
+		in   io.Reader
+	}{
+		{"line", endless('a')},
+		{"framed", strings.NewReader(fmt.Sprintf("Content-Length: %d\r\n\r\n", maxMessageSize+1))},
+		{"header", io.MultiReader(strings.NewReader("Content-Length: 2\r\nX-Padding: "), endless('a'))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := readMessage(bufio.NewReader(tt.in)); !errors.Is(err, errTooLarge) {
+				t.Errorf("readMessage error %v, want %v", err, errTooLarge)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Package jsonstore keeps records in a JSON file, as a list sorted for
+// reading and diffing. The file is read on every access and replaced on
+// every change, which suits the small per-index files of saved searches,
+// bookmarks, judgments and conversations, and lets several rag processes
+// share them.
+package jsonstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Store persists records of type T, identified by key, as a JSON file.
+type Store[T any] struct {
+	mu   sync.Mutex
+	path string
+	key  func(T) string
+	less func(a, b T) bool
+}
+
+// New returns a store backed by the file at path, which is created on
+// first save. key identifies a record, of which the store keeps one per
+// key, and less orders the records in the file and in List.
+func New[T any](path string, key func(T) string, less func(a, b T) bool) *Store[T] {
+	return &Store[T]{path: path, key: key, less: less}
+}
+
+// List returns all records in order.
+func (s *Store[T]) List() ([]T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return s.sorted(m), nil
+}
+
+// Get returns the record with the given key, if there is one.
+func (s *Store[T]) Get(key string) (T, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, err := s.load()
+	if err != nil {
+		var zero T
+		return zero, false, err
+	}
+	v, ok := m[key]
+	return v, ok, nil
+}
+
+// Put stores v, replacing the record with the same key.
+func (s *Store[T]) Put(v T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, err := s.load()
+	if err != nil {
+		return err
+	}
+	m[s.key(v)] = v
+	return s.write(m)
+}
+
+// Delete removes the record with the given key and reports whether there
+// was one.
+func (s *Store[T]) Delete(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := m[key]; !ok {
+		return false, nil
+	}
+	delete(m, key)
+	return true, s.write(m)
+}
+
+func (s *Store[T]) load() (map[string]T, error) {
+	m := make(map[string]T)
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return m, nil
+		}
+		return nil, err
+	}
+	var list []T
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", s.path, err)
+	}
+	for _, v := range list {
+		m[s.key(v)] = v
+	}
+	return m, nil
+}
+
+// write replaces the file with the records of m through a temporary file,
+// so that a crash or a full disk never leaves it half written.
+func (s *Store[T]) write(m map[string]T) error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.sorted(m), "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
+
+func (s *Store[T]) sorted(m map[string]T) []T {
+	list := make([]T, 0, len(m))
+	for _, v := range m {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool { return s.less(list[i], list[j]) })
+	return list
+}
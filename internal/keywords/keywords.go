@@ -0,0 +1,87 @@
+package keywords
+
+import (
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"rag/internal/textutil"
+)
+
+// Extractor ranks terms by TF-IDF across a set of documents.
+type Extractor struct {
+	idf  map[string]float64
+	docs []map[string]float64
+}
+
+// NewExtractor computes term statistics over the given document texts.
+func NewExtractor(texts []string) *Extractor {
+	e := &Extractor{idf: make(map[string]float64), docs: make([]map[string]float64, len(texts))}
+	df := make(map[string]int)
+	for i, text := range texts {
+		tf := make(map[string]float64)
+		total := 0
+		for _, tok := range textutil.Words(text) {
+			if !isCandidate(tok) {
+				continue
+			}
+			tf[tok]++
+			total++
+		}
+		for tok := range tf {
+			tf[tok] /= float64(total)
+			df[tok]++
+		}
+		e.docs[i] = tf
+	}
+	n := float64(len(texts))
+	for tok, d := range df {
+		e.idf[tok] = math.Log((1+n)/(1+float64(d))) + 1
+	}
+	return e
+}
+
+// Document returns the top n keywords of the i-th document.
+func (e *Extractor) Document(i, n int) []string {
+	if i < 0 || i >= len(e.docs) {
+		return nil
+	}
+	scores := make(map[string]float64, len(e.docs[i]))
+	for tok, tf := range e.docs[i] {
+		scores[tok] = tf * e.idf[tok]
+	}
+	return top(scores, n)
+}
+
+// Corpus returns the top n keywords of the whole corpus, favoring terms that
+// are prominent in many documents.
+func (e *Extractor) Corpus(n int) []string {
+	scores := make(map[string]float64)
+	for _, tf := range e.docs {
+		for tok, v := range tf {
+			scores[tok] += v
+		}
+	}
+	return top(scores, n)
+}
+
+func isCandidate(tok string) bool {
+	return utf8.RuneCountInString(tok) > 2 && !textutil.IsStopword(tok)
+}
+
+func top(scores map[string]float64, n int) []string {
+	terms := make([]string, 0, len(scores))
+	for t := range scores {
+		terms = append(terms, t)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if scores[terms[i]] != scores[terms[j]] {
+			return scores[terms[i]] > scores[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	if n > 0 && len(terms) > n {
+		terms = terms[:n]
+	}
+	return terms
+}
@@ -0,0 +1,168 @@
+// Package langchain reads and writes corpora as JSON Lines of LangChain
+// Documents, one {"page_content": ..., "metadata": {...}} object per line,
+// the interchange format of Python RAG stacks. LlamaIndex nodes, which name
+// the content "text", are read as well.
+package langchain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"rag/internal/domain"
+)
+
+// Document is a LangChain Document.
+type Document struct {
+	PageContent string         `json:"page_content"`
+	Metadata    map[string]any `json:"metadata"`
+	// Text holds the content of LlamaIndex nodes; it is only read.
+	Text string `json:"text,omitempty"`
+}
+
+// Content returns the document text, from page_content or a LlamaIndex
+// text field.
+func (d Document) Content() string {
+	if d.PageContent != "" {
+		return d.PageContent
+	}
+	return d.Text
+}
+
+// Metadata keys written for every chunk, besides the chunk's own metadata.
+// "source" and "start_index" follow LangChain's loaders and text splitters.
+const (
+	KeySource     = "source"
+	KeyStartIndex = "start_index"
+	KeyChunkIndex = "chunk_index"
+	KeyDocumentID = "document_id"
+	KeyDate       = "date"
+	KeyKeywords   = "keywords"
+)
+
+// FromChunk converts a chunk into a Document whose metadata records where
+// the chunk came from.
+func FromChunk(ch domain.Chunk) Document {
+	meta := make(map[string]any, len(ch.Metadata)+5)
+	for key, value := range ch.Metadata {
+		meta[key] = value
+	}
+	meta[KeySource] = ch.Path
+	meta[KeyDocumentID] = ch.DocumentID
+	meta[KeyChunkIndex] = ch.Index
+	if ch.End > ch.Start {
+		meta[KeyStartIndex] = ch.Start
+	}
+	if !ch.Time.IsZero() {
+		meta[KeyDate] = ch.Time.Format(time.RFC3339)
+	}
+	if len(ch.Keywords) > 0 {
+		meta[KeyKeywords] = ch.Keywords
+	}
+	return Document{PageContent: ch.Text, Metadata: meta}
+}
+
+// Write writes chunks to w as JSON Lines of Documents.
+func Write(w io.Writer, chunks []domain.Chunk) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	for _, ch := range chunks {
+		if err := enc.Encode(FromChunk(ch)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Read parses JSON Lines of Documents, skipping blank lines. Lines are
+// numbered from 1 in errors.
+func Read(data []byte) ([]Document, error) {
+	var docs []Document
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var d Document
+		if err := json.Unmarshal(line, &d); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		docs = append(docs, d)
+	}
+	return docs, nil
+}
+
+// Detect reports whether the first non-blank line of data is a Document
+// with content.
+func Detect(data []byte) bool {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var d Document
+		return json.Unmarshal(line, &d) == nil && d.Content() != ""
+	}
+	return false
+}
+
+// StringMetadata flattens metadata into strings: numbers and booleans are
+// formatted, lists of scalars joined with ", ", and nested objects dropped.
+// The keys in skip are left out.
+func StringMetadata(meta map[string]any, skip ...string) map[string]string {
+	out := make(map[string]string, len(meta))
+	for key, value := range meta {
+		if slices.Contains(skip, key) {
+			continue
+		}
+		if s, ok := scalar(value); ok {
+			out[key] = s
+			continue
+		}
+		list, ok := value.([]any)
+		if !ok {
+			continue
+		}
+		var parts []string
+		for _, v := range list {
+			if s, ok := scalar(v); ok && s != "" {
+				parts = append(parts, s)
+			}
+		}
+		if len(parts) > 0 {
+			out[key] = strings.Join(parts, ", ")
+		}
+	}
+	return out
+}
+
+// Date parses the date metadata written by FromChunk, also accepting plain
+// YYYY-MM-DD dates.
+func Date(meta map[string]any) (time.Time, bool) {
+	s, ok := meta[KeyDate].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func scalar(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64, bool:
+		return fmt.Sprint(v), true
+	}
+	return "", false
+}
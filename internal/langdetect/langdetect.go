@@ -0,0 +1,117 @@
+// Package langdetect guesses the language of a text from its script and,
+// for Latin-script text, from common function words.
+package langdetect
+
+import (
+	"strings"
+	"unicode"
+
+	"rag/internal/textutil"
+)
+
+// stopwords lists frequent function words per ISO 639-1 code. They identify
+// Latin- and Cyrillic-script languages and serve as stopword lists.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "was", "for", "with", "as", "are", "on", "this", "be", "by", "at", "from", "or", "an", "have", "not", "but", "which", "they", "you", "has"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "den", "ein", "eine", "zu", "von", "auf", "für", "sich", "dem", "auch", "es", "wird", "im", "dass", "sind", "wie", "oder", "aber", "bei"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une", "du", "que", "dans", "pour", "pas", "qui", "sur", "au", "avec", "il", "sont", "ce", "par", "plus", "ne", "se", "elle", "mais", "ou"},
+	"es": {"el", "la", "los", "las", "y", "es", "que", "del", "una", "en", "por", "con", "para", "no", "se", "su", "al", "lo", "como", "más", "pero", "sus", "fue", "son", "muy", "también"},
+	"it": {"il", "la", "di", "che", "è", "e", "un", "una", "per", "non", "sono", "del", "della", "con", "nel", "gli", "si", "le", "da", "anche", "come", "ma", "più", "alla", "questo", "dei"},
+	"pt": {"o", "a", "os", "as", "e", "é", "que", "do", "da", "um", "uma", "em", "para", "não", "com", "se", "por", "mais", "dos", "das", "como", "mas", "ao", "foi", "são", "também"},
+	"nl": {"de", "het", "een", "en", "is", "van", "niet", "dat", "op", "te", "zijn", "met", "voor", "in", "er", "ook", "maar", "aan", "als", "bij", "om", "wordt", "nog", "naar", "dit", "worden"},
+	"ru": {"и", "в", "не", "на", "что", "с", "по", "это", "как", "а", "но", "к", "из", "у", "за", "от", "же", "для", "он", "она", "так", "было", "или", "его", "то", "при"},
+	"uk": {"і", "в", "не", "на", "що", "з", "це", "як", "а", "але", "до", "від", "для", "та", "й", "у", "за", "його", "був", "або", "чи", "ще", "при", "вона", "він", "є"},
+}
+
+// stopwordSets indexes stopwords for lookups.
+var stopwordSets = func() map[string]map[string]struct{} {
+	out := make(map[string]map[string]struct{}, len(stopwords))
+	for lang, words := range stopwords {
+		set := make(map[string]struct{}, len(words))
+		for _, w := range words {
+			set[w] = struct{}{}
+		}
+		out[lang] = set
+	}
+	return out
+}()
+
+// Detect returns the ISO 639-1 code of the language of text, or "" when it
+// cannot tell (too short, or no known function words).
+func Detect(text string) string {
+	var latin, cyrillic, han, kana, hangul, arabic, hebrew, greek, thai int
+	for _, r := range text {
+		switch {
+		case !unicode.IsLetter(r):
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Hebrew, r):
+			hebrew++
+		case unicode.Is(unicode.Greek, r):
+			greek++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		}
+	}
+	script, best := "", 0
+	for _, c := range []struct {
+		script string
+		n      int
+	}{{"latin", latin}, {"cyrillic", cyrillic}, {"cjk", han + kana}, {"ko", hangul}, {"ar", arabic}, {"he", hebrew}, {"el", greek}, {"th", thai}} {
+		if c.n > best {
+			script, best = c.script, c.n
+		}
+	}
+	switch script {
+	case "":
+		return ""
+	case "cjk":
+		if kana > 0 {
+			return "ja"
+		}
+		return "zh"
+	case "latin":
+		return byStopwords(text, "en", "de", "fr", "es", "it", "pt", "nl")
+	case "cyrillic":
+		if strings.ContainsAny(strings.ToLower(text), "іїєґ") {
+			return "uk"
+		}
+		return byStopwords(text, "ru", "uk")
+	default:
+		return script
+	}
+}
+
+// byStopwords picks the candidate language with the most function words.
+func byStopwords(text string, candidates ...string) string {
+	counts := make(map[string]int, len(candidates))
+	for _, tok := range textutil.Words(text) {
+		for _, lang := range candidates {
+			if _, ok := stopwordSets[lang][tok]; ok {
+				counts[lang]++
+			}
+		}
+	}
+	best, bestN := "", 0
+	for _, lang := range candidates {
+		if counts[lang] > bestN {
+			best, bestN = lang, counts[lang]
+		}
+	}
+	return best
+}
+
+// Stopwords returns the function words of lang, or nil if unknown.
+func Stopwords(lang string) map[string]struct{} {
+	return stopwordSets[lang]
+}
@@ -0,0 +1,81 @@
+// Package linkgraph builds the graph of wiki-links between notes.
+package linkgraph
+
+import (
+	"path/filepath"
+	"strings"
+
+	"rag/internal/domain"
+)
+
+// Graph holds outgoing links and backlinks between documents by ID.
+type Graph struct {
+	out map[string][]string
+	in  map[string][]string
+}
+
+// Build resolves the wiki-link targets of docs against note names: the file
+// name without extension, the front matter title and aliases (ignoring case).
+// Links to unknown notes and self-links are dropped.
+func Build(docs []domain.Document) *Graph {
+	names := make(map[string]string)
+	for _, d := range docs {
+		for _, name := range noteNames(d) {
+			if _, taken := names[name]; !taken {
+				names[name] = d.ID
+			}
+		}
+	}
+	g := &Graph{out: make(map[string][]string), in: make(map[string][]string)}
+	for _, d := range docs {
+		seen := make(map[string]bool)
+		for _, target := range d.Links {
+			id, ok := names[normalize(filepath.Base(target))]
+			if !ok || id == d.ID || seen[id] {
+				continue
+			}
+			seen[id] = true
+			g.out[d.ID] = append(g.out[d.ID], id)
+			g.in[id] = append(g.in[id], d.ID)
+		}
+	}
+	return g
+}
+
+// Links returns the IDs of notes that id links to.
+func (g *Graph) Links(id string) []string { return g.out[id] }
+
+// Backlinks returns the IDs of notes linking to id.
+func (g *Graph) Backlinks(id string) []string { return g.in[id] }
+
+// InDegree is the number of distinct notes linking to id.
+func (g *Graph) InDegree(id string) int { return len(g.in[id]) }
+
+// MaxInDegree is the largest in-degree in the graph.
+func (g *Graph) MaxInDegree() int {
+	best := 0
+	for _, ids := range g.in {
+		if len(ids) > best {
+			best = len(ids)
+		}
+	}
+	return best
+}
+
+func noteNames(d domain.Document) []string {
+	base := filepath.Base(d.Path)
+	names := []string{normalize(strings.TrimSuffix(base, filepath.Ext(base)))}
+	if title := d.Metadata["title"]; title != "" {
+		names = append(names, normalize(title))
+	}
+	for _, alias := range strings.Split(d.Metadata["aliases"], ", ") {
+		if alias != "" {
+			names = append(names, normalize(alias))
+		}
+	}
+	return names
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimSuffix(name, ".md")))
+}
@@ -0,0 +1,376 @@
+// Package llm is a client for OpenAI-compatible chat completion APIs, such
+// as those of OpenAI, Ollama or a llama.cpp server, for the features that
+// need a language model rather than an embedder.
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message roles.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one message of a chat.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Client is an OpenAI-compatible chat completions client.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	client     *http.Client
+	maxRetries int
+}
+
+// Config configures the chat completions client.
+type Config struct {
+	BaseURL string
+	// APIKeyEnv names the environment variable holding the API key; empty
+	// sends no key, as local servers need none.
+	APIKeyEnv string
+	Model     string
+	Timeout   time.Duration
+	// MaxRetries bounds retries per request on network errors, 429 and 5xx.
+	// Zero uses the default of 3; a negative value disables retries.
+	MaxRetries int
+}
+
+// NewClient creates a chat completions client using the provided
+// configuration.
+func NewClient(cfg Config) (*Client, error) {
+	var key string
+	if cfg.APIKeyEnv != "" {
+		if key = os.Getenv(cfg.APIKeyEnv); key == "" {
+			return nil, fmt.Errorf("missing API key in env %s", cfg.APIKeyEnv)
+		}
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.openai.com/v1"
+	}
+	if cfg.Model == "" {
+		cfg.Model = "gpt-4o-mini"
+	}
+	t := cfg.Timeout
+	if t == 0 {
+		t = 60 * time.Second
+	}
+	retries := cfg.MaxRetries
+	if retries == 0 {
+		retries = 3
+	} else if retries < 0 {
+		retries = 0
+	}
+	return &Client{
+		baseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		apiKey:     key,
+		model:      cfg.Model,
+		client:     &http.Client{Timeout: t},
+		maxRetries: retries,
+	}, nil
+}
+
+// Model returns the name of the model the client asks.
+func (c *Client) Model() string { return c.model }
+
+// Options are generation parameters of a chat request; zero values leave
+// the client's model and the server's defaults.
+type Options struct {
+	// Model replaces the client's model.
+	Model string
+	// Temperature sets how random the reply is, from 0 (the likeliest
+	// reply) to 2.
+	Temperature *float64
+	// MaxTokens bounds the length of the reply.
+	MaxTokens int
+}
+
+// Override returns o with the parameters set in over replacing its own.
+func (o Options) Override(over Options) Options {
+	if over.Model != "" {
+		o.Model = over.Model
+	}
+	if over.Temperature != nil {
+		o.Temperature = over.Temperature
+	}
+	if over.MaxTokens != 0 {
+		o.MaxTokens = over.MaxTokens
+	}
+	return o
+}
+
+// Validate reports parameters out of range.
+func (o Options) Validate() error {
+	if o.Temperature != nil && (*o.Temperature < 0 || *o.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %g", *o.Temperature)
+	}
+	if o.MaxTokens < 0 {
+		return fmt.Errorf("max tokens must not be negative, got %d", o.MaxTokens)
+	}
+	return nil
+}
+
+// Chat sends messages and returns the model's reply.
+func (c *Client) Chat(ctx context.Context, messages []Message) (string, error) {
+	return c.ChatWith(ctx, messages, Options{})
+}
+
+// ChatWith sends messages with the generation parameters opts and returns
+// the model's reply.
+func (c *Client) ChatWith(ctx context.Context, messages []Message, opts Options) (string, error) {
+	resp, err := c.send(ctx, messages, opts, false)
+	if err != nil {
+		return "", err
+	}
+	return reply(resp)
+}
+
+// reply reads the reply of a chat completion response that is not
+// streamed.
+func reply(resp *http.Response) (string, error) {
+	payload, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("chat completion: %w", err)
+	}
+	var out struct {
+		Choices []struct {
+			Message Message `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(payload, &out); err != nil {
+		return "", fmt.Errorf("chat completion: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return "", errors.New("chat completion returned no choices")
+	}
+	return strings.TrimSpace(out.Choices[0].Message.Content), nil
+}
+
+// ChatStream sends messages with the generation parameters opts and
+// returns the model's reply, calling onText with the reply so far each time
+// more of it arrives.
+func (c *Client) ChatStream(ctx context.Context, messages []Message, opts Options, onText func(string)) (string, error) {
+	resp, err := c.send(ctx, messages, opts, true)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		// Servers ignoring "stream" send the whole reply at once.
+		text, err := reply(resp)
+		if err == nil {
+			onText(text)
+		}
+		return text, err
+	}
+	defer resp.Body.Close()
+	var b strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// Server-sent events: "data: {chunk}" lines, ended by "data: [DONE]".
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta Message `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", fmt.Errorf("chat completion: %w", err)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		b.WriteString(chunk.Choices[0].Delta.Content)
+		onText(strings.TrimLeft(b.String(), " \n"))
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("chat completion: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// send posts a chat completion request, retrying on network errors, 429
+// and 5xx, and returns the response of the first attempt that succeeds.
+func (c *Client) send(ctx context.Context, messages []Message, opts Options, stream bool) (*http.Response, error) {
+	model := c.model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+	data, err := json.Marshal(struct {
+		Model       string    `json:"model"`
+		Messages    []Message `json:"messages"`
+		Stream      bool      `json:"stream"`
+		Temperature *float64  `json:"temperature,omitempty"`
+		MaxTokens   int       `json:"max_tokens,omitempty"`
+	}{model, messages, stream, opts.Temperature, opts.MaxTokens})
+	if err != nil {
+		return nil, err
+	}
+	url := c.baseURL + "/chat/completions"
+	var lastErr error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, retryDelay(attempt-1, lastErr)); err != nil {
+				return nil, err
+			}
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			_ = resp.Body.Close()
+			lastErr = &statusError{status: resp.Status, retryAfter: resp.Header.Get("Retry-After")}
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			payload, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("chat completion failed: %s: %s", resp.Status, strings.TrimSpace(string(payload)))
+		}
+		return resp, nil
+	}
+	return nil, fmt.Errorf("chat completion failed: %w", lastErr)
+}
+
+// Translate asks the model to translate text into language, given as a
+// name or an ISO 639-1 code.
+func (c *Client) Translate(ctx context.Context, text, language string) (string, error) {
+	return c.Chat(ctx, []Message{
+		{Role: RoleSystem, Content: "You translate text into " + language + ". Reply with the translation only, keeping the meaning, tone and formatting; leave code, names and text already in " + language + " unchanged."},
+		{Role: RoleUser, Content: text},
+	})
+}
+
+// Question asks the model for a search query that passage answers, as a
+// user looking for it would type, for generating evaluation queries.
+func (c *Client) Question(ctx context.Context, passage string) (string, error) {
+	return c.Chat(ctx, []Message{
+		{Role: RoleSystem, Content: "You write search queries. Given a passage, reply with one short question or query, in the passage's language, that a user looking for it would type and that it answers. Avoid copying its distinctive phrases; reply with the query only."},
+		{Role: RoleUser, Content: passage},
+	})
+}
+
+// Situate asks the model for a sentence or two situating chunk within
+// document, to be embedded with the chunk so that it is found by queries
+// naming what it leaves implicit.
+func (c *Client) Situate(ctx context.Context, document, chunk string) (string, error) {
+	return c.Chat(ctx, []Message{
+		{Role: RoleSystem, Content: "You situate passages within their documents to improve search retrieval. Given a document and a chunk of it, reply with a short, succinct context, in the document's language, that says what the document is and where the chunk fits in it, naming what the chunk refers to without saying it. Reply with the context only."},
+		{Role: RoleUser, Content: "<document>\n" + document + "\n</document>\n\n<chunk>\n" + chunk + "\n</chunk>"},
+	})
+}
+
+// Hypothesize asks the model for a short passage answering query, as a
+// document of the corpus might, to be embedded in place of the query
+// (hypothetical document embeddings): an answer is closer to the passages
+// holding the real one than a question is. It need not be correct.
+func (c *Client) Hypothesize(ctx context.Context, query string) (string, error) {
+	return c.Chat(ctx, []Message{
+		{Role: RoleSystem, Content: "You write passages for search retrieval. Given a question or search query, reply with a short passage, in the query's language, that answers it the way a document on the subject would, with the terms such a document would use. Do not hedge or mention the query; if unsure, write a plausible answer. Reply with the passage only."},
+		{Role: RoleUser, Content: query},
+	})
+}
+
+// listMarker matches the list markers a model may start lines with, such
+// as "-", "*", "1." or "2)".
+var listMarker = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s+`)
+
+// Decompose asks the model for the questions query asks, at most max, each
+// self-contained so that it can be searched alone. A query asking one thing
+// comes back as it is.
+func (c *Client) Decompose(ctx context.Context, query string, max int) ([]string, error) {
+	reply, err := c.Chat(ctx, []Message{
+		{Role: RoleSystem, Content: "You split search queries into the separate questions they ask, to search for each on its own. Reply with one question per line, at most " + strconv.Itoa(max) + ", in the query's language, each self-contained: repeat the subject instead of using pronouns. If the query asks one thing, reply with it unchanged. Reply with the questions only."},
+		{Role: RoleUser, Content: query},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, line := range strings.Split(reply, "\n") {
+		line = strings.TrimSpace(listMarker.ReplaceAllString(line, ""))
+		if line != "" && len(out) < max {
+			out = append(out, line)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("the model returned no questions")
+	}
+	return out, nil
+}
+
+// statusError is a response status worth retrying.
+type statusError struct {
+	status     string
+	retryAfter string
+}
+
+func (e *statusError) Error() string { return e.status }
+
+// sleep waits for d or until ctx is canceled.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryDelay is the wait before retrying after attempt failed with err:
+// the server's Retry-After if it sent one, else an exponential backoff
+// capped at 5s.
+func retryDelay(attempt int, err error) time.Duration {
+	var se *statusError
+	if errors.As(err, &se) {
+		if secs, err := strconv.Atoi(se.retryAfter); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	d := 200 * time.Millisecond << attempt
+	if d > 5*time.Second {
+		d = 5 * time.Second
+	}
+	return d
+}
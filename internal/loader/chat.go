@@ -0,0 +1,73 @@
+package loader
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"rag/internal/domain"
+)
+
+// message is one chat message from any export format.
+type message struct {
+	time   time.Time
+	sender string
+	text   string
+}
+
+// chatWindows groups chat messages into conversation windows, each of which
+// becomes one atomic document.
+type chatWindows struct {
+	gap time.Duration
+	max int
+}
+
+// documents splits the messages of a channel into windows: a window ends
+// after a silence longer than gap or when it holds max messages.
+func (w chatWindows) documents(channel string, msgs []message) []domain.Document {
+	sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].time.Before(msgs[j].time) })
+	var docs []domain.Document
+	start := 0
+	for i := 1; i <= len(msgs); i++ {
+		if i < len(msgs) && i-start < w.max && msgs[i].time.Sub(msgs[i-1].time) <= w.gap {
+			continue
+		}
+		if doc, ok := w.window(channel, msgs[start:i]); ok {
+			docs = append(docs, doc)
+		}
+		start = i
+	}
+	return docs
+}
+
+func (w chatWindows) window(channel string, msgs []message) (domain.Document, bool) {
+	var b strings.Builder
+	var senders []string
+	seen := make(map[string]bool)
+	for _, m := range msgs {
+		text := strings.TrimSpace(m.text)
+		if text == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(m.sender + ": " + text)
+		if !seen[m.sender] {
+			seen[m.sender] = true
+			senders = append(senders, m.sender)
+		}
+	}
+	if b.Len() == 0 {
+		return domain.Document{}, false
+	}
+	first := msgs[0].time
+	return domain.Document{
+		Content:     b.String(),
+		Title:       channel + " " + first.Format("2006-01-02 15:04"),
+		Time:        first,
+		Metadata:    map[string]string{"channel": channel, "from": strings.Join(senders, ", ")},
+		Transformed: true,
+		Atomic:      true,
+	}, true
+}
@@ -0,0 +1,261 @@
+package loader
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"rag/internal/domain"
+)
+
+// EPUB loads e-books chapter by chapter: each document of the book's
+// reading order becomes a document, its text extracted like an HTML page's.
+// The chapter's title, from the table of contents or else its first
+// heading, is the document's title and goes to the "chapter" metadata
+// field; the book's title goes to "title", its authors to "author" and its
+// language to "lang". Books encrypted with DRM cannot be read.
+type EPUB struct{}
+
+// epubPackage is the package document of a book: its metadata, files and
+// reading order.
+type epubPackage struct {
+	Titles    []string `xml:"metadata>title"`
+	Creators  []string `xml:"metadata>creator"`
+	Languages []string `xml:"metadata>language"`
+	Manifest  []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine struct {
+		// TOC is the ID of the EPUB 2 table of contents.
+		TOC   string `xml:"toc,attr"`
+		Items []struct {
+			IDRef  string `xml:"idref,attr"`
+			Linear string `xml:"linear,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+// Detect accepts ZIP archives.
+func (EPUB) Detect(_ string, data []byte) bool { return isZip(data) }
+
+// Load returns a document per chapter, in reading order.
+func (EPUB) Load(file string, data []byte) ([]domain.Document, error) {
+	archive, err := openZip(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	container, err := archive.part("META-INF/container.xml")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	var c struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := xml.Unmarshal(container, &c); err != nil || len(c.Rootfiles) == 0 {
+		return nil, fmt.Errorf("%s: no package document in META-INF/container.xml", file)
+	}
+	opfPath := c.Rootfiles[0].FullPath
+	opf, err := archive.part(opfPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	var pkg epubPackage
+	if err := xml.Unmarshal(opf, &pkg); err != nil {
+		return nil, fmt.Errorf("%s: %s: %w", file, opfPath, err)
+	}
+	dir := path.Dir(opfPath)
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	types := make(map[string]string, len(pkg.Manifest))
+	// Without a table of contents, chapters are named by their headings.
+	chapters := make(map[string]string)
+	for _, item := range pkg.Manifest {
+		hrefs[item.ID] = epubJoin(dir, item.Href)
+		types[item.ID] = item.MediaType
+		if strings.Contains(" "+item.Properties+" ", " nav ") {
+			if nav, err := archive.part(hrefs[item.ID]); err == nil {
+				epubNav(nav, path.Dir(hrefs[item.ID]), chapters)
+			}
+		}
+	}
+	if len(chapters) == 0 && pkg.Spine.TOC != "" {
+		if ncx, err := archive.part(hrefs[pkg.Spine.TOC]); err == nil {
+			epubNCX(ncx, path.Dir(hrefs[pkg.Spine.TOC]), chapters)
+		}
+	}
+	encrypted := epubEncrypted(archive)
+
+	meta := make(map[string]string)
+	if len(pkg.Titles) > 0 {
+		meta["title"] = strings.TrimSpace(pkg.Titles[0])
+	}
+	if len(pkg.Creators) > 0 {
+		meta["author"] = strings.Join(pkg.Creators, ", ")
+	}
+	if len(pkg.Languages) > 0 {
+		lang, _, _ := strings.Cut(strings.TrimSpace(pkg.Languages[0]), "-")
+		meta["lang"] = strings.ToLower(lang)
+	}
+	var docs []domain.Document
+	// Books split long chapters over several files, of which only the
+	// first is in the table of contents.
+	previous := ""
+	for _, ref := range pkg.Spine.Items {
+		name, ok := hrefs[ref.IDRef]
+		// Non-linear items, such as notes shown in pop-ups, are outside the
+		// reading order; images and SVG pages have no text.
+		if !ok || ref.Linear == "no" || !strings.Contains(types[ref.IDRef], "html") {
+			continue
+		}
+		if encrypted[name] {
+			return nil, fmt.Errorf("%s: %s is encrypted (DRM)", file, name)
+		}
+		chapter, err := archive.part(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		page := parseHTML(string(chapter))
+		blocks, heading := page.text()
+		if len(blocks) == 0 {
+			continue
+		}
+		d := domain.Document{Content: strings.Join(blocks, "\n\n"), Metadata: make(map[string]string, len(meta)+1), Transformed: true}
+		for k, v := range meta {
+			d.Metadata[k] = v
+		}
+		if d.Title = cmp.Or(chapters[name], heading, previous); d.Title != "" {
+			d.Metadata["chapter"] = d.Title
+		}
+		previous = d.Title
+		docs = append(docs, d)
+	}
+	return docs, nil
+}
+
+// epubJoin resolves a URL-encoded href, without its fragment, against the
+// directory of the file it is in.
+func epubJoin(dir, href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if u, err := url.PathUnescape(href); err == nil {
+		href = u
+	}
+	return path.Join(dir, href)
+}
+
+// epubNav adds the chapters of an EPUB 3 navigation document, in dir, to
+// chapters: the text of the links of its table of contents by the file
+// they lead to. Each file is named by its first link.
+func epubNav(data []byte, dir string, chapters map[string]string) {
+	dec := epubDecoder(data)
+	var inTOC, inLink bool
+	var href string
+	var label strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Local == "nav" && strings.Contains(" "+xmlAttr(t, "type")+" ", " toc "):
+				inTOC = true
+			case inTOC && t.Name.Local == "a":
+				inLink, href = true, xmlAttr(t, "href")
+				label.Reset()
+			}
+		case xml.EndElement:
+			switch {
+			case t.Name.Local == "nav":
+				inTOC = false
+			case inLink && t.Name.Local == "a":
+				inLink = false
+				epubChapter(chapters, epubJoin(dir, href), label.String())
+			}
+		case xml.CharData:
+			if inLink {
+				label.Write(t)
+			}
+		}
+	}
+}
+
+// epubNCX adds the chapters of an EPUB 2 table of contents, in dir, to
+// chapters, like epubNav.
+func epubNCX(data []byte, dir string, chapters map[string]string) {
+	dec := epubDecoder(data)
+	var inLabel bool
+	var label strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "navLabel":
+				inLabel = true
+				label.Reset()
+			case "content":
+				// A navigation point's label comes before its target.
+				epubChapter(chapters, epubJoin(dir, xmlAttr(t, "src")), label.String())
+			}
+		case xml.EndElement:
+			if t.Name.Local == "navLabel" {
+				inLabel = false
+			}
+		case xml.CharData:
+			if inLabel {
+				label.Write(t)
+			}
+		}
+	}
+}
+
+// epubChapter names the chapter in file, unless it is named already.
+func epubChapter(chapters map[string]string, file, label string) {
+	label = strings.TrimSpace(htmlSpaceRe.ReplaceAllString(label, " "))
+	if label != "" && chapters[file] == "" {
+		chapters[file] = label
+	}
+}
+
+// epubEncrypted returns the files listed in META-INF/encryption.xml. Fonts
+// are often among them, obfuscated against copying; chapters only under
+// DRM.
+func epubEncrypted(archive *zipArchive) map[string]bool {
+	encrypted := make(map[string]bool)
+	data, err := archive.part("META-INF/encryption.xml")
+	if err != nil {
+		return encrypted
+	}
+	dec := epubDecoder(data)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return encrypted
+		}
+		if t, ok := tok.(xml.StartElement); ok && t.Name.Local == "CipherReference" {
+			encrypted[epubJoin("", xmlAttr(t, "URI"))] = true
+		}
+	}
+}
+
+// epubDecoder returns a decoder for the XHTML and XML files of a book,
+// tolerating the HTML entities and unclosed elements of sloppy ones.
+func epubDecoder(data []byte) *xml.Decoder {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	return dec
+}
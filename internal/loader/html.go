@@ -0,0 +1,387 @@
+package loader
+
+import (
+	"cmp"
+	"html"
+	"regexp"
+	"strings"
+
+	"rag/internal/domain"
+)
+
+// HTML loads saved web pages and exported wikis as their main text, the way
+// reader modes extract it: scripts, styles and markup are dropped, and so is
+// the boilerplate around the content, namely navigation, headers, footers,
+// sidebars and form controls, recognized by their element, role, class or id, hidden
+// elements, and blocks made mostly of links. When the page marks its content
+// with <main> or <article>, only that is kept. The <title>, or else the
+// first heading, goes to the "title" metadata field, and the page's language
+// to "lang".
+type HTML struct{}
+
+var (
+	// htmlBoilerplateRe matches the classes and IDs of boilerplate
+	// elements, as whole words of names like "site-nav" or "sidebar_left".
+	htmlBoilerplateRe = regexp.MustCompile(`(?i)(?:^|[-_\s])(?:nav|navbar|navigation|menu|sidebar|footer|breadcrumbs?|cookies?|banner|advert|ads|social|share|sharing|comments?|related|popup|modal|skip-link|toc)(?:$|[-_\s])`)
+	htmlSpaceRe       = regexp.MustCompile(`\s+`)
+)
+
+// htmlDropped are the elements dropped with their content wherever they are.
+var htmlDropped = map[string]bool{
+	"nav": true, "aside": true, "button": true, "iframe": true, "select": true,
+	"dialog": true, "menu": true, "noscript": true, "template": true, "svg": true, "canvas": true,
+	"head": true, "object": true, "video": true, "audio": true,
+}
+
+// htmlOutside are the elements dropped outside <main> and <article>, where
+// they hold the site's rather than the content's header and footer.
+var htmlOutside = map[string]bool{"header": true, "footer": true}
+
+// htmlBoilerplateRoles are the ARIA roles of boilerplate landmarks.
+var htmlBoilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true,
+	"search": true, "menu": true, "menubar": true, "dialog": true,
+}
+
+// htmlBlocks are the elements that start a block of text of their own.
+var htmlBlocks = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true, "header": true,
+	"footer": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true, "table": true,
+	"tr": true, "blockquote": true, "pre": true, "figure": true, "figcaption": true,
+	"hr": true, "br": true, "details": true, "summary": true, "address": true,
+}
+
+// htmlVoid are the elements without content or end tag.
+var htmlVoid = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// htmlRaw are the elements whose content is not markup.
+var htmlRaw = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+// Detect accepts any HTML file.
+func (HTML) Detect(string, []byte) bool { return true }
+
+// Load returns the page's main text as a single document.
+func (HTML) Load(_ string, data []byte) ([]domain.Document, error) {
+	page := parseHTML(string(data))
+	meta := make(map[string]string)
+	blocks, heading := page.text()
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	if title := cmp.Or(page.title, heading); title != "" {
+		meta["title"] = title
+	}
+	if page.lang != "" {
+		meta["lang"] = page.lang
+	}
+	return []domain.Document{{Content: strings.Join(blocks, "\n\n"), Metadata: meta, Transformed: true}}, nil
+}
+
+// htmlElement is an open element of a page being parsed.
+type htmlElement struct {
+	name string
+	// drop marks boilerplate, main the page's content, link an <a>.
+	drop, main, link, pre, heading bool
+}
+
+// htmlBlock is a block of text of a page.
+type htmlBlock struct {
+	text string
+	// linkChars is how much of the text is link text.
+	linkChars     int
+	main, heading bool
+}
+
+// htmlPage is the text of a page, by block.
+type htmlPage struct {
+	blocks      []htmlBlock
+	title, lang string
+	// hasMain is set when the page marks its content.
+	hasMain bool
+}
+
+// text returns the blocks of the page's main text and the first heading
+// among them.
+func (p htmlPage) text() (blocks []string, heading string) {
+	for _, b := range p.blocks {
+		if p.hasMain && !b.main {
+			continue
+		}
+		// Blocks made mostly of links are menus and link lists.
+		if !b.heading && b.linkChars*2 > len(b.text) {
+			continue
+		}
+		if heading == "" && b.heading {
+			heading = b.text
+		}
+		blocks = append(blocks, b.text)
+	}
+	return blocks, heading
+}
+
+// parseHTML extracts the blocks of text of src, tolerating the unclosed and
+// misnested elements of real pages.
+func parseHTML(src string) htmlPage {
+	var page htmlPage
+	var stack []htmlElement
+	var cur strings.Builder
+	var curLinks int
+	var curMain, curHeading bool
+	flush := func() {
+		text := strings.TrimSpace(cur.String())
+		if text != "" {
+			page.blocks = append(page.blocks, htmlBlock{text: text, linkChars: curLinks, main: curMain, heading: curHeading})
+			page.hasMain = page.hasMain || curMain
+		}
+		cur.Reset()
+		curLinks, curMain, curHeading = 0, false, false
+	}
+	state := func() (e htmlElement) {
+		for _, s := range stack {
+			e.drop = e.drop || s.drop
+			e.main = e.main || s.main
+			e.link = e.link || s.link
+			e.pre = e.pre || s.pre
+			e.heading = e.heading || s.heading
+		}
+		return e
+	}
+	text := func(raw string) {
+		st := state()
+		if st.drop {
+			return
+		}
+		t := html.UnescapeString(raw)
+		if !st.pre {
+			t = htmlSpaceRe.ReplaceAllString(t, " ")
+			if cur.Len() == 0 {
+				t = strings.TrimLeft(t, " ")
+			}
+		}
+		if t == "" {
+			return
+		}
+		cur.WriteString(t)
+		if st.link {
+			curLinks += len(strings.TrimSpace(t))
+		}
+		curMain = curMain || st.main
+		curHeading = curHeading || st.heading
+	}
+scan:
+	for i := 0; i < len(src); {
+		lt := strings.IndexByte(src[i:], '<')
+		if lt < 0 {
+			text(src[i:])
+			break scan
+		}
+		text(src[i : i+lt])
+		i += lt
+		rest := src[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				break scan
+			}
+			i += end + len("-->")
+			continue
+		case strings.HasPrefix(rest, "<!"), strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				break scan
+			}
+			i += end + 1
+			continue
+		}
+		closing := strings.HasPrefix(rest, "</")
+		start := 1
+		if closing {
+			start = 2
+		}
+		if start >= len(rest) || !isASCIILetter(rest[start]) {
+			text("<")
+			i++
+			continue
+		}
+		name, attrs, selfClosing, n := parseTag(rest[start:])
+		i += start + n
+		if closing {
+			for j := len(stack) - 1; j >= 0; j-- {
+				if stack[j].name == name {
+					stack = stack[:j]
+					break
+				}
+			}
+			if htmlBlocks[name] {
+				flush()
+			}
+			continue
+		}
+		if htmlRaw[name] {
+			end := indexFold(src[i:], "</"+name)
+			body := src[i:]
+			if end >= 0 {
+				body = src[i : i+end]
+				i += end
+				if gt := strings.IndexByte(src[i:], '>'); gt >= 0 {
+					i += gt + 1
+				} else {
+					i = len(src)
+				}
+			} else {
+				i = len(src)
+			}
+			if name == "title" && page.title == "" {
+				page.title = strings.TrimSpace(htmlSpaceRe.ReplaceAllString(html.UnescapeString(body), " "))
+			}
+			continue
+		}
+		if name == "body" {
+			// The head ends where the body starts, closed or not.
+			for j, e := range stack {
+				if e.name == "head" {
+					stack = stack[:j]
+					break
+				}
+			}
+		}
+		if name == "html" && attrs["lang"] != "" {
+			lang, _, _ := strings.Cut(attrs["lang"], "-")
+			page.lang = strings.ToLower(lang)
+		}
+		if htmlBlocks[name] {
+			flush()
+		}
+		if name == "img" {
+			// Images say what they show in their alt text.
+			if alt := strings.TrimSpace(attrs["alt"]); alt != "" {
+				text(" " + alt + " ")
+			}
+		}
+		if htmlVoid[name] || selfClosing {
+			continue
+		}
+		st := state()
+		e := htmlElement{
+			name:    name,
+			main:    name == "main" || name == "article" || attrs["role"] == "main",
+			link:    name == "a",
+			pre:     name == "pre",
+			heading: len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6',
+		}
+		e.drop = htmlDropped[name] || (htmlOutside[name] && !st.main) ||
+			htmlBoilerplateRoles[attrs["role"]] || htmlBoilerplateRe.MatchString(attrs["class"]) ||
+			htmlBoilerplateRe.MatchString(attrs["id"]) || isHidden(attrs)
+		stack = append(stack, e)
+		switch name {
+		case "li":
+			text("- ")
+		case "td", "th":
+			if cur.Len() > 0 {
+				text(" | ")
+			}
+		}
+	}
+	flush()
+	return page
+}
+
+// parseTag parses the tag at the start of s, after its "<" or "</", up to
+// and including its ">". It returns the lowercased name, the attributes by
+// lowercased name, whether the tag closes itself, and the bytes read.
+func parseTag(s string) (name string, attrs map[string]string, selfClosing bool, n int) {
+	i := 0
+	for i < len(s) && !isTagSpace(s[i]) && s[i] != '>' && s[i] != '/' {
+		i++
+	}
+	name = strings.ToLower(s[:i])
+	attrs = make(map[string]string)
+	for i < len(s) {
+		for i < len(s) && isTagSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		switch s[i] {
+		case '>':
+			return name, attrs, selfClosing, i + 1
+		case '/':
+			selfClosing = true
+			i++
+			continue
+		}
+		selfClosing = false
+		k := i
+		for i < len(s) && !isTagSpace(s[i]) && s[i] != '>' && s[i] != '=' && s[i] != '/' {
+			i++
+		}
+		key := strings.ToLower(s[k:i])
+		for i < len(s) && isTagSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) || s[i] != '=' {
+			attrs[key] = ""
+			continue
+		}
+		i++
+		for i < len(s) && isTagSpace(s[i]) {
+			i++
+		}
+		var value string
+		if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+			q := s[i]
+			end := strings.IndexByte(s[i+1:], q)
+			if end < 0 {
+				return name, attrs, false, len(s)
+			}
+			value = s[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			v := i
+			for i < len(s) && !isTagSpace(s[i]) && s[i] != '>' {
+				i++
+			}
+			value = s[v:i]
+		}
+		attrs[key] = html.UnescapeString(value)
+	}
+	return name, attrs, selfClosing, len(s)
+}
+
+// isHidden reports whether attributes hide an element from readers.
+func isHidden(attrs map[string]string) bool {
+	if _, ok := attrs["hidden"]; ok {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(attrs["style"]), " ", "")
+	return attrs["aria-hidden"] == "true" || strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// indexFold is strings.Index ignoring ASCII case.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		j := strings.IndexByte(s[i:], substr[0])
+		if j < 0 || i+j+len(substr) > len(s) {
+			return -1
+		}
+		i += j
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isTagSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
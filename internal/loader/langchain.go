@@ -0,0 +1,52 @@
+package loader
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"rag/internal/domain"
+	"rag/internal/langchain"
+)
+
+// LangChain loads JSON Lines of LangChain Documents (or LlamaIndex nodes),
+// as written by `rag export` or Python RAG stacks, one document per line.
+// String metadata becomes filterable metadata; the source path names the
+// document.
+type LangChain struct{}
+
+// Detect accepts files whose first line is a document with content.
+func (LangChain) Detect(_ string, data []byte) bool {
+	return langchain.Detect(data)
+}
+
+// Load returns one document per line with content.
+func (LangChain) Load(_ string, data []byte) ([]domain.Document, error) {
+	lines, err := langchain.Read(data)
+	if err != nil {
+		return nil, err
+	}
+	var docs []domain.Document
+	for i, l := range lines {
+		content := l.Content()
+		if content == "" {
+			continue
+		}
+		// Bookkeeping of the exporting tool is not worth filtering on.
+		meta := langchain.StringMetadata(l.Metadata, langchain.KeyStartIndex, langchain.KeyChunkIndex, langchain.KeyDocumentID, langchain.KeyDate, langchain.KeyKeywords)
+		title := fmt.Sprintf("line %d", i+1)
+		if source := meta[langchain.KeySource]; source != "" {
+			title = filepath.Base(source)
+		}
+		doc := domain.Document{
+			Content:     content,
+			Title:       title,
+			Metadata:    meta,
+			Transformed: true,
+		}
+		if t, ok := langchain.Date(l.Metadata); ok {
+			doc.Time = t
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
@@ -0,0 +1,121 @@
+package loader
+
+import (
+	"regexp"
+	"strings"
+
+	"rag/internal/domain"
+)
+
+// LaTeX loads .tex sources as plain text: comments, display math, floats and
+// references are dropped, inline math is reduced to its symbols, and
+// formatting commands are replaced by their arguments. Each section becomes
+// a document with its title in the "section" metadata field.
+type LaTeX struct{}
+
+var (
+	texCommentRe  = regexp.MustCompile(`(?m)(^|[^\\])%.*$`)
+	texEscapeRe   = regexp.MustCompile(`\\([%&$#_{}])`)
+	texBodyRe     = regexp.MustCompile(`(?s)\\begin\{document\}(.*?)(\\end\{document\}|$)`)
+	texDisplayRes = []*regexp.Regexp{
+		regexp.MustCompile(`(?s)\$\$.*?\$\$`),
+		regexp.MustCompile(`(?s)\\\[.*?\\\]`),
+	}
+	texInlineRe   = regexp.MustCompile(`(?s)\$([^$]+)\$|\\\((.*?)\\\)`)
+	texSectionRe  = regexp.MustCompile(`\\(?:chapter|section|subsection|subsubsection)\*?(?:\[[^\]]*\])?\{((?:[^{}]|\{[^{}]*\})*)\}|\\begin\{abstract\}`)
+	texDropRe     = regexp.MustCompile(`\\(?:cite[a-z]*|ref|eqref|autoref|cref|label|includegraphics|bibliography|bibliographystyle|input|include|vspace|hspace|url|footnotemark)\*?(?:\[[^\]]*\])*(?:\{[^{}]*\})?`)
+	texEnvRe      = regexp.MustCompile(`\\(?:begin|end)\{[^{}]*\}(?:\[[^\]]*\])?`)
+	texArgCmdRe   = regexp.MustCompile(`\\[a-zA-Z]+\*?(?:\[[^\]]*\])?\{([^{}]*)\}`)
+	texCommandRe  = regexp.MustCompile(`\\[a-zA-Z]+\*?|\\\\`)
+	texBraceRe    = regexp.MustCompile(`[{}^_]`)
+	texSpaceRe    = regexp.MustCompile(`[ \t]+`)
+	texPunctRe    = regexp.MustCompile(` +([.,;:)])`)
+	texLineRe     = regexp.MustCompile(` *\n *`)
+	texMathCmdRe  = regexp.MustCompile(`\\([a-zA-Z]+)`)
+	texBlankRe    = regexp.MustCompile(`\n\s*\n\s*`)
+	texDropEnvs   = []string{"equation", "align", "gather", "multline", "eqnarray", "displaymath", "math", "figure", "table", "tabular"}
+	texDropEnvRes = make([]*regexp.Regexp, len(texDropEnvs))
+	// texEscapes maps escaped characters to private-use placeholders so that
+	// cleaning does not mistake them for syntax.
+	texEscapes = strings.NewReplacer("%", "", "&", "", "$", "", "#", "", "_", "", "{", "", "}", "")
+	texRestore = strings.NewReplacer("", "%", "", "&", "", "$", "", "#", "", "_", "", "{", "", "}")
+)
+
+func init() {
+	for i, env := range texDropEnvs {
+		texDropEnvRes[i] = regexp.MustCompile(`(?s)\\begin\{` + env + `\*?\}.*?\\end\{` + env + `\*?\}`)
+	}
+}
+
+// Detect accepts any .tex file.
+func (LaTeX) Detect(string, []byte) bool { return true }
+
+// Load returns one document per section, plus one for text before the first.
+func (LaTeX) Load(_ string, data []byte) ([]domain.Document, error) {
+	src := texEscapeRe.ReplaceAllStringFunc(string(data), func(m string) string {
+		return texEscapes.Replace(m[1:])
+	})
+	src = texCommentRe.ReplaceAllString(src, "$1")
+	if m := texBodyRe.FindStringSubmatch(src); m != nil {
+		src = m[1]
+	}
+	for _, re := range texDropEnvRes {
+		src = re.ReplaceAllString(src, " ")
+	}
+	for _, re := range texDisplayRes {
+		src = re.ReplaceAllString(src, " ")
+	}
+	src = texInlineRe.ReplaceAllStringFunc(src, func(m string) string {
+		sub := texInlineRe.FindStringSubmatch(m)
+		// Keep operator names such as \log as words.
+		return latexToText(texMathCmdRe.ReplaceAllString(sub[1]+sub[2], " $1 "))
+	})
+
+	var docs []domain.Document
+	add := func(title, body string) {
+		text := latexToText(body)
+		if text == "" {
+			return
+		}
+		doc := domain.Document{Content: text, Title: title, Transformed: true}
+		if title != "" {
+			doc.Metadata = map[string]string{"section": title}
+		}
+		docs = append(docs, doc)
+	}
+	title, start := "", 0
+	for _, loc := range texSectionRe.FindAllStringSubmatchIndex(src, -1) {
+		add(title, src[start:loc[0]])
+		title = "Abstract"
+		if loc[2] >= 0 {
+			title = latexToText(src[loc[2]:loc[3]])
+		}
+		start = loc[1]
+	}
+	add(title, src[start:])
+	return docs, nil
+}
+
+// latexToText strips commands from s, keeping the arguments of formatting
+// commands such as \emph{...}.
+func latexToText(s string) string {
+	s = texDropRe.ReplaceAllString(s, "")
+	s = texEnvRe.ReplaceAllString(s, "")
+	for {
+		next := texArgCmdRe.ReplaceAllString(s, "$1")
+		if next == s {
+			break
+		}
+		s = next
+	}
+	s = strings.ReplaceAll(s, `\\`, "\n")
+	s = texCommandRe.ReplaceAllString(s, "")
+	s = texBraceRe.ReplaceAllString(s, "")
+	s = strings.ReplaceAll(s, "~", " ")
+	s = texRestore.Replace(s)
+	s = texSpaceRe.ReplaceAllString(s, " ")
+	s = texPunctRe.ReplaceAllString(s, "$1")
+	s = texLineRe.ReplaceAllString(s, "\n")
+	s = texBlankRe.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,187 @@
+// Package loader turns source files into documents. Each file format is
+// handled by a Loader registered for its extension in a Registry.
+package loader
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"rag/internal/domain"
+)
+
+// Loader converts the contents of one file into documents.
+type Loader interface {
+	// Detect reports whether the loader understands the file; loaders
+	// sharing an extension are tried in turn.
+	Detect(path string, data []byte) bool
+	Load(path string, data []byte) ([]domain.Document, error)
+}
+
+// Config tunes the built-in loaders.
+type Config struct {
+	// ChatWindow is the silence after which a chat message starts a new
+	// conversation window (default 30 minutes).
+	ChatWindow time.Duration
+	// ChatWindowMessages caps the messages per window (default 30).
+	ChatWindowMessages int
+}
+
+// Registry maps file extensions to loaders, and path patterns to the
+// chunkers that split their documents.
+type Registry struct {
+	byExt    map[string][]Loader
+	chunkers []chunkerRule
+}
+
+// chunkerRule assigns a chunker to files matching a pattern.
+type chunkerRule struct {
+	pattern string
+	chunker domain.Chunker
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{byExt: make(map[string][]Loader)}
+}
+
+// Default returns a registry with all built-in loaders.
+func Default(cfg Config) *Registry {
+	if cfg.ChatWindow <= 0 {
+		cfg.ChatWindow = 30 * time.Minute
+	}
+	if cfg.ChatWindowMessages <= 0 {
+		cfg.ChatWindowMessages = 30
+	}
+	chat := chatWindows{gap: cfg.ChatWindow, max: cfg.ChatWindowMessages}
+	r := NewRegistry()
+	r.Register(".txt", Text{})
+	r.Register(".md", Markdown{})
+	r.Register(".txt", WhatsApp{windows: chat})
+	r.Register(".json", Slack{windows: chat})
+	r.Register(".json", Telegram{windows: chat})
+	r.Register(".ipynb", Notebook{})
+	r.Register(".tex", LaTeX{})
+	for _, ext := range []string{".html", ".htm", ".xhtml"} {
+		r.Register(ext, HTML{})
+	}
+	r.Register(".docx", DOCX{})
+	r.Register(".odt", ODT{})
+	r.Register(".epub", EPUB{})
+	r.Register(".jsonl", LangChain{})
+	for ext, language := range codeLanguages {
+		r.Register(ext, Code{Language: language})
+	}
+	return r
+}
+
+// Register adds l for files with extension ext (including the dot). Loaders
+// registered later for the same extension are tried first, so specific
+// formats can be layered over generic ones.
+func (r *Registry) Register(ext string, l Loader) {
+	ext = strings.ToLower(ext)
+	r.byExt[ext] = append([]Loader{l}, r.byExt[ext]...)
+}
+
+// SetChunker makes documents of files matching pattern split with c.
+// Patterns use path.Match syntax with / as separator and match the end
+// of the path, so "*.go" matches Go files anywhere and "docs/*.md" the
+// Markdown files in any docs directory. Rules set earlier take precedence.
+func (r *Registry) SetChunker(pattern string, c domain.Chunker) {
+	r.chunkers = append(r.chunkers, chunkerRule{pattern: pattern, chunker: c})
+}
+
+// Chunker returns the chunker set for file, or nil when no rule matches.
+func (r *Registry) Chunker(file string) domain.Chunker {
+	elems := strings.Split(filepath.ToSlash(file), "/")
+	for _, rule := range r.chunkers {
+		n := strings.Count(rule.pattern, "/") + 1
+		if n > len(elems) {
+			continue
+		}
+		if ok, _ := path.Match(rule.pattern, strings.Join(elems[len(elems)-n:], "/")); ok {
+			return rule.chunker
+		}
+	}
+	return nil
+}
+
+// Extensions lists the registered extensions in sorted order.
+func (r *Registry) Extensions() []string {
+	exts := make([]string, 0, len(r.byExt))
+	for ext := range r.byExt {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// Load reads path and converts it with the first loader that detects its
+// format. ok is false when no loader handles the file. Documents get IDs
+// derived from the path, numbered when a file yields several.
+func (r *Registry) Load(path string) (docs []domain.Document, ok bool, err error) {
+	return r.LoadAs(path, filepath.Ext(path))
+}
+
+// LoadAs is Load with the loaders registered for extension ext instead of
+// those of path's own extension.
+func (r *Registry) LoadAs(path, ext string) (docs []domain.Document, ok bool, err error) {
+	if len(r.byExt[strings.ToLower(ext)]) == 0 {
+		return nil, false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	return r.decode(path, ext, data)
+}
+
+// decode converts data read from path with the loaders of ext.
+func (r *Registry) decode(path, ext string, data []byte) ([]domain.Document, bool, error) {
+	for _, l := range r.byExt[strings.ToLower(ext)] {
+		if !l.Detect(path, data) {
+			continue
+		}
+		docs, err := l.Load(path, data)
+		if err != nil {
+			return nil, false, err
+		}
+		for i := range docs {
+			docs[i].Path = path
+			if len(docs) == 1 {
+				docs[i].ID = hashString(path)
+			} else {
+				docs[i].ID = hashString(path + "#" + strconv.Itoa(i))
+			}
+		}
+		return docs, true, nil
+	}
+	return nil, false, nil
+}
+
+// Expand resolves a path argument into file paths: a leading ~ is the home
+// directory and glob patterns are matched here, since shells on Windows
+// expand neither. A pattern matching nothing is returned as is.
+func Expand(pattern string) []string {
+	if pattern == "~" || strings.HasPrefix(pattern, "~/") || strings.HasPrefix(pattern, `~`+string(filepath.Separator)) {
+		if home, err := os.UserHomeDir(); err == nil {
+			pattern = filepath.Join(home, pattern[1:])
+		}
+	}
+	matches, _ := filepath.Glob(pattern)
+	if matches == nil {
+		return []string{pattern}
+	}
+	return matches
+}
+
+func hashString(s string) string {
+	h := sha1.Sum([]byte(s))
+	return hex.EncodeToString(h[:8])
+}
@@ -0,0 +1,159 @@
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"rag/internal/docdate"
+	"rag/internal/domain"
+)
+
+// Markdown loads Markdown notes, reading YAML front matter (title, tags,
+// aliases, date) into document metadata and resolving Obsidian wiki-links
+// ([[Note|alias]]) to their display text. Inline #tags count as tags.
+type Markdown struct{}
+
+var (
+	frontMatterRe = regexp.MustCompile(`(?s)\A---\r?\n(.*?)\r?\n---[ \t]*(?:\r?\n|\z)`)
+	wikiLinkRe    = regexp.MustCompile(`(!?)\[\[([^\]|#]*)(?:#([^\]|]*))?(?:\|([^\]]*))?\]\]`)
+	inlineTagRe   = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_/-]*[\p{L}_/-][\p{L}\p{N}_/-]*)`)
+)
+
+// Detect accepts any Markdown file.
+func (Markdown) Detect(string, []byte) bool { return true }
+
+// Load returns the note as a single document without its front matter.
+func (Markdown) Load(path string, data []byte) ([]domain.Document, error) {
+	doc := domain.Document{Content: string(data)}
+	var fm map[string]any
+	if loc := frontMatterRe.FindSubmatchIndex(data); loc != nil {
+		if err := yaml.Unmarshal(data[loc[2]:loc[3]], &fm); err != nil {
+			return nil, fmt.Errorf("front matter: %w", err)
+		}
+		doc.Content = string(data[loc[1]:])
+		doc.Offset = loc[1]
+		doc.LineOffset = bytes.Count(data[:loc[1]], []byte("\n"))
+	}
+	meta := make(map[string]string)
+	tags := yamlStrings(fm["tags"])
+	tags = append(tags, yamlStrings(fm["tag"])...)
+	for _, m := range inlineTagRe.FindAllStringSubmatch(doc.Content, -1) {
+		tags = append(tags, m[1])
+	}
+	if tags = uniqueTags(tags); len(tags) > 0 {
+		meta["tags"] = strings.Join(tags, ", ")
+	}
+	aliases := append(yamlStrings(fm["aliases"]), yamlStrings(fm["alias"])...)
+	if len(aliases) > 0 {
+		meta["aliases"] = strings.Join(aliases, ", ")
+	}
+	if title := yamlStrings(fm["title"]); len(title) > 0 {
+		doc.Title = title[0]
+		meta["title"] = title[0]
+	}
+	if len(meta) > 0 {
+		doc.Metadata = meta
+	}
+	doc.Time = frontMatterDate(fm)
+	if doc.Time.IsZero() {
+		doc.Time = docdate.Detect(path, doc.Content)
+	}
+	for _, m := range wikiLinkRe.FindAllStringSubmatch(doc.Content, -1) {
+		if m[1] == "" && m[2] != "" {
+			doc.Links = append(doc.Links, m[2])
+		}
+	}
+	if linked := resolveWikiLinks(doc.Content); linked != doc.Content {
+		doc.Content = linked
+		doc.Transformed = true
+	}
+	return []domain.Document{doc}, nil
+}
+
+// resolveWikiLinks replaces wiki-links by the text Obsidian shows for them
+// and drops embeds (![[image.png]]).
+func resolveWikiLinks(s string) string {
+	return wikiLinkRe.ReplaceAllStringFunc(s, func(m string) string {
+		sub := wikiLinkRe.FindStringSubmatch(m)
+		switch {
+		case sub[1] == "!":
+			return ""
+		case sub[4] != "":
+			return sub[4]
+		case sub[3] != "" && sub[2] != "":
+			return sub[2] + " " + sub[3]
+		case sub[3] != "":
+			return sub[3]
+		default:
+			return filepath.Base(sub[2])
+		}
+	})
+}
+
+// yamlStrings reads a front matter value that is a string, a list, or a
+// comma- or space-separated list of tags.
+func yamlStrings(v any) []string {
+	switch v := v.(type) {
+	case string:
+		if strings.Contains(v, ",") {
+			var out []string
+			for _, p := range strings.Split(v, ",") {
+				if p = strings.TrimSpace(p); p != "" {
+					out = append(out, p)
+				}
+			}
+			return out
+		}
+		if v = strings.TrimSpace(v); v != "" {
+			return []string{v}
+		}
+	case []any:
+		var out []string
+		for _, e := range v {
+			if e != nil {
+				out = append(out, strings.TrimSpace(fmt.Sprint(e)))
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// uniqueTags strips leading '#' and removes duplicates, keeping order.
+func uniqueTags(tags []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, t := range tags {
+		for _, f := range strings.Fields(t) {
+			f = strings.TrimPrefix(f, "#")
+			if f == "" || seen[strings.ToLower(f)] {
+				continue
+			}
+			seen[strings.ToLower(f)] = true
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// frontMatterDate reads the date or created field, which YAML may already
+// have decoded as a timestamp.
+func frontMatterDate(fm map[string]any) time.Time {
+	for _, key := range []string{"date", "created"} {
+		switch v := fm[key].(type) {
+		case time.Time:
+			return v
+		case string:
+			if t, ok := docdate.Parse(v); ok {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,86 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"rag/internal/domain"
+)
+
+// Notebook loads Jupyter notebooks, indexing each markdown and code cell as a
+// separate document with its cell type in the "cell" metadata field. Code
+// cells are kept whole; outputs are not indexed.
+type Notebook struct{}
+
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+	} `json:"metadata"`
+}
+
+type notebookCell struct {
+	CellType string          `json:"cell_type"`
+	Source   json.RawMessage `json:"source"`
+}
+
+// Detect accepts JSON objects with a cell list.
+func (Notebook) Detect(_ string, data []byte) bool {
+	var nb notebook
+	return json.Unmarshal(data, &nb) == nil && nb.Cells != nil
+}
+
+// Load returns one document per non-empty markdown or code cell.
+func (Notebook) Load(_ string, data []byte) ([]domain.Document, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, err
+	}
+	language := nb.Metadata.LanguageInfo.Name
+	if language == "" {
+		language = nb.Metadata.Kernelspec.Language
+	}
+	var docs []domain.Document
+	for i, c := range nb.Cells {
+		if c.CellType != "markdown" && c.CellType != "code" {
+			continue
+		}
+		source := strings.TrimSpace(cellSource(c.Source))
+		if source == "" {
+			continue
+		}
+		doc := domain.Document{
+			Content:     source,
+			Title:       fmt.Sprintf("cell %d (%s)", i+1, c.CellType),
+			Metadata:    map[string]string{"cell": c.CellType},
+			Transformed: true,
+		}
+		if c.CellType == "code" {
+			doc.Atomic = true
+			if language != "" {
+				doc.Metadata["language"] = language
+			}
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// cellSource joins a cell source, stored as a string or a list of lines.
+func cellSource(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var lines []string
+	if json.Unmarshal(raw, &lines) == nil {
+		return strings.Join(lines, "")
+	}
+	return ""
+}
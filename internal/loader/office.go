@@ -0,0 +1,436 @@
+package loader
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"rag/internal/domain"
+)
+
+// maxOfficePartBytes bounds the size of an XML part read from an office
+// document, against archives that unpack to far more than they weigh.
+const maxOfficePartBytes = 256 << 20
+
+// maxArchiveBytes bounds the total size of the parts read from an archive,
+// since an EPUB of many chapters can unpack to far more than any one part.
+const maxArchiveBytes = 1 << 30
+
+// DOCX loads Word documents (.docx) as their text: a block per paragraph,
+// table rows with their cells separated by " | ", and list items starting
+// with "- ". Deleted revisions, comments, headers and footers are left out.
+// The document's title, or else its first heading, goes to the "title"
+// metadata field, and its author to "author".
+type DOCX struct{}
+
+// ODT loads OpenDocument text files (.odt), as written by LibreOffice and
+// Google Docs, like DOCX; footnotes are left out as well.
+type ODT struct{}
+
+// Detect accepts ZIP archives.
+func (DOCX) Detect(_ string, data []byte) bool { return isZip(data) }
+
+// Load returns the document's text as a single document.
+func (DOCX) Load(path string, data []byte) ([]domain.Document, error) {
+	archive, err := openZip(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	body, err := archive.part("word/document.xml")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	// Without styles, no paragraph is a heading.
+	styles, _ := archive.part("word/styles.xml")
+	var doc officeText
+	if err := doc.parse(body, docxElements(docxHeadingStyles(styles))); err != nil {
+		return nil, fmt.Errorf("%s: word/document.xml: %w", path, err)
+	}
+	// Properties are optional, and a document without them is still read.
+	props, _ := archive.part("docProps/core.xml")
+	return doc.documents(xmlFields(props, "title", "creator")), nil
+}
+
+// Detect accepts ZIP archives.
+func (ODT) Detect(_ string, data []byte) bool { return isZip(data) }
+
+// Load returns the document's text as a single document.
+func (ODT) Load(path string, data []byte) ([]domain.Document, error) {
+	archive, err := openZip(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	body, err := archive.part("content.xml")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var doc officeText
+	if err := doc.parse(body, odtElement); err != nil {
+		return nil, fmt.Errorf("%s: content.xml: %w", path, err)
+	}
+	meta, _ := archive.part("meta.xml")
+	fields := xmlFields(meta, "title", "creator", "initial-creator")
+	if fields["creator"] == "" {
+		fields["creator"] = fields["initial-creator"]
+	}
+	return doc.documents(fields), nil
+}
+
+// officePara is a paragraph being read.
+type officePara struct {
+	text          strings.Builder
+	heading, list bool
+}
+
+// officeText collects the blocks of text of an office document. Paragraphs,
+// table rows and cells are stacks, since text boxes hold paragraphs within
+// paragraphs and cells hold tables.
+type officeText struct {
+	blocks []string
+	// heading is the first heading.
+	heading string
+	paras   []*officePara
+	rows    [][]string
+	cells   []*strings.Builder
+	// list marks the next paragraph as a list item.
+	list bool
+}
+
+// officeAction is what a format's element means for the text: the parser
+// calls it for each start element, and reads the text directly within
+// those it marks.
+type officeAction func(d *officeText, e xml.StartElement) (skip, text bool)
+
+// parse reads the text of an XML part, given what its elements mean.
+func (d *officeText) parse(data []byte, element officeAction) error {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	// inText holds, for each open element, whether its text is read.
+	var inText []bool
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			skip, text := element(d, t)
+			if skip {
+				if err := dec.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			inText = append(inText, text)
+		case xml.EndElement:
+			if len(inText) > 0 {
+				inText = inText[:len(inText)-1]
+			}
+			d.end(t.Name.Local)
+		case xml.CharData:
+			if len(inText) > 0 && inText[len(inText)-1] {
+				d.write(string(t))
+			}
+		}
+	}
+}
+
+// docxBodyLevel is the outline level of body text.
+const docxBodyLevel = "9"
+
+// docxElements returns the interpretation of the elements of
+// word/document.xml, whose paragraphs in the headings styles are headings.
+func docxElements(headings map[string]bool) officeAction {
+	return func(d *officeText, e xml.StartElement) (skip, text bool) {
+		switch e.Name.Local {
+		case "p":
+			d.startPara()
+		case "pStyle":
+			if headings[xmlAttr(e, "val")] {
+				d.markHeading()
+			}
+		case "outlineLvl":
+			if xmlAttr(e, "val") != docxBodyLevel {
+				d.markHeading()
+			}
+		case "numPr":
+			if p := d.para(); p != nil {
+				p.list = true
+			}
+		case "t":
+			return false, true
+		case "tab", "br", "cr":
+			d.write(" ")
+		case "tr":
+			d.rows = append(d.rows, nil)
+		case "tc":
+			d.cells = append(d.cells, new(strings.Builder))
+		case "Fallback":
+			// Fallbacks repeat the text boxes of their choice for older
+			// readers.
+			return true, false
+		}
+		return false, false
+	}
+}
+
+// docxHeadingStyles returns the IDs of the heading and title styles of
+// word/styles.xml. Built-in styles keep their English names, such as
+// "heading 1", when Word shows them translated and gives them IDs such as
+// "berschrift1"; other headings have an outline level.
+func docxHeadingStyles(data []byte) map[string]bool {
+	headings := make(map[string]bool)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	id := ""
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return headings
+		}
+		e, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch e.Name.Local {
+		case "style":
+			id = xmlAttr(e, "styleId")
+		case "name":
+			name := strings.ToLower(xmlAttr(e, "val"))
+			if id != "" && (strings.HasPrefix(name, "heading") || name == "title") {
+				headings[id] = true
+			}
+		case "outlineLvl":
+			if id != "" && xmlAttr(e, "val") != docxBodyLevel {
+				headings[id] = true
+			}
+		}
+	}
+}
+
+// odtElement interprets the elements of content.xml.
+func odtElement(d *officeText, e xml.StartElement) (skip, text bool) {
+	if (e.Name.Local == "title" || e.Name.Local == "desc") && strings.Contains(e.Name.Space, "svg") {
+		// Frames have a title and a description, which are not part of
+		// the text.
+		return true, false
+	}
+	switch e.Name.Local {
+	case "p", "h":
+		d.startPara()
+		if e.Name.Local == "h" {
+			d.markHeading()
+		}
+		if d.list {
+			d.para().list = true
+			d.list = false
+		}
+		return false, true
+	case "s", "tab", "line-break":
+		// Whitespace is collapsed in the text, so spaces, tabs and line
+		// breaks are all one space.
+		d.write(" ")
+	case "list-item":
+		d.list = true
+	case "table-row":
+		d.rows = append(d.rows, nil)
+	case "table-cell":
+		d.cells = append(d.cells, new(strings.Builder))
+	case "note", "annotation", "tracked-changes":
+		return true, false
+	}
+	// Text outside paragraphs, such as the whitespace between elements, is
+	// not content.
+	return false, d.para() != nil
+}
+
+func (d *officeText) startPara() {
+	d.paras = append(d.paras, &officePara{})
+}
+
+// para returns the innermost paragraph being read, if any.
+func (d *officeText) para() *officePara {
+	if len(d.paras) == 0 {
+		return nil
+	}
+	return d.paras[len(d.paras)-1]
+}
+
+func (d *officeText) markHeading() {
+	if p := d.para(); p != nil {
+		p.heading = true
+	}
+}
+
+// write adds text to the paragraph being read.
+func (d *officeText) write(s string) {
+	if p := d.para(); p != nil {
+		p.text.WriteString(s)
+	}
+}
+
+// end closes the paragraph, cell or row ended by the element name, of
+// either format.
+func (d *officeText) end(name string) {
+	switch name {
+	case "p", "h":
+		p := d.para()
+		if p == nil {
+			return
+		}
+		d.paras = d.paras[:len(d.paras)-1]
+		text := strings.TrimSpace(htmlSpaceRe.ReplaceAllString(p.text.String(), " "))
+		if text == "" {
+			return
+		}
+		if p.heading && d.heading == "" {
+			d.heading = text
+		}
+		if p.list {
+			text = "- " + text
+		}
+		d.add(text)
+	case "tc", "table-cell":
+		if len(d.cells) == 0 {
+			return
+		}
+		cell := strings.TrimSpace(d.cells[len(d.cells)-1].String())
+		d.cells = d.cells[:len(d.cells)-1]
+		if len(d.rows) > 0 {
+			d.rows[len(d.rows)-1] = append(d.rows[len(d.rows)-1], cell)
+		}
+	case "tr", "table-row":
+		if len(d.rows) == 0 {
+			return
+		}
+		var cells []string
+		for _, c := range d.rows[len(d.rows)-1] {
+			if c != "" {
+				cells = append(cells, c)
+			}
+		}
+		d.rows = d.rows[:len(d.rows)-1]
+		if len(cells) > 0 {
+			d.add(strings.Join(cells, " | "))
+		}
+	}
+}
+
+// add adds a block of text to the innermost table cell being read, or else
+// to the document.
+func (d *officeText) add(text string) {
+	if len(d.cells) > 0 {
+		cell := d.cells[len(d.cells)-1]
+		if cell.Len() > 0 {
+			cell.WriteString(" ")
+		}
+		cell.WriteString(text)
+		return
+	}
+	d.blocks = append(d.blocks, text)
+}
+
+// documents returns the text read as a document, with its title and
+// author from the properties fields.
+func (d *officeText) documents(fields map[string]string) []domain.Document {
+	if len(d.blocks) == 0 {
+		return nil
+	}
+	meta := make(map[string]string)
+	if title := cmp.Or(fields["title"], d.heading); title != "" {
+		meta["title"] = title
+	}
+	if fields["creator"] != "" {
+		meta["author"] = fields["creator"]
+	}
+	return []domain.Document{{Content: strings.Join(d.blocks, "\n\n"), Metadata: meta, Transformed: true}}
+}
+
+// zipArchive reads the parts of an archive out of a budget of
+// maxArchiveBytes shared by all of them.
+type zipArchive struct {
+	zr *zip.Reader
+	// left is what remains of the budget.
+	left int64
+}
+
+// openZip opens the archive held in data.
+func openZip(data []byte) (*zipArchive, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return &zipArchive{zr: zr, left: maxArchiveBytes}, nil
+}
+
+// part returns the contents of the file name in the archive.
+func (a *zipArchive) part(name string) ([]byte, error) {
+	f, err := a.zr.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, min(maxOfficePartBytes, a.left)+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if len(data) > maxOfficePartBytes {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, maxOfficePartBytes)
+	}
+	a.left -= int64(len(data))
+	if a.left < 0 {
+		return nil, fmt.Errorf("archive unpacks to more than %d bytes", maxArchiveBytes)
+	}
+	return data, nil
+}
+
+// xmlFields returns the text of the first elements of data with the given
+// local names, such as the "title" of Dublin Core properties.
+func xmlFields(data []byte, names ...string) map[string]string {
+	fields := make(map[string]string)
+	want := make(map[string]bool, len(names))
+	for _, n := range names {
+		want[n] = true
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	field := ""
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return fields
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			field = ""
+			if want[t.Name.Local] && fields[t.Name.Local] == "" {
+				field = t.Name.Local
+			}
+		case xml.EndElement:
+			field = ""
+		case xml.CharData:
+			if field != "" {
+				fields[field] = strings.TrimSpace(htmlSpaceRe.ReplaceAllString(string(t), " "))
+			}
+		}
+	}
+}
+
+// xmlAttr returns the value of e's attribute with the given local name.
+func xmlAttr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// isZip reports whether data starts like a ZIP archive.
+func isZip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
@@ -0,0 +1,104 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"rag/internal/domain"
+)
+
+// Slack loads the per-day message files of a Slack workspace export
+// (<export>/<channel>/<YYYY-MM-DD>.json). Sender names are resolved from
+// the message profile or the export's users.json.
+type Slack struct {
+	windows chatWindows
+}
+
+type slackMessage struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	User        string `json:"user"`
+	Text        string `json:"text"`
+	TS          string `json:"ts"`
+	UserProfile struct {
+		RealName string `json:"real_name"`
+		Name     string `json:"name"`
+	} `json:"user_profile"`
+}
+
+// Detect accepts JSON arrays of Slack messages.
+func (Slack) Detect(_ string, data []byte) bool {
+	var msgs []slackMessage
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) || json.Unmarshal(data, &msgs) != nil || len(msgs) == 0 {
+		return false
+	}
+	return msgs[0].Type == "message" && msgs[0].TS != ""
+}
+
+// Load returns the day's messages grouped into conversation windows.
+func (s Slack) Load(path string, data []byte) ([]domain.Document, error) {
+	var raw []slackMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	users := slackUsers(filepath.Join(filepath.Dir(path), "..", "users.json"))
+	msgs := make([]message, 0, len(raw))
+	for _, m := range raw {
+		if m.Type != "message" || m.Subtype == "channel_join" || m.Subtype == "channel_leave" {
+			continue
+		}
+		sender := m.UserProfile.RealName
+		if sender == "" {
+			sender = m.UserProfile.Name
+		}
+		if sender == "" {
+			sender = users[m.User]
+		}
+		if sender == "" {
+			sender = m.User
+		}
+		msgs = append(msgs, message{time: slackTime(m.TS), sender: sender, text: m.Text})
+	}
+	channel := "#" + filepath.Base(filepath.Dir(path))
+	return s.windows.documents(channel, msgs), nil
+}
+
+// slackUsers maps user IDs to display names; a missing file yields none.
+func slackUsers(path string) map[string]string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var users []struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		RealName string `json:"real_name"`
+	}
+	if json.Unmarshal(data, &users) != nil {
+		return nil
+	}
+	out := make(map[string]string, len(users))
+	for _, u := range users {
+		name := u.RealName
+		if name == "" {
+			name = u.Name
+		}
+		out[u.ID] = name
+	}
+	return out
+}
+
+// slackTime parses a Slack timestamp ("1705312800.000200").
+func slackTime(ts string) time.Time {
+	secs, _, _ := strings.Cut(ts, ".")
+	n, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(n, 0).UTC()
+}
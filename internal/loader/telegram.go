@@ -0,0 +1,96 @@
+package loader
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"rag/internal/domain"
+)
+
+// Telegram loads the result.json of a Telegram Desktop export, either of a
+// single chat or of the whole account.
+type Telegram struct {
+	windows chatWindows
+}
+
+type telegramChat struct {
+	Name     string            `json:"name"`
+	Messages []telegramMessage `json:"messages"`
+}
+
+type telegramExport struct {
+	telegramChat
+	Chats struct {
+		List []telegramChat `json:"list"`
+	} `json:"chats"`
+}
+
+type telegramMessage struct {
+	Type string          `json:"type"`
+	Date string          `json:"date"`
+	From string          `json:"from"`
+	Text json.RawMessage `json:"text"`
+}
+
+// Detect accepts JSON objects holding messages or a chat list.
+func (Telegram) Detect(_ string, data []byte) bool {
+	var e telegramExport
+	if json.Unmarshal(data, &e) != nil {
+		return false
+	}
+	return len(e.Messages) > 0 || len(e.Chats.List) > 0
+}
+
+// Load returns every chat's messages grouped into conversation windows.
+func (t Telegram) Load(_ string, data []byte) ([]domain.Document, error) {
+	var e telegramExport
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	chats := e.Chats.List
+	if len(e.Messages) > 0 {
+		chats = append(chats, e.telegramChat)
+	}
+	var docs []domain.Document
+	for _, c := range chats {
+		msgs := make([]message, 0, len(c.Messages))
+		for _, m := range c.Messages {
+			if m.Type != "message" {
+				continue
+			}
+			ts, err := time.Parse("2006-01-02T15:04:05", m.Date)
+			if err != nil {
+				continue
+			}
+			msgs = append(msgs, message{time: ts, sender: m.From, text: telegramText(m.Text)})
+		}
+		docs = append(docs, t.windows.documents(c.Name, msgs)...)
+	}
+	return docs, nil
+}
+
+// telegramText flattens a message text, which is either a string or a list
+// of strings and formatted entities.
+func telegramText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var parts []json.RawMessage
+	if json.Unmarshal(raw, &parts) != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, p := range parts {
+		var entity struct {
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(p, &s) == nil {
+			b.WriteString(s)
+		} else if json.Unmarshal(p, &entity) == nil {
+			b.WriteString(entity.Text)
+		}
+	}
+	return b.String()
+}
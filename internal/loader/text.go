@@ -0,0 +1,18 @@
+package loader
+
+import (
+	"rag/internal/docdate"
+	"rag/internal/domain"
+)
+
+// Text loads plain text and Markdown files verbatim as one document.
+type Text struct{}
+
+// Detect accepts any file.
+func (Text) Detect(string, []byte) bool { return true }
+
+// Load returns the whole file as a single document.
+func (Text) Load(path string, data []byte) ([]domain.Document, error) {
+	content := string(data)
+	return []domain.Document{{Content: content, Time: docdate.Detect(path, content)}}, nil
+}
@@ -0,0 +1,78 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"rag/internal/domain"
+)
+
+// maxURLBytes bounds the size of a fetched document.
+const maxURLBytes = 32 << 20
+
+// urlClient fetches URL sources.
+var urlClient = &http.Client{Timeout: 60 * time.Second}
+
+// contentTypeExt maps media types to the extension whose loaders parse them.
+var contentTypeExt = map[string]string{
+	"text/plain":               ".txt",
+	"text/markdown":            ".md",
+	"text/x-markdown":          ".md",
+	"application/json":         ".json",
+	"application/x-ipynb+json": ".ipynb",
+	"application/x-tex":        ".tex",
+	"text/x-tex":               ".tex",
+	"text/html":                ".html",
+	"application/xhtml+xml":    ".html",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": ".docx",
+	"application/vnd.oasis.opendocument.text":                                 ".odt",
+	"application/epub+zip":                                                    ".epub",
+}
+
+// LoadURL fetches rawURL and converts the response like Load. The loaders
+// are those of ext when set, otherwise of the URL path's extension, falling
+// back to the response's content type. Fetched documents have no source
+// file to re-read, so they are marked Transformed.
+func (r *Registry) LoadURL(ctx context.Context, rawURL, ext string) (docs []domain.Document, ok bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	resp, err := urlClient.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	if ext == "" {
+		if u, err := url.Parse(rawURL); err == nil {
+			ext = path.Ext(u.Path)
+		}
+	}
+	ext = strings.ToLower(ext)
+	if len(r.byExt[ext]) == 0 {
+		mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		ext = contentTypeExt[mediaType]
+	}
+	if len(r.byExt[ext]) == 0 {
+		return nil, false, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxURLBytes))
+	if err != nil {
+		return nil, false, err
+	}
+	docs, ok, err = r.decode(rawURL, ext, data)
+	for i := range docs {
+		docs[i].Transformed = true
+	}
+	return docs, ok, err
+}
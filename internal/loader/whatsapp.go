@@ -0,0 +1,92 @@
+package loader
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"rag/internal/domain"
+)
+
+// WhatsApp loads "Export chat" text files from Android
+// ("15/01/2024, 10:00 - Alice: hi") and iOS ("[15/01/2024, 10:00:00] Alice: hi").
+type WhatsApp struct {
+	windows chatWindows
+}
+
+var (
+	whatsappLineRe = regexp.MustCompile(`^\x{200e}?\[?(\d{1,2})[./](\d{1,2})[./](\d{2,4}),? (\d{1,2}):(\d{2})(?::(\d{2}))?(?:\s?([AaPp]\.?[Mm]\.?))?\]?(?: -)? (.*)$`)
+	whatsappFromRe = regexp.MustCompile(`(?s)^([^:\n]{1,80}): (.*)$`)
+)
+
+// Detect accepts files whose first line starts with a WhatsApp timestamp.
+func (WhatsApp) Detect(_ string, data []byte) bool {
+	first, _, _ := strings.Cut(strings.TrimPrefix(string(data), "\ufeff"), "\n")
+	return whatsappLineRe.MatchString(strings.TrimRight(first, "\r"))
+}
+
+// Load returns the chat's messages grouped into conversation windows. System
+// lines without a sender are skipped; lines without a timestamp continue the
+// previous message.
+func (w WhatsApp) Load(path string, data []byte) ([]domain.Document, error) {
+	lines := strings.Split(strings.TrimPrefix(string(data), "\ufeff"), "\n")
+	type header struct {
+		fields []string
+		body   string
+	}
+	var heads []*header
+	dayFirst := true
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		m := whatsappLineRe.FindStringSubmatch(line)
+		if m == nil {
+			if len(heads) > 0 && line != "" {
+				heads[len(heads)-1].body += "\n" + line
+			}
+			continue
+		}
+		if n, _ := strconv.Atoi(m[2]); n > 12 {
+			dayFirst = false
+		}
+		heads = append(heads, &header{fields: m, body: m[8]})
+	}
+	var msgs []message
+	for _, h := range heads {
+		from := whatsappFromRe.FindStringSubmatch(h.body)
+		if from == nil {
+			continue
+		}
+		msgs = append(msgs, message{time: whatsappTime(h.fields, dayFirst), sender: from[1], text: from[2]})
+	}
+	channel := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	channel = strings.TrimPrefix(channel, "WhatsApp Chat with ")
+	return w.windows.documents(channel, msgs), nil
+}
+
+// whatsappTime builds the time of a matched header line. The date order
+// depends on the phone's locale: day first unless some line had a second
+// field above 12.
+func whatsappTime(m []string, dayFirst bool) time.Time {
+	a, _ := strconv.Atoi(m[1])
+	b, _ := strconv.Atoi(m[2])
+	year, _ := strconv.Atoi(m[3])
+	hour, _ := strconv.Atoi(m[4])
+	minute, _ := strconv.Atoi(m[5])
+	sec, _ := strconv.Atoi(m[6])
+	if year < 100 {
+		year += 2000
+	}
+	day, month := a, b
+	if !dayFirst {
+		day, month = b, a
+	}
+	ampm := strings.ToLower(strings.ReplaceAll(m[7], ".", ""))
+	if ampm == "pm" && hour < 12 {
+		hour += 12
+	} else if ampm == "am" && hour == 12 {
+		hour = 0
+	}
+	return time.Date(year, time.Month(month), day, hour, minute, sec, 0, time.UTC)
+}
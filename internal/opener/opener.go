@@ -0,0 +1,103 @@
+// Package opener builds the commands opening result files in the user's
+// tool of choice: a command template such as "code -g {path}:{line}", or
+// else $VISUAL or $EDITOR at the result's line.
+package opener
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Command returns the arguments of the command opening path at line (from
+// 1). template is split into arguments at spaces, except within single or
+// double quotes, and {path} and {line} are replaced in each argument, so
+// that no shell sees the path; a template without {path} gets it as its
+// last argument. An empty template opens the file in Editor, with +line
+// past the first line.
+func Command(template, path string, line int) ([]string, error) {
+	line = max(line, 1)
+	if strings.TrimSpace(template) == "" {
+		args := Editor()
+		if line > 1 {
+			args = append(args, "+"+strconv.Itoa(line))
+		}
+		return append(args, path), nil
+	}
+	args, err := split(template)
+	if err != nil {
+		return nil, err
+	}
+	r := strings.NewReplacer("{path}", path, "{line}", strconv.Itoa(line))
+	withPath := false
+	for i, a := range args {
+		withPath = withPath || strings.Contains(a, "{path}")
+		args[i] = r.Replace(a)
+	}
+	if !withPath {
+		args = append(args, path)
+	}
+	return args, nil
+}
+
+// Check reports whether template can be split into arguments; an empty
+// template is valid.
+func Check(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return nil
+	}
+	_, err := split(template)
+	return err
+}
+
+// Editor returns $VISUAL or $EDITOR split into arguments, or vi if neither
+// is set.
+func Editor() []string {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	return strings.Fields(editor)
+}
+
+// split splits a command line into arguments at unquoted spaces, removing
+// the quotes.
+func split(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			cur.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("open command %q: unterminated quote", s)
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("open command %q is empty", s)
+	}
+	return args, nil
+}
@@ -0,0 +1,75 @@
+// Package ordering sorts search results for display: by score, in
+// document order, or newest first.
+package ordering
+
+import (
+	"fmt"
+	"sort"
+
+	"rag/internal/domain"
+)
+
+// Order is a way of sorting results.
+type Order string
+
+const (
+	// Score lists the best hits first, as ranked by the search.
+	Score Order = "score"
+	// Document lists hits by document path, then by position in the
+	// document, for reviewing one file at a time.
+	Document Order = "document"
+	// Recency lists the hits of the newest documents first; undated hits
+	// come last. Hits of the same date keep their ranking.
+	Recency Order = "recency"
+)
+
+// Orders lists the orders in the order the TUI cycles through them.
+var Orders = []Order{Score, Document, Recency}
+
+// Parse returns the order named s; "" is Score.
+func Parse(s string) (Order, error) {
+	if s == "" {
+		return Score, nil
+	}
+	for _, o := range Orders {
+		if string(o) == s {
+			return o, nil
+		}
+	}
+	return "", fmt.Errorf("unknown result order %q (use score, document or recency)", s)
+}
+
+// Next returns the order after o in Orders, wrapping around.
+func (o Order) Next() Order {
+	for i, x := range Orders {
+		if x == o {
+			return Orders[(i+1)%len(Orders)]
+		}
+	}
+	return Score
+}
+
+// Sort sorts results in place by o. The sort is stable, so results that
+// compare equal keep the ranking of the search.
+func Sort(results []domain.SearchResult, o Order) {
+	var less func(a, b domain.SearchResult) bool
+	switch o {
+	case Document:
+		less = func(a, b domain.SearchResult) bool {
+			if a.Chunk.Path != b.Chunk.Path {
+				return a.Chunk.Path < b.Chunk.Path
+			}
+			return a.Chunk.Index < b.Chunk.Index
+		}
+	case Recency:
+		less = func(a, b domain.SearchResult) bool {
+			if a.Chunk.Time.IsZero() || b.Chunk.Time.IsZero() {
+				return !a.Chunk.Time.IsZero() && b.Chunk.Time.IsZero()
+			}
+			return a.Chunk.Time.After(b.Chunk.Time)
+		}
+	default:
+		less = func(a, b domain.SearchResult) bool { return a.Score > b.Score }
+	}
+	sort.SliceStable(results, func(i, j int) bool { return less(results[i], results[j]) })
+}
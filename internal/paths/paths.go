@@ -0,0 +1,121 @@
+// Package paths locates the files rag keeps outside the corpus. They are
+// split by the XDG base directory spec into config (config.yaml), data that
+// must survive (per-index state such as saved searches, the failed chunk
+// record, the disk vector store) and cache that can be deleted at any time (chunk text logs).
+//
+// On Windows config and data live under %APPDATA%\rag and the cache under
+// %LOCALAPPDATA%\rag. Other platforms, macOS included, use the XDG layout,
+// as command-line tools there commonly do.
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+const app = "rag"
+
+// ConfigDir returns $XDG_CONFIG_HOME/rag or ~/.config/rag.
+func ConfigDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, app), nil
+	}
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir returns $XDG_DATA_HOME/rag or ~/.local/share/rag.
+func DataDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		dir, err := ConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "data"), nil
+	}
+	return xdgDir("XDG_DATA_HOME", ".local", "share")
+}
+
+// CacheDir returns $XDG_CACHE_HOME/rag or ~/.cache/rag.
+func CacheDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, app), nil
+	}
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+// ConfigFile returns the user config file.
+func ConfigFile() (string, error) {
+	return under(ConfigDir, "config.yaml")
+}
+
+// SavedSearches returns the saved searches file of the index named key.
+func SavedSearches(key string) (string, error) {
+	return under(DataDir, "indexes", key, "saved_searches.json")
+}
+
+// Bookmarks returns the bookmarks file of the index named key.
+func Bookmarks(key string) (string, error) {
+	return under(DataDir, "indexes", key, "bookmarks.json")
+}
+
+// Conversations returns the conversations file of the index named key.
+func Conversations(key string) (string, error) {
+	return under(DataDir, "indexes", key, "conversations.json")
+}
+
+// Feedback returns the result judgments file of the index named key.
+func Feedback(key string) (string, error) {
+	return under(DataDir, "indexes", key, "feedback.json")
+}
+
+// VectorDB returns the database file of the disk vector store.
+func VectorDB() (string, error) {
+	return under(DataDir, "vectors.db")
+}
+
+// FailedChunks returns the record of chunks the last ingest of the index
+// named key failed to embed.
+func FailedChunks(key string) (string, error) {
+	return under(DataDir, "indexes", key, "failed_chunks.json")
+}
+
+// TextLogDir returns the directory of on-disk chunk text logs.
+func TextLogDir() (string, error) {
+	return under(CacheDir, "textlog")
+}
+
+// ChunkContexts returns the cache of chunk contexts written by the llm
+// model for enrichment.
+func ChunkContexts() (string, error) {
+	return under(CacheDir, "chunk_contexts.jsonl")
+}
+
+func under(base func() (string, error), elem ...string) (string, error) {
+	dir, err := base()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append([]string{dir}, elem...)...), nil
+}
+
+// xdgDir returns rag under the directory named by the variable env, or
+// under the home-relative fallback when it is unset.
+func xdgDir(env string, fallback ...string) (string, error) {
+	if dir := os.Getenv(env); dir != "" {
+		return filepath.Join(dir, app), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append(append([]string{home}, fallback...), app)...), nil
+}
@@ -0,0 +1,193 @@
+// Package queryparse splits search operators off a raw query string.
+package queryparse
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// dateLayout is the format of after:/before: operands.
+const dateLayout = "2006-01-02"
+
+// MaxLimit is the largest number of results a query may ask for, with k:N
+// or a caller's own limit.
+const MaxLimit = 1000
+
+// MaxBoost is the largest weight of a boosted term; higher weights, such as
+// term^1e9, are lowered to it, since boosted terms are repeated as often as
+// their weight in the embedded query.
+const MaxBoost = 10
+
+// metadataOperators maps operators that filter on document metadata to the
+// metadata field they test.
+var metadataOperators = map[string]string{
+	"from":     "from",
+	"channel":  "channel",
+	"cell":     "cell",
+	"language": "language",
+	"lang":     "lang",
+	"section":  "section",
+	"heading":  "heading",
+	"tag":      "tags",
+	"alias":    "aliases",
+	"title":    "title",
+	"author":   "author",
+	"chapter":  "chapter",
+}
+
+// Query is a parsed query: the free text to embed plus any filters.
+type Query struct {
+	Text string
+	// After and Before bound the chunk date; both days are inclusive.
+	// Zero values leave that side open.
+	After  time.Time
+	Before time.Time
+	// Metadata requires chunk metadata fields to have the given values.
+	Metadata map[string]string
+	// Boosts weights lowercase terms written term^2 in lexical scoring.
+	Boosts map[string]float64
+	// Required lists the lowercase terms written +term, which every result
+	// must contain.
+	Required []string
+	// Limit is the number of results asked for with k:N; 0 leaves it to the
+	// caller.
+	Limit int
+	// Deleted searches the soft-deleted chunks instead of the live ones
+	// (is:deleted).
+	Deleted bool
+	// HyDE asks, with hyde:on or hyde:off, to embed a hypothetical answer
+	// drafted by a language model instead of the query, or not; nil leaves
+	// it to the caller.
+	HyDE *bool
+}
+
+// Parse extracts `after:YYYY-MM-DD` and `before:YYYY-MM-DD` date operators
+// and metadata operators such as `from:alice` or `channel:"dev ops"` from raw
+// and returns the remaining words as the query text. Operators with
+// unparsable dates are an error rather than silently searched for. Words
+// may be marked required (`+term`) or boosted (`term^2`); the marks are
+// stripped from the text. `k:25` asks for 25 results, `is:deleted`
+// searches soft-deleted documents, and `hyde:on` embeds a hypothetical
+// answer instead of the query.
+func Parse(raw string) (Query, error) {
+	var q Query
+	var words []string
+	for _, w := range fields(raw) {
+		name, value, ok := strings.Cut(w, ":")
+		if !ok {
+			words = append(words, q.term(w))
+			continue
+		}
+		name = strings.ToLower(name)
+		if key, ok := metadataOperators[name]; ok && value != "" {
+			if q.Metadata == nil {
+				q.Metadata = make(map[string]string)
+			}
+			q.Metadata[key] = strings.Trim(value, `"`)
+			continue
+		}
+		switch name {
+		case "after":
+			t, err := time.Parse(dateLayout, value)
+			if err != nil {
+				return Query{}, fmt.Errorf("after: expects a date like 2024-01-31, got %q", value)
+			}
+			q.After = t
+		case "before":
+			t, err := time.Parse(dateLayout, value)
+			if err != nil {
+				return Query{}, fmt.Errorf("before: expects a date like 2024-01-31, got %q", value)
+			}
+			q.Before = t
+		case "k":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 || n > MaxLimit {
+				return Query{}, fmt.Errorf("k: expects a number of results from 1 to %d, got %q", MaxLimit, value)
+			}
+			q.Limit = n
+		case "hyde":
+			var on bool
+			switch strings.ToLower(value) {
+			case "on":
+				on = true
+			case "off":
+			default:
+				return Query{}, fmt.Errorf("hyde: expects on or off, got %q", value)
+			}
+			q.HyDE = &on
+		case "is":
+			if !strings.EqualFold(value, "deleted") {
+				words = append(words, q.term(w))
+				continue
+			}
+			q.Deleted = true
+		default:
+			words = append(words, q.term(w))
+		}
+	}
+	q.Text = strings.Join(words, " ")
+	return q, nil
+}
+
+// term records the required and boost marks of a query word and returns
+// the bare word.
+func (q *Query) term(w string) string {
+	required := len(w) > 1 && w[0] == '+'
+	if required {
+		w = w[1:]
+	}
+	if base, weight, ok := strings.Cut(w, "^"); ok && base != "" {
+		// Infinite and NaN weights, which ParseFloat accepts as "inf" and
+		// "nan", leave the word as written.
+		if f, err := strconv.ParseFloat(weight, 64); err == nil && f > 0 && !math.IsInf(f, 0) {
+			if q.Boosts == nil {
+				q.Boosts = make(map[string]float64)
+			}
+			q.Boosts[strings.ToLower(base)] = min(f, MaxBoost)
+			w = base
+		}
+	}
+	if required {
+		q.Required = append(q.Required, strings.ToLower(w))
+	}
+	return w
+}
+
+// fields splits s on white space, keeping double-quoted runs together.
+func fields(s string) []string {
+	var out []string
+	var b strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			b.WriteRune(r)
+		case !quoted && unicode.IsSpace(r):
+			if b.Len() > 0 {
+				out = append(out, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		out = append(out, b.String())
+	}
+	return out
+}
+
+// Terms returns the free text of raw, ignoring operators and errors; used to
+// highlight query words.
+func Terms(raw string) string {
+	q, err := Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return q.Text
+}
@@ -0,0 +1,138 @@
+// Package bm25 ranks texts by Okapi BM25 over an inverted index. A query
+// term adds to a text's score its inverse document frequency, times its
+// frequency in the text saturated at a rate set by k1, and normalized by the
+// text's length relative to the average to a degree set by b. Unlike the
+// cosine of TF-IDF vectors, a rare term found once outweighs common terms
+// found often, which suits corpora searched by names and identifiers.
+package bm25
+
+import (
+	"fmt"
+	"math"
+)
+
+// Default parameters, the usual ones.
+const (
+	DefaultK1 = 1.2
+	DefaultB  = 0.75
+)
+
+// Params are the BM25 parameters.
+type Params struct {
+	// K1 sets how fast repeated occurrences of a term stop counting: 0
+	// counts a term once, larger values let repetitions count longer.
+	K1 float64
+	// B sets how much longer texts are penalized, from 0 (not at all) to
+	// 1 (in proportion to their length).
+	B float64
+}
+
+// DefaultParams returns the default parameters.
+func DefaultParams() Params {
+	return Params{K1: DefaultK1, B: DefaultB}
+}
+
+// Validate reports parameters out of range.
+func (p Params) Validate() error {
+	if p.K1 < 0 {
+		return fmt.Errorf("k1 must not be negative, got %g", p.K1)
+	}
+	if p.B < 0 || p.B > 1 {
+		return fmt.Errorf("b must be between 0 and 1, got %g", p.B)
+	}
+	return nil
+}
+
+// Posting is an occurrence of a term in a text, with its frequency there.
+type Posting struct {
+	Doc int32
+	TF  int32
+}
+
+// Index is an inverted index of texts, numbered from 0 in the order they
+// are added. It is not safe for concurrent use while texts are added.
+type Index struct {
+	params Params
+	// terms numbers the terms; postings lists the texts of each term in
+	// text order. lengths and distinct count the tokens and distinct
+	// tokens of each text.
+	terms    map[string]int32
+	postings [][]Posting
+	lengths  []int32
+	distinct []int32
+	total    int
+}
+
+// New returns an empty index ranking by p.
+func New(p Params) *Index {
+	return &Index{params: p, terms: make(map[string]int32)}
+}
+
+// Add indexes the next text by its tokens.
+func (x *Index) Add(tokens []string) {
+	doc := int32(len(x.lengths))
+	var distinct int32
+	for _, t := range tokens {
+		id, ok := x.terms[t]
+		if !ok {
+			id = int32(len(x.postings))
+			x.terms[t] = id
+			x.postings = append(x.postings, nil)
+		}
+		list := x.postings[id]
+		if n := len(list); n > 0 && list[n-1].Doc == doc {
+			list[n-1].TF++
+			continue
+		}
+		x.postings[id] = append(list, Posting{Doc: doc, TF: 1})
+		distinct++
+	}
+	x.lengths = append(x.lengths, int32(len(tokens)))
+	x.distinct = append(x.distinct, distinct)
+	x.total += len(tokens)
+}
+
+// Len returns the number of texts indexed.
+func (x *Index) Len() int { return len(x.lengths) }
+
+// Postings returns the texts containing term, in text order.
+func (x *Index) Postings(term string) []Posting {
+	id, ok := x.terms[term]
+	if !ok {
+		return nil
+	}
+	return x.postings[id]
+}
+
+// Distinct returns the number of distinct terms of text doc.
+func (x *Index) Distinct(doc int) int { return int(x.distinct[doc]) }
+
+// Scores returns the BM25 score of every text for a query of terms
+// weighted by weights; texts with none of the terms score 0. Only the
+// postings of the query terms are visited.
+func (x *Index) Scores(weights map[string]float64) []float64 {
+	scores := make([]float64, x.Len())
+	if x.Len() == 0 {
+		return scores
+	}
+	n := float64(x.Len())
+	avg := float64(x.total) / n
+	k1, b := x.params.K1, x.params.B
+	for t, w := range weights {
+		list := x.Postings(t)
+		if len(list) == 0 || w == 0 {
+			continue
+		}
+		df := float64(len(list))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range list {
+			tf := float64(p.TF)
+			norm := 1 - b
+			if avg > 0 {
+				norm += b * float64(x.lengths[p.Doc]) / avg
+			}
+			scores[p.Doc] += w * idf * tf * (k1 + 1) / (tf + k1*norm)
+		}
+	}
+	return scores
+}
@@ -0,0 +1,93 @@
+// Package roots confines the files rag reads to configured directories, so
+// that an instance indexing what others ask for, such as rag rpc or rag
+// watch fed a shared corpus manifest, cannot be made to read /etc or a home
+// directory: paths are resolved, symbolic links included, before they are
+// compared with the roots.
+package roots
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOutside is returned for paths outside every root.
+var ErrOutside = errors.New("outside the allowed roots")
+
+// Set is a set of root directories. A nil Set allows every path.
+type Set struct {
+	dirs []string
+}
+
+// New returns the set of dirs, resolved to absolute paths without symbolic
+// links; each must be an existing directory. No dirs returns nil, which
+// allows every path.
+func New(dirs []string) (*Set, error) {
+	if len(dirs) == 0 {
+		return nil, nil
+	}
+	s := &Set{}
+	for _, d := range dirs {
+		if strings.HasPrefix(d, "~/") || d == "~" {
+			if home, err := os.UserHomeDir(); err == nil {
+				d = filepath.Join(home, d[1:])
+			}
+		}
+		resolved, err := resolve(d)
+		if err != nil {
+			return nil, fmt.Errorf("root %s: %w", d, err)
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("root %s: %w", d, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("root %s is not a directory", d)
+		}
+		s.dirs = append(s.dirs, resolved)
+	}
+	return s, nil
+}
+
+// Check returns nil when path, once resolved, is within one of the roots,
+// and ErrOutside when it is not. A path that does not exist yet is
+// resolved through its nearest existing parent.
+func (s *Set) Check(path string) error {
+	if s == nil {
+		return nil
+	}
+	resolved, err := resolve(path)
+	if err != nil {
+		return err
+	}
+	for _, d := range s.dirs {
+		rel, err := filepath.Rel(d, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return ErrOutside
+}
+
+// resolve returns the absolute form of path with the symbolic links of its
+// longest existing prefix evaluated.
+func resolve(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	var rest []string
+	for p := abs; ; p = filepath.Dir(p) {
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			return filepath.Join(append([]string{real}, rest...)...), nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		if filepath.Dir(p) == p {
+			return abs, nil
+		}
+		rest = append([]string{filepath.Base(p)}, rest...)
+	}
+}
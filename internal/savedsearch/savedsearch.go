@@ -0,0 +1,59 @@
+package savedsearch
+
+import (
+	"errors"
+	"fmt"
+
+	"rag/internal/jsonstore"
+)
+
+// Search is a named query that can be re-run later.
+type Search struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	TopK  int    `json:"top_k,omitempty"`
+}
+
+// Store persists saved searches as a JSON file.
+type Store struct {
+	file *jsonstore.Store[Search]
+}
+
+// NewStore returns a store backed by the file at path. The file is created
+// on first save.
+func NewStore(path string) *Store {
+	return &Store{file: jsonstore.New(path,
+		func(s Search) string { return s.Name },
+		func(a, b Search) bool { return a.Name < b.Name })}
+}
+
+// List returns all saved searches ordered by name.
+func (s *Store) List() ([]Search, error) {
+	return s.file.List()
+}
+
+// Get returns the saved search with the given name.
+func (s *Store) Get(name string) (Search, error) {
+	v, ok, err := s.file.Get(name)
+	if err != nil {
+		return Search{}, err
+	}
+	if !ok {
+		return Search{}, fmt.Errorf("no saved search named %q", name)
+	}
+	return v, nil
+}
+
+// Save stores search under its name, replacing any previous one.
+func (s *Store) Save(search Search) error {
+	if search.Name == "" {
+		return errors.New("saved search needs a name")
+	}
+	return s.file.Put(search)
+}
+
+// Delete removes the saved search with the given name.
+func (s *Store) Delete(name string) error {
+	_, err := s.file.Delete(name)
+	return err
+}
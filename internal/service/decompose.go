@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"rag/internal/domain"
+	"rag/internal/queryparse"
+)
+
+// subQuestions returns the questions the text of parsed asks, or nil when
+// queries are searched whole.
+func (s *RAGServiceImpl) subQuestions(parsed queryparse.Query) ([]string, error) {
+	if s.decompose == nil || parsed.Text == "" {
+		return nil, nil
+	}
+	// Questions are kept one per line.
+	joined, err := s.drafts.get("decompose\x00"+parsed.Text, func() (string, error) {
+		questions, err := s.decompose(context.Background(), parsed.Text)
+		return strings.Join(questions, "\n"), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(joined, "\n"), nil
+}
+
+// decomposedPage searches each of questions with the operators of parsed
+// and returns the page at offset of the fusion of their rankings, each hit
+// with the questions that found it.
+func (s *RAGServiceImpl) decomposedPage(parsed queryparse.Query, questions []string, offset, limit int) ([]domain.SearchResult, error) {
+	rankings := make([][]domain.SearchResult, len(questions))
+	via := make(map[string][]string)
+	for i, q := range questions {
+		sub := parsed
+		sub.Text = q
+		res, err := s.page(sub, 0, offset+limit)
+		if err != nil {
+			return nil, err
+		}
+		rankings[i] = res
+		for _, hit := range res {
+			via[hit.Chunk.ChunkID] = append(via[hit.Chunk.ChunkID], q)
+		}
+	}
+	out := fuse(s.rrfK, offset, limit, rankings...)
+	for i := range out {
+		out[i].Via = via[out[i].Chunk.ChunkID]
+	}
+	return out, nil
+}
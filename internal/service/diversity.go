@@ -0,0 +1,44 @@
+package service
+
+import "rag/internal/domain"
+
+// searchFunc returns one page of a ranking.
+type searchFunc func(offset, limit int) ([]domain.SearchResult, error)
+
+// limitPerDocument returns the page [offset, offset+limit) of the ranking
+// produced by search after dropping hits beyond the first maxPerDoc of each
+// document. The underlying ranking is read from the top in growing batches
+// until enough hits survive or it is exhausted.
+func limitPerDocument(search searchFunc, offset, limit, maxPerDoc int) ([]domain.SearchResult, error) {
+	want := offset + limit
+	perDoc := make(map[string]int)
+	var kept []domain.SearchResult
+	batch := want
+	read := 0
+	for len(kept) < want {
+		page, err := search(read, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range page {
+			if perDoc[r.Chunk.DocumentID] >= maxPerDoc {
+				continue
+			}
+			perDoc[r.Chunk.DocumentID]++
+			kept = append(kept, r)
+		}
+		read += len(page)
+		if len(page) < batch {
+			break
+		}
+		batch *= 2
+	}
+	if offset >= len(kept) {
+		return nil, nil
+	}
+	kept = kept[offset:]
+	if len(kept) > limit {
+		kept = kept[:limit]
+	}
+	return kept, nil
+}
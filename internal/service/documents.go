@@ -0,0 +1,51 @@
+package service
+
+import "rag/internal/domain"
+
+// Documents lists the ingested documents with their keywords; for a store
+// filled elsewhere, the documents of its chunks without keywords.
+func (s *RAGServiceImpl) Documents() []domain.DocumentInfo {
+	_, _ = s.lexicon()
+	out := make([]domain.DocumentInfo, len(s.documents))
+	copy(out, s.documents)
+	return out
+}
+
+// Chunks returns all indexed chunks, with their texts. It is empty when the
+// store cannot be read back.
+func (s *RAGServiceImpl) Chunks() []domain.Chunk {
+	lexical, err := s.lexicon()
+	if err != nil {
+		return nil
+	}
+	return lexical.all(func(domain.Chunk) bool { return true })
+}
+
+// DocumentChunks returns the chunks of one document, with their texts.
+func (s *RAGServiceImpl) DocumentChunks(documentID string) []domain.Chunk {
+	lexical, err := s.lexicon()
+	if err != nil {
+		return nil
+	}
+	return lexical.all(func(ch domain.Chunk) bool { return ch.DocumentID == documentID })
+}
+
+// Keywords returns the top keywords of a document, or of the whole corpus
+// when documentID is empty.
+func (s *RAGServiceImpl) Keywords(documentID string) []string {
+	if documentID == "" {
+		return s.corpusKeywords
+	}
+	for _, d := range s.documents {
+		if d.ID == documentID {
+			return d.Keywords
+		}
+	}
+	return nil
+}
+
+// Suggest returns up to n completions for the word being typed at the end
+// of input, drawn from the corpus vocabulary and frequent bigrams.
+func (s *RAGServiceImpl) Suggest(input string, n int) []string {
+	return s.completions.Complete(input, n)
+}
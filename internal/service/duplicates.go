@@ -0,0 +1,104 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"rag/internal/dedup"
+	"rag/internal/domain"
+	"rag/internal/vectorstore"
+)
+
+// Duplicate methods supported by Duplicates.
+const (
+	DupMinHash   = "minhash"
+	DupEmbedding = "embedding"
+)
+
+// Duplicate is a pair of near-identical chunks or documents. At document
+// level the chunk fields are empty.
+type Duplicate struct {
+	PathA, PathB   string
+	ChunkA, ChunkB domain.Chunk
+	Similarity     float64
+}
+
+// Duplicates reports chunk pairs (or, with byDocument, document pairs) whose
+// similarity is at least threshold. The minhash method compares word
+// shingles; the embedding method compares vectors by cosine similarity.
+func (s *RAGServiceImpl) Duplicates(method string, threshold float64, byDocument bool) ([]Duplicate, error) {
+	scanner, ok := s.store.(vectorstore.Scanner)
+	if !ok {
+		return nil, ErrScanUnsupported
+	}
+	chunks, vectors, err := scanner.All()
+	if err != nil {
+		return nil, err
+	}
+	chunks, vectors = vectorstore.Live(chunks, vectors)
+	for i := range chunks {
+		s.hydrateChunk(&chunks[i])
+	}
+	// items are the units being compared: single chunks or whole documents.
+	type item struct {
+		path   string
+		chunk  domain.Chunk
+		text   strings.Builder
+		vector []float64
+	}
+	var items []*item
+	if byDocument {
+		byID := make(map[string]*item)
+		for i, ch := range chunks {
+			it, ok := byID[ch.DocumentID]
+			if !ok {
+				it = &item{path: ch.Path, vector: make([]float64, len(vectors[i]))}
+				byID[ch.DocumentID] = it
+				items = append(items, it)
+			}
+			it.text.WriteString(ch.Text)
+			it.text.WriteString(" ")
+			for j, v := range vectors[i] {
+				it.vector[j] += v
+			}
+		}
+	} else {
+		for i, ch := range chunks {
+			it := &item{path: ch.Path, chunk: ch, vector: vectors[i]}
+			it.text.WriteString(ch.Text)
+			items = append(items, it)
+		}
+	}
+
+	var pairs []dedup.Pair
+	switch method {
+	case DupMinHash, "":
+		sigs := make([]dedup.Signature, len(items))
+		for i, it := range items {
+			sigs[i] = dedup.Sign(it.text.String())
+		}
+		pairs = dedup.NearDuplicates(sigs, threshold)
+	case DupEmbedding:
+		for a := range items {
+			for b := a + 1; b < len(items); b++ {
+				if sim := cosine(items[a].vector, items[b].vector); sim >= threshold {
+					pairs = append(pairs, dedup.Pair{A: a, B: b, Similarity: sim})
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown duplicate detection method %q", method)
+	}
+
+	out := make([]Duplicate, 0, len(pairs))
+	for _, p := range pairs {
+		a, b := items[p.A], items[p.B]
+		if strings.TrimSpace(a.text.String()) == "" || strings.TrimSpace(b.text.String()) == "" {
+			continue
+		}
+		out = append(out, Duplicate{PathA: a.path, PathB: b.path, ChunkA: a.chunk, ChunkB: b.chunk, Similarity: p.Similarity})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Similarity > out[j].Similarity })
+	return out, nil
+}
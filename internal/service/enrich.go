@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"rag/internal/domain"
+	"rag/internal/embedding"
+	"rag/internal/i18n"
+)
+
+// Enricher rewrites chunks before they are embedded, e.g. to situate them
+// in their document. The chunks are stored and shown as they are.
+type Enricher interface {
+	// Enrich returns the text to embed for chunk of document.
+	Enrich(ctx context.Context, document domain.Document, chunk domain.Chunk) (string, error)
+	// Fingerprint identifies the enrichment, so that vectors of chunks
+	// enriched otherwise are not reused.
+	Fingerprint() string
+}
+
+// enrich returns the texts to embed for chunks, the i-th of them a chunk of
+// documents[docOf[i]], with up to enrichWorkers chunks enriched at once.
+// Chunks that fail to enrich are embedded as they are, with a warning in
+// result.
+func (s *RAGServiceImpl) enrich(ctx context.Context, documents []domain.Document, docOf []int, chunks []domain.Chunk, report func(stage string, done, total int), result *domain.IngestReport) ([]string, error) {
+	texts := make([]string, len(chunks))
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := range chunks {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		done     int
+		failed   int
+		firstErr error
+	)
+	report(domain.StageEnriching, 0, len(chunks))
+	for w := 0; w < min(s.enrichWorkers, len(chunks)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				text, err := s.enricher.Enrich(ctx, documents[docOf[i]], chunks[i])
+				mu.Lock()
+				if err != nil {
+					text = chunks[i].Text
+					if ctx.Err() == nil {
+						if failed == 0 {
+							firstErr = err
+						}
+						failed++
+					}
+				}
+				texts[i] = text
+				done++
+				report(domain.StageEnriching, done, len(chunks))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if failed > 0 {
+		result.Warnings = append(result.Warnings, i18n.Sprintf("%d chunks could not be enriched and are embedded as they are: %v", failed, firstErr))
+	}
+	return texts, nil
+}
+
+// fingerprint identifies the vectors the service embeds with fp: those of
+// the embedder, of chunks enriched by the configured enricher if any.
+func (s *RAGServiceImpl) fingerprint(fp embedding.Fingerprinter) string {
+	if s.enricher == nil {
+		return fp.Fingerprint()
+	}
+	return fp.Fingerprint() + "+enrich:" + s.enricher.Fingerprint()
+}
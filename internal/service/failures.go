@@ -0,0 +1,157 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"rag/internal/domain"
+	"rag/internal/vectorstore"
+)
+
+// FailedChunk is a chunk whose embedding failed during ingestion.
+type FailedChunk struct {
+	Chunk domain.Chunk `json:"chunk"`
+	// Text is what was embedded in place of the chunk's text, when the
+	// chunk was enriched.
+	Text  string `json:"text,omitempty"`
+	Error string `json:"error"`
+}
+
+// FailedChunks returns the chunks that could not be embedded by the last ingest.
+func (s *RAGServiceImpl) FailedChunks() []FailedChunk {
+	out := make([]FailedChunk, len(s.failed))
+	copy(out, s.failed)
+	return out
+}
+
+// RetryFailed re-embeds the given chunks and upserts those that succeed.
+// The embedder is first prepared with the chunks of sources, the corpus
+// the failed chunks were ingested from, as IngestDocuments prepares it, so
+// that embedders fitted to the corpus such as tfidf embed them like the
+// stored chunks. The store keeps what it holds: it must implement
+// vectorstore.DimensionChecker, and its vectors must be of the embedder's
+// dimension. It returns the chunks that still failed.
+func (s *RAGServiceImpl) RetryFailed(ctx context.Context, sources []Source, failed []FailedChunk) ([]FailedChunk, error) {
+	var (
+		chunks    []domain.Chunk
+		vectors   [][]float64
+		remaining []FailedChunk
+	)
+	corpus, err := s.corpusChunks(ctx, sources)
+	if err != nil {
+		return failed, err
+	}
+	// Failed chunks were embedded enriched, when they were.
+	enriched := make(map[string]string, len(failed))
+	for _, f := range failed {
+		if f.Text != "" {
+			enriched[f.Chunk.ChunkID] = f.Text
+		}
+	}
+	texts := make([]string, len(corpus))
+	for i, ch := range corpus {
+		texts[i] = cmp.Or(enriched[ch.ChunkID], ch.Text)
+	}
+	if err := s.embedder.Prepare(texts); err != nil {
+		return failed, fmt.Errorf("%w: %w", ErrEmbedderUnavailable, err)
+	}
+	dim, err := s.embedderDimension(ctx)
+	if err != nil {
+		return failed, err
+	}
+	checker, ok := s.store.(vectorstore.DimensionChecker)
+	if !ok {
+		return failed, fmt.Errorf("%T cannot take retried chunks", s.store)
+	}
+	if err := checker.CheckDimension(dim); err != nil {
+		return failed, err
+	}
+	s.dimension = dim
+	s.invalidateLexicon()
+	for _, f := range failed {
+		text := f.Chunk.Text
+		if f.Text != "" {
+			text = f.Text
+		}
+		vec, err := s.embedder.Embed(text)
+		if err == nil && len(vec) != dim {
+			err = dimensionError(len(vec), dim)
+		}
+		if err != nil {
+			remaining = append(remaining, FailedChunk{Chunk: f.Chunk, Text: f.Text, Error: err.Error()})
+			continue
+		}
+		chunks = append(chunks, f.Chunk)
+		vectors = append(vectors, vec)
+	}
+	if len(chunks) > 0 {
+		if s.hydrateFromSource {
+			chunks = withoutText(chunks)
+		}
+		if err := s.store.Upsert(chunks, vectors); err != nil {
+			return failed, err
+		}
+	}
+	s.failed = remaining
+	return remaining, nil
+}
+
+func (s *RAGServiceImpl) exceedsFailureThreshold(total int) bool {
+	if len(s.failed) == 0 || total == 0 {
+		return false
+	}
+	if s.failureThreshold < 0 {
+		return true
+	}
+	return float64(len(s.failed))/float64(total) > s.failureThreshold
+}
+
+// SaveFailedChunks writes the failed chunk record to path. An empty list
+// removes any previous record.
+func SaveFailedChunks(path string, failed []FailedChunk) error {
+	if len(failed) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(failed, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadFailedChunks reads a failed chunk record written by SaveFailedChunks.
+func LoadFailedChunks(path string) ([]FailedChunk, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var failed []FailedChunk
+	if err := json.Unmarshal(data, &failed); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return failed, nil
+}
+
+// failedChunk records that embedding text, the text of ch or what it was
+// enriched to, failed with err.
+func (s *RAGServiceImpl) failedChunk(ch domain.Chunk, text string, err error) FailedChunk {
+	f := FailedChunk{Chunk: ch, Error: err.Error()}
+	if text != ch.Text {
+		f.Text = text
+	}
+	return f
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"math"
+	"sort"
+
+	"rag/internal/queryparse"
+	"rag/internal/textutil"
+)
+
+// maxHighlights bounds how many sentences are highlighted per result.
+const maxHighlights = 2
+
+// Highlight returns byte ranges of the sentences in text that best match the
+// query. Sentences are ranked by embedding similarity to the query, so
+// semantic matches are highlighted even without literal token overlap; when
+// the embedder gives no signal, token overlap is used instead, counting
+// inflected and misspelled forms of the query words.
+func (s *RAGServiceImpl) Highlight(query, text string) ([][2]int, error) {
+	spans := textutil.SentenceSpans(text)
+	if len(spans) == 0 {
+		return nil, nil
+	}
+	query = queryparse.Terms(query)
+	scores := make([]float64, len(spans))
+	qvec, err := s.embedder.Embed(query)
+	if err != nil {
+		return nil, err
+	}
+	best := 0.0
+	if norm(qvec) > 0 {
+		for i, sp := range spans {
+			svec, err := s.embedder.Embed(text[sp[0]:sp[1]])
+			if err != nil {
+				return nil, err
+			}
+			scores[i] = cosine(qvec, svec)
+			best = math.Max(best, scores[i])
+		}
+	}
+	if best <= 1e-9 {
+		// Token overlap, tolerant of inflection and spelling variants.
+		m := textutil.NewTermMatcher(query)
+		qn := float64(len(textutil.TokenSet(query)))
+		for i, sp := range spans {
+			sentence := text[sp[0]:sp[1]]
+			if n := len(textutil.TokenSet(sentence)); n > 0 && qn > 0 {
+				scores[i] = float64(m.Count(sentence)) / math.Sqrt(qn*float64(n))
+			}
+			best = math.Max(best, scores[i])
+		}
+	}
+	if best <= 1e-9 {
+		return nil, nil
+	}
+	order := make([]int, len(spans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	var out [][2]int
+	for _, i := range order {
+		// Keep runner-up sentences only when they are nearly as good.
+		if len(out) == maxHighlights || scores[i] < 0.9*best {
+			break
+		}
+		out = append(out, spans[i])
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a][0] < out[b][0] })
+	return out, nil
+}
+
+func cosine(a, b []float64) float64 {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	d := 0.0
+	for i := 0; i < n; i++ {
+		d += a[i] * b[i]
+	}
+	na, nb := norm(a), norm(b)
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return d / (na * nb)
+}
+
+func norm(v []float64) float64 {
+	sum := 0.0
+	for _, x := range v {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"sort"
+
+	"rag/internal/domain"
+)
+
+// defaultRRFK is the usual constant of reciprocal rank fusion, which keeps
+// the top few ranks of either ranking from outweighing the rest.
+const defaultRRFK = 60
+
+// fuse merges rankings by reciprocal rank fusion: a hit at rank r (from 1)
+// of a ranking adds 1/(k+r), and hits are ordered by their sum. Scores are
+// divided by that of a hit ranked first everywhere, so they fall in 0..1.
+// The page at offset of up to limit hits is returned.
+func fuse(k, offset, limit int, rankings ...[]domain.SearchResult) []domain.SearchResult {
+	index := make(map[string]int)
+	var out []domain.SearchResult
+	for _, ranking := range rankings {
+		for rank, hit := range ranking {
+			i, ok := index[hit.Chunk.ChunkID]
+			if !ok {
+				i = len(out)
+				index[hit.Chunk.ChunkID] = i
+				out = append(out, domain.SearchResult{Chunk: hit.Chunk})
+			}
+			out[i].Score += 1 / float64(k+rank+1)
+		}
+	}
+	best := float64(len(rankings)) / float64(k+1)
+	for i := range out {
+		out[i].Score /= best
+	}
+	sort.SliceStable(out, func(a, b int) bool { return out[a].Score > out[b].Score })
+	if offset >= len(out) {
+		return nil
+	}
+	return out[offset:min(offset+limit, len(out))]
+}
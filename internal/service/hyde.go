@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"rag/internal/queryparse"
+)
+
+// errNoHypothesizer is returned for a query asking for HyDE when the
+// service has no language model to draft answers with.
+var errNoHypothesizer = errors.New("hyde:on needs a language model; add an llm section to the config")
+
+// maxDrafts bounds the drafts kept; they are all dropped when it is
+// reached.
+const maxDrafts = 256
+
+// drafts keeps what was drafted for queries, such as hypothetical answers,
+// so that all pages of a query use the same draft, and drafting it costs
+// one request.
+type drafts struct {
+	mu sync.Mutex
+	m  map[string]string
+}
+
+// get returns the draft kept under key, or else the one draft makes.
+func (d *drafts) get(key string, draft func() (string, error)) (string, error) {
+	d.mu.Lock()
+	text, ok := d.m[key]
+	d.mu.Unlock()
+	if ok {
+		return text, nil
+	}
+	text, err := draft()
+	if err != nil {
+		return "", err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.m == nil || len(d.m) >= maxDrafts {
+		d.m = make(map[string]string)
+	}
+	d.m[key] = text
+	return text, nil
+}
+
+// queryText returns the text embedded for q: the query itself, or with
+// HyDE, an answer drafted by the hypothesizer, with boosted terms repeated
+// either way.
+func (s *RAGServiceImpl) queryText(q queryparse.Query) (string, error) {
+	on := s.hyde
+	if q.HyDE != nil {
+		on = *q.HyDE
+	}
+	if !on || q.Text == "" {
+		return embedText(q), nil
+	}
+	if s.hypothesize == nil {
+		return "", errNoHypothesizer
+	}
+	answer, err := s.drafts.get("hyde\x00"+q.Text, func() (string, error) {
+		return s.hypothesize(context.Background(), q.Text)
+	})
+	if err != nil {
+		return "", err
+	}
+	q.Text = answer
+	return embedText(q), nil
+}
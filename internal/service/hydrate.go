@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"rag/internal/domain"
+	"rag/internal/roots"
+)
+
+// readSource re-reads a chunk's text from its source file using the byte
+// offsets recorded at chunking time, if the file is within allowed.
+func readSource(ch domain.Chunk, allowed *roots.Set) (string, error) {
+	if ch.Path == "" || ch.End <= ch.Start {
+		return "", fmt.Errorf("chunk %s has no source location", ch.ChunkID)
+	}
+	// Store payloads name the files to read, and a store written elsewhere
+	// could name any.
+	if err := allowed.Check(ch.Path); err != nil {
+		return "", fmt.Errorf("%s: %w", ch.Path, err)
+	}
+	f, err := os.Open(ch.Path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, ch.End-ch.Start)
+	n, err := f.ReadAt(buf, int64(ch.Start))
+	if n < len(buf) {
+		return "", fmt.Errorf("%s changed since indexing: %w", ch.Path, err)
+	}
+	if !utf8.Valid(buf) {
+		return "", fmt.Errorf("%s changed since indexing", ch.Path)
+	}
+	return string(buf), nil
+}
+
+// hydrate fills in texts of results whose store kept only source locations.
+func (s *RAGServiceImpl) hydrate(results []domain.SearchResult) {
+	for i := range results {
+		s.hydrateChunk(&results[i].Chunk)
+	}
+}
+
+func (s *RAGServiceImpl) hydrateChunk(ch *domain.Chunk) {
+	if ch.Text != "" || ch.Path == "" {
+		return
+	}
+	text, err := readSource(*ch, s.roots)
+	if err != nil {
+		text = "[" + err.Error() + "]"
+	}
+	ch.Text = text
+}
+
+// withoutText returns copies of chunks stripped of their text, leaving only
+// the source location for later hydration. Chunks without a source location
+// (from transformed documents) keep their text.
+func withoutText(chunks []domain.Chunk) []domain.Chunk {
+	out := make([]domain.Chunk, len(chunks))
+	for i, ch := range chunks {
+		if ch.End > ch.Start {
+			ch.Text = ""
+		}
+		out[i] = ch
+	}
+	return out
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"rag/internal/domain"
+	"rag/internal/retriever/bm25"
+	"rag/internal/roots"
+	"rag/internal/textlog"
+	"rag/internal/textutil"
+	"rag/internal/vectorstore"
+)
+
+// lexicalIndex holds the chunks ranked by the lexical fallback, with their
+// texts in memory, in a text log, or re-read from the source files.
+type lexicalIndex struct {
+	mu     sync.RWMutex
+	chunks []domain.Chunk
+	texts  *textlog.Log
+	refs   []textlog.Ref
+	// fromSource keeps only source locations and reads texts back from the
+	// files.
+	fromSource bool
+	// roots confines the files read back.
+	roots *roots.Set
+	// current is set once chunks mirror the store, either because the
+	// service indexed them or because they were read back from it.
+	current bool
+	// bm25 ranks by BM25 with params instead of the weightedOchiai
+	// coefficient.
+	bm25   bool
+	params bm25.Params
+	// inverted is the inverted index of the chunks, built by the first
+	// search after a reset, so that queries only visit the chunks that
+	// contain their terms.
+	inverted *bm25.Index
+}
+
+// reset replaces the indexed chunks, moving their texts to the text log
+// when one is configured.
+func (x *lexicalIndex) reset(chunks []domain.Chunk) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.current = true
+	x.refs = nil
+	x.inverted = nil
+	if x.fromSource {
+		x.chunks = withoutText(chunks)
+		return nil
+	}
+	if x.texts == nil {
+		x.chunks = chunks
+		return nil
+	}
+	if err := x.texts.Reset(); err != nil {
+		x.chunks = nil
+		return err
+	}
+	x.chunks = make([]domain.Chunk, len(chunks))
+	x.refs = make([]textlog.Ref, len(chunks))
+	for i, ch := range chunks {
+		ref, err := x.texts.Append(ch.Text)
+		if err != nil {
+			x.chunks, x.refs = nil, nil
+			return err
+		}
+		ch.Text = ""
+		x.chunks[i] = ch
+		x.refs[i] = ref
+	}
+	return nil
+}
+
+// text returns the text of the i-th chunk; x.mu must be held.
+func (x *lexicalIndex) text(i int) string {
+	if x.fromSource {
+		text, err := readSource(x.chunks[i], x.roots)
+		if err != nil {
+			return ""
+		}
+		return text
+	}
+	if x.texts == nil {
+		return x.chunks[i].Text
+	}
+	text, err := x.texts.Read(x.refs[i])
+	if err != nil {
+		return ""
+	}
+	return text
+}
+
+// chunk returns the i-th chunk with its text; x.mu must be held.
+func (x *lexicalIndex) chunk(i int) domain.Chunk {
+	ch := x.chunks[i]
+	ch.Text = x.text(i)
+	return ch
+}
+
+// all returns the chunks accepted by keep, with their texts.
+func (x *lexicalIndex) all(keep func(domain.Chunk) bool) []domain.Chunk {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	var out []domain.Chunk
+	for i := range x.chunks {
+		if keep(x.chunks[i]) {
+			out = append(out, x.chunk(i))
+		}
+	}
+	return out
+}
+
+// tokenize builds the inverted index of the chunks, unless it is built
+// already.
+func (x *lexicalIndex) tokenize() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.inverted != nil {
+		return
+	}
+	x.inverted = bm25.New(x.params)
+	for i := range x.chunks {
+		x.inverted.Add(textutil.Tokens(x.text(i)))
+	}
+}
+
+// search ranks the chunks by their lexical score for the query: the
+// weightedOchiai coefficient, or BM25 divided by the best score of the
+// query. Only the postings of the query terms are visited; chunks without
+// any query term follow the hits in index order with score 0.
+func (x *lexicalIndex) search(weights map[string]float64, offset, topK int, filter vectorstore.Filter) []domain.SearchResult {
+	x.tokenize()
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if topK <= 0 {
+		topK = 5
+	}
+	var scores []float64
+	var total float64
+	if x.bm25 {
+		scores = x.inverted.Scores(weights)
+	} else {
+		scores = make([]float64, len(x.chunks))
+		// In a fixed order, so that sums and hence ties do not vary with
+		// map iteration.
+		terms := make([]string, 0, len(weights))
+		for t := range weights {
+			terms = append(terms, t)
+		}
+		sort.Strings(terms)
+		for _, t := range terms {
+			w := weights[t]
+			total += w
+			for _, p := range x.inverted.Postings(t) {
+				scores[p.Doc] += w
+			}
+		}
+	}
+	var hits []int
+	var best float64
+	for i, score := range scores {
+		if score <= 0 || !filter.Match(x.chunks[i]) {
+			continue
+		}
+		if x.bm25 {
+			best = max(best, score)
+		} else {
+			scores[i] = score / (math.Sqrt(total) * math.Sqrt(float64(x.inverted.Distinct(i))))
+		}
+		hits = append(hits, i)
+	}
+	if best > 0 {
+		for _, i := range hits {
+			scores[i] /= best
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return scores[hits[i]] > scores[hits[j]] })
+
+	out := make([]domain.SearchResult, 0, topK)
+	for _, i := range hits[min(offset, len(hits)):] {
+		if len(out) == topK {
+			return out
+		}
+		out = append(out, domain.SearchResult{Chunk: x.chunk(i), Score: scores[i]})
+	}
+	skip := max(offset-len(hits), 0)
+	for i := range x.chunks {
+		if len(out) == topK {
+			break
+		}
+		if scores[i] > 0 || !filter.Match(x.chunks[i]) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		out = append(out, domain.SearchResult{Chunk: x.chunk(i)})
+	}
+	return out
+}
+
+// keepChunks makes chunks the lexical fallback's corpus after they were
+// indexed.
+func (s *RAGServiceImpl) keepChunks(chunks []domain.Chunk) error {
+	s.oldest, s.newest = dateRange(chunks)
+	return s.lexical.reset(chunks)
+}
+
+// lexicon returns the lexical index, first reading it back from the store
+// when the service has not indexed the store's current contents itself: a
+// collection written by an earlier run or another pipeline, or one changed
+// by RetryFailed. The documents list and the index dimension are derived
+// from the stored chunks too when no ingest set them. Stores that cannot
+// enumerate their points leave the index as the last ingest made it.
+func (s *RAGServiceImpl) lexicon() (*lexicalIndex, error) {
+	s.lexicalMu.Lock()
+	defer s.lexicalMu.Unlock()
+	s.lexical.mu.RLock()
+	current := s.lexical.current
+	s.lexical.mu.RUnlock()
+	if current {
+		return s.lexical, nil
+	}
+	scanner, ok := s.store.(vectorstore.Scanner)
+	if !ok {
+		s.lexical.mu.Lock()
+		s.lexical.current = true
+		s.lexical.mu.Unlock()
+		return s.lexical, nil
+	}
+	chunks, vectors, err := scanner.All()
+	if err != nil {
+		return nil, err
+	}
+	chunks, vectors = vectorstore.Live(chunks, vectors)
+	if err := s.keepChunks(chunks); err != nil {
+		return nil, err
+	}
+	if len(s.documents) == 0 {
+		s.documents = storedDocuments(chunks)
+	}
+	if s.dimension == 0 && len(vectors) > 0 {
+		s.dimension = len(vectors[0])
+	}
+	return s.lexical, nil
+}
+
+// invalidateLexicon marks the lexical index as out of date with the store,
+// so that its next use reads the store back.
+func (s *RAGServiceImpl) invalidateLexicon() {
+	s.lexical.mu.Lock()
+	defer s.lexical.mu.Unlock()
+	s.lexical.current = false
+}
+
+// storedDocuments lists the documents of chunks read back from a store, in
+// order of first appearance. Keywords are only known after an ingest.
+func storedDocuments(chunks []domain.Chunk) []domain.DocumentInfo {
+	index := make(map[string]int)
+	var out []domain.DocumentInfo
+	for _, ch := range chunks {
+		i, ok := index[ch.DocumentID]
+		if !ok {
+			i = len(out)
+			index[ch.DocumentID] = i
+			out = append(out, domain.DocumentInfo{ID: ch.DocumentID, Path: ch.Path, Title: ch.Metadata["title"]})
+		}
+		out[i].Chunks++
+	}
+	return out
+}
@@ -0,0 +1,25 @@
+package service
+
+import "rag/internal/domain"
+
+// Links returns the notes that a document links to and the notes linking
+// back to it.
+func (s *RAGServiceImpl) Links(documentID string) (links, backlinks []domain.DocumentInfo) {
+	if s.links == nil {
+		return nil, nil
+	}
+	return s.documentInfos(s.links.Links(documentID)), s.documentInfos(s.links.Backlinks(documentID))
+}
+
+func (s *RAGServiceImpl) documentInfos(ids []string) []domain.DocumentInfo {
+	out := make([]domain.DocumentInfo, 0, len(ids))
+	for _, id := range ids {
+		for _, d := range s.documents {
+			if d.ID == id {
+				out = append(out, d)
+				break
+			}
+		}
+	}
+	return out
+}
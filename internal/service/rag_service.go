@@ -1,20 +1,39 @@
 package service
 
 import (
-	"crypto/sha1"
-	"encoding/hex"
+	"context"
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
-	"regexp"
-	"sort"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"rag/internal/domain"
 	"rag/internal/embedding"
+	"rag/internal/i18n"
+	"rag/internal/keywords"
+	"rag/internal/langdetect"
+	"rag/internal/linkgraph"
+	"rag/internal/loader"
+	"rag/internal/queryparse"
+	"rag/internal/retriever/bm25"
+	"rag/internal/roots"
+	"rag/internal/suggest"
+	"rag/internal/textlog"
 	"rag/internal/vectorstore"
 )
 
+// Errors returned by ingests; callers test for them with errors.Is.
+var (
+	// ErrNoDocuments means no source yielded a document any loader reads.
+	ErrNoDocuments = errors.New("no supported documents found")
+	// ErrEmbedderUnavailable means the embedder could not be prepared or
+	// failed on (nearly) every chunk, e.g. because its server is down.
+	ErrEmbedderUnavailable = errors.New("embedder unavailable")
+)
+
 // RAGServiceImpl orchestrates chunking, embedding, storage, and summarization.
 type RAGServiceImpl struct {
 	chunker domain.Chunker
@@ -22,205 +41,1044 @@ type RAGServiceImpl struct {
 	embedder            embedding.Embedder
 	store               vectorstore.Storage
 	summarizer          domain.Summarizer
-	summaryMaxSentences int
-	chunks              []domain.Chunk
+	summaryBudget       domain.Budget
+	failureThreshold    float64
+	hydrateFromSource   bool
+	detectLanguage      bool
+	embedWorkers        int
+	enricher            Enricher
+	enrichWorkers       int
+	softDelete          bool
+	keywordPayloads     bool
+	keywordsPerDocument int
+	topicCount          int
+	maxPerDocument      int
+	hybrid              bool
+	bm25Only            bool
+	hyde                bool
+	hypothesize         func(ctx context.Context, query string) (string, error)
+	decompose           func(ctx context.Context, query string) ([]string, error)
+	drafts              drafts
+	rrfK                int
+	failed              []FailedChunk
+	documents           []domain.DocumentInfo
+	corpusKeywords      []string
+	completions         *suggest.Index
+	loaders             *loader.Registry
+	links               *linkgraph.Graph
+	scorer              Scorer
+	// lexical holds the chunks for lexical fallback; lexicalMu serializes
+	// reading them back from the store.
+	lexical   *lexicalIndex
+	lexicalMu sync.Mutex
+	// oldest and newest bound the dates of the kept chunks.
+	oldest, newest time.Time
+	// dimension is that of the indexed vectors; 0 before an ingest.
+	dimension int
+	roots     *roots.Set
+}
+
+// Config holds tunables of the RAG service.
+type Config struct {
+	// SummaryBudget bounds the corpus summary in sentences, words or tokens.
+	SummaryBudget domain.Budget
+	// FailureThreshold is the fraction of chunks allowed to fail embedding
+	// before IngestDocuments gives up. Negative values mean no failures allowed.
+	FailureThreshold float64
+	// TextLog, when set, holds chunk texts for lexical fallback on disk
+	// instead of in memory. The service resets it on every ingest.
+	TextLog *textlog.Log
+	// HydrateFromSource keeps only source paths and offsets in the store and
+	// in memory; result texts are re-read from the files when returned.
+	HydrateFromSource bool
+	// KeywordsPerDocument is how many keywords are extracted per document.
+	KeywordsPerDocument int
+	// KeywordPayloads attaches the document keywords to every chunk so that
+	// stores can filter on them.
+	KeywordPayloads bool
+	// TopicCount is the number of clusters built by Topics (0 = automatic).
+	TopicCount int
+	// MaxPerDocument caps how many chunks of one document a query returns
+	// (0 = no cap).
+	MaxPerDocument int
+	// Loaders converts files into documents; nil selects the built-in
+	// loaders with default settings.
+	Loaders *loader.Registry
+	// LinkBoost raises the scores of notes with many wiki-link backlinks;
+	// 0 disables the boost. It is the Links weight of the default scorer.
+	LinkBoost float64
+	// LexicalBM25 ranks the lexical fallback by BM25 rather than by the
+	// weighted overlap of query and chunk terms.
+	LexicalBM25 bool
+	// Hybrid always ranks the chunks by BM25 besides vector search and
+	// merges the two rankings by reciprocal rank fusion, rather than
+	// ranking lexically only the queries without a vector signal. The
+	// lexical ranking is BM25 whatever LexicalBM25 says.
+	Hybrid bool
+	// BM25Only ranks every query by BM25 alone, without embedding it.
+	// Chunks are still embedded, for find-similar, topics and duplicates.
+	BM25Only bool
+	// BM25 sets the parameters of the BM25 ranking; nil selects
+	// bm25.DefaultParams.
+	BM25 *bm25.Params
+	// RRFK is the constant k of the fusion, where a hit at rank r of a
+	// ranking adds 1/(k+r); 0 means 60.
+	RRFK int
+	// DetectLanguage tags documents whose source sets no language with the
+	// language detected in their content.
+	DetectLanguage bool
+	// SoftDelete keeps the chunks of documents gone from the corpus in
+	// the store, left out of searches, rather than dropping them, so that
+	// their vectors are reused should they return. Purge drops them.
+	SoftDelete bool
+	// EmbedWorkers is how many requests to an embedder that is not local
+	// (one without EmbedInto) an ingest makes at once; 0 or 1 makes them
+	// one after another. The embedder must be safe for concurrent use.
+	EmbedWorkers int
+	// HyDE embeds, for every query, an answer drafted by Hypothesize
+	// instead of the query (hypothetical document embeddings); queries
+	// override it with hyde:on and hyde:off.
+	HyDE bool
+	// Hypothesize drafts a passage answering query, for HyDE; nil makes
+	// queries asking for HyDE fail.
+	Hypothesize func(ctx context.Context, query string) (string, error)
+	// Decompose splits a query into the questions it asks; when it finds
+	// several, each is searched alone and the rankings are merged by
+	// reciprocal rank fusion. nil searches queries whole.
+	Decompose func(ctx context.Context, query string) ([]string, error)
+	// Enricher rewrites chunks before they are embedded; nil embeds them
+	// as they are.
+	Enricher Enricher
+	// EnrichWorkers is how many chunks are enriched at once (0 = one at
+	// a time). The enricher must be safe for concurrent use.
+	EnrichWorkers int
+	// Scorer computes the final score of each hit; nil scores by vector
+	// similarity (or lexical overlap when the query has no vector signal)
+	// with the link boost.
+	Scorer Scorer
+	// Roots confines the files ingested and read back to their
+	// directories, and rules out URL sources; nil allows any file.
+	Roots *roots.Set
 }
 
 // NewRAGService constructs a new RAG service instance with the provided components.
-func NewRAGService(chunker domain.Chunker, embedder embedding.Embedder, store vectorstore.Storage, summarizer domain.Summarizer, summaryMaxSentences int) *RAGServiceImpl {
-	return &RAGServiceImpl{chunker: chunker, embedder: embedder, store: store, summarizer: summarizer, summaryMaxSentences: summaryMaxSentences}
+func NewRAGService(chunker domain.Chunker, embedder embedding.Embedder, store vectorstore.Storage, summarizer domain.Summarizer, cfg Config) *RAGServiceImpl {
+	if cfg.KeywordsPerDocument <= 0 {
+		cfg.KeywordsPerDocument = 10
+	}
+	if cfg.Loaders == nil {
+		cfg.Loaders = loader.Default(loader.Config{})
+	}
+	if cfg.Scorer == nil {
+		cfg.Scorer = WeightedScorer{Vector: 1, Links: cfg.LinkBoost}
+	}
+	if cfg.RRFK <= 0 {
+		cfg.RRFK = defaultRRFK
+	}
+	params := bm25.DefaultParams()
+	if cfg.BM25 != nil {
+		params = *cfg.BM25
+	}
+	return &RAGServiceImpl{
+		chunker:             chunker,
+		embedder:            embedder,
+		store:               store,
+		summarizer:          summarizer,
+		summaryBudget:       cfg.SummaryBudget,
+		failureThreshold:    cfg.FailureThreshold,
+		hydrateFromSource:   cfg.HydrateFromSource,
+		detectLanguage:      cfg.DetectLanguage,
+		embedWorkers:        max(1, cfg.EmbedWorkers),
+		enricher:            cfg.Enricher,
+		enrichWorkers:       max(1, cfg.EnrichWorkers),
+		softDelete:          cfg.SoftDelete,
+		keywordPayloads:     cfg.KeywordPayloads,
+		keywordsPerDocument: cfg.KeywordsPerDocument,
+		topicCount:          cfg.TopicCount,
+		maxPerDocument:      cfg.MaxPerDocument,
+		hybrid:              cfg.Hybrid,
+		bm25Only:            cfg.BM25Only,
+		hyde:                cfg.HyDE,
+		hypothesize:         cfg.Hypothesize,
+		decompose:           cfg.Decompose,
+		rrfK:                cfg.RRFK,
+		loaders:             cfg.Loaders,
+		scorer:              cfg.Scorer,
+		roots:               cfg.Roots,
+		lexical:             &lexicalIndex{texts: cfg.TextLog, fromSource: cfg.HydrateFromSource, roots: cfg.Roots, bm25: cfg.LexicalBM25 || cfg.Hybrid || cfg.BM25Only, params: params},
+	}
 }
 
-// IngestDocuments loads `.txt` files, chunks, embeds, indexes, and summarizes them.
-func (s *RAGServiceImpl) IngestDocuments(paths []string) (string, error) {
-	var documents []domain.Document
-	for _, p := range paths {
-		matches, _ := filepath.Glob(p)
-		if matches == nil {
-			matches = []string{p}
-		}
-		for _, m := range matches {
-			if !strings.HasSuffix(strings.ToLower(m), ".txt") && !strings.HasSuffix(strings.ToLower(m), ".md") {
-				continue
-			}
-			data, err := os.ReadFile(m)
-			if err != nil {
-				return "", err
-			}
-			id := hashString(m)
-			documents = append(documents, domain.Document{ID: id, Path: m, Content: string(data)})
+// ingestBatchSize is how many embedded chunks are upserted at a time, so
+// that a canceled ingest keeps what it has indexed.
+const ingestBatchSize = 64
+
+// ingestBatch is a batch of embedded chunks on its way to the store.
+type ingestBatch struct {
+	chunks  []domain.Chunk
+	vectors [][]float64
+}
+
+// cancelGrace bounds the final upsert of an ingest after cancellation.
+const cancelGrace = 10 * time.Second
+
+// IngestDocuments loads files through the loader registry, chunks, embeds, indexes, and summarizes them.
+func (s *RAGServiceImpl) IngestDocuments(paths []string) (domain.IngestReport, error) {
+	return s.IngestDocumentsContext(context.Background(), paths, nil)
+}
+
+// IngestDocumentsContext is IngestDocuments with cancellation and progress
+// reports. Cancellation is passed on to embedders and stores that take a
+// context. When ctx is canceled while embedding, the chunks embedded so far
+// stay indexed and queryable, no summary is made, and the returned error
+// wraps ctx.Err(); the report then counts what was indexed.
+func (s *RAGServiceImpl) IngestDocumentsContext(ctx context.Context, paths []string, progress func(domain.IngestProgress)) (domain.IngestReport, error) {
+	sources := make([]Source, len(paths))
+	for i, p := range paths {
+		sources[i] = Source{Pattern: p}
+	}
+	return s.IngestSources(ctx, sources, progress)
+}
+
+// Source is one entry of a corpus, such as a line of a corpus manifest:
+// files matching a pattern, or a URL, with per-source settings.
+type Source struct {
+	// Pattern is a file path or glob pattern.
+	Pattern string
+	// URL, when set, is fetched instead of reading files.
+	URL string
+	// Tags are added to the tags metadata of the source's documents.
+	Tags []string
+	// Loader is the extension whose loaders parse the source (e.g. ".md");
+	// empty uses the file extension.
+	Loader string
+	// Chunker splits the source's documents instead of the service chunker.
+	Chunker domain.Chunker
+	// Language is the language of the source's documents, stored in their
+	// "lang" metadata; empty leaves it to detection, if enabled.
+	Language string
+}
+
+// IngestSources is IngestDocumentsContext over sources with their own
+// loaders, chunkers and tags.
+func (s *RAGServiceImpl) IngestSources(ctx context.Context, sources []Source, progress func(domain.IngestProgress)) (domain.IngestReport, error) {
+	start := time.Now()
+	var result domain.IngestReport
+	report := func(stage string, done, total int) {
+		if progress != nil {
+			progress(domain.IngestProgress{Stage: stage, Done: done, Total: total})
 		}
 	}
+	documents, chunkers, unread, err := s.load(ctx, sources, report)
+	if err != nil {
+		return result, err
+	}
 	if len(documents) == 0 {
-		return "", fmt.Errorf("no .txt/.md documents found")
+		return result, fmt.Errorf("%w (%s)", ErrNoDocuments, strings.Join(s.loaders.Extensions(), ", "))
 	}
+	if len(unread) > 0 {
+		result.Warnings = append(result.Warnings, unreadWarning(unread))
+	}
+	// Extract keywords
+	contents := make([]string, len(documents))
+	for i, d := range documents {
+		contents[i] = d.Content
+	}
+	kw := keywords.NewExtractor(contents)
+	infos := make([]domain.DocumentInfo, len(documents))
 	// Chunk
 	var allChunks []domain.Chunk
 	var allTexts []string
+	// docOf is the index of each chunk's document.
+	var docOf []int
 	var allTextConcat strings.Builder
-	for _, d := range documents {
-		chunks, err := s.chunker.Chunk(d)
+	for i, d := range documents {
+		chunks, err := s.chunkDocument(d, chunkers[i])
 		if err != nil {
-			return "", err
+			return result, err
 		}
+		docKeywords := kw.Document(i, s.keywordsPerDocument)
+		infos[i] = domain.DocumentInfo{ID: d.ID, Path: d.Path, Title: d.Title, Chunks: len(chunks), Keywords: docKeywords}
 		for _, ch := range chunks {
+			if s.keywordPayloads {
+				ch.Keywords = docKeywords
+			}
 			allChunks = append(allChunks, ch)
 			allTexts = append(allTexts, ch.Text)
+			docOf = append(docOf, i)
 		}
 		allTextConcat.WriteString("\n")
 		allTextConcat.WriteString(d.Content)
 	}
-	// Keep chunks for fallback ranking
-	s.chunks = allChunks
+	// adopt makes the new corpus the one the service answers from, once the
+	// store is about to hold it: an ingest that fails before then leaves the
+	// documents, links and lexical fallback of the previous one.
+	adopt := func() error {
+		s.links = linkgraph.Build(documents)
+		s.completions = suggest.NewIndex(contents)
+		s.corpusKeywords = kw.Corpus(s.keywordsPerDocument)
+		s.documents = infos
+		// Keep chunks for fallback ranking
+		return s.keepChunks(allChunks)
+	}
+	if s.enricher != nil {
+		// The chunks are stored as they are, but embedded enriched.
+		if allTexts, err = s.enrich(ctx, documents, docOf, allChunks, report, &result); err != nil {
+			return result, err
+		}
+		for i, text := range allTexts {
+			if text != allChunks[i].Text {
+				allChunks[i].Embedded = embeddedDigest(text)
+			}
+		}
+	}
 	// Prepare embedder with corpus
 	if err := s.embedder.Prepare(allTexts); err != nil {
-		return "", err
+		return result, fmt.Errorf("%w: %w", ErrEmbedderUnavailable, err)
 	}
 
-	// Reset store; we'll initialize it once we know the embedding dimension
+	// Learn the embedding dimension before touching the store, so a failing
+	// embedder leaves the previous index intact.
+	dim, err := s.embedderDimension(ctx)
+	if err != nil {
+		return result, fmt.Errorf("%w: %w", ErrEmbedderUnavailable, err)
+	}
+	// The chunks of documents gone from the corpus are stored again after
+	// the others, soft-deleted.
+	tombstoned, err := s.tombstones(allChunks)
+	if err != nil {
+		return result, err
+	}
+	toStore, texts := allChunks, allTexts
+	if len(tombstoned) > 0 {
+		toStore = append(slices.Clip(allChunks), tombstoned...)
+		texts = slices.Clip(texts)
+		for _, ch := range tombstoned {
+			texts = append(texts, ch.Text)
+		}
+	}
+	result.Deleted = len(tombstoned)
+	stamp, current, reuse, err := s.storedVectors(toStore, dim)
+	if err != nil {
+		return result, err
+	}
+	s.dimension = dim
+	s.failed = nil
+	if current {
+		// The store already holds these chunks, embedded by this embedder.
+		if err := adopt(); err != nil {
+			return result, err
+		}
+		return s.finishIngest(result, start, contents, allTextConcat.String(), len(allChunks), len(allChunks), report)
+	}
 	if err := s.store.Clear(); err != nil {
-		return "", err
+		return result, err
+	}
+	if err := s.store.Init(dim); err != nil {
+		return result, err
+	}
+	if err := adopt(); err != nil {
+		return result, err
+	}
+	if stamp.Embedder != "" {
+		// Stamp the embedder now, so that the vectors of a canceled
+		// ingest can be reused by the next one.
+		if err := s.store.(vectorstore.Persistent).SetStamp(vectorstore.Stamp{Embedder: stamp.Embedder}); err != nil {
+			return result, err
+		}
 	}
+	if r, ok := s.store.(vectorstore.Reserver); ok {
+		r.Reserve(len(toStore))
+	}
+
+	// Embed and upsert in batches; chunks that fail are recorded instead of
+	// aborting, as long as the failure ratio stays within the configured
+	// threshold. Chunks whose text the store already holds a vector for
+	// are not embedded again. Each batch is upserted in the background
+	// while the next one embeds. Embedders that write into a buffer fill one backing array
+	// per batch, alternating between two: a batch's array is in use until
+	// its upsert returns, and stores copy what they keep.
+	var (
+		indexed  []domain.Chunk
+		chunks   []domain.Chunk
+		vectors  [][]float64
+		canceled bool
 
-	// Embed and upsert
-	vectors := make([][]float64, len(allChunks))
-	initialized := false
-	for i := range allChunks {
-		vec, err := s.embedder.Embed(allChunks[i].Text)
+		backing, spare []float64
+		// upserting is the batch being upserted, kept until it is stored;
+		// done delivers the result of its upsert.
+		upserting ingestBatch
+		done      chan error
+	)
+	into, _ := s.embedder.(embedding.IntoEmbedder)
+	// Embedders that take several texts per request, or several requests
+	// at once, embed the chunks ahead of the loop.
+	batcher, _ := s.embedder.(embedding.BatchEmbedder)
+	lookahead := batcher != nil || s.embedWorkers > 1
+	var ahead map[int]embedded
+	if into != nil {
+		backing = make([]float64, min(ingestBatchSize, len(toStore))*dim)
+		spare = make([]float64, len(backing))
+	}
+	store := func(ctx context.Context, b ingestBatch) error {
+		stored := b.chunks
+		if s.hydrateFromSource {
+			stored = withoutText(b.chunks)
+		}
+		return s.upsert(ctx, stored, b.vectors)
+	}
+	// wait waits for the upsert in progress and counts its chunks as indexed
+	// once they are stored.
+	wait := func() error {
+		if done == nil {
+			return nil
+		}
+		err := <-done
+		done = nil
+		if err != nil {
+			return err
+		}
+		indexed = append(indexed, upserting.chunks...)
+		upserting = ingestBatch{}
+		return nil
+	}
+	defer func() { _ = wait() }()
+	// flush starts upserting the embedded chunks once the previous batch is
+	// stored.
+	flush := func(ctx context.Context) error {
+		if len(chunks) == 0 {
+			return nil
+		}
+		if err := wait(); err != nil {
+			return err
+		}
+		upserting = ingestBatch{chunks: chunks, vectors: vectors}
+		stored := make(chan error, 1)
+		go func(b ingestBatch) { stored <- store(ctx, b) }(upserting)
+		done = stored
+		chunks, vectors = nil, nil
+		backing, spare = spare, backing
+		return nil
+	}
+	for i := range toStore {
+		if ctx.Err() != nil {
+			canceled = true
+			break
+		}
+		report(domain.StageEmbedding, i, len(toStore))
+		var vec []float64
+		if v, ok := reuse[reuseKey(toStore[i])]; ok {
+			vec = v
+		} else if into != nil {
+			n := len(vectors)
+			vec = backing[n*dim : (n+1)*dim : (n+1)*dim]
+			err = into.EmbedInto(texts[i], vec)
+		} else if lookahead {
+			e, ok := ahead[i]
+			if !ok {
+				ahead = s.embedAhead(ctx, batcher, toStore, texts, i, reuse)
+				e = ahead[i]
+			}
+			vec, err = e.vector, e.err
+		} else {
+			vec, err = s.embed(ctx, texts[i])
+		}
 		if err != nil {
-			return "", err
+			if ctx.Err() != nil {
+				canceled = true
+				break
+			}
+			s.failed = append(s.failed, s.failedChunk(toStore[i], texts[i], err))
+			if s.exceedsFailureThreshold(len(toStore)) {
+				return result, fmt.Errorf("%w: embedding failed for %d of %d chunks, last error: %w", ErrEmbedderUnavailable, len(s.failed), len(toStore), err)
+			}
+			continue
+		}
+		if len(vec) != dim {
+			s.failed = append(s.failed, s.failedChunk(toStore[i], texts[i], dimensionError(len(vec), dim)))
+			if s.exceedsFailureThreshold(len(toStore)) {
+				return result, fmt.Errorf("%w: embedding failed for %d of %d chunks: inconsistent embedding dimension", ErrEmbedderUnavailable, len(s.failed), len(toStore))
+			}
+			continue
 		}
-		vectors[i] = vec
-		if !initialized {
-			if err := s.store.Init(len(vec)); err != nil {
-				return "", err
+		chunks = append(chunks, toStore[i])
+		vectors = append(vectors, vec)
+		if len(chunks) >= ingestBatchSize {
+			if err := flush(ctx); err != nil {
+				if ctx.Err() == nil {
+					return result, err
+				}
+				canceled = true
+				break
 			}
-			initialized = true
 		}
 	}
-	if !initialized {
-		return "", fmt.Errorf("no vectors produced")
+	if canceled {
+		// Keep what was embedded: store the batch whose upsert the
+		// cancellation aborted and the last batch, and narrow the lexical
+		// fallback to the indexed chunks.
+		grace, cancel := context.WithTimeout(context.WithoutCancel(ctx), cancelGrace)
+		defer cancel()
+		_ = wait()
+		if len(upserting.chunks) > 0 {
+			if err := store(grace, upserting); err != nil {
+				return result, err
+			}
+			indexed = append(indexed, upserting.chunks...)
+			upserting = ingestBatch{}
+		}
+		if err := flush(grace); err != nil {
+			return result, err
+		}
+		if err := wait(); err != nil {
+			return result, err
+		}
+		live, _ := vectorstore.Live(indexed, nil)
+		if err := s.keepChunks(live); err != nil {
+			return result, err
+		}
+		s.documents = indexedDocuments(s.documents, live)
+		result.Documents, result.Chunks, result.Skipped = len(s.documents), len(live), len(s.failed)
+		result.Deleted = len(indexed) - len(live)
+		result.Duration = time.Since(start)
+		return result, fmt.Errorf("ingest canceled after %d of %d chunks: %w", len(indexed), len(toStore), ctx.Err())
+	}
+	if err := flush(ctx); err != nil {
+		return result, err
 	}
-	if err := s.store.Upsert(allChunks, vectors); err != nil {
-		return "", err
+	if err := wait(); err != nil {
+		return result, err
+	}
+	if len(indexed) == 0 {
+		return result, fmt.Errorf("%w: no vectors produced", ErrEmbedderUnavailable)
+	}
+	if stamp.Embedder != "" {
+		stamp.Contents = s.contentsDigest(indexed)
+		if err := s.store.(vectorstore.Persistent).SetStamp(stamp); err != nil {
+			return result, err
+		}
+	}
+	live, _ := vectorstore.Live(indexed, nil)
+	result.Deleted = len(indexed) - len(live)
+	return s.finishIngest(result, start, contents, allTextConcat.String(), len(live), len(allChunks), report)
+}
+
+// finishIngest summarizes the corpus, given as its documents and as one
+// text, and completes the report of an ingest that indexed chunks of total.
+func (s *RAGServiceImpl) finishIngest(result domain.IngestReport, start time.Time, contents []string, text string, chunks, total int, report func(stage string, done, total int)) (domain.IngestReport, error) {
+	report(domain.StageSummarizing, total, total)
+	// Summarize, document by document when the summarizer supports it
+	var err error
+	if cs, ok := s.summarizer.(domain.CorpusSummarizer); ok {
+		result.Summary, err = cs.SummarizeCorpus(contents, s.summaryBudget)
+	} else {
+		result.Summary, err = s.summarizer.Summarize(text, s.summaryBudget)
 	}
-	// Summarize
-	summary, err := s.summarizer.Summarize(allTextConcat.String(), s.summaryMaxSentences)
 	if err != nil {
-		return "", err
+		return result, err
 	}
-	return summary, nil
+	result.Documents, result.Chunks, result.Skipped = len(s.documents), chunks, len(s.failed)
+	result.Duration = time.Since(start)
+	return result, nil
 }
 
-// Query embeds the query and searches the vector store, falling back to lexical when needed.
-func (s *RAGServiceImpl) Query(query string, topK int) ([]domain.SearchResult, error) {
-	vec, err := s.embedder.Embed(query)
+// unreadWarning describes files that matched a source but that no loader
+// reads.
+func unreadWarning(paths []string) string {
+	const shown = 3
+	list := strings.Join(paths[:min(len(paths), shown)], ", ")
+	if len(paths) > shown {
+		list += i18n.Sprintf(" and %d more", len(paths)-shown)
+	}
+	return i18n.Sprintf("%d files skipped, no loader reads them: %s", len(paths), list)
+}
+
+// dimensionProbe is embedded to learn the dimension of embedders that only
+// know it after their first request.
+const dimensionProbe = "dimension probe"
+
+// embedderDimension returns the embedder's vector dimension, embedding a
+// probe text when the embedder does not know it yet.
+func (s *RAGServiceImpl) embedderDimension(ctx context.Context) (int, error) {
+	if d := s.embedder.Dimension(); d > 0 {
+		return d, nil
+	}
+	vec, err := s.embed(ctx, dimensionProbe)
 	if err != nil {
-		return nil, err
+		return 0, fmt.Errorf("probing embedding dimension: %w", err)
 	}
-	// Detect zero vector (no tokens)
-	zero := true
-	for _, v := range vec {
-		if v != 0 {
-			zero = false
-			break
+	if len(vec) == 0 {
+		return 0, errors.New("embedder returned an empty vector")
+	}
+	return len(vec), nil
+}
+
+// load reads the documents of sources, returning with each the chunker its
+// source sets (nil when it sets none), and the matched files that no loader
+// reads.
+func (s *RAGServiceImpl) load(ctx context.Context, sources []Source, report func(stage string, done, total int)) ([]domain.Document, []domain.Chunker, []string, error) {
+	var documents []domain.Document
+	var chunkers []domain.Chunker
+	var unread []string
+	add := func(docs []domain.Document, src Source) {
+		for _, d := range docs {
+			if len(src.Tags) > 0 {
+				d.Metadata = withTags(d.Metadata, src.Tags)
+			}
+			if lang := s.documentLanguage(d, src); lang != "" {
+				d.Metadata = withLanguage(d.Metadata, lang)
+			}
+			documents = append(documents, d)
+			chunkers = append(chunkers, src.Chunker)
 		}
 	}
-	if zero {
-		return s.lexicalSearch(query, topK), nil
+	for _, src := range sources {
+		if src.URL != "" {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, nil, err
+			}
+			if s.roots != nil {
+				return nil, nil, nil, fmt.Errorf("load %s: URL sources are not allowed with allowed roots", src.URL)
+			}
+			report(domain.StageLoading, len(documents), 0)
+			docs, ok, err := s.loaders.LoadURL(ctx, src.URL, src.Loader)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("load %s: %w", src.URL, err)
+			}
+			if !ok {
+				return nil, nil, nil, fmt.Errorf("load %s: no loader for this content", src.URL)
+			}
+			add(docs, src)
+			continue
+		}
+		for _, m := range loader.Expand(src.Pattern) {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, nil, err
+			}
+			if err := s.roots.Check(m); err != nil {
+				return nil, nil, nil, fmt.Errorf("load %s: %w", m, err)
+			}
+			ext := src.Loader
+			if ext == "" {
+				ext = filepath.Ext(m)
+			}
+			report(domain.StageLoading, len(documents), 0)
+			docs, ok, err := s.loaders.LoadAs(m, ext)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("load %s: %w", m, err)
+			}
+			if !ok {
+				unread = append(unread, m)
+				continue
+			}
+			add(docs, src)
+		}
 	}
-	res, err := s.store.Search(vec, topK)
+	return documents, chunkers, unread, nil
+}
+
+// ChunkSources loads and chunks sources without embedding or indexing them,
+// e.g. to export a corpus.
+func (s *RAGServiceImpl) ChunkSources(ctx context.Context, sources []Source) ([]domain.Chunk, error) {
+	documents, chunkers, _, err := s.load(ctx, sources, func(string, int, int) {})
 	if err != nil {
 		return nil, err
 	}
-	allZero := true
-	for _, r := range res {
-		if r.Score > 1e-9 {
-			allZero = false
-			break
+	var out []domain.Chunk
+	for i, d := range documents {
+		chunks, err := s.chunkDocument(d, chunkers[i])
+		if err != nil {
+			return nil, err
 		}
+		out = append(out, chunks...)
 	}
-	if allZero {
-		return s.lexicalSearch(query, topK), nil
+	return out, nil
+}
+
+// Embed embeds text as queries are, e.g. to compare passages that are not
+// in the index with those that are.
+func (s *RAGServiceImpl) Embed(text string) ([]float64, error) {
+	return s.embedQuery(text)
+}
+
+// embedQuery embeds a query or passage, checking that the vector fits the
+// index: a store would otherwise reject it with a less helpful error, or an
+// embedder configured differently since the ingest would go unnoticed.
+func (s *RAGServiceImpl) embedQuery(text string) ([]float64, error) {
+	vec, err := s.embedder.Embed(text)
+	if err != nil {
+		return nil, err
+	}
+	if s.dimension > 0 && len(vec) != s.dimension {
+		return nil, dimensionError(len(vec), s.dimension)
 	}
-	return res, nil
+	return vec, nil
 }
 
-var (
-	unicodeWordRe = regexp.MustCompile(`\p{L}+(?:['’]\p{L}+)*`)
-)
+// dimensionError reports a vector of the wrong dimension.
+func dimensionError(got, want int) error {
+	return fmt.Errorf("embedder returned %d dims, index built with %d", got, want)
+}
 
-func (s *RAGServiceImpl) lexicalSearch(query string, topK int) []domain.SearchResult {
-	qset := toTokenSet(query)
-	type pair struct {
-		idx   int
-		score float64
+// embed embeds text, passing ctx on when the embedder takes one.
+func (s *RAGServiceImpl) embed(ctx context.Context, text string) ([]float64, error) {
+	if ce, ok := s.embedder.(embedding.ContextEmbedder); ok {
+		return ce.EmbedContext(ctx, text)
 	}
-	scores := make([]pair, len(s.chunks))
-	for i, ch := range s.chunks {
-		scores[i] = pair{i, overlapOchiai(qset, ch.Text)}
+	return s.embedder.Embed(text)
+}
+
+// embedded is a chunk's vector, or why it could not be embedded.
+type embedded struct {
+	vector []float64
+	err    error
+}
+
+// embedAhead embeds the texts of the next chunks from first that have no
+// vector to reuse, ahead of the ingest loop, with up to embedWorkers requests at
+// once: a batch of ingestBatchSize chunks per request when batcher is set,
+// else one chunk. If a batch fails, its chunks are embedded one at a time,
+// so that only those at fault fail.
+func (s *RAGServiceImpl) embedAhead(ctx context.Context, batcher embedding.BatchEmbedder, chunks []domain.Chunk, texts []string, first int, reuse map[string][]float64) map[int]embedded {
+	size, window := 1, max(ingestBatchSize, s.embedWorkers)
+	if batcher != nil {
+		size, window = ingestBatchSize, ingestBatchSize*s.embedWorkers
 	}
-	sort.Slice(scores, func(i, j int) bool { return scores[i].score > scores[j].score })
-	if topK <= 0 {
-		topK = 5
+	var positions []int
+	for i := first; i < len(chunks) && len(positions) < window; i++ {
+		if _, ok := reuse[reuseKey(chunks[i])]; !ok {
+			positions = append(positions, i)
+		}
 	}
-	if topK > len(scores) {
-		topK = len(scores)
+	jobs := make(chan []int)
+	go func() {
+		defer close(jobs)
+		for start := 0; start < len(positions); start += size {
+			jobs <- positions[start:min(start+size, len(positions))]
+		}
+	}()
+	out := make(map[int]embedded, len(positions))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < min(s.embedWorkers, len(positions)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results := s.embedJob(ctx, batcher, texts, job)
+				mu.Lock()
+				for j, i := range job {
+					out[i] = results[j]
+				}
+				mu.Unlock()
+			}
+		}()
 	}
-	out := make([]domain.SearchResult, 0, topK)
-	for i := 0; i < topK; i++ {
-		p := scores[i]
-		out = append(out, domain.SearchResult{Chunk: s.chunks[p.idx], Score: p.score})
+	wg.Wait()
+	return out
+}
+
+// embedJob embeds the texts at positions, in one batch if batcher is set.
+func (s *RAGServiceImpl) embedJob(ctx context.Context, batcher embedding.BatchEmbedder, texts []string, positions []int) []embedded {
+	out := make([]embedded, len(positions))
+	if batcher != nil {
+		batch := make([]string, len(positions))
+		for j, i := range positions {
+			batch[j] = texts[i]
+		}
+		vectors, err := batcher.EmbedBatchContext(ctx, batch)
+		if err == nil && len(vectors) == len(batch) {
+			for j := range out {
+				out[j] = embedded{vector: vectors[j]}
+			}
+			return out
+		}
+	}
+	for j, i := range positions {
+		if ctx.Err() != nil {
+			out[j] = embedded{err: ctx.Err()}
+			continue
+		}
+		v, err := s.embed(ctx, texts[i])
+		out[j] = embedded{vector: v, err: err}
 	}
 	return out
 }
 
-func toTokenSet(s string) map[string]struct{} {
-	tokens := unicodeWordRe.FindAllString(strings.ToLower(s), -1)
-	m := make(map[string]struct{}, len(tokens))
-	for _, t := range tokens {
-		m[t] = struct{}{}
+// upsert stores chunks, passing ctx on when the store takes one.
+func (s *RAGServiceImpl) upsert(ctx context.Context, chunks []domain.Chunk, vectors [][]float64) error {
+	if cu, ok := s.store.(vectorstore.ContextUpserter); ok {
+		return cu.UpsertContext(ctx, chunks, vectors)
 	}
-	return m
+	return s.store.Upsert(chunks, vectors)
 }
 
-func overlapOchiai(qset map[string]struct{}, text string) float64 {
-	stoks := unicodeWordRe.FindAllString(strings.ToLower(text), -1)
-	seen := make(map[string]struct{}, len(stoks))
-	inter := 0
-	for _, t := range stoks {
-		if _, ok := seen[t]; ok {
+// indexedDocuments narrows docs to those with indexed chunks, counting only
+// those.
+func indexedDocuments(docs []domain.DocumentInfo, indexed []domain.Chunk) []domain.DocumentInfo {
+	counts := make(map[string]int)
+	for _, ch := range indexed {
+		counts[ch.DocumentID]++
+	}
+	var out []domain.DocumentInfo
+	for _, d := range docs {
+		if n := counts[d.ID]; n > 0 {
+			d.Chunks = n
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// withTags returns a copy of meta with tags added to its tags field.
+func withTags(meta map[string]string, tags []string) map[string]string {
+	out := make(map[string]string, len(meta)+1)
+	for k, v := range meta {
+		out[k] = v
+	}
+	all := tags
+	if existing := out["tags"]; existing != "" {
+		all = append(strings.Split(existing, ", "), tags...)
+	}
+	out["tags"] = strings.Join(all, ", ")
+	return out
+}
+
+// LanguageKey is the metadata field holding the language of a document.
+const LanguageKey = "lang"
+
+// documentLanguage returns the language to tag d with: its source's, or
+// the one detected in its content when detection is enabled and d is not
+// tagged yet. It returns "" to leave d as it is.
+func (s *RAGServiceImpl) documentLanguage(d domain.Document, src Source) string {
+	if src.Language != "" {
+		return src.Language
+	}
+	if !s.detectLanguage || d.Metadata[LanguageKey] != "" {
+		return ""
+	}
+	return langdetect.Detect(d.Content)
+}
+
+// withLanguage returns a copy of meta with its language field set to lang.
+func withLanguage(meta map[string]string, lang string) map[string]string {
+	out := make(map[string]string, len(meta)+1)
+	for k, v := range meta {
+		out[k] = v
+	}
+	out[LanguageKey] = lang
+	return out
+}
+
+// chunkDocument splits d with chunker or, when nil, the chunker the loader
+// registry sets for its path or else the configured one; atomic documents
+// are kept whole. Chunk offsets are made relative to the source file;
+// chunks of transformed documents get none.
+func (s *RAGServiceImpl) chunkDocument(d domain.Document, chunker domain.Chunker) ([]domain.Chunk, error) {
+	if chunker == nil {
+		chunker = s.loaders.Chunker(d.Path)
+	}
+	if chunker == nil {
+		chunker = s.chunker
+	}
+	var chunks []domain.Chunk
+	if d.Atomic {
+		if text := strings.TrimSpace(d.Content); text != "" {
+			chunks = []domain.Chunk{{
+				DocumentID: d.ID,
+				ChunkID:    d.ID + ":0",
+				Text:       text,
+				Path:       d.Path,
+				Time:       d.Time,
+				Metadata:   d.Metadata,
+			}}
+		}
+	} else {
+		var err error
+		if chunks, err = chunker.Chunk(d); err != nil {
+			return nil, err
+		}
+	}
+	var lines lineIndex
+	if !d.Transformed {
+		lines = newLineIndex(d.Content)
+	}
+	for i := range chunks {
+		if d.Transformed {
+			chunks[i].Start, chunks[i].End = 0, 0
 			continue
 		}
-		seen[t] = struct{}{}
-		if _, ok := qset[t]; ok {
-			inter++
+		if chunks[i].End > chunks[i].Start {
+			chunks[i].StartLine = d.LineOffset + lines.line(chunks[i].Start)
+			chunks[i].EndLine = d.LineOffset + lines.line(chunks[i].End-1)
 		}
+		chunks[i].Start += d.Offset
+		chunks[i].End += d.Offset
 	}
-	if len(qset) == 0 || len(seen) == 0 {
-		return 0
+	return chunks, nil
+}
+
+// lineIndex holds the byte offsets at which the lines of a text start.
+type lineIndex []int
+
+func newLineIndex(text string) lineIndex {
+	starts := lineIndex{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
 	}
-	// Ochiai coefficient: |A∩B| / sqrt(|A||B|)
-	// sqrt sizes; use float64
-	qa := float64(len(qset))
-	ba := float64(len(seen))
-	return float64(inter) / (sqrt(qa) * sqrt(ba))
+	return starts
 }
 
-func sqrt(x float64) float64 {
-	// small inline sqrt to avoid extra imports
-	// use Newton's method for a couple of iterations
-	if x <= 0 {
-		return 0
+// line returns the line, from 1, of the byte at offset.
+func (x lineIndex) line(offset int) int {
+	// The line is the number of line starts at or before offset.
+	n, found := slices.BinarySearch(x, offset)
+	if found {
+		n++
+	}
+	return n
+}
+
+// Query embeds the query and searches the vector store, falling back to lexical when needed.
+func (s *RAGServiceImpl) Query(query string, topK int) ([]domain.SearchResult, error) {
+	return s.QueryPage(query, 0, topK)
+}
+
+// QueryPage is like Query but skips the first offset results, so callers can
+// fetch further pages of the same ranking. The query may restrict results to
+// a date range with after:YYYY-MM-DD and before:YYYY-MM-DD (both inclusive).
+func (s *RAGServiceImpl) QueryPage(query string, offset, limit int) ([]domain.SearchResult, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	parsed, err := queryparse.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 5
 	}
-	z := x
-	for i := 0; i < 6; i++ {
-		z = 0.5 * (z + x/z)
+	questions, err := s.subQuestions(parsed)
+	if err != nil {
+		return nil, err
+	}
+	if len(questions) > 1 {
+		return s.decomposedPage(parsed, questions, offset, limit)
+	}
+	return s.page(parsed, offset, limit)
+}
+
+// page returns the results of parsed at offset.
+func (s *RAGServiceImpl) page(parsed queryparse.Query, offset, limit int) ([]domain.SearchResult, error) {
+	r, err := s.retrieveParsed(parsed)
+	if err != nil {
+		return nil, err
+	}
+	search := s.rescore(r)
+	if len(r.parsed.Required) > 0 {
+		search = requireTerms(search, r.parsed.Required)
+	}
+	if s.maxPerDocument > 0 {
+		return limitPerDocument(search, offset, limit, s.maxPerDocument)
+	}
+	return search(offset, limit)
+}
+
+// retrieval is the first-stage ranking of a query, before scoring.
+type retrieval struct {
+	parsed queryparse.Query
+	// search pages through the vector ranking, or the lexical one when
+	// the query has no vector signal (hasVector is false) or in BM25
+	// retrieval, or in hybrid retrieval the fusion of the two (fused is
+	// true).
+	search    searchFunc
+	hasVector bool
+	fused     bool
+	// weights are the query's term weights for the lexical signal.
+	weights map[string]float64
+}
+
+// retrieve parses query and sets up its first-stage ranking.
+func (s *RAGServiceImpl) retrieve(query string) (retrieval, error) {
+	parsed, err := queryparse.Parse(query)
+	if err != nil {
+		return retrieval{}, err
 	}
-	return z
+	return s.retrieveParsed(parsed)
 }
 
-func hashString(s string) string {
-	h := sha1.Sum([]byte(s))
-	return hex.EncodeToString(h[:8])
+// retrieveParsed sets up the first-stage ranking of parsed.
+func (s *RAGServiceImpl) retrieveParsed(parsed queryparse.Query) (retrieval, error) {
+	// The lexical index also sets the dimension and date range of a store
+	// this service did not fill.
+	lexical, err := s.lexicon()
+	if err != nil {
+		return retrieval{}, err
+	}
+	filter := vectorstore.Filter{After: parsed.After, Metadata: parsed.Metadata, Deleted: parsed.Deleted}
+	if !parsed.Before.IsZero() {
+		filter.Before = parsed.Before.AddDate(0, 0, 1)
+	}
+	r := retrieval{parsed: parsed, weights: queryWeights(parsed)}
+	r.search = func(offset, limit int) ([]domain.SearchResult, error) {
+		return lexical.search(r.weights, offset, limit, filter), nil
+	}
+	if s.bm25Only {
+		return r, nil
+	}
+	text, err := s.queryText(parsed)
+	if err != nil {
+		return retrieval{}, err
+	}
+	vec, err := s.embedQuery(text)
+	if err != nil {
+		return retrieval{}, err
+	}
+	// Detect zero vector (no tokens)
+	zero := true
+	for _, v := range vec {
+		if v != 0 {
+			zero = false
+			break
+		}
+	}
+	if !zero {
+		// Decide on the fallback from the top of the ranking so that all
+		// pages of one query come from the same retriever.
+		head, err := s.store.Search(vec, 0, 1, filter)
+		if err != nil {
+			return retrieval{}, err
+		}
+		if len(head) > 0 && head[0].Score > 1e-9 {
+			r.hasVector = true
+			r.search = func(offset, limit int) ([]domain.SearchResult, error) {
+				res, err := s.store.Search(vec, offset, limit, filter)
+				if err != nil {
+					return nil, err
+				}
+				s.hydrate(res)
+				return res, nil
+			}
+		}
+	}
+	if s.hybrid && r.hasVector {
+		r.fused = true
+		vector := r.search
+		r.search = func(offset, limit int) ([]domain.SearchResult, error) {
+			vres, err := vector(0, offset+limit)
+			if err != nil {
+				return nil, err
+			}
+			lres := lexical.search(r.weights, 0, offset+limit, filter)
+			// The chunks without any query term that follow the lexical
+			// hits, with score 0, are not ranked.
+			for len(lres) > 0 && lres[len(lres)-1].Score <= 0 {
+				lres = lres[:len(lres)-1]
+			}
+			return fuse(s.rrfK, offset, limit, vres, lres), nil
+		}
+	}
+	return r, nil
 }
@@ -0,0 +1,165 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rag/internal/chunker"
+	"rag/internal/domain"
+	"rag/internal/embedding/tfidf"
+	"rag/internal/service"
+	"rag/internal/summarizer"
+	"rag/internal/vectorstore/disk"
+	"rag/internal/vectorstore/memory"
+)
+
+// newCorpusService ingests testdata/corpus with the tfidf embedder into the
+// memory store, three sentences to a chunk.
+func newCorpusService(t *testing.T, cfg service.Config) *service.RAGServiceImpl {
+	t.Helper()
+	svc := service.NewRAGService(chunker.NewSentenceChunker(3, 0), tfidf.NewEmbedder(), memory.NewStorage(), summarizer.NewFrequencySummarizer(summarizer.Config{}), cfg)
+	if _, err := svc.IngestDocuments([]string{"testdata/corpus/*.md"}); err != nil {
+		t.Fatal(err)
+	}
+	return svc
+}
+
+// chunkNames returns the chunk IDs of results with the document ID, a hash
+// of the path, replaced by the file name.
+func chunkNames(results []domain.SearchResult) []string {
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = filepath.Base(r.Chunk.Path) + strings.TrimPrefix(r.Chunk.ChunkID, r.Chunk.DocumentID)
+	}
+	return names
+}
+
+// TestQueryRanking pins the ranking of the corpus for a fixed set of
+// queries, so that changes to the chunker, the embedder or the store that
+// move results show up here.
+func TestQueryRanking(t *testing.T) {
+	svc := newCorpusService(t, service.Config{})
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"leader election timeout", []string{"raft.md:0", "raft.md:2", "clocks.md:1"}},
+		{"acceptors ballot prepare", []string{"paxos.md:0", "paxos.md:1"}},
+		{"starter flour water", []string{"sourdough.md:0"}},
+		{"compost soil", []string{"garden.md:1", "garden.md:0"}},
+		{"lamport vector clocks", []string{"clocks.md:0"}},
+		{"committed log entries majority", []string{"raft.md:1", "raft.md:2", "paxos.md:1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results, err := svc.Query(tt.query, len(tt.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := chunkNames(results); !reflect.DeepEqual(got, tt.want) {
+				for _, r := range results {
+					t.Logf("%s %.3f %q", r.Chunk.ChunkID, r.Score, r.Chunk.Text)
+				}
+				t.Errorf("Query(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRetryFailedKeepsStore checks that retrying a failed chunk adds it to
+// a persistent store populated by an earlier ingest, instead of replacing
+// what the store holds.
+func TestRetryFailedKeepsStore(t *testing.T) {
+	store, err := disk.Open(disk.Config{Path: filepath.Join(t.TempDir(), "index.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	svc := service.NewRAGService(chunker.NewSentenceChunker(3, 0), tfidf.NewEmbedder(), store, summarizer.NewFrequencySummarizer(summarizer.Config{}), service.Config{})
+	sources := []service.Source{{Pattern: "testdata/corpus/*.md"}}
+	if _, err := svc.IngestDocuments([]string{sources[0].Pattern}); err != nil {
+		t.Fatal(err)
+	}
+	before, _, err := store.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := before[0]
+	failed.ChunkID += "-retried"
+	remaining, err := svc.RetryFailed(context.Background(), sources, []service.FailedChunk{{Chunk: failed, Error: "timeout"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 0 {
+		t.Fatalf("RetryFailed left %d chunks failing", len(remaining))
+	}
+	after, _, err := store.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before)+1 {
+		t.Errorf("store holds %d chunks after the retry, want %d", len(after), len(before)+1)
+	}
+}
+
+// flakyEmbedder is the tfidf embedder, failing while fail is set. It then
+// does not know its dimension, so an ingest fails probing it.
+type flakyEmbedder struct {
+	*tfidf.Embedder
+	fail bool
+}
+
+func (e *flakyEmbedder) Prepare(corpus []string) error {
+	if e.fail {
+		return nil
+	}
+	return e.Embedder.Prepare(corpus)
+}
+
+func (e *flakyEmbedder) Dimension() int {
+	if e.fail {
+		return 0
+	}
+	return e.Embedder.Dimension()
+}
+
+func (e *flakyEmbedder) Embed(text string) ([]float64, error) {
+	if e.fail {
+		return nil, errors.New("embedder down")
+	}
+	return e.Embedder.Embed(text)
+}
+
+// TestIngestFailureKeepsCorpus checks that an ingest whose embedder fails
+// leaves the documents and the lexical ranking of the previous one.
+func TestIngestFailureKeepsCorpus(t *testing.T) {
+	embedder := &flakyEmbedder{Embedder: tfidf.NewEmbedder()}
+	svc := service.NewRAGService(chunker.NewSentenceChunker(3, 0), embedder, memory.NewStorage(), summarizer.NewFrequencySummarizer(summarizer.Config{}), service.Config{BM25Only: true})
+	if _, err := svc.IngestDocuments([]string{"testdata/corpus/*.md"}); err != nil {
+		t.Fatal(err)
+	}
+	documents := svc.Documents()
+	results, err := svc.Query("starter flour water", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embedder.fail = true
+	if _, err := svc.IngestDocuments([]string{"testdata/corpus/raft.md"}); !errors.Is(err, service.ErrEmbedderUnavailable) {
+		t.Fatalf("IngestDocuments error %v, want ErrEmbedderUnavailable", err)
+	}
+	if got := svc.Documents(); !reflect.DeepEqual(got, documents) {
+		t.Errorf("Documents() = %v after the failed ingest, want %v", got, documents)
+	}
+	got, err := svc.Query("starter flour water", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(chunkNames(got), chunkNames(results)) {
+		t.Errorf("Query results %q after the failed ingest, want %q", chunkNames(got), chunkNames(results))
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"rag/internal/domain"
+	"rag/internal/embedding"
+	"rag/internal/vectorstore"
+)
+
+// storedVectors prepares the reuse of the vectors a persistent store holds
+// from an earlier ingest with the same embedder. It returns the stamp for
+// this ingest, zero when the store or the embedder cannot tell what the
+// vectors came from; whether the store holds exactly these chunks already;
+// and otherwise the stored vectors of dimension dim by reuseKey. Stores
+// that keep only source locations are reused only whole, since the sources
+// may have changed since.
+func (s *RAGServiceImpl) storedVectors(chunks []domain.Chunk, dim int) (vectorstore.Stamp, bool, map[string][]float64, error) {
+	p, ok := s.store.(vectorstore.Persistent)
+	fp, ok2 := s.embedder.(embedding.Fingerprinter)
+	if !ok || !ok2 {
+		return vectorstore.Stamp{}, false, nil, nil
+	}
+	stamp := vectorstore.Stamp{Embedder: s.fingerprint(fp), Contents: s.contentsDigest(chunks)}
+	stored, err := p.Stamp()
+	if err != nil || stored.Embedder != stamp.Embedder {
+		return stamp, false, nil, err
+	}
+	if stored.Contents == stamp.Contents {
+		return stamp, true, nil, nil
+	}
+	scanner, ok := s.store.(vectorstore.Scanner)
+	if !ok || s.hydrateFromSource {
+		return stamp, false, nil, nil
+	}
+	old, vectors, err := scanner.All()
+	if err != nil {
+		return stamp, false, nil, err
+	}
+	byKey := make(map[string][]float64, len(old))
+	for i, ch := range old {
+		if len(vectors[i]) == dim {
+			byKey[reuseKey(ch)] = vectors[i]
+		}
+	}
+	return stamp, false, byKey, nil
+}
+
+// reuseKey identifies the text embedded for a chunk: the digest of its
+// enriched text, or else its text. Chunks enriched differently, as by
+// another document context, do not share vectors even when their texts are
+// equal.
+func reuseKey(ch domain.Chunk) string {
+	if ch.Embedded != "" {
+		return ch.Embedded
+	}
+	return ch.Text
+}
+
+// embeddedDigest is the Embedded digest of an enriched text.
+func embeddedDigest(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// contentsDigest identifies the chunks an ingest indexes, in the form it
+// stores them.
+func (s *RAGServiceImpl) contentsDigest(chunks []domain.Chunk) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%t\x00", s.hydrateFromSource)
+	for _, ch := range chunks {
+		fmt.Fprintf(h, "%q %q %q %d %q %d %d %d %d %q %d %q\x00", ch.DocumentID, ch.ChunkID, ch.Text, ch.Index, ch.Path, ch.Start, ch.End, ch.StartLine, ch.EndLine, ch.Keywords, ch.Time.UnixNano(), ch.Embedded)
+		keys := make([]string, 0, len(ch.Metadata))
+		for k := range ch.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(h, "%q=%q\x00", k, ch.Metadata[k])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"rag/internal/domain"
+)
+
+// Signals is the evidence about one search hit that a Scorer combines.
+type Signals struct {
+	// Vector is the similarity of the hit to the query embedding.
+	Vector float64
+	// HasVector is false when the query embeds to no signal, so that the
+	// hits come from the lexical ranking and Vector is meaningless.
+	HasVector bool
+	// Lexical is the weighted overlap of query and chunk terms (0..1), or
+	// for hits of the lexical ranking their score there.
+	Lexical float64
+	// Recency places the hit's date between the oldest (0) and newest (1)
+	// dated chunks of the corpus; undated hits get 0.
+	Recency float64
+	// Links is log(1+backlinks)/log(1+most backlinks) of the hit's note
+	// (0..1); 0 for corpora without wiki links.
+	Links float64
+	// Fusion is the reciprocal rank fusion score of the hit (0..1) in
+	// hybrid retrieval, where it stands for the vector and lexical scores;
+	// Vector is then 0. It is 0 for hits of a single ranking.
+	Fusion float64
+}
+
+// Scorer computes the final ranking score of a search hit.
+type Scorer interface {
+	Score(Signals) float64
+}
+
+// WeightedScorer blends the vector and lexical scores by their weights and
+// multiplies the result by 1 + weight·signal for recency and links, so
+// these raise good hits rather than rank poor ones up on their own. Without
+// a vector signal the lexical score stands alone, and in hybrid retrieval
+// the fusion score replaces the blend.
+type WeightedScorer struct {
+	Vector  float64
+	Lexical float64
+	Recency float64
+	Links   float64
+}
+
+// Score implements Scorer.
+func (w WeightedScorer) Score(s Signals) float64 {
+	base := s.Lexical
+	switch {
+	case s.Fusion > 0:
+		base = s.Fusion
+	case s.HasVector && w.Vector+w.Lexical > 0:
+		base = (w.Vector*s.Vector + w.Lexical*s.Lexical) / (w.Vector + w.Lexical)
+	}
+	return base * (1 + w.Recency*s.Recency) * (1 + w.Links*s.Links)
+}
+
+// rerankDepth is how many extra hits beyond the requested page are
+// re-scored, so hits the scorer favors can move up into the page.
+const rerankDepth = 50
+
+// rescore re-ranks the top of the first-stage ranking r by the service's
+// scorer.
+func (s *RAGServiceImpl) rescore(r retrieval) searchFunc {
+	maxIn := s.maxInDegree()
+	return func(offset, limit int) ([]domain.SearchResult, error) {
+		res, err := r.search(0, offset+limit+rerankDepth)
+		if err != nil {
+			return nil, err
+		}
+		for i := range res {
+			res[i].Score = s.scorer.Score(s.signals(res[i], r, maxIn))
+		}
+		sort.SliceStable(res, func(i, j int) bool { return res[i].Score > res[j].Score })
+		if offset >= len(res) {
+			return nil, nil
+		}
+		res = res[offset:]
+		if len(res) > limit {
+			res = res[:limit]
+		}
+		return res, nil
+	}
+}
+
+// maxInDegree is the log(1+backlinks) of the most linked note, which the
+// Links signal is relative to; 0 without wiki links.
+func (s *RAGServiceImpl) maxInDegree() float64 {
+	if s.links == nil {
+		return 0
+	}
+	return math.Log1p(float64(s.links.MaxInDegree()))
+}
+
+// signals gathers the signals of a hit of the first-stage ranking r.
+func (s *RAGServiceImpl) signals(hit domain.SearchResult, r retrieval, maxIn float64) Signals {
+	// Lexical hits already carry their lexical score.
+	sig := Signals{HasVector: r.hasVector, Lexical: hit.Score}
+	switch {
+	case r.fused:
+		sig.Fusion = hit.Score
+		sig.Lexical = weightedOchiai(r.weights, hit.Chunk.Text)
+	case r.hasVector:
+		sig.Vector = hit.Score
+		sig.Lexical = weightedOchiai(r.weights, hit.Chunk.Text)
+	}
+	sig.Recency = s.recency(hit.Chunk.Time)
+	if maxIn > 0 {
+		sig.Links = math.Log1p(float64(s.links.InDegree(hit.Chunk.DocumentID))) / maxIn
+	}
+	return sig
+}
+
+// Candidate is a hit of the first-stage ranking with its signals.
+type Candidate struct {
+	Chunk   domain.Chunk
+	Signals Signals
+}
+
+// Candidates returns the hits that scoring re-ranks for the first limit
+// results of query, with their signals, in first-stage order. These hits
+// do not depend on the scorer, so that scorers can be compared on them
+// without searching again.
+func (s *RAGServiceImpl) Candidates(query string, limit int) ([]Candidate, error) {
+	r, err := s.retrieve(query)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.search(0, limit+rerankDepth)
+	if err != nil {
+		return nil, err
+	}
+	maxIn := s.maxInDegree()
+	out := make([]Candidate, 0, len(res))
+	for _, hit := range res {
+		if len(r.parsed.Required) > 0 && !containsTerms(hit.Chunk.Text, r.parsed.Required) {
+			continue
+		}
+		out = append(out, Candidate{Chunk: hit.Chunk, Signals: s.signals(hit, r, maxIn)})
+	}
+	return out, nil
+}
+
+// recency returns the Recency signal of a chunk dated t.
+func (s *RAGServiceImpl) recency(t time.Time) float64 {
+	if t.IsZero() || !s.newest.After(s.oldest) {
+		return 0
+	}
+	return float64(t.Sub(s.oldest)) / float64(s.newest.Sub(s.oldest))
+}
+
+// dateRange returns the oldest and newest dates of chunks.
+func dateRange(chunks []domain.Chunk) (oldest, newest time.Time) {
+	for _, ch := range chunks {
+		if ch.Time.IsZero() {
+			continue
+		}
+		if oldest.IsZero() || ch.Time.Before(oldest) {
+			oldest = ch.Time
+		}
+		if ch.Time.After(newest) {
+			newest = ch.Time
+		}
+	}
+	return oldest, newest
+}
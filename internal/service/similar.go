@@ -0,0 +1,48 @@
+package service
+
+import (
+	"rag/internal/domain"
+	"rag/internal/vectorstore"
+)
+
+// Similar returns the chunks nearest to passage, skipping the first offset.
+// Unlike QueryPage the passage is embedded as is: there is no query syntax,
+// no lexical fallback and no link or per-document reranking, so scores are
+// plain vector similarities, which suits finding copies and paraphrases of
+// a pasted passage. A passage with no indexable terms yields no results,
+// and chunks with no similarity are left out.
+func (s *RAGServiceImpl) Similar(passage string, offset, limit int) ([]domain.SearchResult, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = 5
+	}
+	vec, err := s.embedQuery(passage)
+	if err != nil {
+		return nil, err
+	}
+	zero := true
+	for _, v := range vec {
+		if v != 0 {
+			zero = false
+			break
+		}
+	}
+	if zero {
+		return nil, nil
+	}
+	res, err := s.store.Search(vec, offset, limit, vectorstore.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	// Chunks sharing nothing with the passage are not similar at all.
+	for i, r := range res {
+		if r.Score <= 0 {
+			res = res[:i]
+			break
+		}
+	}
+	s.hydrate(res)
+	return res, nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"rag/internal/domain"
+	"rag/internal/queryparse"
+	"rag/internal/textutil"
+)
+
+// queryWeights returns the lexical weight of each query token: 1, or the
+// boost written as term^2.
+func queryWeights(q queryparse.Query) map[string]float64 {
+	weights := make(map[string]float64)
+	for t := range textutil.TokenSet(q.Text) {
+		weights[t] = 1
+	}
+	for term, boost := range q.Boosts {
+		for t := range textutil.TokenSet(term) {
+			weights[t] = boost
+		}
+	}
+	return weights
+}
+
+// embedText is the query text to embed: boosted terms are repeated about
+// as often as their weight, which raises their share of bag-of-words
+// vectors and draws the attention of neural embedders.
+func embedText(q queryparse.Query) string {
+	terms := make([]string, 0, len(q.Boosts))
+	for term := range q.Boosts {
+		terms = append(terms, term)
+	}
+	// A fixed order keeps the vector, and so the ranking, stable across
+	// pages.
+	sort.Strings(terms)
+	words := []string{q.Text}
+	for _, term := range terms {
+		for i := 1; i < int(math.Round(q.Boosts[term])); i++ {
+			words = append(words, term)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// weightedOchiai is the Ochiai coefficient |A∩B| / sqrt(|A||B|) of the
+// query and text token sets, with query tokens weighted.
+func weightedOchiai(weights map[string]float64, text string) float64 {
+	stoks := textutil.Tokens(text)
+	seen := make(map[string]struct{}, len(stoks))
+	var inter, total float64
+	for _, t := range stoks {
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		inter += weights[t]
+	}
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 || len(seen) == 0 {
+		return 0
+	}
+	return inter / (math.Sqrt(total) * math.Sqrt(float64(len(seen))))
+}
+
+// containsTerms reports whether text has every token of the required terms.
+func containsTerms(text string, required []string) bool {
+	tokens := textutil.TokenSet(text)
+	for _, term := range required {
+		for t := range textutil.TokenSet(term) {
+			if _, ok := tokens[t]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// requireTerms wraps search to return only results containing the required
+// terms, reading further into the ranking to fill each page.
+func requireTerms(search searchFunc, required []string) searchFunc {
+	return func(offset, limit int) ([]domain.SearchResult, error) {
+		want := offset + limit
+		var kept []domain.SearchResult
+		batch := want
+		read := 0
+		for len(kept) < want {
+			page, err := search(read, batch)
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range page {
+				if containsTerms(r.Chunk.Text, required) {
+					kept = append(kept, r)
+				}
+			}
+			read += len(page)
+			if len(page) < batch {
+				break
+			}
+			batch *= 2
+		}
+		if offset >= len(kept) {
+			return nil, nil
+		}
+		kept = kept[offset:]
+		if len(kept) > limit {
+			kept = kept[:limit]
+		}
+		return kept, nil
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"rag/internal/domain"
+	"rag/internal/vectorstore"
+)
+
+// DeletedDocument is a soft-deleted document: one gone from the corpus
+// whose chunks the store keeps, left out of searches.
+type DeletedDocument struct {
+	ID, Path string
+	Chunks   int
+	// Deleted is when the document was first missing from an ingest.
+	Deleted time.Time
+}
+
+// tombstones returns the chunks of the store's documents that are not among
+// those of chunks, so that an ingest keeps them soft-deleted: newly missing
+// ones marked with the current time, earlier ones as they were. Nothing is
+// kept without soft deletion, from stores that cannot list their points, or
+// when the store keeps only source locations, since the source files of
+// deleted documents are gone.
+func (s *RAGServiceImpl) tombstones(chunks []domain.Chunk) ([]domain.Chunk, error) {
+	scanner, ok := s.store.(vectorstore.Scanner)
+	if !s.softDelete || !ok || s.hydrateFromSource {
+		return nil, nil
+	}
+	stored, _, err := scanner.All()
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]bool)
+	for _, ch := range chunks {
+		current[ch.DocumentID] = true
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	var out []domain.Chunk
+	for _, ch := range stored {
+		if current[ch.DocumentID] {
+			continue
+		}
+		if !vectorstore.IsDeleted(ch) {
+			ch.Metadata = withDeleted(ch.Metadata, now)
+		}
+		out = append(out, ch)
+	}
+	return out, nil
+}
+
+// withDeleted returns a copy of meta marked deleted at the given time.
+func withDeleted(meta map[string]string, at string) map[string]string {
+	out := make(map[string]string, len(meta)+1)
+	for k, v := range meta {
+		out[k] = v
+	}
+	out[vectorstore.DeletedKey] = at
+	return out
+}
+
+// DeletedDocuments lists the soft-deleted documents of the store, oldest
+// deletion first.
+func (s *RAGServiceImpl) DeletedDocuments() ([]DeletedDocument, error) {
+	scanner, ok := s.store.(vectorstore.Scanner)
+	if !ok {
+		return nil, ErrScanUnsupported
+	}
+	chunks, _, err := scanner.All()
+	if err != nil {
+		return nil, err
+	}
+	return deletedDocuments(chunks), nil
+}
+
+func deletedDocuments(chunks []domain.Chunk) []DeletedDocument {
+	index := make(map[string]int)
+	var out []DeletedDocument
+	for _, ch := range chunks {
+		if !vectorstore.IsDeleted(ch) {
+			continue
+		}
+		i, ok := index[ch.DocumentID]
+		if !ok {
+			i = len(out)
+			index[ch.DocumentID] = i
+			at, _ := time.Parse(time.RFC3339, ch.Metadata[vectorstore.DeletedKey])
+			out = append(out, DeletedDocument{ID: ch.DocumentID, Path: ch.Path, Deleted: at})
+		}
+		out[i].Chunks++
+	}
+	sort.SliceStable(out, func(a, b int) bool { return out[a].Deleted.Before(out[b].Deleted) })
+	return out
+}
+
+// Purge removes the soft-deleted documents deleted before the given time
+// from the store for good, and returns them. The store is rebuilt from the
+// points it keeps, without embedding anything; a persistent store is
+// stamped as holding them, so the next ingest of the same corpus still
+// finds it current.
+func (s *RAGServiceImpl) Purge(before time.Time) ([]DeletedDocument, error) {
+	scanner, ok := s.store.(vectorstore.Scanner)
+	if !ok {
+		return nil, ErrScanUnsupported
+	}
+	chunks, vectors, err := scanner.All()
+	if err != nil {
+		return nil, err
+	}
+	var keptChunks, purgedChunks []domain.Chunk
+	var keptVectors [][]float64
+	for i, ch := range chunks {
+		if vectorstore.IsDeleted(ch) {
+			at, err := time.Parse(time.RFC3339, ch.Metadata[vectorstore.DeletedKey])
+			if err != nil || at.Before(before) {
+				purgedChunks = append(purgedChunks, ch)
+				continue
+			}
+		}
+		keptChunks = append(keptChunks, ch)
+		keptVectors = append(keptVectors, vectors[i])
+	}
+	if len(purgedChunks) == 0 {
+		return nil, nil
+	}
+	var stamp vectorstore.Stamp
+	p, persistent := s.store.(vectorstore.Persistent)
+	if persistent {
+		if stamp, err = p.Stamp(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.store.Clear(); err != nil {
+		return nil, err
+	}
+	if len(keptChunks) > 0 {
+		if err := s.store.Init(len(keptVectors[0])); err != nil {
+			return nil, err
+		}
+		for start := 0; start < len(keptChunks); start += ingestBatchSize {
+			end := min(start+ingestBatchSize, len(keptChunks))
+			if err := s.store.Upsert(keptChunks[start:end], keptVectors[start:end]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if persistent && stamp.Embedder != "" {
+		stamp.Contents = s.contentsDigest(keptChunks)
+		if err := p.SetStamp(stamp); err != nil {
+			return nil, err
+		}
+	}
+	s.invalidateLexicon()
+	return deletedDocuments(purgedChunks), nil
+}
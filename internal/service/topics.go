@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+
+	"rag/internal/cluster"
+	"rag/internal/domain"
+	"rag/internal/keywords"
+	"rag/internal/vectorstore"
+)
+
+// ErrScanUnsupported is returned when the store cannot enumerate its points.
+var ErrScanUnsupported = errors.New("vector store does not support listing all chunks")
+
+// Topics clusters the indexed chunks into k topics using k-means over their
+// embeddings and labels each topic with its most distinctive terms. A k of
+// zero or less uses the configured topic count, or picks one based on the
+// corpus size.
+func (s *RAGServiceImpl) Topics(k int) ([]domain.Topic, error) {
+	scanner, ok := s.store.(vectorstore.Scanner)
+	if !ok {
+		return nil, ErrScanUnsupported
+	}
+	chunks, vectors, err := scanner.All()
+	if err != nil {
+		return nil, err
+	}
+	chunks, vectors = vectorstore.Live(chunks, vectors)
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+	if k <= 0 {
+		k = s.topicCount
+	}
+	if k <= 0 {
+		k = int(math.Sqrt(float64(len(chunks)) / 2))
+		k = min(max(k, 2), 20)
+	}
+	res := cluster.KMeans(vectors, k, 50, 1)
+
+	members := make([][]int, len(res.Centroids))
+	for i, c := range res.Assignments {
+		members[c] = append(members[c], i)
+	}
+	texts := make([]string, 0, len(members))
+	topics := make([]domain.Topic, 0, len(members))
+	for c, idxs := range members {
+		if len(idxs) == 0 {
+			continue
+		}
+		centroid := res.Centroids[c]
+		sort.SliceStable(idxs, func(a, b int) bool {
+			return sqDist(vectors[idxs[a]], centroid) < sqDist(vectors[idxs[b]], centroid)
+		})
+		var b strings.Builder
+		topic := domain.Topic{Chunks: make([]domain.Chunk, len(idxs))}
+		for j, i := range idxs {
+			ch := chunks[i]
+			s.hydrateChunk(&ch)
+			topic.Chunks[j] = ch
+			b.WriteString(ch.Text)
+			b.WriteString("\n")
+		}
+		texts = append(texts, b.String())
+		topics = append(topics, topic)
+	}
+	kw := keywords.NewExtractor(texts)
+	for i := range topics {
+		topics[i].Keywords = kw.Document(i, 8)
+		label := topics[i].Keywords
+		if len(label) > 3 {
+			label = label[:3]
+		}
+		topics[i].Label = strings.Join(label, " / ")
+	}
+	sort.SliceStable(topics, func(a, b int) bool { return len(topics[a].Chunks) > len(topics[b].Chunks) })
+	return topics, nil
+}
+
+func sqDist(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"rag/internal/domain"
+	"rag/internal/embedding"
+	"rag/internal/i18n"
+	"rag/internal/keywords"
+	"rag/internal/vectorstore"
+)
+
+// Kinds of Issue found by Verify.
+const (
+	// IssueMissing is a chunk of the corpus the store has no point for.
+	IssueMissing = "missing"
+	// IssueOrphan is a stored point of no chunk of the corpus.
+	IssueOrphan = "orphan"
+	// IssueStale is a stored point whose text is not its chunk's.
+	IssueStale = "stale"
+	// IssueDuplicate is a chunk stored more than once.
+	IssueDuplicate = "duplicate"
+	// IssueDimension is a stored vector whose length is not the
+	// embedder's, or that is empty.
+	IssueDimension = "dimension"
+	// IssueSource is a stored point whose source file is gone.
+	IssueSource = "source"
+	// IssueStamp is a store stamp that does not match the embedder or the
+	// chunks of the corpus, so the next ingest embeds again.
+	IssueStamp = "stamp"
+)
+
+// Issue is an inconsistency between the store and the corpus.
+type Issue struct {
+	Kind    string
+	ChunkID string
+	Path    string
+	Detail  string
+}
+
+// VerifyReport is the outcome of Verify.
+type VerifyReport struct {
+	// Chunks is the number of chunks of the corpus, Points that of points
+	// in the store.
+	Chunks, Points int
+	// Dimension is the embedder's vector dimension; 0 if unknown.
+	Dimension int
+	// Deleted counts the soft-deleted points, which are not searched.
+	Deleted int
+	Issues  []Issue
+}
+
+// Count returns the number of issues of kind.
+func (r VerifyReport) Count(kind string) int {
+	n := 0
+	for _, is := range r.Issues {
+		if is.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Verify checks the store against the chunks of sources, without embedding
+// them: that every chunk is stored once with its text, that no point is
+// left of chunks no longer in the corpus, that vectors have the embedder's
+// dimension, that the stored source files still exist, and that the stamp
+// of a persistent store matches the embedder and the chunks. Soft-deleted
+// points are only counted and checked for their dimension. With no
+// sources, as for a read-only collection, only the stored points are
+// checked.
+func (s *RAGServiceImpl) Verify(ctx context.Context, sources []Source) (VerifyReport, error) {
+	var report VerifyReport
+	scanner, ok := s.store.(vectorstore.Scanner)
+	if !ok {
+		return report, ErrScanUnsupported
+	}
+	var chunks []domain.Chunk
+	if len(sources) > 0 {
+		var err error
+		if chunks, err = s.corpusChunks(ctx, sources); err != nil {
+			return report, err
+		}
+	}
+	report.Chunks = len(chunks)
+	texts := make([]string, len(chunks))
+	for i, ch := range chunks {
+		texts[i] = ch.Text
+	}
+	if err := s.embedder.Prepare(texts); err != nil {
+		return report, fmt.Errorf("%w: %w", ErrEmbedderUnavailable, err)
+	}
+	stored, vectors, err := scanner.All()
+	if err != nil {
+		return report, err
+	}
+	report.Points = len(stored)
+	if len(stored) > 0 {
+		dim, err := s.embedderDimension(ctx)
+		if err != nil {
+			return report, fmt.Errorf("%w: %w", ErrEmbedderUnavailable, err)
+		}
+		report.Dimension = dim
+	}
+	add := func(kind string, ch domain.Chunk, detail string) {
+		report.Issues = append(report.Issues, Issue{Kind: kind, ChunkID: ch.ChunkID, Path: ch.Path, Detail: detail})
+	}
+
+	// Vectors of another embedder are all embedded again by the next
+	// ingest; they are only checked against each other.
+	dim := report.Dimension
+	var stampContents *string
+	p, ok := s.store.(vectorstore.Persistent)
+	fp, ok2 := s.embedder.(embedding.Fingerprinter)
+	if ok && ok2 && len(sources) > 0 {
+		stamp, err := p.Stamp()
+		if err != nil {
+			return report, err
+		}
+		if stamp.Embedder != s.fingerprint(fp) {
+			add(IssueStamp, domain.Chunk{}, i18n.T("embedded by another embedder or on another corpus"))
+			if len(vectors) > 0 {
+				dim = len(vectors[0])
+			}
+		} else {
+			// Compared once the soft-deleted chunks are known.
+			stampContents = &stamp.Contents
+		}
+	}
+
+	corpus := make(map[string]domain.Chunk, len(chunks))
+	documents := make(map[string]bool)
+	for _, ch := range chunks {
+		corpus[ch.ChunkID] = ch
+		documents[ch.DocumentID] = true
+	}
+	seen := make(map[string]bool, len(stored))
+	missingSources := make(map[string]bool)
+	// expected are the chunks the stamp should describe: those of the
+	// corpus, then the soft-deleted ones an ingest keeps.
+	expected := slices.Clip(chunks)
+	for i, ch := range stored {
+		if n := len(vectors[i]); n == 0 || n != dim {
+			add(IssueDimension, ch, fmt.Sprintf("%d, want %d", n, dim))
+		}
+		// Soft-deleted chunks are not searched, and their sources are
+		// expected to be gone.
+		if vectorstore.IsDeleted(ch) {
+			report.Deleted++
+			if s.softDelete && !documents[ch.DocumentID] {
+				expected = append(expected, ch)
+			}
+			continue
+		}
+		if seen[ch.ChunkID] {
+			add(IssueDuplicate, ch, "")
+		}
+		seen[ch.ChunkID] = true
+		if ch.Path != "" && !strings.Contains(ch.Path, "://") {
+			gone, ok := missingSources[ch.Path]
+			if !ok {
+				_, err := os.Stat(ch.Path)
+				gone = errors.Is(err, os.ErrNotExist)
+				missingSources[ch.Path] = gone
+			}
+			if gone {
+				add(IssueSource, ch, "")
+				continue
+			}
+		}
+		if len(sources) == 0 {
+			continue
+		}
+		want, ok := corpus[ch.ChunkID]
+		switch {
+		case !ok:
+			add(IssueOrphan, ch, "")
+		// Stores that keep only source locations hold no text to compare.
+		case ch.Text != "" && ch.Text != want.Text:
+			add(IssueStale, ch, "")
+		}
+	}
+	for _, ch := range chunks {
+		if !seen[ch.ChunkID] {
+			add(IssueMissing, ch, "")
+		}
+	}
+	if stampContents != nil && *stampContents != s.contentsDigest(expected) {
+		add(IssueStamp, domain.Chunk{}, i18n.T("indexed from other chunks"))
+	}
+	return report, nil
+}
+
+// Repair rebuilds the store from sources by ingesting them anew. Vectors
+// the store holds for unchanged chunks with the right dimension are reused
+// when it is persistent and the embedder the same; the rest are embedded.
+func (s *RAGServiceImpl) Repair(ctx context.Context, sources []Source, progress func(domain.IngestProgress)) (domain.IngestReport, error) {
+	if p, ok := s.store.(vectorstore.Persistent); ok {
+		// Forget what the store holds, so that the ingest rebuilds it
+		// rather than taking it as current.
+		stamp, err := p.Stamp()
+		if err != nil {
+			return domain.IngestReport{}, err
+		}
+		if err := p.SetStamp(vectorstore.Stamp{Embedder: stamp.Embedder}); err != nil {
+			return domain.IngestReport{}, err
+		}
+	}
+	return s.IngestSources(ctx, sources, progress)
+}
+
+// corpusChunks loads and chunks sources as an ingest stores them, with
+// document keywords when they go into the payloads.
+func (s *RAGServiceImpl) corpusChunks(ctx context.Context, sources []Source) ([]domain.Chunk, error) {
+	documents, chunkers, _, err := s.load(ctx, sources, func(string, int, int) {})
+	if err != nil {
+		return nil, err
+	}
+	var kw *keywords.Extractor
+	if s.keywordPayloads {
+		contents := make([]string, len(documents))
+		for i, d := range documents {
+			contents[i] = d.Content
+		}
+		kw = keywords.NewExtractor(contents)
+	}
+	var out []domain.Chunk
+	for i, d := range documents {
+		chunks, err := s.chunkDocument(d, chunkers[i])
+		if err != nil {
+			return nil, err
+		}
+		if kw != nil {
+			docKeywords := kw.Document(i, s.keywordsPerDocument)
+			for j := range chunks {
+				chunks[j].Keywords = docKeywords
+			}
+		}
+		out = append(out, chunks...)
+	}
+	return out, nil
+}
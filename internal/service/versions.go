@@ -0,0 +1,211 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"rag/internal/domain"
+	"rag/internal/vectorstore"
+)
+
+// Kinds of ChunkChange.
+const (
+	ChunkUnchanged = "unchanged"
+	ChunkChanged   = "changed"
+	ChunkAdded     = "added"
+	ChunkRemoved   = "removed"
+)
+
+// ChunkChange is how a chunk differs between two versions of a corpus.
+// Added chunks have no Old chunk and removed ones no New chunk.
+type ChunkChange struct {
+	Kind       string
+	Old, New   domain.Chunk
+	Similarity float64
+}
+
+// versionVector is a chunk vector prepared for many comparisons: its norm,
+// and for sparse vectors (such as TF-IDF ones) the positions of its
+// non-zero entries.
+type versionVector struct {
+	values  []float64
+	nonzero []int32
+	norm    float64
+}
+
+func newVersionVector(v []float64) versionVector {
+	vv := versionVector{values: v, norm: norm(v)}
+	var nz []int32
+	for i, x := range v {
+		if x != 0 {
+			nz = append(nz, int32(i))
+			if len(nz) > len(v)/4 {
+				return vv
+			}
+		}
+	}
+	vv.nonzero = nz
+	return vv
+}
+
+func (a versionVector) cosine(b versionVector) float64 {
+	if a.norm == 0 || b.norm == 0 {
+		return 0
+	}
+	if b.nonzero != nil && (a.nonzero == nil || len(b.nonzero) < len(a.nonzero)) {
+		a, b = b, a
+	}
+	d := 0.0
+	if a.nonzero != nil {
+		for _, i := range a.nonzero {
+			if int(i) < len(b.values) {
+				d += a.values[i] * b.values[i]
+			}
+		}
+	} else {
+		n := min(len(a.values), len(b.values))
+		for i := 0; i < n; i++ {
+			d += a.values[i] * b.values[i]
+		}
+	}
+	return d / (a.norm * b.norm)
+}
+
+// CompareVersions compares two versions of a corpus indexed together. The
+// version function tells whether a chunk belongs to the old version, and
+// the path of its document within that version, so that the documents of
+// the two versions correspond. Chunks with the same text on both sides are
+// unchanged. The others are paired one to one by cosine similarity of their
+// vectors, most similar first, and pairs of at least threshold are changed:
+// first within corresponding documents, then, for chunks that moved, across
+// the corpus. New chunks left unpaired were added and old ones removed.
+// Changes are in order of kind, then of path and position.
+func (s *RAGServiceImpl) CompareVersions(version func(domain.Chunk) (old bool, document string), threshold float64) ([]ChunkChange, error) {
+	scanner, ok := s.store.(vectorstore.Scanner)
+	if !ok {
+		return nil, ErrScanUnsupported
+	}
+	chunks, vectors, err := scanner.All()
+	if err != nil {
+		return nil, err
+	}
+	chunks, vectors = vectorstore.Live(chunks, vectors)
+	var olds, news []int
+	documents := make([]string, len(chunks))
+	// byText lists the old chunks with each text, not yet paired.
+	byText := make(map[string][]int)
+	for i := range chunks {
+		s.hydrateChunk(&chunks[i])
+		var old bool
+		old, documents[i] = version(chunks[i])
+		if old {
+			olds = append(olds, i)
+			key := versionKey(chunks[i].Text)
+			byText[key] = append(byText[key], i)
+		} else {
+			news = append(news, i)
+		}
+	}
+	paired := make(map[int]bool)
+	var out []ChunkChange
+	var rest []int
+	for _, n := range news {
+		key := versionKey(chunks[n].Text)
+		if same := byText[key]; len(same) > 0 {
+			byText[key] = same[1:]
+			paired[same[0]] = true
+			out = append(out, ChunkChange{Kind: ChunkUnchanged, Old: chunks[same[0]], New: chunks[n], Similarity: 1})
+			continue
+		}
+		rest = append(rest, n)
+	}
+
+	prepared := make(map[int]versionVector)
+	prepare := func(i int) versionVector {
+		v, ok := prepared[i]
+		if !ok {
+			v = newVersionVector(vectors[i])
+			prepared[i] = v
+		}
+		return v
+	}
+	matched := make(map[int]bool)
+	// match pairs the unmatched new chunks with the unpaired old chunks that
+	// candidates offers them, most similar pairs first.
+	match := func(candidates func(n int) []int) {
+		type pair struct {
+			old, new int
+			sim      float64
+		}
+		var pairs []pair
+		for _, n := range rest {
+			if matched[n] {
+				continue
+			}
+			for _, o := range candidates(n) {
+				if paired[o] {
+					continue
+				}
+				if sim := prepare(n).cosine(prepare(o)); sim >= threshold {
+					pairs = append(pairs, pair{o, n, sim})
+				}
+			}
+		}
+		sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].sim > pairs[j].sim })
+		for _, p := range pairs {
+			if paired[p.old] || matched[p.new] {
+				continue
+			}
+			paired[p.old], matched[p.new] = true, true
+			out = append(out, ChunkChange{Kind: ChunkChanged, Old: chunks[p.old], New: chunks[p.new], Similarity: p.sim})
+		}
+	}
+	byDocument := make(map[string][]int)
+	for _, o := range olds {
+		if !paired[o] {
+			byDocument[documents[o]] = append(byDocument[documents[o]], o)
+		}
+	}
+	match(func(n int) []int { return byDocument[documents[n]] })
+	var left []int
+	for _, o := range olds {
+		if !paired[o] {
+			left = append(left, o)
+		}
+	}
+	match(func(int) []int { return left })
+
+	for _, n := range rest {
+		if !matched[n] {
+			out = append(out, ChunkChange{Kind: ChunkAdded, New: chunks[n]})
+		}
+	}
+	for _, o := range olds {
+		if !paired[o] {
+			out = append(out, ChunkChange{Kind: ChunkRemoved, Old: chunks[o]})
+		}
+	}
+
+	rank := map[string]int{ChunkChanged: 0, ChunkAdded: 1, ChunkRemoved: 2, ChunkUnchanged: 3}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Kind != b.Kind {
+			return rank[a.Kind] < rank[b.Kind]
+		}
+		ca, cb := a.New, b.New
+		if a.Kind == ChunkRemoved {
+			ca, cb = a.Old, b.Old
+		}
+		if ca.Path != cb.Path {
+			return ca.Path < cb.Path
+		}
+		return ca.Index < cb.Index
+	})
+	return out, nil
+}
+
+// versionKey is the text of a chunk with whitespace collapsed, so that
+// reflowed but otherwise equal chunks count as unchanged.
+func versionKey(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,188 @@
+package snippet
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+
+	"rag/internal/textutil"
+)
+
+// DefaultWidth is the target snippet width in terminal columns.
+const DefaultWidth = 200
+
+const ellipsis = "…"
+
+// cluster is one grapheme cluster of the text: what the terminal draws as
+// a single character.
+type cluster struct {
+	pos   int  // byte offset in the text
+	col   int  // column the cluster starts at
+	width int  // columns it occupies
+	space bool // whitespace, where snippets may be cut
+}
+
+// Generate extracts the window of about width columns of text that contains
+// the most query terms. When no term matches, the window is centered on the
+// first of the given spans (e.g. the most similar sentence), or taken from
+// the beginning of the text. Cut ends are marked with ellipses. Widths are
+// measured in terminal columns, so CJK text and emoji are not cut in half
+// or allowed to overflow.
+func Generate(text, query string, spans [][2]int, width int) string {
+	if width <= 0 {
+		width = DefaultWidth
+	}
+	if textutil.Width(text) <= width {
+		return collapseSpaces(text)
+	}
+	cl := clusters(text)
+	total := columnOf(cl, len(cl))
+	startCol := 0
+	if hits := termHits(text, query, cl); len(hits) > 0 {
+		startCol = densestWindow(hits, width)
+	} else if len(spans) > 0 {
+		s, e := columnAt(cl, spans[0][0]), columnAt(cl, spans[0][1])
+		startCol = (s+e)/2 - width/2
+	}
+	if startCol > total-width {
+		startCol = total - width
+	}
+	if startCol < 0 {
+		startCol = 0
+	}
+	start := sort.Search(len(cl), func(i int) bool { return cl[i].col >= startCol })
+	end := start
+	for end < len(cl) && cl[end].col+cl[end].width-cl[start].col <= width {
+		end++
+	}
+	// Snap to nearby word boundaries so words are not cut in half. Text
+	// without spaces nearby (as in Chinese or Japanese) is cut where it is.
+	const slack = 20 // columns
+	for i := start - 1; i >= 0 && cl[start].col-cl[i].col <= slack; i-- {
+		if i == 0 || cl[i-1].space {
+			start = i
+			break
+		}
+	}
+	for i := end; i <= len(cl) && columnOf(cl, i)-columnOf(cl, end) <= slack; i++ {
+		if i == len(cl) || cl[i].space {
+			end = i
+			break
+		}
+	}
+	from, to := cl[start].pos, offset(text, cl, end)
+	out := strings.TrimSpace(text[from:to])
+	if strings.TrimSpace(text[:from]) != "" {
+		out = ellipsis + out
+	}
+	if strings.TrimSpace(text[to:]) != "" {
+		out += ellipsis
+	}
+	return collapseSpaces(out)
+}
+
+// clusters splits text into grapheme clusters with their columns.
+func clusters(text string) []cluster {
+	var out []cluster
+	pos, col := 0, 0
+	state := -1
+	for rest := text; rest != ""; {
+		var c string
+		var w int
+		c, rest, w, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		r, _ := utf8.DecodeRuneInString(c)
+		out = append(out, cluster{pos: pos, col: col, width: w, space: unicode.IsSpace(r)})
+		pos += len(c)
+		col += w
+	}
+	return out
+}
+
+// offset is the byte offset of cluster i, or the text length past the end.
+func offset(text string, cl []cluster, i int) int {
+	if i >= len(cl) {
+		return len(text)
+	}
+	return cl[i].pos
+}
+
+// columnOf is the column of cluster i, or the text width past the end.
+func columnOf(cl []cluster, i int) int {
+	if i >= len(cl) {
+		last := cl[len(cl)-1]
+		return last.col + last.width
+	}
+	return cl[i].col
+}
+
+// columnAt returns the column of the cluster at byte offset pos.
+func columnAt(cl []cluster, pos int) int {
+	return columnOf(cl, sort.Search(len(cl), func(i int) bool { return cl[i].pos >= pos }))
+}
+
+// termHits returns the columns of words in text that match a query word,
+// allowing inflected and misspelled forms.
+func termHits(text, query string, cl []cluster) []int {
+	qset := textutil.TokenSet(query)
+	if len(qset) == 0 {
+		return nil
+	}
+	m := textutil.NewTermMatcher(query)
+	var hits []int
+	for _, loc := range textutil.TokenSpans(text) {
+		if m.Match(text[loc[0]:loc[1]]) {
+			hits = append(hits, columnAt(cl, loc[0]))
+		}
+	}
+	// Words of scripts written without spaces are found inside the runs
+	// the tokenizer sees as one word.
+	for term := range qset {
+		if !unspaced(term) {
+			continue
+		}
+		for i := 0; ; {
+			j := strings.Index(text[i:], term)
+			if j < 0 {
+				break
+			}
+			hits = append(hits, columnAt(cl, i+j))
+			i += j + len(term)
+		}
+	}
+	sort.Ints(hits)
+	return hits
+}
+
+// unspaced reports whether term is written in a script that does not
+// separate words with spaces.
+func unspaced(term string) bool {
+	for _, r := range term {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai) {
+			return true
+		}
+	}
+	return false
+}
+
+// densestWindow returns the window start covering the most hits, leaving a
+// little leading context before the first covered hit.
+func densestWindow(hits []int, width int) int {
+	best, bestCount := hits[0], 0
+	j := 0
+	for i := range hits {
+		for j < len(hits) && hits[j] < hits[i]+width*3/4 {
+			j++
+		}
+		if j-i > bestCount {
+			best, bestCount = hits[i], j-i
+		}
+	}
+	return best - width/4
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
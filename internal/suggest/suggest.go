@@ -0,0 +1,122 @@
+package suggest
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"rag/internal/textutil"
+)
+
+// minBigramCount filters out bigrams too rare to be useful as phrases.
+const minBigramCount = 2
+
+// Index offers query completions from corpus vocabulary and bigrams.
+type Index struct {
+	terms   []string // sorted for prefix lookup
+	freq    map[string]int
+	bigrams map[string]map[string]int
+}
+
+// NewIndex builds a completion index over the given texts.
+func NewIndex(texts []string) *Index {
+	idx := &Index{freq: make(map[string]int), bigrams: make(map[string]map[string]int)}
+	for _, text := range texts {
+		prev := ""
+		for _, tok := range textutil.Words(text) {
+			if textutil.IsStopword(tok) || utf8.RuneCountInString(tok) < 2 {
+				prev = ""
+				continue
+			}
+			idx.freq[tok]++
+			if prev != "" {
+				next, ok := idx.bigrams[prev]
+				if !ok {
+					next = make(map[string]int)
+					idx.bigrams[prev] = next
+				}
+				next[tok]++
+			}
+			prev = tok
+		}
+	}
+	idx.terms = make([]string, 0, len(idx.freq))
+	for t := range idx.freq {
+		idx.terms = append(idx.terms, t)
+	}
+	sort.Strings(idx.terms)
+	return idx
+}
+
+// Complete returns up to n completions for the last, partially typed word
+// of input. A completion replaces that word and may be a single term or a
+// two-word phrase. Terms following the previous word come first.
+func (idx *Index) Complete(input string, n int) []string {
+	if idx == nil || n <= 0 || input == "" || strings.HasSuffix(input, " ") {
+		return nil
+	}
+	words := strings.Fields(strings.ToLower(input))
+	prefix := words[len(words)-1]
+	if utf8.RuneCountInString(prefix) < 2 {
+		return nil
+	}
+	var out []string
+	seen := make(map[string]struct{})
+	add := func(s string) {
+		if _, ok := seen[s]; ok || s == prefix {
+			return
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	if len(words) > 1 {
+		for _, t := range byCount(idx.bigrams[words[len(words)-2]], prefix, 1) {
+			add(t)
+		}
+	}
+	matches := idx.withPrefix(prefix)
+	for _, t := range matches {
+		add(t)
+	}
+	if len(matches) > n {
+		matches = matches[:n]
+	}
+	for _, t := range matches {
+		for _, next := range byCount(idx.bigrams[t], "", minBigramCount) {
+			add(t + " " + next)
+		}
+	}
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// withPrefix returns vocabulary terms starting with prefix, most frequent first.
+func (idx *Index) withPrefix(prefix string) []string {
+	i := sort.SearchStrings(idx.terms, prefix)
+	var out []string
+	for ; i < len(idx.terms) && strings.HasPrefix(idx.terms[i], prefix); i++ {
+		out = append(out, idx.terms[i])
+	}
+	sort.SliceStable(out, func(a, b int) bool { return idx.freq[out[a]] > idx.freq[out[b]] })
+	return out
+}
+
+// byCount returns keys of counts starting with prefix and seen at least min
+// times, most frequent first.
+func byCount(counts map[string]int, prefix string, min int) []string {
+	var out []string
+	for t, c := range counts {
+		if c >= min && strings.HasPrefix(t, prefix) {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(a, b int) bool {
+		if counts[out[a]] != counts[out[b]] {
+			return counts[out[a]] > counts[out[b]]
+		}
+		return out[a] < out[b]
+	})
+	return out
+}
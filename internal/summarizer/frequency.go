@@ -5,37 +5,74 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+
+	"rag/internal/domain"
+	"rag/internal/langdetect"
+	"rag/internal/textutil"
 )
 
 // FrequencySummarizer ranks sentences by word frequency (stopwords filtered).
 type FrequencySummarizer struct {
-	tokenPattern *regexp.Regexp
-	stopwords    map[string]struct{}
+	tokenPattern    *regexp.Regexp
+	sentencePattern *regexp.Regexp
+	stopwords       map[string]struct{}
+	language        string
+}
+
+// Config configures the frequency summarizer.
+type Config struct {
+	// Language is the ISO 639-1 code of the summary language. Sentences in
+	// other languages are left out, so mixed-language corpora yield a
+	// coherent summary. Empty or "auto" picks the dominant language.
+	Language string
 }
 
 // NewFrequencySummarizer creates a frequency-based sentence ranker summarizer.
-func NewFrequencySummarizer() *FrequencySummarizer {
+func NewFrequencySummarizer(cfg Config) *FrequencySummarizer {
+	language := strings.ToLower(cfg.Language)
+	if language == "auto" {
+		language = ""
+	}
 	return &FrequencySummarizer{
-		tokenPattern: regexp.MustCompile(`\p{L}+(?:['’]\p{L}+)*`),
-		stopwords:    defaultStopwords(),
+		tokenPattern:    regexp.MustCompile(`\p{L}+(?:['’]\p{L}+)*`),
+		sentencePattern: regexp.MustCompile(`(?m)(?U)([^.!?]+[.!?])`),
+		stopwords:       defaultStopwords(),
+		language:        language,
 	}
 }
 
-// Summarize returns a short summary by ranking sentences using token frequency.
-func (s *FrequencySummarizer) Summarize(text string, maxSentences int) (string, error) {
-	if maxSentences <= 0 {
-		maxSentences = 5
-	}
+// Default budget sizes per unit, used when the budget size is not positive.
+const (
+	defaultSentences = 5
+	defaultWords     = 60
+	defaultTokens    = 80
+)
+
+// Summarize returns a short summary by ranking sentences using token
+// frequency and keeping the best ones that fit the budget.
+func (s *FrequencySummarizer) Summarize(text string, budget domain.Budget) (string, error) {
 	// Split into sentences
-	sentences := regexp.MustCompile(`(?m)(?U)([^.!?]+[.!?])`).FindAllString(text, -1)
+	sentences := s.sentencePattern.FindAllString(text, -1)
 	if len(sentences) == 0 {
 		return strings.TrimSpace(text), nil
 	}
+	// Keep to one language
+	language := s.language
+	if language == "" {
+		language = dominantLanguage(sentences)
+	}
+	sentences = inLanguage(sentences, language)
+	stopwords := langdetect.Stopwords(language)
+	isStopword := func(tok string) bool {
+		_, common := s.stopwords[tok]
+		_, local := stopwords[tok]
+		return common || local
+	}
 	// Compute word frequencies
 	freq := map[string]float64{}
 	for _, sent := range sentences {
 		for _, tok := range s.tokens(sent) {
-			if _, ok := s.stopwords[tok]; ok {
+			if isStopword(tok) {
 				continue
 			}
 			freq[tok]++
@@ -74,20 +111,105 @@ func (s *FrequencySummarizer) Summarize(text string, maxSentences int) (string,
 		scores[i] = pair{i, sscore}
 	}
 	sort.Slice(scores, func(i, j int) bool { return scores[i].score > scores[j].score })
-	if maxSentences > len(scores) {
-		maxSentences = len(scores)
+	ranked := make([]int, len(scores))
+	for i, p := range scores {
+		ranked[i] = p.idx
 	}
-	// Keep original order among selected
-	selected := make([]int, maxSentences)
-	for i := 0; i < maxSentences; i++ {
-		selected[i] = scores[i].idx
+	for i := range sentences {
+		sentences[i] = strings.TrimSpace(sentences[i])
+	}
+	return strings.Join(selectSentences(sentences, ranked, budget), " "), nil
+}
+
+// selectSentences picks sentences in rank order while they fit the budget
+// and returns them in document order. When not even the best sentence fits
+// a word or token budget, it is cut to fit.
+func selectSentences(sentences []string, ranked []int, budget domain.Budget) []string {
+	var cost func(string) int
+	size := budget.Size
+	switch budget.Unit {
+	case domain.BudgetWords:
+		cost = func(s string) int { return len(strings.Fields(s)) }
+		if size <= 0 {
+			size = defaultWords
+		}
+	case domain.BudgetTokens:
+		cost = textutil.EstimateTokens
+		if size <= 0 {
+			size = defaultTokens
+		}
+	default:
+		cost = func(string) int { return 1 }
+		if size <= 0 {
+			size = defaultSentences
+		}
+	}
+	var selected []int
+	used := 0
+	for _, idx := range ranked {
+		if c := cost(sentences[idx]); used+c <= size {
+			selected = append(selected, idx)
+			used += c
+		}
+	}
+	if len(selected) == 0 && len(ranked) > 0 {
+		return []string{cutToBudget(sentences[ranked[0]], size, cost)}
 	}
+	// Keep original order among selected
 	sort.Ints(selected)
+	out := make([]string, len(selected))
+	for i, idx := range selected {
+		out[i] = sentences[idx]
+	}
+	return out
+}
+
+// cutToBudget keeps the leading words of s that fit within size.
+func cutToBudget(s string, size int, cost func(string) int) string {
+	words := strings.Fields(s)
+	n := len(words)
+	for n > 0 && cost(strings.Join(words[:n], " ")+" …") > size {
+		n--
+	}
+	if n == 0 {
+		return ""
+	}
+	return strings.Join(words[:n], " ") + " …"
+}
+
+// dominantLanguage returns the language most of the text is written in.
+func dominantLanguage(sentences []string) string {
+	weight := make(map[string]int)
+	best := ""
+	for _, sent := range sentences {
+		lang := langdetect.Detect(sent)
+		if lang == "" {
+			continue
+		}
+		weight[lang] += len(sent)
+		if best == "" || weight[lang] > weight[best] {
+			best = lang
+		}
+	}
+	return best
+}
+
+// inLanguage keeps sentences in language or of undetermined language; if
+// none remain, all sentences are kept.
+func inLanguage(sentences []string, language string) []string {
+	if language == "" {
+		return sentences
+	}
 	var out []string
-	for _, idx := range selected {
-		out = append(out, strings.TrimSpace(sentences[idx]))
+	for _, sent := range sentences {
+		if lang := langdetect.Detect(sent); lang == "" || lang == language {
+			out = append(out, sent)
+		}
+	}
+	if len(out) == 0 {
+		return sentences
 	}
-	return strings.Join(out, " "), nil
+	return out
 }
 
 func (s *FrequencySummarizer) tokens(text string) []string {
@@ -0,0 +1,92 @@
+package summarizer
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+
+	"rag/internal/domain"
+)
+
+// defaultFanIn is how many summaries are merged per reduce step.
+const defaultFanIn = 16
+
+// Hierarchical summarizes a corpus map-reduce style: every document is
+// summarized on its own (concurrently), then the summaries are merged in
+// batches until one summary remains. Each pass only sees small inputs, so
+// large corpora are never processed as one text.
+type Hierarchical struct {
+	base    domain.Summarizer
+	workers int
+	fanIn   int
+}
+
+// NewHierarchical wraps base; workers <= 0 uses one worker per CPU.
+func NewHierarchical(base domain.Summarizer, workers int) *Hierarchical {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &Hierarchical{base: base, workers: workers, fanIn: defaultFanIn}
+}
+
+// Summarize summarizes a single text with the wrapped summarizer.
+func (h *Hierarchical) Summarize(text string, budget domain.Budget) (string, error) {
+	return h.base.Summarize(text, budget)
+}
+
+// SummarizeCorpus summarizes each document, then reduces the summaries.
+func (h *Hierarchical) SummarizeCorpus(documents []string, budget domain.Budget) (string, error) {
+	level, err := h.summarizeEach(documents, budget)
+	if err != nil {
+		return "", err
+	}
+	for len(level) > 1 {
+		batches := make([]string, 0, (len(level)+h.fanIn-1)/h.fanIn)
+		for i := 0; i < len(level); i += h.fanIn {
+			end := min(i+h.fanIn, len(level))
+			batches = append(batches, strings.Join(level[i:end], "\n"))
+		}
+		if level, err = h.summarizeEach(batches, budget); err != nil {
+			return "", err
+		}
+	}
+	if len(level) == 0 {
+		return "", nil
+	}
+	return level[0], nil
+}
+
+// summarizeEach summarizes texts concurrently, dropping empty and repeated
+// summaries so duplicate documents do not crowd out the rest.
+func (h *Hierarchical) summarizeEach(texts []string, budget domain.Budget) ([]string, error) {
+	out := make([]string, len(texts))
+	errs := make([]error, len(texts))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(h.workers, len(texts)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				out[i], errs[i] = h.base.Summarize(texts[i], budget)
+			}
+		}()
+	}
+	for i := range texts {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	kept := out[:0]
+	seen := make(map[string]bool)
+	for i, s := range out {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if s = strings.TrimSpace(s); s != "" && !seen[s] {
+			seen[s] = true
+			kept = append(kept, s)
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,143 @@
+// Package sweep compares chunker parameters on the user's own corpus and
+// queries: an index is built for every point of a grid of chunk sizes and
+// overlaps, and each is scored on how high it ranks the passages judged
+// relevant to a set of queries. As chunk boundaries move with the
+// parameters, a result counts as relevant when it comes from the same file
+// as a relevant passage and the two share at least half their sentences.
+package sweep
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"rag/internal/domain"
+	"rag/internal/feedback"
+	"rag/internal/textutil"
+)
+
+// Params are the sentence chunker parameters of one index.
+type Params struct {
+	SentencesPerChunk, OverlapSentences int
+}
+
+// Result is how well the index built with Params answered the queries.
+type Result struct {
+	Params
+	// Chunks is the number of chunks indexed.
+	Chunks  int
+	Queries int
+	// Top1 and TopK count the queries with a relevant result first, and
+	// among the first k.
+	Top1, TopK int
+	// MRR is the mean reciprocal rank of the first relevant result,
+	// counting queries with none in the first k as 0.
+	MRR float64
+	// Index is the time taken to chunk, embed and index the corpus.
+	Index time.Duration
+	Err   error
+}
+
+// Grid returns every pairing of sentences and overlaps in which chunks
+// still advance, i.e. the overlap is less than the chunk size, each once.
+func Grid(sentences, overlaps []int) []Params {
+	var out []Params
+	seen := make(map[Params]bool)
+	for _, s := range sentences {
+		for _, o := range overlaps {
+			p := Params{SentencesPerChunk: s, OverlapSentences: o}
+			if s > 0 && o >= 0 && o < s && !seen[p] {
+				seen[p] = true
+				out = append(out, p)
+			}
+		}
+	}
+	return out
+}
+
+// Run calls build for every point of grid, up to parallel at a time, and
+// returns the results in grid order.
+func Run(grid []Params, parallel int, build func(Params) Result) []Result {
+	parallel = max(parallel, 1)
+	results := make([]Result, len(grid))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, p := range grid {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = build(p)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Score searches the first k results of each query with a relevant passage
+// and adds up where the first relevant one ranks.
+func Score(res *Result, examples []feedback.Example, k int, search func(query string, k int) ([]domain.SearchResult, error)) error {
+	for _, e := range examples {
+		if len(e.Positives) == 0 {
+			continue
+		}
+		results, err := search(e.Query, k)
+		if err != nil {
+			return err
+		}
+		res.Queries++
+		for rank, r := range results {
+			if !Relevant(r.Chunk, e.Positives) {
+				continue
+			}
+			if rank == 0 {
+				res.Top1++
+			}
+			res.TopK++
+			res.MRR += 1 / float64(rank+1)
+			break
+		}
+	}
+	if res.Queries > 0 {
+		res.MRR /= float64(res.Queries)
+	}
+	return nil
+}
+
+// Relevant reports whether ch matches one of the passages: it comes from
+// the same file, and at least half the sentences of the longer of the two
+// texts are in the other. A chunk much larger than the passage thus does not
+// count: its answer is diluted among text that is not.
+func Relevant(ch domain.Chunk, passages []feedback.Passage) bool {
+	for _, p := range passages {
+		if p.Path == ch.Path && overlaps(p.Text, ch.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+func overlaps(a, b string) bool {
+	sa, sb := sentences(a), sentences(b)
+	if len(sa) == 0 || len(sb) == 0 {
+		return false
+	}
+	shared := 0
+	for s := range sa {
+		if _, ok := sb[s]; ok {
+			shared++
+		}
+	}
+	return 2*shared >= max(len(sa), len(sb))
+}
+
+// sentences returns the set of sentences of text, with runs of whitespace
+// collapsed.
+func sentences(text string) map[string]struct{} {
+	out := make(map[string]struct{})
+	for _, sp := range textutil.SentenceSpans(text) {
+		out[strings.Join(strings.Fields(text[sp[0]:sp[1]]), " ")] = struct{}{}
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package textcodec
+
+import (
+	"encoding/base64"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	initOnce sync.Once
+	encoder  *zstd.Encoder
+	decoder  *zstd.Decoder
+	initErr  error
+)
+
+func setup() error {
+	initOnce.Do(func() {
+		encoder, initErr = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		if initErr != nil {
+			return
+		}
+		decoder, initErr = zstd.NewReader(nil)
+	})
+	return initErr
+}
+
+// Compress returns the zstd-compressed form of text.
+func Compress(text string) ([]byte, error) {
+	if err := setup(); err != nil {
+		return nil, err
+	}
+	return encoder.EncodeAll([]byte(text), nil), nil
+}
+
+// Decompress reverses Compress.
+func Decompress(data []byte) (string, error) {
+	if err := setup(); err != nil {
+		return "", err
+	}
+	out, err := decoder.DecodeAll(data, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// CompressBase64 compresses text and encodes it for JSON payloads.
+func CompressBase64(text string) (string, error) {
+	data, err := Compress(text)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecompressBase64 reverses CompressBase64.
+func DecompressBase64(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return Decompress(data)
+}
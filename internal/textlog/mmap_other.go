@@ -0,0 +1,15 @@
+//go:build !unix
+
+package textlog
+
+import (
+	"errors"
+	"os"
+)
+
+var errMmapUnsupported = errors.New("mmap unsupported")
+
+// mmap is not available on this platform; Log falls back to ReadAt.
+func mmap(f *os.File, size int64) ([]byte, error) { return nil, errMmapUnsupported }
+
+func unmap(data []byte) error { return nil }
@@ -0,0 +1,22 @@
+//go:build unix
+
+package textlog
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+var errMmapUnsupported = errors.New("mmap unsupported")
+
+func mmap(f *os.File, size int64) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func unmap(data []byte) error {
+	if data == nil {
+		return nil
+	}
+	return syscall.Munmap(data)
+}
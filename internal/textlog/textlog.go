@@ -0,0 +1,174 @@
+package textlog
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"sync"
+
+	"rag/internal/textcodec"
+)
+
+// Ref locates a text record inside a Log.
+type Ref struct {
+	Offset int64
+	Length int
+}
+
+// Log is an append-only file of chunk texts. Records are read back through a
+// read-only memory mapping of the file, so callers only need to keep the
+// small Ref values in memory instead of the texts themselves.
+type Log struct {
+	mu       sync.RWMutex
+	file     *os.File
+	writer   *bufio.Writer
+	size     int64
+	mapped   []byte
+	compress bool
+}
+
+// Create opens a fresh log file in dir. The file is removed on Close.
+// With compress set, records are stored zstd-compressed.
+func Create(dir string, compress bool) (*Log, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, "chunks-*.log")
+	if err != nil {
+		return nil, err
+	}
+	return &Log{file: f, writer: bufio.NewWriterSize(f, 1<<20), compress: compress}, nil
+}
+
+// Append writes text to the end of the log and returns its location.
+func (l *Log) Append(text string) (Ref, error) {
+	record := []byte(text)
+	if l.compress {
+		var err error
+		if record, err = textcodec.Compress(text); err != nil {
+			return Ref{}, err
+		}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return Ref{}, errors.New("text log closed")
+	}
+	n, err := l.writer.Write(record)
+	if err != nil {
+		return Ref{}, err
+	}
+	ref := Ref{Offset: l.size, Length: n}
+	l.size += int64(n)
+	return ref, nil
+}
+
+// Read returns the text stored at ref.
+func (l *Log) Read(ref Ref) (string, error) {
+	record, err := l.readRecord(ref)
+	if err != nil {
+		return "", err
+	}
+	if l.compress {
+		return textcodec.Decompress(record)
+	}
+	return string(record), nil
+}
+
+func (l *Log) readRecord(ref Ref) ([]byte, error) {
+	end := ref.Offset + int64(ref.Length)
+	l.mu.RLock()
+	if end <= int64(len(l.mapped)) {
+		record := append([]byte(nil), l.mapped[ref.Offset:end]...)
+		l.mu.RUnlock()
+		return record, nil
+	}
+	l.mu.RUnlock()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil, errors.New("text log closed")
+	}
+	if end > l.size {
+		return nil, errors.New("text log reference out of range")
+	}
+	if end > int64(len(l.mapped)) {
+		if err := l.remap(); err != nil {
+			return nil, err
+		}
+	}
+	if end <= int64(len(l.mapped)) {
+		return append([]byte(nil), l.mapped[ref.Offset:end]...), nil
+	}
+	// Memory mapping unavailable on this platform; read from the file.
+	buf := make([]byte, ref.Length)
+	if _, err := l.file.ReadAt(buf, ref.Offset); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Reset discards all records while keeping the log open.
+func (l *Log) Reset() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return errors.New("text log closed")
+	}
+	if err := unmap(l.mapped); err != nil {
+		return err
+	}
+	l.mapped = nil
+	l.writer.Reset(l.file)
+	if err := l.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := l.file.Seek(0, 0); err != nil {
+		return err
+	}
+	l.size = 0
+	return nil
+}
+
+// Close unmaps and deletes the log file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	_ = unmap(l.mapped)
+	l.mapped = nil
+	name := l.file.Name()
+	err := l.file.Close()
+	l.file = nil
+	if rmErr := os.Remove(name); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// remap flushes pending writes and maps the whole file again.
+// Callers must hold the write lock.
+func (l *Log) remap() error {
+	if err := l.writer.Flush(); err != nil {
+		return err
+	}
+	if err := unmap(l.mapped); err != nil {
+		return err
+	}
+	l.mapped = nil
+	if l.size == 0 {
+		return nil
+	}
+	data, err := mmap(l.file, l.size)
+	if err != nil {
+		if errors.Is(err, errMmapUnsupported) {
+			return nil
+		}
+		return err
+	}
+	l.mapped = data
+	return nil
+}
@@ -0,0 +1,141 @@
+package textutil
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TermMatcher decides whether a word of a text matches one of the query
+// terms, tolerating inflection and spelling variants so that highlighting
+// finds "optimisation" for "optimization" and "indexes" for "indexing".
+type TermMatcher struct {
+	terms []matchTerm
+}
+
+type matchTerm struct {
+	word, stem string
+	// maxEdits is the edit distance tolerated for the term's length.
+	maxEdits int
+}
+
+// NewTermMatcher creates a matcher for the words of query.
+func NewTermMatcher(query string) *TermMatcher {
+	m := &TermMatcher{}
+	for t := range TokenSet(query) {
+		m.terms = append(m.terms, matchTerm{word: t, stem: Stem(t), maxEdits: maxEdits(t)})
+	}
+	return m
+}
+
+// Empty reports whether the query had no words.
+func (m *TermMatcher) Empty() bool { return len(m.terms) == 0 }
+
+// Match reports whether word (any case or form) matches a query term: exactly, by
+// stem, or within an edit distance of 1 for words of 5 to 7 letters and 2
+// for longer ones. Short words only match exactly or by stem, since a
+// single edit turns them into other words.
+func (m *TermMatcher) Match(word string) bool {
+	word = NormalizeWord(word)
+	stem := Stem(word)
+	for _, t := range m.terms {
+		if t.matches(word, stem) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns how many distinct query terms the words of text match.
+func (m *TermMatcher) Count(text string) int {
+	matched := make([]bool, len(m.terms))
+	n := 0
+	for w := range TokenSet(text) {
+		stem := Stem(w)
+		for i, t := range m.terms {
+			if !matched[i] && t.matches(w, stem) {
+				matched[i] = true
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// MatchAll reports whether the words of text match every query term.
+func (m *TermMatcher) MatchAll(text string) bool {
+	return m.Count(text) == len(m.terms)
+}
+
+func (t matchTerm) matches(word, stem string) bool {
+	if word == t.word || stem == t.stem {
+		return true
+	}
+	return t.maxEdits > 0 && maxEdits(word) > 0 &&
+		(withinEdits(word, t.word, t.maxEdits) || withinEdits(stem, t.stem, t.maxEdits))
+}
+
+func maxEdits(word string) int {
+	switch n := utf8.RuneCountInString(word); {
+	case n < 5:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// stemSuffixes are stripped by Stem, longest first; the replacement keeps
+// related forms together (studies, study).
+var stemSuffixes = []struct{ suffix, replace string }{
+	{"izations", "iz"}, {"isations", "iz"}, {"ization", "iz"}, {"isation", "iz"},
+	{"ations", "ate"}, {"ation", "ate"}, {"ments", ""}, {"ment", ""},
+	{"ingly", ""}, {"edly", ""}, {"ings", ""}, {"ing", ""}, {"ies", "y"},
+	{"ied", "y"}, {"ers", ""}, {"er", ""}, {"ed", ""}, {"es", ""}, {"ly", ""}, {"s", ""},
+}
+
+// Stem reduces an English word to a rough stem by stripping one common
+// inflectional suffix, keeping at least three letters. It is deliberately
+// light: words of other languages are mostly left alone.
+func Stem(word string) string {
+	for _, s := range stemSuffixes {
+		if strings.HasSuffix(word, s.suffix) && utf8.RuneCountInString(word)-utf8.RuneCountInString(s.suffix) >= 3 {
+			if s.suffix == "s" && strings.HasSuffix(word, "ss") {
+				return word
+			}
+			return word[:len(word)-len(s.suffix)] + s.replace
+		}
+	}
+	return word
+}
+
+// withinEdits reports whether the Levenshtein distance of a and b is at
+// most max.
+func withinEdits(a, b string, max int) bool {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return false
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > max {
+			return false
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)] <= max
+}
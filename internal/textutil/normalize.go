@@ -0,0 +1,62 @@
+package textutil
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization selects how words are normalized for matching, beyond
+// lowercasing.
+type Normalization struct {
+	// NFKC applies Unicode compatibility normalization, so composed and
+	// decomposed accents, ligatures (ﬁ) and full-width letters match their
+	// usual forms.
+	NFKC bool
+	// FoldDiacritics strips accents and other marks, so "café" matches
+	// "cafe".
+	FoldDiacritics bool
+}
+
+var normalization Normalization
+
+// SetNormalization sets the normalization applied by Tokens and the
+// matchers built on it, in TF-IDF vectors, lexical search and highlighting
+// alike. Set it once, before ingesting: an index and its queries must be
+// tokenized the same way.
+func SetNormalization(n Normalization) { normalization = n }
+
+// foldMarks removes combining marks after canonical decomposition.
+func foldMarks() transform.Transformer {
+	return transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+}
+
+// NormalizeWord lowercases word and applies the configured normalization.
+func NormalizeWord(word string) string {
+	word = strings.ToLower(word)
+	if isASCII(word) {
+		return word
+	}
+	if normalization.NFKC {
+		word = norm.NFKC.String(word)
+	}
+	if normalization.FoldDiacritics {
+		if folded, _, err := transform.String(foldMarks(), word); err == nil {
+			word = folded
+		}
+	}
+	return word
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
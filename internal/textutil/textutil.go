@@ -0,0 +1,178 @@
+package textutil
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+var (
+	// Words are letters with any combining marks, so decomposed accents
+	// stay inside their word.
+	wordRe = regexp.MustCompile(`\p{L}[\p{L}\p{M}]*(?:['’]\p{L}[\p{L}\p{M}]*)*`)
+)
+
+// Tokens returns the words of text normalized for matching: lowercased and
+// normalized as set by SetNormalization.
+func Tokens(text string) []string {
+	words := wordRe.FindAllString(text, -1)
+	for i, w := range words {
+		words[i] = NormalizeWord(w)
+	}
+	return words
+}
+
+// Words returns the lowercased words of text as written, for display and
+// for language detection, which depend on the accents.
+func Words(text string) []string {
+	return wordRe.FindAllString(strings.ToLower(text), -1)
+}
+
+// TokenSpans returns the byte ranges of words in text.
+func TokenSpans(text string) [][]int {
+	return wordRe.FindAllStringIndex(text, -1)
+}
+
+// TokenSet returns the distinct normalized words of text.
+func TokenSet(text string) map[string]struct{} {
+	tokens := Tokens(text)
+	m := make(map[string]struct{}, len(tokens))
+	for _, t := range tokens {
+		m[t] = struct{}{}
+	}
+	return m
+}
+
+// SentenceSpans returns the byte ranges of sentences in text, trimmed of
+// surrounding whitespace. A sentence ends at a run of terminators (. ! ? and
+// their CJK forms) together with the closing quotes and brackets after it,
+// so "Really?!" and "He said “no.”" stay whole. A period followed directly
+// by a letter or digit, as in 3.14 or example.com, does not end a sentence,
+// and neither does punctuation before any words. Text after the last
+// terminator is a sentence of its own, so every word of text is covered.
+func SentenceSpans(text string) [][2]int {
+	var spans [][2]int
+	add := func(start, end int) {
+		if sp, ok := TrimSpan(text, start, end); ok {
+			spans = append(spans, sp)
+		}
+	}
+	start, words := 0, false
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if !isTerminator(r) {
+			words = words || !unicode.IsSpace(r) && !unicode.IsPunct(r)
+			continue
+		}
+		if !words {
+			continue
+		}
+		if r == '.' && i < len(text) {
+			next, _ := utf8.DecodeRuneInString(text[i:])
+			if unicode.IsLetter(next) || unicode.IsDigit(next) {
+				continue
+			}
+		}
+		for i < len(text) {
+			next, size := utf8.DecodeRuneInString(text[i:])
+			if !isTerminator(next) && !isCloser(next) {
+				break
+			}
+			i += size
+		}
+		add(start, i)
+		start, words = i, false
+	}
+	add(start, len(text))
+	return spans
+}
+
+func isTerminator(r rune) bool {
+	switch r {
+	case '.', '!', '?', '。', '！', '？':
+		return true
+	}
+	return false
+}
+
+// isCloser reports whether r closes a quotation or bracket.
+func isCloser(r rune) bool {
+	return r == '"' || r == '\'' || unicode.In(r, unicode.Pe, unicode.Pf)
+}
+
+// TrimSpan narrows [start, end) of s to exclude surrounding whitespace.
+func TrimSpan(s string, start, end int) ([2]int, bool) {
+	sub := s[start:end]
+	trimmedLeft := strings.TrimLeftFunc(sub, unicode.IsSpace)
+	start += len(sub) - len(trimmedLeft)
+	end = start + len(strings.TrimRightFunc(trimmedLeft, unicode.IsSpace))
+	return [2]int{start, end}, end > start
+}
+
+var stopwords = func() map[string]struct{} {
+	words := []string{
+		"a", "an", "the", "and", "or", "but", "if", "then", "else", "for", "to", "of", "in", "on", "at", "by", "with", "as", "is", "are", "was", "were", "be", "been", "being", "it", "this", "that", "these", "those", "from", "up", "down", "over", "under", "again", "further", "than", "so", "such", "into", "about", "between", "through", "during", "before", "after", "above", "below", "out", "off", "own", "same", "too", "very", "can", "will", "just", "don", "should", "now",
+	}
+	m := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		m[w] = struct{}{}
+	}
+	return m
+}()
+
+// IsStopword reports whether the lowercased token is a common English stopword.
+func IsStopword(token string) bool {
+	_, ok := stopwords[token]
+	return ok
+}
+
+// EstimateTokens approximates a BPE token count without a tokenizer:
+// about four ASCII characters per token, and one token per non-ASCII rune,
+// which errs on the safe side for CJK and Cyrillic text.
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// Width returns the number of terminal columns s occupies: wide (CJK)
+// characters and most emoji take two, combining marks none.
+func Width(s string) int {
+	return uniseg.StringWidth(s)
+}
+
+// Truncate shortens s to at most n columns, ending with an ellipsis when
+// cut. It never splits a grapheme cluster, so accented letters, emoji and
+// wide characters stay intact.
+func Truncate(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if Width(s) <= n {
+		return s
+	}
+	var b strings.Builder
+	used := 0
+	state := -1
+	for rest := s; rest != ""; {
+		var cluster string
+		var w int
+		cluster, rest, w, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		if used+w > n-1 {
+			break
+		}
+		b.WriteString(cluster)
+		used += w
+	}
+	return b.String() + "…"
+}
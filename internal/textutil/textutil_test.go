@@ -0,0 +1,94 @@
+package textutil
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sentenceSeeds are texts exercising the edge cases of SentenceSpans:
+// decimals and domains, runs of terminators, closing quotes, CJK
+// terminators, leading punctuation and a trailing sentence without a
+// terminator.
+var sentenceSeeds = []string{
+	"",
+	"   ",
+	"One. Two! Three?",
+	"Pi is 3.14 and the site is example.com. Next sentence.",
+	"Really?! \"Yes.\" (Fine.) He said “no.” She left…",
+	"これはペンです。あれは本ですか？はい！",
+	"... ?! leading punctuation. Then words",
+	"\n\nParagraph one.\n\nParagraph two without end",
+	"it's the dog's.'s ball.’ Then",
+	"\xff\xfe broken. utf8\x80.",
+}
+
+func FuzzSentenceSpans(f *testing.F) {
+	for _, s := range sentenceSeeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, text string) {
+		spans := SentenceSpans(text)
+		prev := 0
+		for i, sp := range spans {
+			if sp[0] < prev || sp[0] >= sp[1] || sp[1] > len(text) {
+				t.Fatalf("span %d %v out of order or bounds (previous end %d, length %d)", i, sp, prev, len(text))
+			}
+			prev = sp[1]
+			s := text[sp[0]:sp[1]]
+			first, _ := utf8.DecodeRuneInString(s)
+			last, _ := utf8.DecodeLastRuneInString(s)
+			if unicode.IsSpace(first) || unicode.IsSpace(last) {
+				t.Fatalf("span %d %q is not trimmed", i, s)
+			}
+		}
+		// Every word lies within a sentence.
+		for _, w := range TokenSpans(text) {
+			covered := false
+			for _, sp := range spans {
+				if sp[0] <= w[0] && w[1] <= sp[1] {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				t.Fatalf("word %q at %v is in no sentence of %v", text[w[0]:w[1]], w, spans)
+			}
+		}
+	})
+}
+
+func FuzzTokens(f *testing.F) {
+	for _, s := range sentenceSeeds {
+		f.Add(s)
+	}
+	f.Add("Straße ǅemal İstanbul café café ﬁne ＡＢＣ")
+	f.Fuzz(func(t *testing.T, text string) {
+		tokens := Tokens(text)
+		spans := TokenSpans(text)
+		if len(tokens) != len(spans) {
+			t.Fatalf("%d tokens for %d word spans", len(tokens), len(spans))
+		}
+		for i, tok := range tokens {
+			if tok == "" || !utf8.ValidString(tok) {
+				t.Fatalf("token %d %q is empty or not UTF-8", i, tok)
+			}
+			if want := NormalizeWord(text[spans[i][0]:spans[i][1]]); tok != want {
+				t.Fatalf("token %d is %q, want %q", i, tok, want)
+			}
+			if strings.ContainsFunc(tok, unicode.IsSpace) {
+				t.Fatalf("token %d %q contains white space", i, tok)
+			}
+		}
+		set := TokenSet(text)
+		for _, tok := range tokens {
+			if _, ok := set[tok]; !ok {
+				t.Fatalf("token %q missing from the token set", tok)
+			}
+		}
+		if len(set) > len(tokens) {
+			t.Fatalf("token set of %d for %d tokens", len(set), len(tokens))
+		}
+	})
+}
@@ -0,0 +1,322 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"rag/internal/bookmark"
+	"rag/internal/domain"
+	"rag/internal/feedback"
+	"rag/internal/i18n"
+	"rag/internal/opener"
+)
+
+// action is an entry of the per-result actions menu, run with Enter or its
+// key.
+type action struct {
+	key   string
+	label string
+	run   func(Model, domain.Chunk) (Model, tea.Cmd)
+}
+
+// neighborRadius is how many chunks before and after a result "show
+// neighbors" lists.
+const neighborRadius = 2
+
+// resultActions lists the actions offered for chunk.
+func (m Model) resultActions(chunk domain.Chunk) []action {
+	mark := i18n.T("Bookmark")
+	if m.bookmarks != nil {
+		if ok, _ := m.bookmarks.Has(chunk.ChunkID); ok {
+			mark = i18n.T("Remove bookmark")
+		}
+	}
+	actions := []action{
+		{"e", i18n.T("Open in editor"), Model.openResultInEditor},
+		{"c", i18n.T("Copy text"), Model.copyResultText},
+		{"p", i18n.T("Copy path"), Model.copyResultPath},
+		{"n", i18n.T("Show neighbors"), Model.showNeighbors},
+		{"s", i18n.T("Find similar"), Model.findSimilar},
+		{"b", mark, Model.toggleBookmark},
+	}
+	if m.feedback != nil && m.lastQuery != "" {
+		relevant, irrelevant := i18n.T("Relevant to the query"), i18n.T("Not relevant to the query")
+		if j, ok, _ := m.feedback.Get(m.lastQuery, chunk.ChunkID); ok {
+			if j.Relevant {
+				relevant = i18n.T("Withdraw: relevant")
+			} else {
+				irrelevant = i18n.T("Withdraw: not relevant")
+			}
+		}
+		actions = append(actions,
+			action{"+", relevant, func(m Model, chunk domain.Chunk) (Model, tea.Cmd) { return m.judgeResult(chunk, true) }},
+			action{"-", irrelevant, func(m Model, chunk domain.Chunk) (Model, tea.Cmd) { return m.judgeResult(chunk, false) }},
+		)
+	}
+	if m.translate != nil {
+		actions = append(actions, action{"t", i18n.Sprintf("Translate into %s", m.translateTo), Model.translateResult})
+	}
+	return actions
+}
+
+// openActions shows the actions menu for the selected result.
+func (m Model) openActions() Model {
+	m.mode = modeActions
+	m.actionCursor = 0
+	m.suggestions = nil
+	m.status = i18n.T("Actions: Enter or a key to run, Esc to return")
+	m.viewport.SetContent(m.renderActions())
+	m.viewport.GotoTop()
+	return m
+}
+
+// updateActions handles keys while the actions menu is open.
+func (m Model) updateActions(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	chunk := m.results[m.cursor].Chunk
+	actions := m.resultActions(chunk)
+	switch key := msg.String(); key {
+	case "esc":
+		m = m.closeActions()
+		m.status = i18n.T("Type to search.")
+		return m, nil
+	case "down":
+		m.actionCursor = (m.actionCursor + 1) % len(actions)
+	case "up":
+		m.actionCursor = (m.actionCursor - 1 + len(actions)) % len(actions)
+	case "enter":
+		return actions[m.actionCursor].run(m.closeActions(), chunk)
+	default:
+		for _, a := range actions {
+			if a.key == key {
+				return a.run(m.closeActions(), chunk)
+			}
+		}
+	}
+	m.viewport.SetContent(m.renderActions())
+	return m, nil
+}
+
+func (m Model) closeActions() Model {
+	m.mode = modeSearch
+	m.viewport.SetContent(m.renderCurrentResult())
+	return m
+}
+
+func (m Model) renderActions() string {
+	chunk := m.results[m.cursor].Chunk
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", i18n.Sprintf("Actions for %s#%d", filepath.Base(chunk.Path), chunk.Index))
+	for i, a := range m.resultActions(chunk) {
+		marker := "  "
+		label := a.label
+		if i == m.actionCursor {
+			marker = "▸ "
+			label = docPathStyle.Render(label)
+		}
+		fmt.Fprintf(&b, "%s%s  %s\n", marker, listScoreStyle.Render(a.key), label)
+	}
+	return b.String()
+}
+
+// resultOpenedMsg reports that the editor showing a result's file exited.
+type resultOpenedMsg struct{ err error }
+
+// openResultInEditor suspends the TUI and opens the chunk's file at the
+// chunk's first line with the open command, or in $VISUAL or $EDITOR.
+func (m Model) openResultInEditor(chunk domain.Chunk) (Model, tea.Cmd) {
+	if chunk.Path == "" || strings.Contains(chunk.Path, "://") {
+		m.status = i18n.T("This result has no local file.")
+		return m, nil
+	}
+	args, err := opener.Command(m.openCommand, chunk.Path, chunkLine(chunk))
+	if err != nil {
+		m.status = i18n.Sprintf("Error: %v", err)
+		return m, nil
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg { return resultOpenedMsg{err: err} })
+}
+
+// chunkLine returns the 1-based line of the file at which chunk starts, or
+// 1 when the file cannot be read or the chunk has no offsets. Chunks indexed
+// before lines were recorded are located by reading the file.
+func chunkLine(chunk domain.Chunk) int {
+	if chunk.StartLine > 0 {
+		return chunk.StartLine
+	}
+	if chunk.End <= chunk.Start {
+		return 1
+	}
+	data, err := os.ReadFile(chunk.Path)
+	if err != nil || chunk.Start > len(data) {
+		return 1
+	}
+	return bytes.Count(data[:chunk.Start], []byte("\n")) + 1
+}
+
+func (m Model) copyResultText(chunk domain.Chunk) (Model, tea.Cmd) {
+	return m.copyToClipboard(chunk.Text, i18n.T("Copied the text of the result.")), nil
+}
+
+func (m Model) copyResultPath(chunk domain.Chunk) (Model, tea.Cmd) {
+	return m.copyToClipboard(chunk.Path, i18n.T("Copied the path of the result.")), nil
+}
+
+func (m Model) copyToClipboard(s, done string) Model {
+	if err := clipboard.WriteAll(s); err != nil {
+		m.status = i18n.Sprintf("Copy failed: %v", err)
+		return m
+	}
+	m.status = done
+	return m
+}
+
+// showNeighbors lists the chunks around the result in its document, the
+// way openNote lists a whole note.
+func (m Model) showNeighbors(chunk domain.Chunk) (Model, tea.Cmd) {
+	var around []domain.SearchResult
+	cursor := 0
+	for _, ch := range m.service.DocumentChunks(chunk.DocumentID) {
+		if ch.Index < chunk.Index-neighborRadius || ch.Index > chunk.Index+neighborRadius {
+			continue
+		}
+		if ch.ChunkID == chunk.ChunkID {
+			cursor = len(around)
+		}
+		around = append(around, domain.SearchResult{Chunk: ch})
+	}
+	if len(around) == 0 {
+		m.status = i18n.T("The chunks of this document are not available.")
+		return m, nil
+	}
+	m.results = around
+	m.cursor = cursor
+	m.exhausted = true
+	m.groupRow = 0
+	m = m.regroup()
+	m.status = i18n.Sprintf("Chunks around %s#%d", filepath.Base(chunk.Path), chunk.Index)
+	m.viewport.SetContent(m.renderCurrentResult())
+	m.viewport.GotoTop()
+	return m, nil
+}
+
+// findSimilar searches for passages similar to the result's text.
+func (m Model) findSimilar(chunk domain.Chunk) (Model, tea.Cmd) {
+	if !m.passage {
+		m = m.togglePassageMode()
+	}
+	m.input.SetValue(chunk.Text)
+	m.input.CursorEnd()
+	return m.runQuery(chunk.Text, 0), nil
+}
+
+// toggleBookmark bookmarks the result, or removes its bookmark.
+func (m Model) toggleBookmark(chunk domain.Chunk) (Model, tea.Cmd) {
+	if m.bookmarks == nil {
+		m.status = i18n.T("Bookmarks are not available.")
+		return m, nil
+	}
+	marked, err := m.bookmarks.Has(chunk.ChunkID)
+	if err == nil {
+		if marked {
+			err = m.bookmarks.Delete(chunk.ChunkID)
+		} else {
+			err = m.bookmarks.Add(bookmark.Bookmark{
+				ChunkID: chunk.ChunkID,
+				Path:    chunk.Path,
+				Index:   chunk.Index,
+				Text:    chunk.Text,
+				Query:   m.lastQuery,
+				Created: time.Now(),
+			})
+		}
+	}
+	switch {
+	case err != nil:
+		m.status = i18n.Sprintf("Error: %v", err)
+	case marked:
+		m.status = i18n.T("Bookmark removed.")
+	default:
+		m.status = i18n.Sprintf("Bookmarked %s#%d.", filepath.Base(chunk.Path), chunk.Index)
+	}
+	return m, nil
+}
+
+// judgeResult records whether the result is relevant to the last query,
+// or withdraws the same judgment made before.
+func (m Model) judgeResult(chunk domain.Chunk, relevant bool) (Model, tea.Cmd) {
+	j, ok, err := m.feedback.Get(m.lastQuery, chunk.ChunkID)
+	withdraw := ok && j.Relevant == relevant
+	if err == nil {
+		if withdraw {
+			err = m.feedback.Delete(m.lastQuery, chunk.ChunkID)
+		} else {
+			r := m.results[m.cursor]
+			err = m.feedback.Set(feedback.Judgment{
+				Query:    m.lastQuery,
+				ChunkID:  chunk.ChunkID,
+				Path:     chunk.Path,
+				Index:    chunk.Index,
+				Text:     chunk.Text,
+				Relevant: relevant,
+				Rank:     m.cursor + 1,
+				Score:    r.Score,
+				Created:  time.Now(),
+			})
+		}
+	}
+	switch {
+	case err != nil:
+		m.status = i18n.Sprintf("Error: %v", err)
+	case withdraw:
+		m.status = i18n.T("Judgment withdrawn.")
+	case relevant:
+		m.status = i18n.Sprintf("Marked %s#%d relevant to the query.", filepath.Base(chunk.Path), chunk.Index)
+	default:
+		m.status = i18n.Sprintf("Marked %s#%d not relevant to the query.", filepath.Base(chunk.Path), chunk.Index)
+	}
+	return m, nil
+}
+
+// translatedMsg delivers the translation of a result.
+type translatedMsg struct {
+	chunkID string
+	text    string
+	err     error
+}
+
+// translateResult translates the result in the background; the translation
+// is shown under its text once it arrives.
+func (m Model) translateResult(chunk domain.Chunk) (Model, tea.Cmd) {
+	if _, ok := m.translations[chunk.ChunkID]; ok {
+		return m, nil
+	}
+	m.status = i18n.T("Translating…")
+	translate := m.translate
+	return m, func() tea.Msg {
+		text, err := translate(context.Background(), chunk.Text)
+		return translatedMsg{chunkID: chunk.ChunkID, text: text, err: err}
+	}
+}
+
+func (m Model) showTranslation(msg translatedMsg) Model {
+	if msg.err != nil {
+		m.status = i18n.Sprintf("Translation failed: %v", msg.err)
+		return m
+	}
+	m.translations[msg.chunkID] = msg.text
+	m.status = i18n.T("Translated.")
+	if m.mode == modeSearch || m.mode == modeReading {
+		m.viewport.SetContent(m.renderCurrentResult())
+	}
+	return m
+}
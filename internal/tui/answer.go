@@ -0,0 +1,182 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"rag/internal/domain"
+	"rag/internal/generator"
+	"rag/internal/grounding"
+	"rag/internal/i18n"
+	"rag/internal/queryparse"
+)
+
+// AnswerFunc writes an answer to query from results, calling onText with
+// the text written so far as it arrives.
+type AnswerFunc func(ctx context.Context, query string, results []domain.SearchResult, onText func(string)) (generator.Answer, error)
+
+// answerTextMsg delivers the text of the answer to a query written so far;
+// wait waits for the next answer message.
+type answerTextMsg struct {
+	query string
+	text  string
+	wait  tea.Cmd
+}
+
+// answeredMsg delivers the answer written for a query.
+type answeredMsg struct {
+	query  string
+	answer generator.Answer
+	err    error
+}
+
+// openAnswer shows the answer to the last query, written in the background
+// from the results shown unless it was written from them already.
+func (m Model) openAnswer() (Model, tea.Cmd) {
+	if m.answerFunc == nil {
+		m.status = i18n.T("Answers need an llm section in the config.")
+		return m, nil
+	}
+	m.mode = modeAnswer
+	m.viewport.GotoTop()
+	if m.answer != nil && m.answerQuery == m.lastQuery && sameSources(m.answer.Sources, m.results) {
+		m.status = i18n.T("Answer: Up/Down scroll, Esc returns")
+		m.viewport.SetContent(m.renderAnswer())
+		return m, nil
+	}
+	m.answer, m.answerQuery, m.answerErr, m.answerDraft = nil, m.lastQuery, nil, ""
+	m.status = i18n.T("Writing an answer…")
+	m.viewport.SetContent(m.renderAnswer())
+	answer, query, results := m.answerFunc, m.lastQuery, m.results
+	events := make(chan tea.Msg, 1)
+	var wait tea.Cmd = func() tea.Msg { return <-events }
+	go func() {
+		a, err := answer(context.Background(), queryparse.Terms(query), results, func(text string) {
+			// Each message holds the whole text so far, so those the UI
+			// has not caught up with are dropped.
+			select {
+			case events <- answerTextMsg{query: query, text: text, wait: wait}:
+			default:
+			}
+		})
+		events <- answeredMsg{query: query, answer: a, err: err}
+	}()
+	return m, wait
+}
+
+// showAnswerText shows the text of the answer written so far if it is for
+// the last query and the answer panel is open, following it down when the
+// panel is scrolled to the end.
+func (m Model) showAnswerText(msg answerTextMsg) Model {
+	if msg.query != m.answerQuery || m.answer != nil {
+		return m
+	}
+	m.answerDraft = msg.text
+	if m.mode == modeAnswer {
+		follow := m.viewport.AtBottom()
+		m.viewport.SetContent(m.renderAnswer())
+		if follow {
+			m.viewport.GotoBottom()
+		}
+	}
+	return m
+}
+
+// showAnswer keeps the answer that arrived if it is for the last query, and
+// shows it if the answer panel is still open.
+func (m Model) showAnswer(msg answeredMsg) Model {
+	if msg.query != m.answerQuery {
+		return m
+	}
+	if msg.err != nil {
+		m.answerErr = msg.err
+	} else {
+		m.answer = &msg.answer
+	}
+	if m.mode == modeAnswer {
+		m.status = i18n.T("Answer: Up/Down scroll, Esc returns")
+		m.viewport.SetContent(m.renderAnswer())
+	}
+	return m
+}
+
+// updateAnswer handles keys while the answer panel is open.
+func (m Model) updateAnswer(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "?":
+		m.mode = modeSearch
+		m.status = i18n.T("Type to search.")
+		m.viewport.SetContent(m.renderCurrentResult())
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+// renderAnswer renders the answer wrapped to the viewport, followed by the
+// sources it cites.
+func (m Model) renderAnswer() string {
+	title := i18n.Sprintf("Answer to %q", queryparse.Terms(m.answerQuery))
+	switch {
+	case m.answerErr != nil:
+		return title + "\n\n" + i18n.Sprintf("Answer failed: %v", m.answerErr)
+	case m.answer == nil:
+		return title + "\n\n" + lipgloss.NewStyle().Width(m.viewport.Width).Render(m.answerDraft+"▍")
+	}
+	var b strings.Builder
+	b.WriteString(title + "\n\n")
+	b.WriteString(lipgloss.NewStyle().Width(m.viewport.Width).Render(markUnsupported(m.answer.Text, m.answer.Claims)))
+	switch unsupported := len(grounding.Unsupported(m.answer.Claims)); {
+	case m.answer.CheckErr != nil:
+		b.WriteString("\n\n" + listScoreStyle.Render(i18n.Sprintf("Claims not checked: %v", m.answer.CheckErr)))
+	case unsupported > 0:
+		b.WriteString("\n\n" + unsupportedStyle.Render(i18n.Sprintf("Sentences not supported by the sources (underlined): %d", unsupported)))
+	}
+	b.WriteString("\n\n" + docPathStyle.Render(i18n.T("Sources:")) + "\n")
+	cited := make(map[int]bool, len(m.answer.Cited))
+	for _, n := range m.answer.Cited {
+		cited[n] = true
+	}
+	for i, r := range m.answer.Sources {
+		line := fmt.Sprintf("[%d] %s", i+1, r.Chunk.Location())
+		if !cited[i+1] {
+			line = listScoreStyle.Render(line + "  " + i18n.T("(not cited)"))
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// unsupportedStyle marks the sentences of an answer no source supports.
+var unsupportedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Underline(true)
+
+// markUnsupported styles the claims of text no source supports.
+func markUnsupported(text string, claims []grounding.Claim) string {
+	var b strings.Builder
+	prev := 0
+	for _, c := range grounding.Unsupported(claims) {
+		b.WriteString(text[prev:c.Start])
+		b.WriteString(unsupportedStyle.Render(text[c.Start:c.End]))
+		prev = c.End
+	}
+	b.WriteString(text[prev:])
+	return b.String()
+}
+
+// sameSources reports whether the results begin with sources.
+func sameSources(sources, results []domain.SearchResult) bool {
+	if len(results) < len(sources) {
+		return false
+	}
+	for i, s := range sources {
+		if results[i].Chunk.ChunkID != s.Chunk.ChunkID {
+			return false
+		}
+	}
+	return true
+}
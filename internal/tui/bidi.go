@@ -0,0 +1,301 @@
+package tui
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// Most terminals print text in logical order, left to right, so Arabic and
+// Hebrew come out backwards. Unless the terminal does its own bidi
+// reordering, text with right-to-left characters is wrapped here and each
+// line is put into visual order, a simplified form of the Unicode
+// Bidirectional Algorithm: runs of right-to-left characters are reversed
+// and numbers and Latin words embedded in them keep their order.
+
+// glyph is a grapheme cluster with its rendering attributes.
+type glyph struct {
+	s         string
+	width     int
+	highlight bool
+	class     bidiClass
+}
+
+type bidiClass int
+
+const (
+	bidiNeutral bidiClass = iota // spaces and punctuation
+	bidiLTR
+	bidiRTL
+	bidiNumber
+)
+
+// rtlTables are the scripts written right to left.
+var rtlTables = []*unicode.RangeTable{unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko}
+
+func classify(r rune) bidiClass {
+	switch {
+	case unicode.In(r, rtlTables...):
+		if unicode.IsDigit(r) {
+			return bidiNumber
+		}
+		return bidiRTL
+	case unicode.IsDigit(r):
+		return bidiNumber
+	case unicode.IsLetter(r):
+		return bidiLTR
+	}
+	return bidiNeutral
+}
+
+// hasRTL reports whether s contains right-to-left letters.
+func hasRTL(s string) bool {
+	for _, r := range s {
+		if classify(r) == bidiRTL {
+			return true
+		}
+	}
+	return false
+}
+
+// glyphs splits s into grapheme clusters marked with whether they lie in
+// one of the highlighted byte spans.
+func glyphs(s string, spans [][2]int) []glyph {
+	var out []glyph
+	pos := 0
+	state := -1
+	for rest := s; rest != ""; {
+		var c string
+		var w int
+		c, rest, w, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		r, _ := utf8.DecodeRuneInString(c)
+		out = append(out, glyph{s: c, width: w, class: classify(r), highlight: inSpans([2]int{pos, pos + len(c)}, spans)})
+		pos += len(c)
+	}
+	return out
+}
+
+// wrapGlyphs breaks glyphs into lines of at most width columns, at spaces
+// where possible; lines after the first are indent columns narrower.
+// Newlines start a new line.
+func wrapGlyphs(gs []glyph, width, indent int) [][]glyph {
+	var lines [][]glyph
+	var line []glyph
+	used, lastSpace := 0, -1
+	room := func() int {
+		if len(lines) == 0 {
+			return width
+		}
+		return max(1, width-indent)
+	}
+	for _, g := range gs {
+		if g.s == "\n" || g.s == "\r\n" {
+			lines = append(lines, line)
+			line, used, lastSpace = nil, 0, -1
+			continue
+		}
+		if used+g.width > room() && len(line) > 0 {
+			if g.s == " " {
+				lines = append(lines, line)
+				line, used, lastSpace = nil, 0, -1
+				continue
+			}
+			if lastSpace >= 0 {
+				lines = append(lines, line[:lastSpace])
+				line = append([]glyph(nil), line[lastSpace+1:]...)
+			} else {
+				lines = append(lines, line)
+				line = nil
+			}
+			used, lastSpace = 0, -1
+			for _, h := range line {
+				used += h.width
+			}
+		}
+		if g.s == " " {
+			lastSpace = len(line)
+		}
+		line = append(line, g)
+		used += g.width
+	}
+	return append(lines, line)
+}
+
+// baseRTL reports whether a paragraph runs right to left, which its first
+// strong (letter) character decides.
+func baseRTL(gs []glyph) bool {
+	for _, g := range gs {
+		if g.class == bidiLTR || g.class == bidiRTL {
+			return g.class == bidiRTL
+		}
+	}
+	return false
+}
+
+// visualOrder returns a line of a paragraph with base direction rtl in
+// display order.
+func visualOrder(line []glyph, rtl bool) []glyph {
+	base := 0
+	if rtl {
+		base = 1
+	}
+	// Resolve embedding levels: even levels run left to right.
+	levels := make([]int, len(line))
+	lastStrong := bidiLTR
+	if rtl {
+		lastStrong = bidiRTL
+	}
+	for i, g := range line {
+		switch g.class {
+		case bidiRTL:
+			levels[i] = 1
+			lastStrong = bidiRTL
+		case bidiLTR:
+			levels[i] = 2 * base
+			lastStrong = bidiLTR
+		case bidiNumber:
+			// Numbers read left to right, also inside right-to-left text.
+			if rtl || lastStrong == bidiRTL {
+				levels[i] = 2
+			}
+		default:
+			levels[i] = -1
+		}
+	}
+	// Neutrals take the direction of their surroundings when both sides
+	// agree, and the base direction otherwise.
+	for i := 0; i < len(line); {
+		if levels[i] >= 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(line) && levels[j] < 0 {
+			j++
+		}
+		before, after := base, base
+		if i > 0 {
+			before = levels[i-1]
+		}
+		if j < len(line) {
+			after = levels[j]
+		}
+		level := base
+		if before%2 == after%2 {
+			level = min(before, after)
+		}
+		for k := i; k < j; k++ {
+			levels[k] = level
+		}
+		i = j
+	}
+	out := append([]glyph(nil), line...)
+	maxLevel := 0
+	for _, l := range levels {
+		maxLevel = max(maxLevel, l)
+	}
+	// Reverse every maximal run at or above each level, highest first.
+	lv := append([]int(nil), levels...)
+	for level := maxLevel; level >= 1; level-- {
+		for i := 0; i < len(out); {
+			if lv[i] < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(out) && lv[j] >= level {
+				j++
+			}
+			reverseGlyphs(out[i:j])
+			reverseInts(lv[i:j])
+			i = j
+		}
+	}
+	// Brackets in right-to-left runs are drawn mirrored.
+	for i := range out {
+		if lv[i]%2 == 1 {
+			if m, ok := mirrored[out[i].s]; ok {
+				out[i].s = m
+			}
+		}
+	}
+	return out
+}
+
+var mirrored = map[string]string{"(": ")", ")": "(", "[": "]", "]": "[", "{": "}", "}": "{", "<": ">", ">": "<", "«": "»", "»": "«"}
+
+func reverseGlyphs(gs []glyph) {
+	for i, j := 0, len(gs)-1; i < j; i, j = i+1, j-1 {
+		gs[i], gs[j] = gs[j], gs[i]
+	}
+}
+
+func reverseInts(xs []int) {
+	for i, j := 0, len(xs)-1; i < j; i, j = i+1, j-1 {
+		xs[i], xs[j] = xs[j], xs[i]
+	}
+}
+
+// renderGlyphs draws a line, styling highlighted glyphs.
+func renderGlyphs(line []glyph) string {
+	var b strings.Builder
+	for i := 0; i < len(line); {
+		j := i
+		var seg strings.Builder
+		for j < len(line) && line[j].highlight == line[i].highlight {
+			seg.WriteString(line[j].s)
+			j++
+		}
+		if line[i].highlight {
+			b.WriteString(highlightStyle.Render(seg.String()))
+		} else {
+			b.WriteString(seg.String())
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// renderRTL wraps text to width and lays out each line in visual order,
+// right-aligning right-to-left lines, with the highlighted spans styled.
+func renderRTL(text string, spans [][2]int, width int) string {
+	// Like renderHighlighted, show the text as one paragraph.
+	var gs []glyph
+	for _, g := range glyphs(strings.TrimSpace(text), spans) {
+		if strings.TrimSpace(g.s) == "" {
+			if len(gs) > 0 && gs[len(gs)-1].s == " " {
+				continue
+			}
+			g.s, g.width, g.highlight = " ", 1, false
+		}
+		gs = append(gs, g)
+	}
+	rtl := baseRTL(gs)
+	lines := wrapGlyphs(gs, width, 0)
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		visual := visualOrder(line, rtl)
+		s := renderGlyphs(visual)
+		if rtl {
+			used := 0
+			for _, g := range visual {
+				used += g.width
+			}
+			s = strings.Repeat(" ", max(0, width-used)) + s
+		}
+		out[i] = s
+	}
+	return strings.Join(out, "\n")
+}
+
+// visualLine puts a single line of text, such as a snippet, into visual
+// order.
+func visualLine(s string) string {
+	if !hasRTL(s) {
+		return s
+	}
+	gs := glyphs(s, nil)
+	return renderGlyphs(visualOrder(gs, baseRTL(gs)))
+}
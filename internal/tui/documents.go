@@ -0,0 +1,97 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"rag/internal/i18n"
+)
+
+var docPathStyle = lipgloss.NewStyle().Bold(true)
+
+// openDocuments switches to the document browser.
+func (m Model) openDocuments() Model {
+	m.mode = modeDocuments
+	m.docs = m.service.Documents()
+	if m.docCursor >= len(m.docs) {
+		m.docCursor = 0
+	}
+	m.status = i18n.T("Documents: Up/Down to browse, Esc or Ctrl+B to return")
+	m.viewport.SetContent(m.renderDocuments())
+	m.scrollToDocument()
+	return m
+}
+
+// updateDocuments handles keys while the document browser is open.
+func (m Model) updateDocuments(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+b":
+		m.mode = modeSearch
+		m.status = i18n.T("Type to search.")
+		m.viewport.SetContent(m.renderCurrentResult())
+		m.viewport.GotoTop()
+	case "down":
+		if len(m.docs) > 0 {
+			m.docCursor = (m.docCursor + 1) % len(m.docs)
+		}
+	case "up":
+		if len(m.docs) > 0 {
+			m.docCursor = (m.docCursor - 1 + len(m.docs)) % len(m.docs)
+		}
+	}
+	if m.mode == modeDocuments {
+		m.viewport.SetContent(m.renderDocuments())
+		m.scrollToDocument()
+	}
+	return m, nil
+}
+
+func (m Model) renderDocuments() string {
+	if len(m.docs) == 0 {
+		return i18n.T("No documents ingested.")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", i18n.Sprintf("Documents %d/%d", m.docCursor+1, len(m.docs)))
+	for i, d := range m.docs {
+		marker := "  "
+		path := d.Path
+		if d.Title != "" {
+			path += " — " + d.Title
+		}
+		if i == m.docCursor {
+			marker = "▸ "
+			path = docPathStyle.Render(path)
+		}
+		fmt.Fprintf(&b, "%s%s  %s\n", marker, path, i18n.Sprintf("(%d chunks)", d.Chunks))
+		keywords := truncate(strings.Join(d.Keywords, ", "), m.viewport.Width-4)
+		fmt.Fprintf(&b, "    %s\n", listScoreStyle.Render(keywords))
+	}
+	return b.String()
+}
+
+// renderCorpusKeywords fills the list pane while browsing documents.
+func (m Model) renderCorpusKeywords() string {
+	keywords := m.service.Keywords("")
+	text := i18n.Sprintf("Corpus keywords: %s", strings.Join(keywords, ", "))
+	lines := strings.Split(lipgloss.NewStyle().Width(m.viewport.Width).Render(text), "\n")
+	if len(lines) > listHeight {
+		lines = lines[:listHeight]
+	}
+	for len(lines) < listHeight {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// scrollToDocument keeps the selected document within the viewport.
+func (m *Model) scrollToDocument() {
+	line := 2 + m.docCursor*2
+	if line < m.viewport.YOffset {
+		m.viewport.SetYOffset(line)
+	} else if line+1 >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(line + 2 - m.viewport.Height)
+	}
+}
@@ -0,0 +1,138 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"rag/internal/facets"
+	"rag/internal/i18n"
+)
+
+// facetHeadings title the kinds of facets.
+var facetHeadings = map[facets.Kind]string{
+	facets.Document:  "Documents",
+	facets.Tag:       "Tags",
+	facets.Directory: "Directories",
+}
+
+// openFacets shows how many of the results come from each document, tag
+// and directory.
+func (m Model) openFacets() Model {
+	if len(m.results) == 0 {
+		m.status = i18n.T("Run a query to see its facets.")
+		return m
+	}
+	m.mode = modeFacets
+	m.facets = facets.Count(m.results)
+	m.facetCursor = 0
+	m.suggestions = nil
+	m.status = i18n.T("Facets: Enter toggles a facet as a filter, Esc or Ctrl+A to return")
+	m.viewport.SetContent(m.renderFacets())
+	m.viewport.GotoTop()
+	return m
+}
+
+// updateFacets handles keys while the facets are shown.
+func (m Model) updateFacets(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+a":
+		m.mode = modeSearch
+		m.status = i18n.T("Type to search.")
+		m.viewport.SetContent(m.renderCurrentResult())
+		m.viewport.GotoTop()
+		return m, nil
+	case "down":
+		if len(m.facets) > 0 {
+			m.facetCursor = (m.facetCursor + 1) % len(m.facets)
+		}
+	case "up":
+		if len(m.facets) > 0 {
+			m.facetCursor = (m.facetCursor - 1 + len(m.facets)) % len(m.facets)
+		}
+	case "enter":
+		if len(m.facets) > 0 {
+			m = m.toggleFacet(m.facets[m.facetCursor])
+		}
+	}
+	m.viewport.SetContent(m.renderFacets())
+	return m, nil
+}
+
+// toggleFacet applies f as the path or tag filter, or clears that filter
+// when f is applied already, and reruns the last query. The facets are
+// counted again over the new results.
+func (m Model) toggleFacet(f facets.Facet) Model {
+	value := ""
+	if !m.facetActive(f) {
+		value = f.Value
+	}
+	switch f.Kind {
+	case facets.Tag:
+		m.filter.tag = value
+	default:
+		if value != "" {
+			value = facetPath(f)
+		}
+		m.filter.path = value
+	}
+	m = m.runQuery(m.lastQuery, m.pageSize)
+	m.mode = modeFacets
+	m.facets = facets.Count(m.results)
+	m.facetCursor = 0
+	for i, g := range m.facets {
+		if g.Kind == f.Kind && g.Value == f.Value {
+			m.facetCursor = i
+		}
+	}
+	m.status = i18n.T("Filters cleared.")
+	if m.filter.active() {
+		m.status = i18n.Sprintf("Filters: %s", m.filter.String())
+	}
+	return m
+}
+
+// facetPath is the path filter a document or directory facet applies.
+func facetPath(f facets.Facet) string {
+	if f.Kind == facets.Directory {
+		return f.Value + string(filepath.Separator)
+	}
+	return f.Value
+}
+
+// facetActive reports whether f is applied as a filter.
+func (m Model) facetActive(f facets.Facet) bool {
+	if f.Kind == facets.Tag {
+		return strings.EqualFold(m.filter.tag, f.Value)
+	}
+	return m.filter.path == facetPath(f)
+}
+
+func (m Model) renderFacets() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", i18n.Sprintf("Facets of %d results", len(m.results)))
+	kind := facets.Kind(-1)
+	for i, f := range m.facets {
+		if f.Kind != kind {
+			kind = f.Kind
+			fmt.Fprintf(&b, "\n%s\n", i18n.T(facetHeadings[kind]))
+		}
+		marker := "  "
+		value := f.Value
+		if f.Kind == facets.Document {
+			value = filepath.Base(value)
+		}
+		if i == m.facetCursor {
+			marker = "▸ "
+			value = docPathStyle.Render(value)
+		}
+		check := "[ ]"
+		if m.facetActive(f) {
+			check = "[x]"
+		}
+		fmt.Fprintf(&b, "%s%s %s  %s\n", marker, check, value, listScoreStyle.Render(fmt.Sprint(f.Count)))
+	}
+	return b.String()
+}
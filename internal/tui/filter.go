@@ -0,0 +1,166 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"rag/internal/domain"
+	"rag/internal/i18n"
+	"rag/internal/vectorstore"
+)
+
+// filters narrow the results of every query. The tag filter is pushed down
+// to the store as a tag: operator; path and score filters are applied to
+// the results here.
+type filters struct {
+	// path is a substring the result's path must contain, ignoring case.
+	path string
+	// tag is a tag the result's document must have.
+	tag string
+	// minScore is the lowest score shown (0 = no threshold).
+	minScore float64
+}
+
+// parseFilters reads the filter bar: path:, tag: and score: terms, with
+// bare words taken as path substrings.
+func parseFilters(s string) (filters, error) {
+	var f filters
+	for _, w := range strings.Fields(s) {
+		name, value, ok := strings.Cut(w, ":")
+		if !ok {
+			name, value = "path", w
+		}
+		switch strings.ToLower(name) {
+		case "path":
+			f.path = value
+		case "tag":
+			f.tag = strings.TrimPrefix(value, "#")
+		case "score":
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil || v < 0 {
+				return filters{}, fmt.Errorf(i18n.T("score: expects a number like 0.3, got %q"), value)
+			}
+			f.minScore = v
+		default:
+			return filters{}, fmt.Errorf(i18n.T("unknown filter %q (use path:, tag: or score:)"), name+":")
+		}
+	}
+	return f, nil
+}
+
+// String renders the filters as they are typed into the filter bar.
+func (f filters) String() string {
+	var parts []string
+	if f.path != "" {
+		parts = append(parts, "path:"+f.path)
+	}
+	if f.tag != "" {
+		parts = append(parts, "tag:"+f.tag)
+	}
+	if f.minScore > 0 {
+		parts = append(parts, "score:"+strconv.FormatFloat(f.minScore, 'g', -1, 64))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (f filters) active() bool { return f != filters{} }
+
+// keep reports whether r passes the filters applied here; pushedDown says
+// whether the store has applied the tag filter already.
+func (f filters) keep(r domain.SearchResult, pushedDown bool) bool {
+	if f.path != "" && !strings.Contains(strings.ToLower(r.Chunk.Path), strings.ToLower(f.path)) {
+		return false
+	}
+	if f.tag != "" && !pushedDown && !vectorstore.MetadataContains(r.Chunk.Metadata["tags"], f.tag) {
+		return false
+	}
+	return true
+}
+
+// fetch returns results of q that pass the filters and refinements,
+// reading the ranking from position offset until limit of them are found.
+// It returns how far
+// into the ranking it read, for the next page, and whether the ranking is
+// exhausted. Results are ranked by score, so reading stops at the first
+// one below the score threshold.
+func (m Model) fetch(q string, offset, limit int) (res []domain.SearchResult, next int, exhausted bool, err error) {
+	// Passages are searched as they are, without query operators.
+	pushDown := !m.passage && m.filter.tag != ""
+	if pushDown {
+		q += ` tag:"` + m.filter.tag + `"`
+	}
+	for {
+		page, err := m.search(q, offset, limit)
+		if err != nil {
+			return nil, offset, false, err
+		}
+		offset += len(page)
+		for _, r := range page {
+			if r.Score < m.filter.minScore {
+				return res, offset, true, nil
+			}
+			if m.filter.keep(r, pushDown) && m.refined(r) {
+				res = append(res, r)
+			}
+		}
+		if len(page) < limit {
+			return res, offset, true, nil
+		}
+		if len(res) >= limit {
+			return res, offset, false, nil
+		}
+	}
+}
+
+// openFilterBar shows the filter bar in place of the query input.
+func (m Model) openFilterBar() Model {
+	m.mode = modeFilter
+	m.pendingQuery = m.input.Value()
+	setPrompt(&m.input, i18n.T("filter> "))
+	m.input.SetValue(m.filter.String())
+	m.input.CursorEnd()
+	m.suggestions = nil
+	m.status = i18n.T("Filters: path:text tag:name score:0.3 (Enter applies, empty clears, Esc cancels)")
+	return m
+}
+
+// updateFilterBar handles keys while the filter bar is shown. Applying new
+// filters reruns the last query.
+func (m Model) updateFilterBar(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		f, err := parseFilters(m.input.Value())
+		if err != nil {
+			m.status = i18n.Sprintf("Error: %v", err)
+			return m, nil
+		}
+		m.filter = f
+		m = m.leaveFilterBar()
+		m.status = i18n.T("Filters cleared.")
+		if f.active() {
+			m.status = i18n.Sprintf("Filters: %s", f.String())
+		}
+		if m.lastQuery != "" {
+			m = m.runQuery(m.lastQuery, m.pageSize)
+		}
+		return m, nil
+	case "esc":
+		m = m.leaveFilterBar()
+		m.status = i18n.T("Filters unchanged.")
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m Model) leaveFilterBar() Model {
+	m.mode = modeSearch
+	setPrompt(&m.input, m.prompt())
+	m.input.SetValue(m.pendingQuery)
+	m.input.CursorEnd()
+	return m
+}
@@ -0,0 +1,159 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"rag/internal/grouping"
+	"rag/internal/i18n"
+	"rag/internal/queryparse"
+	"rag/internal/snippet"
+	"rag/internal/textutil"
+)
+
+// groupRow is one line of the grouped result list: a document header
+// (hit < 0) or one hit of an expanded document.
+type groupRow struct {
+	group int
+	hit   int
+}
+
+// toggleGrouping switches the result list between flat and grouped by document.
+func (m Model) toggleGrouping() Model {
+	m.grouped = !m.grouped
+	m = m.regroup()
+	if m.grouped {
+		m.groupRow = 0
+		for i, r := range m.groupRows() {
+			if m.rowResult(r) == m.cursor {
+				m.groupRow = i
+				break
+			}
+		}
+		m.status = i18n.T("Grouped by document: Ctrl+X expands or collapses, Ctrl+G to ungroup")
+	} else {
+		m.status = i18n.T("Showing all results.")
+	}
+	return m
+}
+
+// regroup rebuilds the document groups after the results changed.
+func (m Model) regroup() Model {
+	if !m.grouped {
+		m.groups = nil
+		return m
+	}
+	m.groups = grouping.ByDocument(m.results)
+	return m
+}
+
+// toggleExpanded expands or collapses the group under the cursor.
+func (m Model) toggleExpanded() Model {
+	rows := m.groupRows()
+	if !m.grouped || len(rows) == 0 {
+		return m
+	}
+	g := m.groups[rows[m.groupRow].group]
+	if m.expanded == nil {
+		m.expanded = make(map[string]bool)
+	}
+	m.expanded[g.DocumentID] = !m.expanded[g.DocumentID]
+	// Keep the cursor on the group header.
+	for i, r := range m.groupRows() {
+		if m.groups[r.group].DocumentID == g.DocumentID && r.hit < 0 {
+			m.groupRow = i
+			break
+		}
+	}
+	m.cursor = g.Hits[0]
+	return m
+}
+
+// groupRows lists the visible rows of the grouped result list.
+func (m Model) groupRows() []groupRow {
+	var rows []groupRow
+	for gi, g := range m.groups {
+		rows = append(rows, groupRow{group: gi, hit: -1})
+		if m.expanded[g.DocumentID] {
+			for h := range g.Hits {
+				rows = append(rows, groupRow{group: gi, hit: h})
+			}
+		}
+	}
+	return rows
+}
+
+// rowResult returns the index into m.results shown for a row; document
+// headers show their best hit.
+func (m Model) rowResult(r groupRow) int {
+	g := m.groups[r.group]
+	if r.hit < 0 {
+		return g.Hits[0]
+	}
+	return g.Hits[r.hit]
+}
+
+// moveGroupRow moves the grouped-list cursor by delta rows, wrapping around
+// and fetching more results when moving past the last row.
+func (m Model) moveGroupRow(delta int) Model {
+	rows := m.groupRows()
+	if len(rows) == 0 {
+		return m
+	}
+	if delta > 0 && m.groupRow == len(rows)-1 && !m.exhausted {
+		m = m.fetchMore().regroup()
+		rows = m.groupRows()
+	}
+	m.groupRow = (m.groupRow + delta + len(rows)) % len(rows)
+	m.cursor = m.rowResult(rows[m.groupRow])
+	return m
+}
+
+// renderGroupedList renders the grouped result list around the cursor.
+func (m Model) renderGroupedList() string {
+	width := m.viewport.Width
+	rows := m.groupRows()
+	lines := make([]string, 0, listHeight)
+	if len(rows) == 0 {
+		lines = append(lines, listScoreStyle.Render(i18n.T("No results yet.")))
+	}
+	first := 0
+	if m.groupRow >= listHeight {
+		first = m.groupRow - listHeight + 1
+	}
+	for i := first; i < len(rows) && i < first+listHeight; i++ {
+		r := rows[i]
+		g := m.groups[r.group]
+		marker := "  "
+		if i == m.groupRow {
+			marker = "▸ "
+		}
+		var prefix, text string
+		if r.hit < 0 {
+			fold := "+"
+			if m.expanded[g.DocumentID] {
+				fold = "-"
+			}
+			prefix = fmt.Sprintf("%s%s %.3f  ", marker, fold, g.Score)
+			text = i18n.Sprintf("%s  (%d hits)", filepath.Base(g.Path), len(g.Hits))
+		} else {
+			idx := g.Hits[r.hit]
+			prefix = fmt.Sprintf("%s    %.3f  ", marker, m.results[idx].Score)
+			text = snippet.Generate(m.results[idx].Chunk.Text, queryparse.Terms(m.lastQuery), m.highlights[m.results[idx].Chunk.ChunkID], width-textutil.Width(prefix))
+		}
+		text = truncate(text, width-textutil.Width(prefix))
+		if !m.terminalBidi {
+			text = visualLine(text)
+		}
+		if i == m.groupRow {
+			lines = append(lines, listSelectedStyle.Render(prefix+text))
+		} else {
+			lines = append(lines, prefix+listScoreStyle.Render(text))
+		}
+	}
+	for len(lines) < listHeight {
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,52 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	"rag/internal/queryparse"
+)
+
+// highlightedMsg delivers the sentence spans of a result for a query.
+type highlightedMsg struct {
+	query   string
+	chunkID string
+	spans   [][2]int
+}
+
+// highlightCurrent returns the command ranking the sentences of the current
+// result against the last query, unless they are ranked or being ranked
+// already. Ranking embeds every sentence, so it runs in the background and
+// the result is shown with word-overlap highlights until it is done.
+func (m Model) highlightCurrent() tea.Cmd {
+	if (m.mode != modeSearch && m.mode != modeReading) || m.cursor >= len(m.results) || m.highlighting == nil {
+		return nil
+	}
+	chunk := m.results[m.cursor].Chunk
+	if _, ok := m.highlights[chunk.ChunkID]; ok || m.highlighting[chunk.ChunkID] {
+		return nil
+	}
+	m.highlighting[chunk.ChunkID] = true
+	svc, query := m.service, m.lastQuery
+	return func() tea.Msg {
+		spans, err := svc.Highlight(query, chunk.Text)
+		if err != nil {
+			// Fall back to token overlap when embedding fails.
+			spans = bestOverlapSentence(chunk.Text, queryparse.Terms(query))
+		}
+		return highlightedMsg{query: query, chunkID: chunk.ChunkID, spans: spans}
+	}
+}
+
+// showHighlights caches the spans of a result, unless another query was
+// made since, and shows them if the result is in view.
+func (m Model) showHighlights(msg highlightedMsg) Model {
+	if msg.query != m.lastQuery {
+		return m
+	}
+	delete(m.highlighting, msg.chunkID)
+	m.highlights[msg.chunkID] = msg.spans
+	if (m.mode == modeSearch || m.mode == modeReading) && m.cursor < len(m.results) && m.results[m.cursor].Chunk.ChunkID == msg.chunkID {
+		m.viewport.SetContent(m.renderCurrentResult())
+	}
+	return m
+}
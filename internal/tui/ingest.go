@@ -0,0 +1,150 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"rag/internal/domain"
+	"rag/internal/i18n"
+)
+
+// IngestFunc indexes the corpus, reporting progress, and returns the ingest
+// report, whose summary heads the search screen and whose warnings go to the
+// status bar. When ctx is canceled it should keep what was indexed and
+// return an error wrapping context.Canceled.
+type IngestFunc func(ctx context.Context, progress func(domain.IngestProgress)) (domain.IngestReport, error)
+
+// ingestProgressMsg and ingestDoneMsg carry ingest events into the update
+// loop.
+type ingestProgressMsg domain.IngestProgress
+
+type ingestDoneMsg struct {
+	report domain.IngestReport
+	err    error
+}
+
+// ingestRun is the state of a background ingest, shared by model copies.
+type ingestRun struct {
+	run    IngestFunc
+	ctx    context.Context
+	cancel context.CancelFunc
+	events chan tea.Msg
+	start  time.Time
+}
+
+func newIngestRun(run IngestFunc) *ingestRun {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ingestRun{run: run, ctx: ctx, cancel: cancel, events: make(chan tea.Msg, 1)}
+}
+
+// startIngest runs the ingest in the background and waits for its first
+// event.
+func (r *ingestRun) startIngest() tea.Cmd {
+	r.start = time.Now()
+	go func() {
+		report, err := r.run(r.ctx, func(p domain.IngestProgress) {
+			// Progress is only drawn, so updates the UI has not caught up
+			// with are dropped.
+			select {
+			case r.events <- ingestProgressMsg(p):
+			default:
+			}
+		})
+		r.events <- ingestDoneMsg{report: report, err: err}
+	}()
+	return r.wait()
+}
+
+// wait returns the next ingest event.
+func (r *ingestRun) wait() tea.Cmd {
+	return func() tea.Msg { return <-r.events }
+}
+
+// updateIngest handles keys while the ingest screen is shown: Esc or
+// Ctrl+C cancel the ingest, a second Ctrl+C quits. After a failed ingest
+// any key quits.
+func (m Model) updateIngest(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.ingestErr != nil {
+		return m, tea.Quit
+	}
+	switch msg.String() {
+	case "ctrl+c", "ctrl+d":
+		if m.ingestCanceled {
+			return m, tea.Quit
+		}
+		fallthrough
+	case "esc":
+		m.ingest.cancel()
+		m.ingestCanceled = true
+		m.status = i18n.T("Canceling ingest; keeping what is indexed…")
+	}
+	return m, nil
+}
+
+// finishIngest switches to searching once the ingest is over.
+func (m Model) finishIngest(msg ingestDoneMsg) Model {
+	switch {
+	case msg.err == nil:
+		m.summary = msg.report.Summary
+		m.status = loadedStatus(msg.report)
+	case errors.Is(msg.err, context.Canceled):
+		m.summary = i18n.T("The ingest was canceled; results cover the chunks indexed so far.")
+		m.status = i18n.T("Ingest canceled; searching the partial index.")
+	default:
+		m.ingestErr = msg.err
+		m.status = i18n.Sprintf("Ingest failed: %v (press any key to quit)", msg.err)
+		return m
+	}
+	m.warnings = append(m.warnings, msg.report.Warnings...)
+	m.mode = modeSearch
+	m = m.countIndex()
+	m.viewport.SetContent(m.renderCurrentResult())
+	return m
+}
+
+// loadedStatus reports a finished ingest in the status line.
+func loadedStatus(r domain.IngestReport) string {
+	status := i18n.Sprintf("Indexed %d documents (%d chunks) in %s", r.Documents, r.Chunks, r.Duration.Round(100*time.Millisecond))
+	if r.Skipped > 0 {
+		status += i18n.Sprintf(", %d chunks skipped", r.Skipped)
+	}
+	return status + ". " + i18n.T("Type to search.")
+}
+
+var progressBarStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+
+// renderIngest renders the progress of the running ingest.
+func (m Model) renderIngest() string {
+	p := m.ingestProgress
+	var b strings.Builder
+	switch p.Stage {
+	case domain.StageEnriching, domain.StageEmbedding:
+		label := i18n.Sprintf("Embedding chunks %d/%d", p.Done, p.Total)
+		if p.Stage == domain.StageEnriching {
+			label = i18n.Sprintf("Enriching chunks %d/%d", p.Done, p.Total)
+		}
+		fmt.Fprintf(&b, "%s\n\n", label)
+		width := max(10, m.viewport.Width-10)
+		filled := 0
+		if p.Total > 0 {
+			filled = width * p.Done / p.Total
+		}
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+		fmt.Fprintf(&b, "%s %3d%%\n", progressBarStyle.Render(bar), 100*p.Done/max(1, p.Total))
+	case domain.StageSummarizing:
+		b.WriteString(i18n.T("Summarizing the corpus…") + "\n")
+	default:
+		fmt.Fprintf(&b, "%s\n", i18n.Sprintf("Loading documents (%d so far)…", p.Done))
+	}
+	fmt.Fprintf(&b, "\n%s", i18n.Sprintf("Elapsed %s", time.Since(m.ingest.start).Round(time.Second)))
+	if m.ingestErr == nil && !m.ingestCanceled {
+		b.WriteString("   " + i18n.T("Esc cancels, keeping what is indexed so far"))
+	}
+	return b.String()
+}
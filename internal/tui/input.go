@@ -0,0 +1,107 @@
+package tui
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"rag/internal/i18n"
+	"rag/internal/opener"
+	"rag/internal/textutil"
+)
+
+// maxInputLines bounds the rows the query input grows to; longer queries
+// scroll inside it.
+const maxInputLines = 5
+
+// newQueryInput creates the multi-line query input. Enter is left to the
+// model (it runs the query); Alt+Enter and Ctrl+J insert a newline, which
+// is also what most terminals send for Shift+Enter.
+func newQueryInput() textarea.Model {
+	ta := textarea.New()
+	ta.Placeholder = i18n.T("Type query and press Enter")
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.EndOfBufferCharacter = ' '
+	ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
+	ta.KeyMap.InsertNewline = key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"))
+	ta.KeyMap.LineEnd = key.NewBinding(key.WithKeys("end"))
+	setPrompt(&ta, "> ")
+	ta.SetHeight(1)
+	ta.Focus()
+	return ta
+}
+
+// setPrompt shows prompt on the first input line and indents the others.
+func setPrompt(ta *textarea.Model, prompt string) {
+	width := lipgloss.Width(prompt)
+	indent := strings.Repeat(" ", width)
+	ta.SetPromptFunc(width, func(line int) string {
+		if line == 0 {
+			return prompt
+		}
+		return indent
+	})
+}
+
+// inputRows is the number of screen rows the query currently needs,
+// counting soft-wrapped lines.
+func (m Model) inputRows() int {
+	width := max(1, m.input.Width())
+	rows := 0
+	for _, line := range strings.Split(m.input.Value(), "\n") {
+		rows += max(1, (textutil.Width(line)+width-1)/width)
+	}
+	return min(rows, maxInputLines)
+}
+
+// editorFinishedMsg reports that the external editor opened on path exited.
+type editorFinishedMsg struct {
+	path string
+	err  error
+}
+
+// openEditor suspends the TUI and edits the query in $VISUAL or $EDITOR
+// (vi if neither is set); the saved text replaces the query.
+func (m Model) openEditor() (Model, tea.Cmd) {
+	f, err := os.CreateTemp("", "rag-query-*.txt")
+	if err == nil {
+		_, err = f.WriteString(m.input.Value())
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		m.status = i18n.Sprintf("Error: %v", err)
+		return m, nil
+	}
+	args := append(opener.Editor(), f.Name())
+	path := f.Name()
+	cmd := exec.Command(args[0], args[1:]...)
+	return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return editorFinishedMsg{path: path, err: err}
+	})
+}
+
+// finishEditing loads the edited query back into the input.
+func (m Model) finishEditing(msg editorFinishedMsg) Model {
+	defer os.Remove(msg.path)
+	if msg.err != nil {
+		m.status = i18n.Sprintf("Editor: %v", msg.err)
+		return m
+	}
+	data, err := os.ReadFile(msg.path)
+	if err != nil {
+		m.status = i18n.Sprintf("Error: %v", err)
+		return m
+	}
+	m.input.SetValue(strings.TrimRight(string(data), "\r\n\t "))
+	m.suggestions = nil
+	m.status = i18n.T("Query edited. Press Enter to search.")
+	return m
+}
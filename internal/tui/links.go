@@ -0,0 +1,104 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"rag/internal/domain"
+	"rag/internal/i18n"
+)
+
+// openLinks lists the notes linked from and linking to the current result.
+func (m Model) openLinks() Model {
+	if len(m.results) == 0 {
+		m.status = i18n.T("Run a query to see the links of a result.")
+		return m
+	}
+	doc := m.results[m.cursor].Chunk
+	links, backlinks := m.service.Links(doc.DocumentID)
+	if len(links)+len(backlinks) == 0 {
+		m.status = i18n.Sprintf("%s has no links or backlinks.", filepath.Base(doc.Path))
+		return m
+	}
+	m.mode = modeLinks
+	m.linkFrom = filepath.Base(doc.Path)
+	m.links, m.backlinks = links, backlinks
+	m.linkCursor = 0
+	m.status = i18n.T("Links: Enter to open a note, Esc or Ctrl+L to return")
+	m.viewport.SetContent(m.renderLinks())
+	m.viewport.GotoTop()
+	return m
+}
+
+// updateLinks handles keys while the link list is open.
+func (m Model) updateLinks(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	total := len(m.links) + len(m.backlinks)
+	switch msg.String() {
+	case "esc", "ctrl+l":
+		m.mode = modeSearch
+		m.status = i18n.T("Type to search.")
+		m.viewport.SetContent(m.renderCurrentResult())
+		return m, nil
+	case "down":
+		m.linkCursor = (m.linkCursor + 1) % total
+	case "up":
+		m.linkCursor = (m.linkCursor - 1 + total) % total
+	case "enter":
+		return m.openNote(m.linkTarget()), nil
+	}
+	m.viewport.SetContent(m.renderLinks())
+	return m, nil
+}
+
+func (m Model) linkTarget() domain.DocumentInfo {
+	if m.linkCursor < len(m.links) {
+		return m.links[m.linkCursor]
+	}
+	return m.backlinks[m.linkCursor-len(m.links)]
+}
+
+// openNote shows all chunks of a note as the result list.
+func (m Model) openNote(doc domain.DocumentInfo) Model {
+	chunks := m.service.DocumentChunks(doc.ID)
+	m.mode = modeSearch
+	m.results = make([]domain.SearchResult, len(chunks))
+	for i, ch := range chunks {
+		m.results[i] = domain.SearchResult{Chunk: ch}
+	}
+	m.cursor = 0
+	m.exhausted = true
+	m.groupRow = 0
+	m = m.regroup()
+	m.status = i18n.Sprintf("Note %s (%d chunks); Ctrl+L for its links", filepath.Base(doc.Path), len(chunks))
+	m.viewport.SetContent(m.renderCurrentResult())
+	m.viewport.GotoTop()
+	return m
+}
+
+func (m Model) renderLinks() string {
+	var b strings.Builder
+	row := 0
+	section := func(title string, docs []domain.DocumentInfo) {
+		fmt.Fprintf(&b, "%s (%d)\n", title, len(docs))
+		for _, d := range docs {
+			marker := "  "
+			name := filepath.Base(d.Path)
+			if d.Title != "" {
+				name += " — " + d.Title
+			}
+			if row == m.linkCursor {
+				marker = "▸ "
+				name = docPathStyle.Render(name)
+			}
+			fmt.Fprintf(&b, "%s%s\n", marker, name)
+			row++
+		}
+		b.WriteString("\n")
+	}
+	section(i18n.Sprintf("Links from %s", m.linkFrom), m.links)
+	section(i18n.Sprintf("Backlinks to %s", m.linkFrom), m.backlinks)
+	return b.String()
+}
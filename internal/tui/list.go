@@ -0,0 +1,63 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"rag/internal/i18n"
+	"rag/internal/queryparse"
+	"rag/internal/snippet"
+	"rag/internal/textutil"
+)
+
+// listHeight is the number of result rows shown in the result-list pane.
+const listHeight = 5
+
+var (
+	listBoxStyle      = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
+	listSelectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Bold(true)
+	listScoreStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+// renderResultList renders one snippet line per result around the cursor.
+func (m Model) renderResultList() string {
+	width := m.viewport.Width
+	rows := make([]string, 0, listHeight)
+	if len(m.results) == 0 {
+		rows = append(rows, listScoreStyle.Render(i18n.T("No results yet.")))
+	}
+	first := 0
+	if m.cursor >= listHeight {
+		first = m.cursor - listHeight + 1
+	}
+	for i := first; i < len(m.results) && i < first+listHeight; i++ {
+		r := m.results[i]
+		marker := "  "
+		if i == m.cursor {
+			marker = "▸ "
+		}
+		prefix := fmt.Sprintf("%s%2d. %.3f  ", marker, i+1, r.Score)
+		room := width - textutil.Width(prefix)
+		text := snippet.Generate(r.Chunk.Text, queryparse.Terms(m.lastQuery), m.highlights[r.Chunk.ChunkID], room)
+		text = truncate(text, room)
+		if !m.terminalBidi {
+			text = visualLine(text)
+		}
+		if i == m.cursor {
+			rows = append(rows, listSelectedStyle.Render(prefix+text))
+		} else {
+			rows = append(rows, prefix+listScoreStyle.Render(text))
+		}
+	}
+	for len(rows) < listHeight {
+		rows = append(rows, "")
+	}
+	return strings.Join(rows, "\n")
+}
+
+// truncate shortens s to at most n columns, ending with an ellipsis.
+func truncate(s string, n int) string {
+	return textutil.Truncate(s, n)
+}
@@ -1,99 +1,481 @@
 package tui
 
 import (
-	"fmt"
-	"regexp"
+	"context"
+	"sort"
 	"strings"
+	"time"
 
-	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"rag/internal/bookmark"
 	"rag/internal/domain"
+	"rag/internal/facets"
+	"rag/internal/feedback"
+	"rag/internal/generator"
+	"rag/internal/grouping"
+	"rag/internal/i18n"
+	"rag/internal/ordering"
+	"rag/internal/queryparse"
+	"rag/internal/savedsearch"
+	"rag/internal/textutil"
 )
 
 // RAGPort is the TUI-facing subset of the RAG service.
 type RAGPort interface {
-	IngestDocuments(paths []string) (string, error)
+	IngestDocuments(paths []string) (domain.IngestReport, error)
 	Query(query string, topK int) ([]domain.SearchResult, error)
+	QueryPage(query string, offset, limit int) ([]domain.SearchResult, error)
+	Highlight(query, text string) ([][2]int, error)
+	Documents() []domain.DocumentInfo
+	Keywords(documentID string) []string
+	Topics(k int) ([]domain.Topic, error)
+	Suggest(input string, n int) []string
+	Links(documentID string) (links, backlinks []domain.DocumentInfo)
+	DocumentChunks(documentID string) []domain.Chunk
+	Similar(passage string, offset, limit int) ([]domain.SearchResult, error)
+}
+
+// mode selects which screen the TUI shows.
+type mode int
+
+const (
+	modeSearch mode = iota
+	modeDocuments
+	modeTopics
+	modeSaveName
+	modeSaved
+	modeLinks
+	modeReading
+	modeIngest
+	modeFilter
+	modeActions
+	modeRefine
+	modeFacets
+	modeAnswer
+)
+
+// defaultTopK is the number of results requested per query unless the
+// config or the query (k:N) sets another.
+const defaultTopK = 10
+
+// Config holds optional collaborators of the TUI.
+type Config struct {
+	// Saved persists named searches; nil disables saving.
+	Saved *savedsearch.Store
+	// GroupByDocument starts with results grouped by source document.
+	GroupByDocument bool
+	// Order sorts the results of each query ("" = by score).
+	Order ordering.Order
+	// TerminalBidi leaves right-to-left text in logical order for terminals
+	// that reorder it themselves.
+	TerminalBidi bool
+	// WrapColumn is the column reading mode wraps text at (0 = the window
+	// width).
+	WrapColumn int
+	// HangingIndent indents wrapped paragraph lines in reading mode
+	// (0 = default, negative = none).
+	HangingIndent int
+	// Backend names the active embedder and store for the status bar.
+	Backend string
+	// TopK is the number of results a query returns unless it sets another
+	// with k:N (0 = 10).
+	TopK int
+	// Bookmarks keeps the results bookmarked from the actions menu; nil
+	// disables bookmarking.
+	Bookmarks *bookmark.Store
+	// Feedback keeps the relevance judgments of results made from the
+	// actions menu; nil disables them.
+	Feedback *feedback.Store
+	// Ingest, when set, indexes the corpus behind a progress screen that
+	// can cancel it; otherwise the corpus must be indexed beforehand.
+	Ingest IngestFunc
+	// Warnings are provider problems to show in the status bar, such as
+	// chunks that failed to embed.
+	Warnings []string
+	// Translate translates a result into TranslateTo, offered in the
+	// actions menu; nil disables translation.
+	Translate   func(ctx context.Context, text string) (string, error)
+	TranslateTo string
+	// Answer writes an answer to a query from its results, shown by ? in
+	// a panel as onText delivers it; nil disables answers.
+	Answer AnswerFunc
+	// OpenCommand is the command template opening a result's file, as
+	// opener.Command takes it; empty opens it in $VISUAL or $EDITOR.
+	OpenCommand string
+	// Now reads the clock that times searches for the status bar; nil
+	// uses time.Now.
+	Now func() time.Time
 }
 
 // Model is the Bubble Tea model for the TUI application.
 type Model struct {
-	service   RAGPort
-	input     textinput.Model
-	viewport  viewport.Model
-	results   []domain.SearchResult
-	summary   string
-	status    string
-	cursor    int
-	ready     bool
+	mode     mode
+	service  RAGPort
+	input    textarea.Model
+	viewport viewport.Model
+	results  []domain.SearchResult
+	summary  string
+	status   string
+	cursor   int
+	ready    bool
+	// width and height are the terminal size.
+	width     int
+	height    int
 	lastQuery string
+	// pageSize is the number of results fetched per page of lastQuery.
+	pageSize int
+	// ranked is how far into the ranking of lastQuery results were read;
+	// more than len(results) when filters dropped some.
+	ranked int
+	// filter is set in the filter bar and applies to every query.
+	filter filters
+	// refinements narrow the results of lastQuery, in the order they were
+	// typed in the refine bar; a new query clears them.
+	refinements []refinement
+	// highlights caches the sentence spans of results for lastQuery by
+	// chunk ID; highlighting holds the chunk IDs whose spans are being
+	// computed.
+	highlights   map[string][][2]int
+	highlighting map[string]bool
+	docs         []domain.DocumentInfo
+	docCursor    int
+	// topics are clustered once per session, on first open.
+	topics      []domain.Topic
+	topicCursor int
+	// suggestions complete the word being typed; Tab accepts.
+	suggestions   []string
+	suggestCursor int
+	topK          int
+	saved         *savedsearch.Store
+	savedList     []savedsearch.Search
+	savedCursor   int
+	bookmarks     *bookmark.Store
+	feedback      *feedback.Store
+	actionCursor  int
+	// pendingQuery keeps the input while the save-name prompt is shown.
+	pendingQuery string
+	// terminalBidi disables visual reordering of right-to-left text.
+	terminalBidi bool
+	// backend, warnings and the index size are shown in the status bar,
+	// along with the latency of the last search.
+	backend    string
+	warnings   []string
+	chunkCount int
+	docCount   int
+	latency    time.Duration
+	// now comes from Config.
+	now func() time.Time
+	// ingest is the background ingest shown in modeIngest.
+	ingest         *ingestRun
+	ingestProgress domain.IngestProgress
+	ingestCanceled bool
+	ingestErr      error
+	// wrapColumn and hangingIndent lay out results in reading mode.
+	wrapColumn    int
+	hangingIndent int
+	// passage runs the input as a find-similar passage instead of a query.
+	passage bool
+	// exhausted is set once a fetch-more returned no further results.
+	exhausted bool
+	// grouped shows results grouped by document; groupRow is the cursor
+	// over the visible group rows and expanded the unfolded documents.
+	grouped  bool
+	groups   []grouping.Group
+	groupRow int
+	expanded map[string]bool
+	// facets count the results by document, tag and directory in
+	// modeFacets.
+	facets      []facets.Facet
+	facetCursor int
+	// order sorts the results of each query; Ctrl+Y cycles it.
+	order ordering.Order
+	// links and backlinks of the note linkFrom, shown in modeLinks.
+	linkFrom   string
+	links      []domain.DocumentInfo
+	backlinks  []domain.DocumentInfo
+	linkCursor int
+	// translate and translateTo come from Config; translations holds the
+	// translated texts by chunk ID, shown under the results.
+	translate    func(ctx context.Context, text string) (string, error)
+	translateTo  string
+	translations map[string]string
+	// answerFunc comes from Config; answer is the last answer written, or
+	// answerErr why it failed, for answerQuery, and answerDraft the text
+	// of it written so far.
+	answerFunc  AnswerFunc
+	answer      *generator.Answer
+	answerDraft string
+	answerErr   error
+	answerQuery string
+	// openCommand comes from Config.
+	openCommand string
 }
 
 // New creates a new TUI model instance.
-func New(service RAGPort, summary string) Model {
-	ti := textinput.New()
-	ti.Prompt = "> "
-	ti.Placeholder = "Type query and press Enter"
-	ti.Focus()
-	ti.CharLimit = 0
+func New(service RAGPort, summary string, cfg Config) Model {
 	vp := viewport.New(0, 0)
-	return Model{service: service, input: ti, viewport: vp, summary: summary, status: "Loaded. Type to search."}
+	indent := cfg.HangingIndent
+	if indent == 0 {
+		indent = defaultHangingIndent
+	}
+	m := Model{service: service, input: newQueryInput(), viewport: vp, summary: summary, status: i18n.T("Loaded. Type to search."), topK: defaultTopK, saved: cfg.Saved, grouped: cfg.GroupByDocument, order: cfg.Order, terminalBidi: cfg.TerminalBidi, wrapColumn: cfg.WrapColumn, hangingIndent: max(0, indent), backend: cfg.Backend, warnings: cfg.Warnings, bookmarks: cfg.Bookmarks, feedback: cfg.Feedback, translate: cfg.Translate, translateTo: cfg.TranslateTo, translations: make(map[string]string), highlights: make(map[string][][2]int), highlighting: make(map[string]bool), answerFunc: cfg.Answer, openCommand: cfg.OpenCommand, now: cfg.Now}
+	if m.now == nil {
+		m.now = time.Now
+	}
+	if cfg.TopK > 0 {
+		m.topK = cfg.TopK
+	}
+	if m.order == "" {
+		m.order = ordering.Score
+	}
+	if cfg.Ingest != nil {
+		m.mode = modeIngest
+		m.ingest = newIngestRun(cfg.Ingest)
+		m.status = i18n.T("Indexing…")
+		return m
+	}
+	return m.countIndex()
 }
 
-// Init initializes the model (text input cursor blink).
-func (m Model) Init() tea.Cmd { return textinput.Blink }
+// countIndex records the index size shown in the status bar.
+func (m Model) countIndex() Model {
+	docs := m.service.Documents()
+	m.chunkCount, m.docCount = 0, len(docs)
+	for _, d := range docs {
+		m.chunkCount += d.Chunks
+	}
+	return m
+}
+
+// Init initializes the model (text input cursor blink) and starts the
+// ingest, if there is one.
+func (m Model) Init() tea.Cmd {
+	if m.ingest != nil {
+		return tea.Batch(textarea.Blink, m.ingest.startIngest())
+	}
+	return textarea.Blink
+}
 
 // Update handles key and window events and updates the view state.
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	// The query input grows with its text, which shrinks the result pane.
+	if nm, ok := next.(Model); ok && nm.ready {
+		next = nm.layout()
+	}
+	// Whichever update brought a result into view, its highlights are
+	// computed in the background.
+	if nm, ok := next.(Model); ok {
+		if hcmd := nm.highlightCurrent(); hcmd != nil {
+			cmd = tea.Batch(cmd, hcmd)
+		}
+	}
+	return next, cmd
+}
+
+// layout sizes the panes to the terminal and the current query height.
+func (m Model) layout() Model {
+	// account for frames around result and query boxes
+	rw, rh := resultBoxStyle.GetFrameSize()
+	qw, qh := queryBoxStyle.GetFrameSize()
+	_, lh := listBoxStyle.GetFrameSize()
+	width := max(20, m.width)
+	m.viewport.Width = width - rw
+	m.input.SetWidth(width - qw)
+	rows := m.inputRows()
+	m.input.SetHeight(rows)
+	totalHeaderLines := 2                                                         // header + summary
+	totalFooterLines := 2                                                         // suggestions + status
+	reserved := totalHeaderLines + totalFooterLines + qh + rows + listHeight + lh // query rows
+	vh := m.height - reserved
+	if m.mode == modeReading || m.mode == modeIngest {
+		vh = m.height - totalHeaderLines // header + status
+	}
+	if vh < 3 {
+		vh = 3
+	}
+	m.viewport.Height = max(3, vh-rh)
+	return m
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case editorFinishedMsg:
+		return m.finishEditing(msg), nil
+	case translatedMsg:
+		return m.showTranslation(msg), nil
+	case highlightedMsg:
+		return m.showHighlights(msg), nil
+	case answerTextMsg:
+		return m.showAnswerText(msg), msg.wait
+	case answeredMsg:
+		return m.showAnswer(msg), nil
+	case resultOpenedMsg:
+		if msg.err != nil {
+			m.status = i18n.Sprintf("Editor: %v", msg.err)
+		}
+		return m, nil
+	case ingestProgressMsg:
+		m.ingestProgress = domain.IngestProgress(msg)
+		m.viewport.SetContent(m.renderIngest())
+		return m, m.ingest.wait()
+	case ingestDoneMsg:
+		m = m.finishIngest(msg)
+		if m.mode == modeIngest {
+			m.viewport.SetContent(m.renderIngest())
+		}
+		return m, nil
 	case tea.WindowSizeMsg:
 		m.ready = true
-		// account for frames around result and query boxes
-		_, rh := resultBoxStyle.GetFrameSize()
-		_, qh := queryBoxStyle.GetFrameSize()
-		totalHeaderLines := 2                                    // header + summary
-		totalFooterLines := 1                                    // status
-		reserved := totalHeaderLines + totalFooterLines + qh + 1 // 1 spacer
-		vh := msg.Height - reserved
-		if vh < 3 {
-			vh = 3
+		m.width, m.height = msg.Width, msg.Height
+		m = m.layout()
+		switch m.mode {
+		case modeDocuments:
+			m.viewport.SetContent(m.renderDocuments())
+		case modeTopics:
+			m.viewport.SetContent(m.renderTopics())
+		case modeLinks:
+			m.viewport.SetContent(m.renderLinks())
+		case modeIngest:
+			m.viewport.SetContent(m.renderIngest())
+		case modeActions:
+			m.viewport.SetContent(m.renderActions())
+		case modeFacets:
+			m.viewport.SetContent(m.renderFacets())
+		case modeAnswer:
+			m.viewport.SetContent(m.renderAnswer())
+		default:
+			m.viewport.SetContent(m.renderCurrentResult())
 		}
-		m.viewport.Width = max(20, msg.Width)
-		m.viewport.Height = max(3, vh-rh)
-		m.viewport.SetContent(m.renderCurrentResult())
 		return m, nil
 	case tea.KeyMsg:
+		if m.mode == modeIngest {
+			return m.updateIngest(msg)
+		}
 		// Global quits
 		if msg.Type == tea.KeyCtrlC || msg.Type == tea.KeyCtrlD {
 			return m, tea.Quit
 		}
+		switch m.mode {
+		case modeDocuments:
+			return m.updateDocuments(msg)
+		case modeTopics:
+			return m.updateTopics(msg)
+		case modeSaveName:
+			return m.updateSaveName(msg)
+		case modeSaved:
+			return m.updateSaved(msg)
+		case modeLinks:
+			return m.updateLinks(msg)
+		case modeReading:
+			return m.updateReading(msg)
+		case modeFilter:
+			return m.updateFilterBar(msg)
+		case modeRefine:
+			return m.updateRefineBar(msg)
+		case modeFacets:
+			return m.updateFacets(msg)
+		case modeActions:
+			return m.updateActions(msg)
+		case modeAnswer:
+			return m.updateAnswer(msg)
+		}
 		switch msg.String() {
+		case "ctrl+b":
+			return m.openDocuments(), nil
+		case "ctrl+t":
+			return m.openTopics(), nil
+		case "ctrl+s":
+			return m.startSaveSearch(), nil
+		case "ctrl+o":
+			return m.openSaved(), nil
+		case "ctrl+l":
+			return m.openLinks(), nil
+		case "ctrl+e":
+			return m.openEditor()
+		case "ctrl+f":
+			return m.togglePassageMode(), nil
+		case "ctrl+r":
+			return m.toggleReading(), nil
+		case "f":
+			// With an empty query there is nothing to type, so f filters
+			// the results of the last one.
+			if m.input.Value() == "" && m.lastQuery != "" {
+				return m.openFilterBar(), nil
+			}
+		case "/":
+			// Likewise / refines the results of the last query.
+			if m.input.Value() == "" && len(m.results) > 0 {
+				return m.openRefineBar(), nil
+			}
+		case "?":
+			// And ? answers it from its results.
+			if m.input.Value() == "" && len(m.results) > 0 {
+				return m.openAnswer()
+			}
+		case "ctrl+g":
+			m = m.toggleGrouping()
+			m.viewport.SetContent(m.renderCurrentResult())
+			return m, nil
+		case "ctrl+a":
+			return m.openFacets(), nil
+		case "ctrl+y":
+			m = m.cycleOrder()
+			m.viewport.SetContent(m.renderCurrentResult())
+			return m, nil
+		case "ctrl+x":
+			m = m.toggleExpanded()
+			m.viewport.SetContent(m.renderCurrentResult())
+			return m, nil
+		case "tab":
+			return m.acceptSuggestion(), nil
+		case "ctrl+n":
+			if len(m.suggestions) > 0 {
+				m.suggestCursor = (m.suggestCursor + 1) % len(m.suggestions)
+			}
+			return m, nil
+		case "ctrl+p":
+			if len(m.suggestions) > 0 {
+				m.suggestCursor = (m.suggestCursor - 1 + len(m.suggestions)) % len(m.suggestions)
+			}
+			return m, nil
+		case "esc":
+			m.suggestions = nil
+			return m, nil
 		case "enter":
 			q := strings.TrimSpace(m.input.Value())
+			// Enter on the results of the query in the box, or with an
+			// empty box, acts on the selected result.
+			if len(m.results) > 0 && (q == "" || q == m.lastQuery) {
+				return m.openActions(), nil
+			}
 			if q != "" {
-				res, err := m.service.Query(q, 10)
-				if err != nil {
-					m.status = "Error: " + err.Error()
-					m.results = nil
-				} else {
-					m.status = fmt.Sprintf("Results for %q", q)
-					m.results = res
-					m.cursor = 0
-					m.lastQuery = q
-				}
+				return m.runQuery(q, m.topK), nil
+			}
+		case "down":
+			if m.grouped && len(m.results) > 0 {
+				m = m.moveGroupRow(1)
 				m.viewport.SetContent(m.renderCurrentResult())
 				return m, nil
 			}
-		case "down":
 			if len(m.results) > 0 {
+				if m.cursor == len(m.results)-1 && !m.exhausted {
+					m = m.fetchMore()
+				}
 				m.cursor = (m.cursor + 1) % len(m.results)
 				m.viewport.SetContent(m.renderCurrentResult())
 				return m, nil
 			}
 		case "up":
+			if m.grouped && len(m.results) > 0 {
+				m = m.moveGroupRow(-1)
+				m.viewport.SetContent(m.renderCurrentResult())
+				return m, nil
+			}
 			if len(m.results) > 0 {
 				m.cursor = (m.cursor - 1 + len(m.results)) % len(m.results)
 				m.viewport.SetContent(m.renderCurrentResult())
@@ -102,30 +484,132 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 	var cmd tea.Cmd
+	before := m.input.Value()
 	m.input, cmd = m.input.Update(msg)
+	if _, ok := msg.(tea.KeyMsg); ok {
+		m = m.refreshSuggestions(before)
+	}
 	return m, cmd
 }
 
+// runQuery executes q and shows its results; a k:N operator in q overrides
+// topK.
+func (m Model) runQuery(q string, topK int) Model {
+	if topK <= 0 {
+		topK = m.topK
+	}
+	if !m.passage {
+		if parsed, err := queryparse.Parse(q); err == nil && parsed.Limit > 0 {
+			topK = parsed.Limit
+		}
+	}
+	if q != m.lastQuery {
+		m.refinements = nil
+	}
+	start := m.now()
+	res, ranked, exhausted, err := m.fetch(q, 0, topK)
+	m.latency = m.now().Sub(start)
+	if err != nil {
+		m.status = i18n.Sprintf("Error: %v", err)
+		m.results = nil
+	} else {
+		m.status = i18n.Sprintf("Results for %q", q)
+		if m.passage {
+			m.status = i18n.Sprintf("Passages similar to %q", truncate(q, 40))
+		}
+		m.results = res
+		m.cursor = 0
+		m.lastQuery = q
+		m.pageSize = topK
+		m.ranked = ranked
+		m.highlights = make(map[string][][2]int)
+		m.highlighting = make(map[string]bool)
+		m.exhausted = exhausted
+	}
+	m.groupRow = 0
+	m = m.sortResults()
+	m.suggestions = nil
+	m.viewport.SetContent(m.renderCurrentResult())
+	return m
+}
+
+// fetchMore appends the next page of results for the last query.
+func (m Model) fetchMore() Model {
+	start := m.now()
+	res, ranked, exhausted, err := m.fetch(m.lastQuery, m.ranked, m.pageSize)
+	m.latency = m.now().Sub(start)
+	if err != nil {
+		m.status = i18n.Sprintf("Error: %v", err)
+		return m
+	}
+	m.ranked, m.exhausted = ranked, exhausted
+	if len(res) == 0 {
+		m.status = i18n.Sprintf("All %d results for %q shown", len(m.results), m.lastQuery)
+		return m
+	}
+	m.results = append(m.results, res...)
+	m = m.sortResults()
+	m.status = i18n.Sprintf("Loaded %d more results for %q", len(res), m.lastQuery)
+	return m
+}
+
 // View renders the TUI layout and current result.
 func (m Model) View() string {
 	if !m.ready {
-		return "Loading..."
+		return i18n.T("Loading...")
 	}
 	header := lipgloss.NewStyle().Bold(true).Render("RAG Text Search")
-	summary := lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render(m.summary)
+	if m.mode == modeReading || m.mode == modeIngest {
+		results := resultBoxStyle.Render(m.viewport.View())
+		return header + "\n" + results + "\n" + m.renderStatusBar()
+	}
+	summary := lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render(truncate(m.summary, m.width))
 	input := queryBoxStyle.Render(m.input.View())
-	status := lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render(m.status)
+	listContent := m.renderResultList()
+	if m.grouped {
+		listContent = m.renderGroupedList()
+	}
+	if m.mode == modeDocuments || m.mode == modeTopics {
+		listContent = m.renderCorpusKeywords()
+	}
+	list := listBoxStyle.Width(m.viewport.Width + listBoxStyle.GetHorizontalPadding()).Render(listContent)
 	results := resultBoxStyle.Render(m.viewport.View())
-	return header + "\n" + summary + "\n" + results + "\n" + input + "\n" + status
+	return header + "\n" + summary + "\n" + list + "\n" + results + "\n" + input + "\n" + m.renderSuggestions() + "\n" + m.renderStatusBar()
 }
 
 func (m Model) renderCurrentResult() string {
 	if len(m.results) == 0 {
-		return "No results yet."
+		return i18n.T("No results yet.")
 	}
 	r := m.results[m.cursor]
-	title := fmt.Sprintf("Result %d/%d  score=%.3f", m.cursor+1, len(m.results), r.Score)
-	body := highlightBestSentence(r.Chunk.Text, m.lastQuery)
+	title := i18n.Sprintf("Result %d/%d  score=%.3f", m.cursor+1, len(m.results), r.Score)
+	if !r.Chunk.Time.IsZero() {
+		title += "  " + r.Chunk.Time.Format("2006-01-02")
+	}
+	if meta := formatMetadata(r.Chunk.Metadata); meta != "" {
+		title += "  " + meta
+	}
+	if r.Chunk.Path != "" {
+		title += "\n" + docPathStyle.Render(r.Chunk.Location())
+	}
+	if len(r.Via) > 0 {
+		title += "\n" + docPathStyle.Render(i18n.Sprintf("found for: %s", strings.Join(r.Via, " | ")))
+	}
+	spans, ok := m.highlights[r.Chunk.ChunkID]
+	if !ok {
+		// Until the ranked sentences arrive, those sharing the most words
+		// with the query stand in.
+		spans = bestOverlapSentence(r.Chunk.Text, queryparse.Terms(m.lastQuery))
+	}
+	body := renderHighlighted(r.Chunk.Text, spans)
+	if m.mode == modeReading {
+		body = renderReading(r.Chunk.Text, spans, m.readingWidth(), m.hangingIndent, m.terminalBidi)
+	} else if !m.terminalBidi && hasRTL(r.Chunk.Text) {
+		body = renderRTL(r.Chunk.Text, spans, m.viewport.Width)
+	}
+	if t, ok := m.translations[r.Chunk.ChunkID]; ok {
+		body += "\n\n" + docPathStyle.Render(i18n.Sprintf("Translation (%s):", m.translateTo)) + "\n" + t
+	}
 	return title + "\n\n" + body
 }
 
@@ -133,65 +617,69 @@ var (
 	resultBoxStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
 	queryBoxStyle  = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1)
 	highlightStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Bold(true)
-	unicodeWordRe  = regexp.MustCompile(`\p{L}+(?:['’]\p{L}+)*`)
-	sentenceRe     = regexp.MustCompile(`(?m)(?U)([^.!?]+[.!?])`)
 )
 
-func highlightBestSentence(text, query string) string {
-	if strings.TrimSpace(text) == "" {
-		return text
+// renderHighlighted joins the sentences of text with single spaces, styling
+// those that fall inside one of the highlighted spans. Sentences that were
+// not separated in the text (as in Chinese or Japanese) stay unseparated.
+func renderHighlighted(text string, spans [][2]int) string {
+	var b strings.Builder
+	prevEnd := 0
+	for i, sp := range textutil.SentenceSpans(text) {
+		if i > 0 && sp[0] > prevEnd {
+			b.WriteByte(' ')
+		}
+		prevEnd = sp[1]
+		sent := text[sp[0]:sp[1]]
+		if inSpans(sp, spans) {
+			sent = highlightStyle.Render(sent)
+		}
+		b.WriteString(sent)
 	}
-	sentences := sentenceRe.FindAllString(text, -1)
-	if len(sentences) == 0 {
-		sentences = []string{strings.TrimSpace(text)}
+	return b.String()
+}
+
+func inSpans(sp [2]int, spans [][2]int) bool {
+	for _, h := range spans {
+		if sp[0] < h[1] && h[0] < sp[1] {
+			return true
+		}
 	}
-	qTokens := toTokenSet(query)
-	if len(qTokens) == 0 {
-		return strings.Join(sentences, " ")
+	return false
+}
+
+// bestOverlapSentence returns the span of the sentence matching the most
+// query words, counting inflected and misspelled forms.
+func bestOverlapSentence(text, query string) [][2]int {
+	m := textutil.NewTermMatcher(query)
+	sentences := textutil.SentenceSpans(text)
+	if m.Empty() || len(sentences) == 0 {
+		return nil
 	}
 	bestIdx := 0
 	bestScore := -1
-	for i, s := range sentences {
-		score := tokenOverlapScore(qTokens, s)
+	for i, sp := range sentences {
+		score := m.Count(text[sp[0]:sp[1]])
 		if score > bestScore {
 			bestScore = score
 			bestIdx = i
 		}
 	}
-	for i := range sentences {
-		sent := strings.TrimSpace(sentences[i])
-		if i == bestIdx {
-			sentences[i] = highlightStyle.Render(sent)
-		} else {
-			sentences[i] = sent
-		}
-	}
-	return strings.Join(sentences, " ")
+	return [][2]int{sentences[bestIdx]}
 }
 
-func toTokenSet(s string) map[string]struct{} {
-	tokens := unicodeWordRe.FindAllString(strings.ToLower(s), -1)
-	m := make(map[string]struct{}, len(tokens))
-	for _, t := range tokens {
-		m[t] = struct{}{}
+// formatMetadata renders chunk metadata as "key: value" pairs in key order.
+func formatMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
 	}
-	return m
-}
-
-func tokenOverlapScore(queryTokens map[string]struct{}, sentence string) int {
-	score := 0
-	tokens := unicodeWordRe.FindAllString(strings.ToLower(sentence), -1)
-	seen := make(map[string]struct{}, len(tokens))
-	for _, t := range tokens {
-		if _, ok := seen[t]; ok {
-			continue
-		}
-		seen[t] = struct{}{}
-		if _, ok := queryTokens[t]; ok {
-			score++
-		}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + ": " + metadata[k]
 	}
-	return score
+	return strings.Join(parts, "  ")
 }
 
 func max(a, b int) int {
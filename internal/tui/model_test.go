@@ -0,0 +1,149 @@
+package tui
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/exp/golden"
+	"github.com/charmbracelet/x/exp/teatest"
+	"github.com/muesli/termenv"
+
+	"rag/internal/bookmark"
+	"rag/internal/domain"
+	"rag/internal/i18n"
+)
+
+func init() {
+	// Golden views are plain text in English, whatever the terminal and
+	// locale of the test run.
+	lipgloss.SetColorProfile(termenv.Ascii)
+	i18n.SetLanguage("en")
+}
+
+// fakeService answers every query with the same ranked chunks, or with
+// err when it is set.
+type fakeService struct {
+	results []domain.SearchResult
+	err     error
+}
+
+func newFakeService() *fakeService {
+	chunk := func(doc string, idx int, text string) domain.Chunk {
+		return domain.Chunk{DocumentID: doc, ChunkID: doc + ":" + strconv.Itoa(idx), Path: "notes/" + doc, Index: idx, StartLine: 1 + 4*idx, EndLine: 3 + 4*idx, Text: text}
+	}
+	return &fakeService{results: []domain.SearchResult{
+		{Chunk: chunk("raft.md", 0, "Raft elects a leader with randomized timeouts. The leader replicates its log to the followers. A follower that hears nothing starts an election."), Score: 0.91},
+		{Chunk: chunk("raft.md", 1, "Entries are committed once a majority stores them. Committed entries are applied in log order."), Score: 0.74},
+		{Chunk: chunk("paxos.md", 0, "Paxos agrees on a single value. Multi-Paxos chains instances into a log, with a stable leader skipping the first phase."), Score: 0.52},
+	}}
+}
+
+func (f *fakeService) IngestDocuments(paths []string) (domain.IngestReport, error) {
+	return domain.IngestReport{}, nil
+}
+
+func (f *fakeService) Query(query string, topK int) ([]domain.SearchResult, error) {
+	return f.QueryPage(query, 0, topK)
+}
+
+func (f *fakeService) QueryPage(query string, offset, limit int) ([]domain.SearchResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if offset >= len(f.results) {
+		return nil, nil
+	}
+	return f.results[offset:min(offset+limit, len(f.results))], nil
+}
+
+func (f *fakeService) Highlight(query, text string) ([][2]int, error) { return nil, nil }
+
+func (f *fakeService) Documents() []domain.DocumentInfo {
+	return []domain.DocumentInfo{
+		{ID: "paxos.md", Path: "notes/paxos.md", Chunks: 1},
+		{ID: "raft.md", Path: "notes/raft.md", Chunks: 2},
+	}
+}
+
+func (f *fakeService) Keywords(documentID string) []string             { return nil }
+func (f *fakeService) Topics(k int) ([]domain.Topic, error)            { return nil, nil }
+func (f *fakeService) Suggest(input string, n int) []string            { return nil }
+func (f *fakeService) DocumentChunks(documentID string) []domain.Chunk { return nil }
+
+func (f *fakeService) Links(documentID string) (links, backlinks []domain.DocumentInfo) {
+	return nil, nil
+}
+
+func (f *fakeService) Similar(passage string, offset, limit int) ([]domain.SearchResult, error) {
+	return f.QueryPage(passage, offset, limit)
+}
+
+var (
+	enter = tea.KeyMsg{Type: tea.KeyEnter}
+	down  = tea.KeyMsg{Type: tea.KeyDown}
+	right = tea.KeyMsg{Type: tea.KeyRight}
+	esc   = tea.KeyMsg{Type: tea.KeyEsc}
+	ctrlR = tea.KeyMsg{Type: tea.KeyCtrlR}
+)
+
+// resize is a terminal narrower and shorter than the initial 80x30.
+var resize = tea.WindowSizeMsg{Width: 60, Height: 20}
+
+func runes(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+// TestModel types each key sequence into a fresh model and compares the
+// screen it ends on with testdata/TestModel/<name>.golden; go test -update
+// rewrites the files.
+func TestModel(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []tea.Msg
+		// err fails every query.
+		err error
+	}{
+		{"start", nil, nil},
+		{"search", []tea.Msg{runes("leader election"), enter}, nil},
+		{"search_down", []tea.Msg{runes("leader election"), enter, down}, nil},
+		{"reading", []tea.Msg{runes("leader election"), enter, ctrlR}, nil},
+		{"reading_next", []tea.Msg{runes("leader election"), enter, ctrlR, right, right}, nil},
+		{"reading_closed", []tea.Msg{runes("leader election"), enter, ctrlR, esc}, nil},
+		{"actions", []tea.Msg{runes("leader election"), enter, down, enter, down}, nil},
+		{"actions_closed", []tea.Msg{runes("leader election"), enter, enter, esc}, nil},
+		{"bookmark", []tea.Msg{runes("leader election"), enter, enter, runes("b")}, nil},
+		{"resize", []tea.Msg{runes("leader election"), enter, resize}, nil},
+		{"error", []tea.Msg{runes("leader election"), enter}, errors.New("qdrant: connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newFakeService()
+			svc.err = tt.err
+			m := New(svc, "Notes on consensus protocols.", Config{
+				Backend:   "fake · memory",
+				Bookmarks: bookmark.NewStore(filepath.Join(t.TempDir(), "bookmarks.json")),
+				Now:       tick(),
+			})
+			tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(80, 30))
+			for _, msg := range tt.keys {
+				tm.Send(msg)
+			}
+			tm.Send(tea.KeyMsg{Type: tea.KeyCtrlC})
+			final := tm.FinalModel(t, teatest.WithFinalTimeout(5*time.Second))
+			golden.RequireEqual(t, []byte(final.View()))
+		})
+	}
+}
+
+// tick returns a clock that advances 3ms on every reading, so that every
+// search takes 3ms.
+func tick() func() time.Time {
+	var now time.Time
+	return func() time.Time {
+		now = now.Add(3 * time.Millisecond)
+		return now
+	}
+}
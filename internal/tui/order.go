@@ -0,0 +1,38 @@
+package tui
+
+import (
+	"rag/internal/i18n"
+	"rag/internal/ordering"
+)
+
+// orderNames describe the result orders in the status bar.
+var orderNames = map[ordering.Order]string{
+	ordering.Score:    "Ordered by score.",
+	ordering.Document: "Ordered by document and position.",
+	ordering.Recency:  "Ordered by date, newest first.",
+}
+
+// cycleOrder switches the results to the next order.
+func (m Model) cycleOrder() Model {
+	m.order = m.order.Next()
+	m = m.sortResults()
+	m.status = i18n.T(orderNames[m.order])
+	return m
+}
+
+// sortResults sorts the results by the current order, keeping the cursor
+// on the same result, and regroups them.
+func (m Model) sortResults() Model {
+	if len(m.results) == 0 {
+		return m.regroup()
+	}
+	selected := m.results[m.cursor].Chunk.ChunkID
+	ordering.Sort(m.results, m.order)
+	for i, r := range m.results {
+		if r.Chunk.ChunkID == selected {
+			m.cursor = i
+			break
+		}
+	}
+	return m.regroup()
+}
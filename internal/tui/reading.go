@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"rag/internal/i18n"
+)
+
+// defaultHangingIndent is the indent of wrapped paragraph lines in reading
+// mode.
+const defaultHangingIndent = 2
+
+// toggleReading expands the current result to full screen or returns from
+// reading mode.
+func (m Model) toggleReading() Model {
+	if m.mode == modeReading {
+		m.mode = modeSearch
+		m.status = i18n.T("Type to search.")
+	} else {
+		if len(m.results) == 0 {
+			m.status = i18n.T("No result to read.")
+			return m
+		}
+		m.mode = modeReading
+		m.status = i18n.T("Reading: Up/Down scroll, Left/Right switch results, Esc returns")
+	}
+	m = m.layout()
+	m.viewport.SetContent(m.renderCurrentResult())
+	m.viewport.GotoTop()
+	return m
+}
+
+// updateReading handles keys while a result is shown full screen.
+func (m Model) updateReading(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+r":
+		return m.toggleReading(), nil
+	case "left", "p":
+		if len(m.results) > 0 {
+			m.cursor = (m.cursor - 1 + len(m.results)) % len(m.results)
+		}
+	case "right", "n":
+		if len(m.results) > 0 {
+			if m.cursor == len(m.results)-1 && !m.exhausted {
+				m = m.fetchMore()
+			}
+			m.cursor = (m.cursor + 1) % len(m.results)
+		}
+	default:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	}
+	m.viewport.SetContent(m.renderCurrentResult())
+	m.viewport.GotoTop()
+	return m, nil
+}
+
+// readingWidth is the column reading mode wraps at.
+func (m Model) readingWidth() int {
+	if m.wrapColumn > 0 && m.wrapColumn < m.viewport.Width {
+		return m.wrapColumn
+	}
+	return m.viewport.Width
+}
+
+// renderReading lays text out for reading: paragraphs (separated by blank
+// lines) are kept apart, soft-wrapped at width, and their wrapped lines
+// indented by indent columns. Right-to-left paragraphs are put into visual
+// order unless the terminal reorders them itself.
+func renderReading(text string, spans [][2]int, width, indent int, terminalBidi bool) string {
+	var paragraphs [][]glyph
+	var para []glyph
+	newlines := 0
+	flush := func() {
+		if len(para) > 0 && para[len(para)-1].s == " " {
+			para = para[:len(para)-1]
+		}
+		if len(para) > 0 {
+			paragraphs = append(paragraphs, para)
+		}
+		para = nil
+	}
+	for _, g := range glyphs(text, spans) {
+		if strings.TrimSpace(g.s) == "" {
+			newlines += strings.Count(g.s, "\n")
+			if newlines >= 2 {
+				flush()
+				continue
+			}
+			if len(para) > 0 && para[len(para)-1].s != " " {
+				para = append(para, glyph{s: " ", width: 1, class: bidiNeutral})
+			}
+			continue
+		}
+		newlines = 0
+		para = append(para, g)
+	}
+	flush()
+
+	pad := strings.Repeat(" ", indent)
+	var out []string
+	for i, p := range paragraphs {
+		if i > 0 {
+			out = append(out, "")
+		}
+		rtl := baseRTL(p) && !terminalBidi
+		for j, line := range wrapGlyphs(p, width, indent) {
+			used := 0
+			for _, g := range line {
+				used += g.width
+			}
+			if rtl {
+				line = visualOrder(line, true)
+			}
+			s := renderGlyphs(line)
+			switch {
+			case rtl:
+				room := width
+				if j > 0 {
+					room -= indent
+				}
+				s = strings.Repeat(" ", max(0, room-used)) + s
+			case j > 0:
+				s = pad + s
+			}
+			out = append(out, s)
+		}
+	}
+	return strings.Join(out, "\n")
+}
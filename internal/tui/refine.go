@@ -0,0 +1,128 @@
+package tui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"rag/internal/domain"
+	"rag/internal/i18n"
+	"rag/internal/queryparse"
+	"rag/internal/textutil"
+)
+
+// refinement narrows the results of the last query to those whose text
+// matches every term of a second query, allowing for inflections and
+// typos as highlighting does.
+type refinement struct {
+	query string
+	terms *textutil.TermMatcher
+}
+
+func newRefinement(query string) refinement {
+	return refinement{query: query, terms: textutil.NewTermMatcher(queryparse.Terms(query))}
+}
+
+// refined reports whether r passes every refinement.
+func (m Model) refined(r domain.SearchResult) bool {
+	for _, f := range m.refinements {
+		if !f.terms.MatchAll(r.Chunk.Text) {
+			return false
+		}
+	}
+	return true
+}
+
+// refinedBy describes the refinements as "first › second".
+func (m Model) refinedBy() string {
+	queries := make([]string, len(m.refinements))
+	for i, f := range m.refinements {
+		queries[i] = f.query
+	}
+	return strings.Join(queries, " › ")
+}
+
+// openRefineBar shows the refine bar in place of the query input.
+func (m Model) openRefineBar() Model {
+	m.mode = modeRefine
+	m.pendingQuery = m.input.Value()
+	setPrompt(&m.input, i18n.T("refine> "))
+	m.input.SetValue("")
+	m.suggestions = nil
+	m.status = i18n.T("Refine: words the results must contain (Enter applies, empty removes the last refinement, Esc cancels)")
+	return m
+}
+
+// updateRefineBar handles keys while the refine bar is shown. A refinement
+// narrows the results shown without searching again; removing one reruns
+// the last query with the refinements left.
+func (m Model) updateRefineBar(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		q := strings.TrimSpace(m.input.Value())
+		m = m.leaveRefineBar()
+		if q == "" {
+			if len(m.refinements) == 0 {
+				m.status = i18n.T("No refinements to remove.")
+				return m, nil
+			}
+			m.refinements = m.refinements[:len(m.refinements)-1]
+			m = m.runQuery(m.lastQuery, m.pageSize)
+			m.status = i18n.T("Refinement removed.")
+			if len(m.refinements) > 0 {
+				m.status = i18n.Sprintf("Refined by %s", m.refinedBy())
+			}
+			return m, nil
+		}
+		f := newRefinement(q)
+		if f.terms.Empty() {
+			m.status = i18n.T("A refinement needs words to match.")
+			return m, nil
+		}
+		m = m.refine(f)
+		m.viewport.SetContent(m.renderCurrentResult())
+		m.viewport.GotoTop()
+		return m, nil
+	case "esc":
+		m = m.leaveRefineBar()
+		m.status = i18n.T("Type to search.")
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// refine keeps the results that match f, unless none does.
+func (m Model) refine(f refinement) Model {
+	var kept []domain.SearchResult
+	cursor := 0
+	for i, r := range m.results {
+		if !f.terms.MatchAll(r.Chunk.Text) {
+			continue
+		}
+		if i == m.cursor {
+			cursor = len(kept)
+		}
+		kept = append(kept, r)
+	}
+	if len(kept) == 0 {
+		m.status = i18n.Sprintf("No result contains %q; the results are unchanged.", f.query)
+		return m
+	}
+	m.refinements = append(m.refinements, f)
+	m.results = kept
+	m.cursor = cursor
+	m.groupRow = 0
+	m = m.regroup()
+	m.status = i18n.Sprintf("Refined by %s", m.refinedBy())
+	return m
+}
+
+func (m Model) leaveRefineBar() Model {
+	m.mode = modeSearch
+	setPrompt(&m.input, m.prompt())
+	m.input.SetValue(m.pendingQuery)
+	m.input.CursorEnd()
+	return m
+}
@@ -0,0 +1,145 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"rag/internal/i18n"
+	"rag/internal/savedsearch"
+)
+
+// startSaveSearch asks for a name under which the last query is saved.
+func (m Model) startSaveSearch() Model {
+	if m.saved == nil {
+		m.status = i18n.T("Saved searches are not available.")
+		return m
+	}
+	if m.lastQuery == "" {
+		m.status = i18n.T("Run a query before saving it.")
+		return m
+	}
+	m.mode = modeSaveName
+	m.pendingQuery = m.input.Value()
+	setPrompt(&m.input, i18n.T("name> "))
+	m.input.SetValue("")
+	m.suggestions = nil
+	m.status = i18n.Sprintf("Save %q as: (Enter to save, Esc to cancel)", m.lastQuery)
+	return m
+}
+
+// updateSaveName handles keys while the save-name prompt is active.
+func (m Model) updateSaveName(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		name := strings.TrimSpace(m.input.Value())
+		if name == "" {
+			return m, nil
+		}
+		if err := m.saved.Save(savedsearch.Search{Name: name, Query: m.lastQuery, TopK: m.pageSize}); err != nil {
+			m.status = i18n.Sprintf("Error: %v", err)
+		} else {
+			m.status = i18n.Sprintf("Saved search %q.", name)
+		}
+		return m.leaveSaveName(), nil
+	case "esc":
+		m = m.leaveSaveName()
+		m.status = i18n.T("Save cancelled.")
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m Model) leaveSaveName() Model {
+	m.mode = modeSearch
+	setPrompt(&m.input, m.prompt())
+	m.input.SetValue(m.pendingQuery)
+	m.input.CursorEnd()
+	return m
+}
+
+// openSaved switches to the saved-searches menu.
+func (m Model) openSaved() Model {
+	if m.saved == nil {
+		m.status = i18n.T("Saved searches are not available.")
+		return m
+	}
+	list, err := m.saved.List()
+	if err != nil {
+		m.status = i18n.Sprintf("Error: %v", err)
+		return m
+	}
+	m.mode = modeSaved
+	m.savedList = list
+	m.savedCursor = 0
+	m.status = i18n.T("Saved searches: Enter to run, Delete to remove, Esc to return")
+	m.viewport.SetContent(m.renderSaved())
+	m.viewport.GotoTop()
+	return m
+}
+
+// updateSaved handles keys while the saved-searches menu is open.
+func (m Model) updateSaved(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+o":
+		m.mode = modeSearch
+		m.status = i18n.T("Type to search.")
+		m.viewport.SetContent(m.renderCurrentResult())
+		return m, nil
+	case "down":
+		if len(m.savedList) > 0 {
+			m.savedCursor = (m.savedCursor + 1) % len(m.savedList)
+		}
+	case "up":
+		if len(m.savedList) > 0 {
+			m.savedCursor = (m.savedCursor - 1 + len(m.savedList)) % len(m.savedList)
+		}
+	case "delete", "backspace":
+		if len(m.savedList) > 0 {
+			name := m.savedList[m.savedCursor].Name
+			if err := m.saved.Delete(name); err != nil {
+				m.status = i18n.Sprintf("Error: %v", err)
+				break
+			}
+			m.savedList = append(m.savedList[:m.savedCursor], m.savedList[m.savedCursor+1:]...)
+			if m.savedCursor >= len(m.savedList) {
+				m.savedCursor = max(0, len(m.savedList)-1)
+			}
+			m.status = i18n.Sprintf("Deleted saved search %q.", name)
+		}
+	case "enter":
+		if len(m.savedList) > 0 {
+			s := m.savedList[m.savedCursor]
+			m.mode = modeSearch
+			if m.passage {
+				m = m.togglePassageMode()
+			}
+			m.input.SetValue(s.Query)
+			m.input.CursorEnd()
+			return m.runQuery(s.Query, s.TopK), nil
+		}
+	}
+	m.viewport.SetContent(m.renderSaved())
+	return m, nil
+}
+
+func (m Model) renderSaved() string {
+	if len(m.savedList) == 0 {
+		return i18n.T("No saved searches. Press Ctrl+S after a query to save it.")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", i18n.Sprintf("Saved searches %d/%d", m.savedCursor+1, len(m.savedList)))
+	for i, s := range m.savedList {
+		marker := "  "
+		name := s.Name
+		if i == m.savedCursor {
+			marker = "▸ "
+			name = docPathStyle.Render(name)
+		}
+		fmt.Fprintf(&b, "%s%s  %s\n", marker, name, listScoreStyle.Render(strings.Join(strings.Fields(s.Query), " ")))
+	}
+	return b.String()
+}
@@ -0,0 +1,37 @@
+package tui
+
+import (
+	"rag/internal/domain"
+	"rag/internal/i18n"
+)
+
+// togglePassageMode switches between ordinary queries and find-similar
+// mode, where the input is searched for as a passage: nearest chunks by
+// embedding only, without query syntax or lexical fallback.
+func (m Model) togglePassageMode() Model {
+	m.passage = !m.passage
+	setPrompt(&m.input, m.prompt())
+	if m.passage {
+		m.status = i18n.T("Find similar: paste a passage and press Enter (Ctrl+F for queries)")
+	} else {
+		m.status = i18n.T("Query mode.")
+	}
+	m.suggestions = nil
+	return m
+}
+
+// prompt is the input prompt of the current search mode.
+func (m Model) prompt() string {
+	if m.passage {
+		return "≈ "
+	}
+	return "> "
+}
+
+// search fetches a page of results for q in the current search mode.
+func (m Model) search(q string, offset, limit int) ([]domain.SearchResult, error) {
+	if m.passage {
+		return m.service.Similar(q, offset, limit)
+	}
+	return m.service.QueryPage(q, offset, limit)
+}
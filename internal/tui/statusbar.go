@@ -0,0 +1,79 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"rag/internal/i18n"
+	"rag/internal/textutil"
+)
+
+var (
+	statusStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	statusSegmentStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("15")).Background(lipgloss.Color("8")).Padding(0, 1)
+	statusWarnStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color("11")).Padding(0, 1)
+	statusFilterStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color("14")).Padding(0, 1)
+)
+
+// minStatusWidth is the room the status message keeps in the status bar.
+const minStatusWidth = 24
+
+// renderStatusBar renders the status message followed, on the right, by
+// the active backends, the index size, the last query latency, the filters
+// and provider warnings. Segments that do not fit are dropped, backends first, and the
+// warning and message are shortened.
+func (m Model) renderStatusBar() string {
+	width := m.viewport.Width + resultBoxStyle.GetHorizontalFrameSize()
+	var segments []string
+	if m.backend != "" {
+		segments = append(segments, statusSegmentStyle.Render(m.backend))
+	}
+	segments = append(segments, statusSegmentStyle.Render(i18n.Sprintf("%d chunks · %d docs", m.chunkCount, m.docCount)))
+	if m.latency > 0 {
+		segments = append(segments, statusSegmentStyle.Render(formatLatency(m.latency)))
+	}
+	if m.filter.active() {
+		segments = append(segments, statusFilterStyle.Render(i18n.Sprintf("filter %s", m.filter.String())))
+	}
+	if len(m.refinements) > 0 {
+		segments = append(segments, statusFilterStyle.Render(i18n.Sprintf("refine %s", m.refinedBy())))
+	}
+	if len(m.warnings) > 0 {
+		warn := "⚠ " + m.warnings[0]
+		if len(m.warnings) > 1 {
+			warn += fmt.Sprintf(" (+%d)", len(m.warnings)-1)
+		}
+		used := lipgloss.Width(strings.Join(segments, " ")) + 1
+		room := width - minStatusWidth - used - statusWarnStyle.GetHorizontalFrameSize() - 1
+		if room < 12 && m.backend != "" {
+			// Warnings matter more than the backend names.
+			segments = segments[1:]
+			used = lipgloss.Width(strings.Join(segments, " ")) + 1
+			room = width - minStatusWidth - used - statusWarnStyle.GetHorizontalFrameSize() - 1
+		}
+		segments = append(segments, statusWarnStyle.Render(truncate(warn, max(room, 3))))
+	}
+	right := strings.Join(segments, " ")
+	for len(segments) > 0 && lipgloss.Width(right)+minStatusWidth > width {
+		segments = segments[1:]
+		right = strings.Join(segments, " ")
+	}
+	room := width - lipgloss.Width(right) - 1
+	msg := truncate(m.status, room)
+	gap := strings.Repeat(" ", room-textutil.Width(msg)+1)
+	return statusStyle.Render(msg) + gap + right
+}
+
+// formatLatency renders a query duration at a readable precision.
+func formatLatency(d time.Duration) string {
+	if d < time.Millisecond {
+		return fmt.Sprintf("%dµs", d.Microseconds())
+	}
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
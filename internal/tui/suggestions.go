@@ -0,0 +1,56 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxSuggestions bounds the completions shown under the query input.
+const maxSuggestions = 5
+
+var (
+	suggestionStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	suggestionSelectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(lipgloss.Color("12"))
+)
+
+// refreshSuggestions recomputes completions after the input changed.
+func (m Model) refreshSuggestions(before string) Model {
+	value := m.input.Value()
+	if value == before {
+		return m
+	}
+	m.suggestions = m.service.Suggest(value, maxSuggestions)
+	m.suggestCursor = 0
+	return m
+}
+
+// acceptSuggestion replaces the last word of the input with the selected
+// completion.
+func (m Model) acceptSuggestion() Model {
+	if len(m.suggestions) == 0 {
+		return m
+	}
+	value := m.input.Value()
+	cut := strings.LastIndexAny(value, " \t") + 1
+	m.input.SetValue(value[:cut] + m.suggestions[m.suggestCursor] + " ")
+	m.input.CursorEnd()
+	m.suggestions = nil
+	return m
+}
+
+// renderSuggestions renders the completion row shown under the input.
+func (m Model) renderSuggestions() string {
+	if len(m.suggestions) == 0 {
+		return ""
+	}
+	parts := make([]string, len(m.suggestions))
+	for i, s := range m.suggestions {
+		if i == m.suggestCursor {
+			parts[i] = suggestionSelectedStyle.Render(s)
+		} else {
+			parts[i] = suggestionStyle.Render(s)
+		}
+	}
+	return suggestionStyle.Render("Tab ▸ ") + strings.Join(parts, "  ")
+}
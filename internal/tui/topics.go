@@ -0,0 +1,83 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"rag/internal/i18n"
+	"rag/internal/snippet"
+)
+
+// topicPreviewChunks is how many chunks are listed under the selected topic.
+const topicPreviewChunks = 5
+
+// openTopics clusters the corpus (once) and switches to the topic browser.
+func (m Model) openTopics() Model {
+	if m.topics == nil {
+		topics, err := m.service.Topics(0)
+		if err != nil {
+			m.status = i18n.Sprintf("Error: %v", err)
+			return m
+		}
+		m.topics = topics
+	}
+	m.mode = modeTopics
+	if m.topicCursor >= len(m.topics) {
+		m.topicCursor = 0
+	}
+	m.status = i18n.T("Topics: Up/Down to browse, Esc or Ctrl+T to return")
+	m.viewport.SetContent(m.renderTopics())
+	m.viewport.GotoTop()
+	return m
+}
+
+// updateTopics handles keys while the topic browser is open.
+func (m Model) updateTopics(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+t":
+		m.mode = modeSearch
+		m.status = i18n.T("Type to search.")
+		m.viewport.SetContent(m.renderCurrentResult())
+		m.viewport.GotoTop()
+		return m, nil
+	case "down":
+		if len(m.topics) > 0 {
+			m.topicCursor = (m.topicCursor + 1) % len(m.topics)
+		}
+	case "up":
+		if len(m.topics) > 0 {
+			m.topicCursor = (m.topicCursor - 1 + len(m.topics)) % len(m.topics)
+		}
+	}
+	m.viewport.SetContent(m.renderTopics())
+	// The selected topic is expanded, so keep its header in view.
+	m.viewport.SetYOffset(max(0, 2+m.topicCursor-m.viewport.Height/2))
+	return m, nil
+}
+
+func (m Model) renderTopics() string {
+	if len(m.topics) == 0 {
+		return i18n.T("No topics found.")
+	}
+	width := m.viewport.Width - 4
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", i18n.Sprintf("Topic %d/%d", m.topicCursor+1, len(m.topics)))
+	for i, t := range m.topics {
+		if i != m.topicCursor {
+			fmt.Fprintf(&b, "  %s  %s\n", t.Label, i18n.Sprintf("(%d chunks)", len(t.Chunks)))
+			continue
+		}
+		fmt.Fprintf(&b, "▸ %s  %s\n", docPathStyle.Render(t.Label), i18n.Sprintf("(%d chunks)", len(t.Chunks)))
+		fmt.Fprintf(&b, "    %s\n", listScoreStyle.Render(truncate(strings.Join(t.Keywords, ", "), width)))
+		for j, ch := range t.Chunks {
+			if j == topicPreviewChunks {
+				fmt.Fprintf(&b, "    … %s\n", i18n.Sprintf("%d more", len(t.Chunks)-j))
+				break
+			}
+			fmt.Fprintf(&b, "    - %s\n", truncate(snippet.Generate(ch.Text, "", nil, width), width))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,154 @@
+// Package tune fits the weights of the ranking signals to relevance
+// feedback: it looks for the weights under which the results judged
+// relevant rank highest, and those judged not relevant lowest.
+package tune
+
+import (
+	"math"
+	"sort"
+
+	"rag/internal/service"
+)
+
+// Weights are the weights of the vector, lexical and recency signals, as
+// under search.weights in the config.
+type Weights struct {
+	Vector, Lexical, Recency float64
+}
+
+// Query is a judged query: the candidates of its first-stage ranking, and
+// the judgments of its results by chunk ID (true for relevant).
+type Query struct {
+	Candidates []service.Candidate
+	Judgments  map[string]bool
+}
+
+// Metrics measure a ranking of the judged queries in its first k results.
+type Metrics struct {
+	// Recall is the mean share of a query's relevant results in the first
+	// k, over the queries with any.
+	Recall float64
+	// MRR is the mean reciprocal rank of a query's first relevant result,
+	// 0 beyond k, over the same queries.
+	MRR float64
+	// Negatives is the mean number of results judged not relevant in the
+	// first k, over all queries.
+	Negatives float64
+}
+
+// epsilon is the least difference of metrics that counts.
+const epsilon = 1e-9
+
+// Better reports whether m is a better ranking than o: by recall, then by
+// MRR, then by fewer results judged not relevant.
+func (m Metrics) Better(o Metrics) bool {
+	if d := m.Recall - o.Recall; math.Abs(d) > epsilon {
+		return d > 0
+	}
+	if d := m.MRR - o.MRR; math.Abs(d) > epsilon {
+		return d > 0
+	}
+	return o.Negatives-m.Negatives > epsilon
+}
+
+// Evaluate ranks the candidates of each query by the weights, with the
+// links weight as configured, and measures the first k results.
+func Evaluate(queries []Query, w Weights, links float64, k int) Metrics {
+	scorer := service.WeightedScorer{Vector: w.Vector, Lexical: w.Lexical, Recency: w.Recency, Links: links}
+	var m Metrics
+	withRelevant := 0
+	order := make([]int, 0)
+	scores := make([]float64, 0)
+	for _, q := range queries {
+		order, scores = order[:0], scores[:0]
+		for i, c := range q.Candidates {
+			order = append(order, i)
+			scores = append(scores, scorer.Score(c.Signals))
+		}
+		sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+		relevant := 0
+		for _, ok := range q.Judgments {
+			if ok {
+				relevant++
+			}
+		}
+		found, first := 0, 0
+		for rank, i := range order[:min(k, len(order))] {
+			ok, judged := q.Judgments[q.Candidates[i].Chunk.ChunkID]
+			switch {
+			case !judged:
+			case ok:
+				found++
+				if first == 0 {
+					first = rank + 1
+				}
+			default:
+				m.Negatives++
+			}
+		}
+		if relevant > 0 {
+			withRelevant++
+			m.Recall += float64(found) / float64(relevant)
+			if first > 0 {
+				m.MRR += 1 / float64(first)
+			}
+		}
+	}
+	if withRelevant > 0 {
+		m.Recall /= float64(withRelevant)
+		m.MRR /= float64(withRelevant)
+	}
+	if len(queries) > 0 {
+		m.Negatives /= float64(len(queries))
+	}
+	return m
+}
+
+// Grid values tried for the lexical and recency weights. Only the ratio
+// of the vector and lexical weights matters, so the vector weight is 1, or
+// 0 for lexical ranking alone.
+var (
+	lexicalGrid = []float64{0, 0.1, 0.25, 0.5, 1, 2, 4}
+	recencyGrid = []float64{0, 0.1, 0.25, 0.5, 1, 2}
+)
+
+// Fit searches for the weights that rank the judged queries best: over a
+// grid first, then refining the best point by ever smaller steps of each
+// weight. The start weights are kept unless others rank strictly better.
+// Weights are rounded to three decimals.
+func Fit(queries []Query, start Weights, links float64, k int) (Weights, Metrics) {
+	best, bestM := start, Evaluate(queries, start, links, k)
+	try := func(w Weights) bool {
+		w = Weights{round(w.Vector), round(w.Lexical), round(w.Recency)}
+		if m := Evaluate(queries, w, links, k); m.Better(bestM) {
+			best, bestM = w, m
+			return true
+		}
+		return false
+	}
+	try(Weights{Vector: 0, Lexical: 1})
+	for _, lex := range lexicalGrid {
+		for _, rec := range recencyGrid {
+			try(Weights{Vector: 1, Lexical: lex, Recency: rec})
+		}
+	}
+	for step := 0.5; step >= 0.05; step /= 2 {
+		for improved := true; improved; {
+			improved = false
+			for _, d := range []float64{-step, step} {
+				w := best
+				w.Lexical = math.Max(0, w.Lexical*(1+d))
+				if w.Lexical == 0 && d > 0 {
+					w.Lexical = step
+				}
+				improved = try(w) || improved
+				w = best
+				w.Recency = math.Max(0, w.Recency+d)
+				improved = try(w) || improved
+			}
+		}
+	}
+	return best, bestM
+}
+
+func round(x float64) float64 { return math.Round(x*1000) / 1000 }
@@ -0,0 +1,496 @@
+// Package disk is a vector store that keeps its chunks and vectors in a
+// BoltDB file, so that an index outlives the process and a later run on the
+// same corpus can reuse it instead of embedding every chunk again.
+//
+// Searches run in memory: on open the file is loaded into a memory store,
+// which every change is applied to as well. Changes are appended to a
+// write-ahead log next to the file before they are applied, and written to
+// the database in bulk at checkpoints, every checkpointEvery changes, when
+// the stamp is read or set, and on Close. Entries logged since the last
+// checkpoint are replayed on open, so a crash loses at most the change being
+// logged. Each checkpoint records the sequence number of the last change it
+// wrote, and replay skips the entries up to it: a crash after a checkpoint
+// commits but before the log is emptied must not upsert its chunks twice.
+package disk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"rag/internal/domain"
+	"rag/internal/textcodec"
+	"rag/internal/vectorstore"
+	"rag/internal/vectorstore/memory"
+	"rag/internal/vectorstore/wal"
+)
+
+// Storage is a vector store persisted in a BoltDB file.
+type Storage struct {
+	// mu serializes changes, which go to the log, the memory store and,
+	// at checkpoints, the database in the same order.
+	mu        sync.Mutex
+	db        *bolt.DB
+	log       *wal.Log
+	mem       *memory.Storage
+	dimension int
+	// pending are the logged changes not yet written to the database.
+	pending []wal.Entry
+	// seq is the sequence number of the last logged change.
+	seq      uint64
+	compress bool
+}
+
+// Config configures a disk vector store.
+type Config struct {
+	// Path is the database file; the write-ahead log is kept beside it,
+	// with a ".wal" suffix.
+	Path string
+	// CompressText stores chunk texts zstd-compressed in the database.
+	CompressText bool
+	// Memory configures the in-memory store that searches run in.
+	Memory memory.Config
+}
+
+// checkpointEvery is how many logged changes are written to the database
+// at once. Pending changes are held in memory until then, so it bounds that
+// memory too: an ingest upserts batches of 64 chunks.
+const checkpointEvery = 16
+
+var (
+	metaBucket   = []byte("meta")
+	pointsBucket = []byte("points")
+
+	dimensionKey = []byte("dimension")
+	embedderKey  = []byte("embedder")
+	contentsKey  = []byte("contents")
+	// appliedKey holds the sequence number of the last change written.
+	appliedKey = []byte("applied")
+)
+
+// record is a stored chunk with its vector.
+type record struct {
+	// Chunk has no Text when the text is compressed.
+	Chunk domain.Chunk
+	// Compressed is the zstd-compressed text, if compression is on.
+	Compressed []byte
+	Vector     []byte
+}
+
+// Open opens the store at cfg.Path, creating the file and its directory if
+// needed, and loads it into memory, replaying the changes logged after the
+// last checkpoint. Only one process can have a store open at a time.
+func Open(cfg Config) (*Storage, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("disk store path not set")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, err
+	}
+	mem, err := memory.NewStorageWithConfig(cfg.Memory)
+	if err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(cfg.Path, 0o644, &bolt.Options{Timeout: time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%s is in use by another process", cfg.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &Storage{db: db, mem: mem, compress: cfg.CompressText}
+	if err := s.load(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("loading %s: %w", cfg.Path, err)
+	}
+	log, entries, err := wal.Open(cfg.Path + ".wal")
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	s.log = log
+	applied := s.seq
+	for _, e := range entries {
+		if e.Seq != 0 && e.Seq <= applied {
+			continue
+		}
+		if err := s.apply(e); err != nil {
+			_ = s.close()
+			return nil, fmt.Errorf("replaying %s.wal: %w", cfg.Path, err)
+		}
+		s.pending = append(s.pending, e)
+		s.seq = max(s.seq, e.Seq)
+	}
+	if err := s.checkpoint(); err != nil {
+		_ = s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load fills the memory store from the database.
+func (s *Storage) load() error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		meta, points := tx.Bucket(metaBucket), tx.Bucket(pointsBucket)
+		if meta == nil {
+			return nil
+		}
+		s.seq, _ = binary.Uvarint(meta.Get(appliedKey))
+		if points == nil {
+			return nil
+		}
+		dim, _ := binary.Uvarint(meta.Get(dimensionKey))
+		if dim == 0 {
+			return nil
+		}
+		if err := s.apply(wal.Entry{Op: wal.OpInit, Dimension: int(dim)}); err != nil {
+			return err
+		}
+		s.mem.Reserve(points.Stats().KeyN)
+		var chunks []domain.Chunk
+		var vectors [][]float64
+		err := points.ForEach(func(_, v []byte) error {
+			ch, vec, err := s.decode(v)
+			if err != nil {
+				return err
+			}
+			chunks, vectors = append(chunks, ch), append(vectors, vec)
+			if len(chunks) == 256 {
+				err = s.mem.Upsert(chunks, vectors)
+				chunks, vectors = chunks[:0], vectors[:0]
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return s.mem.Upsert(chunks, vectors)
+	})
+}
+
+// apply makes the change e to the memory store.
+func (s *Storage) apply(e wal.Entry) error {
+	switch e.Op {
+	case wal.OpInit:
+		if err := s.mem.Init(e.Dimension); err != nil {
+			return err
+		}
+		s.dimension = e.Dimension
+		return nil
+	case wal.OpUpsert:
+		return s.mem.Upsert(e.Chunks, e.Vectors)
+	case wal.OpClear:
+		return s.mem.Clear()
+	}
+	return fmt.Errorf("unknown wal op %d", e.Op)
+}
+
+// change logs e, applies it to memory and writes it to the database once
+// enough changes are pending. The caller holds s.mu.
+func (s *Storage) change(e wal.Entry) error {
+	s.seq++
+	e.Seq = s.seq
+	if err := s.log.Append(e); err != nil {
+		return err
+	}
+	if err := s.apply(e); err != nil {
+		return err
+	}
+	s.pending = append(s.pending, e)
+	if len(s.pending) < checkpointEvery {
+		return nil
+	}
+	return s.checkpoint()
+}
+
+// checkpoint writes the pending changes to the database and empties the log.
+func (s *Storage) checkpoint() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	if err := s.commit(); err != nil {
+		return err
+	}
+	s.pending = nil
+	return s.log.Checkpoint()
+}
+
+// commit writes the pending changes to the database in one transaction,
+// along with the sequence number of the last one.
+func (s *Storage) commit() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, e := range s.pending {
+			if err := s.write(tx, e); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(metaBucket).Put(appliedKey, binary.AppendUvarint(nil, s.pending[len(s.pending)-1].Seq))
+	})
+}
+
+// write makes the change e to the database.
+func (s *Storage) write(tx *bolt.Tx, e wal.Entry) error {
+	meta, err := tx.CreateBucketIfNotExists(metaBucket)
+	if err != nil {
+		return err
+	}
+	if e.Op == wal.OpInit || e.Op == wal.OpClear {
+		if tx.Bucket(pointsBucket) != nil {
+			if err := tx.DeleteBucket(pointsBucket); err != nil {
+				return err
+			}
+		}
+		if err := errors.Join(meta.Delete(embedderKey), meta.Delete(contentsKey)); err != nil {
+			return err
+		}
+	}
+	if e.Op == wal.OpInit {
+		if err := meta.Put(dimensionKey, binary.AppendUvarint(nil, uint64(e.Dimension))); err != nil {
+			return err
+		}
+	}
+	points, err := tx.CreateBucketIfNotExists(pointsBucket)
+	if err != nil {
+		return err
+	}
+	for i, ch := range e.Chunks {
+		v, err := s.encode(ch, e.Vectors[i])
+		if err != nil {
+			return err
+		}
+		seq, err := points.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err := points.Put(binary.BigEndian.AppendUint64(nil, seq), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Init sets the vector dimensionality and clears existing data.
+func (s *Storage) Init(dimension int) error {
+	if dimension <= 0 {
+		return errors.New("invalid dimension")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.change(wal.Entry{Op: wal.OpInit, Dimension: dimension})
+}
+
+// CheckDimension reports an error if the store holds vectors of another
+// dimension than the given one; an empty store is initialized with it.
+func (s *Storage) CheckDimension(dimension int) error {
+	if dimension <= 0 {
+		return errors.New("invalid dimension")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dimension == 0 {
+		return s.change(wal.Entry{Op: wal.OpInit, Dimension: dimension})
+	}
+	if s.dimension != dimension {
+		return fmt.Errorf("store holds %d-dimensional vectors but the embedder produces %d", s.dimension, dimension)
+	}
+	return nil
+}
+
+// Upsert appends the given chunks and vectors to the store.
+func (s *Storage) Upsert(chunks []domain.Chunk, vectors [][]float64) error {
+	if len(chunks) != len(vectors) {
+		return errors.New("chunks and vectors length mismatch")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Check the vectors before they are logged, so a bad batch is not
+	// replayed. The pending entry outlives the call, so it keeps copies.
+	copies := make([][]float64, len(vectors))
+	for i, v := range vectors {
+		if len(v) != s.dimension {
+			return fmt.Errorf("vector dimension %d, store initialized with %d", len(v), s.dimension)
+		}
+		copies[i] = slices.Clone(v)
+	}
+	return s.change(wal.Entry{Op: wal.OpUpsert, Chunks: slices.Clone(chunks), Vectors: copies})
+}
+
+// Reserve makes room in memory for n more vectors.
+func (s *Storage) Reserve(n int) { s.mem.Reserve(n) }
+
+// Search returns up to topK chunks matching filter by similarity to the
+// provided vector, skipping the first offset.
+func (s *Storage) Search(vector []float64, offset, topK int, filter vectorstore.Filter) ([]domain.SearchResult, error) {
+	return s.mem.Search(vector, offset, topK, filter)
+}
+
+// All returns every stored chunk with its vector.
+func (s *Storage) All() ([]domain.Chunk, [][]float64, error) {
+	return s.mem.All()
+}
+
+// Clear removes all stored vectors and chunks.
+func (s *Storage) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.change(wal.Entry{Op: wal.OpClear})
+}
+
+// Stamp returns the stamp of the stored contents.
+func (s *Storage) Stamp() (vectorstore.Stamp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkpoint(); err != nil {
+		return vectorstore.Stamp{}, err
+	}
+	var stamp vectorstore.Stamp
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if meta := tx.Bucket(metaBucket); meta != nil {
+			stamp.Embedder = string(meta.Get(embedderKey))
+			stamp.Contents = string(meta.Get(contentsKey))
+		}
+		return nil
+	})
+	return stamp, err
+}
+
+// SetStamp records the stamp of the stored contents, writing pending
+// changes first.
+func (s *Storage) SetStamp(stamp vectorstore.Stamp) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkpoint(); err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+		return errors.Join(meta.Put(embedderKey, []byte(stamp.Embedder)), meta.Put(contentsKey, []byte(stamp.Contents)))
+	})
+}
+
+// Close writes pending changes to the database and closes it.
+func (s *Storage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.checkpoint(), s.close())
+}
+
+func (s *Storage) close() error {
+	return errors.Join(s.log.Close(), s.db.Close())
+}
+
+// encode serializes a chunk and its vector for the database.
+func (s *Storage) encode(ch domain.Chunk, vector []float64) ([]byte, error) {
+	r := record{Chunk: ch, Vector: encodeVector(vector)}
+	if s.compress && ch.Text != "" {
+		var err error
+		if r.Compressed, err = textcodec.Compress(ch.Text); err != nil {
+			return nil, err
+		}
+		r.Chunk.Text = ""
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decode reverses encode.
+func (s *Storage) decode(data []byte) (domain.Chunk, []float64, error) {
+	var r record
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&r); err != nil {
+		return domain.Chunk{}, nil, err
+	}
+	if r.Compressed != nil {
+		text, err := textcodec.Decompress(r.Compressed)
+		if err != nil {
+			return domain.Chunk{}, nil, err
+		}
+		r.Chunk.Text = text
+	}
+	vector, err := decodeVector(r.Vector, s.dimension)
+	return r.Chunk, vector, err
+}
+
+// Vector encodings. Sparse vectors, such as TF-IDF ones over a large
+// vocabulary, store only their non-zero entries.
+const (
+	denseVector  byte = 0
+	sparseVector byte = 1
+)
+
+// encodeVector serializes v densely, or as (position, value) pairs when
+// that is shorter.
+func encodeVector(v []float64) []byte {
+	nonzero := 0
+	for _, x := range v {
+		if x != 0 {
+			nonzero++
+		}
+	}
+	if nonzero*(binary.MaxVarintLen32+8) < len(v)*8 {
+		out := binary.AppendUvarint([]byte{sparseVector}, uint64(nonzero))
+		for i, x := range v {
+			if x != 0 {
+				out = binary.AppendUvarint(out, uint64(i))
+				out = binary.LittleEndian.AppendUint64(out, math.Float64bits(x))
+			}
+		}
+		return out
+	}
+	out := make([]byte, 1, 1+len(v)*8)
+	out[0] = denseVector
+	for _, x := range v {
+		out = binary.LittleEndian.AppendUint64(out, math.Float64bits(x))
+	}
+	return out
+}
+
+// decodeVector reverses encodeVector for a vector of dimension elements.
+func decodeVector(data []byte, dimension int) ([]float64, error) {
+	corrupt := errors.New("corrupt vector")
+	if len(data) == 0 {
+		return nil, corrupt
+	}
+	v := make([]float64, dimension)
+	switch data[0] {
+	case denseVector:
+		if len(data) != 1+dimension*8 {
+			return nil, corrupt
+		}
+		for i := range v {
+			v[i] = math.Float64frombits(binary.LittleEndian.Uint64(data[1+i*8:]))
+		}
+	case sparseVector:
+		n, k := binary.Uvarint(data[1:])
+		if k <= 0 {
+			return nil, corrupt
+		}
+		rest := data[1+k:]
+		for ; n > 0; n-- {
+			i, k := binary.Uvarint(rest)
+			if k <= 0 || i >= uint64(dimension) || len(rest) < k+8 {
+				return nil, corrupt
+			}
+			v[i] = math.Float64frombits(binary.LittleEndian.Uint64(rest[k:]))
+			rest = rest[k+8:]
+		}
+	default:
+		return nil, corrupt
+	}
+	return v, nil
+}
@@ -0,0 +1,146 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"rag/internal/domain"
+	"rag/internal/vectorstore"
+	"rag/internal/vectorstore/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		name := "plain"
+		if compress {
+			name = "compressed"
+		}
+		t.Run(name, func(t *testing.T) {
+			storetest.Run(t, storetest.Store{
+				New: func(t *testing.T) vectorstore.Storage {
+					return open(t, Config{Path: filepath.Join(t.TempDir(), "index.db"), CompressText: compress})
+				},
+				Reopen: func(t *testing.T, s vectorstore.Storage) vectorstore.Storage {
+					d := s.(*Storage)
+					path := d.db.Path()
+					if err := d.Close(); err != nil {
+						t.Fatal(err)
+					}
+					return open(t, Config{Path: path, CompressText: compress})
+				},
+			})
+		})
+	}
+}
+
+// open opens the store at cfg.Path, closing it when the test ends.
+func open(t *testing.T, cfg Config) *Storage {
+	t.Helper()
+	s, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+var crashChunks = []domain.Chunk{
+	{DocumentID: "raft", ChunkID: "raft:0", Text: "Raft elects a leader."},
+	{DocumentID: "paxos", ChunkID: "paxos:0", Text: "Paxos agrees on a value."},
+}
+
+// crash initializes a new store at path and logs one upsert per chunk of
+// crashChunks, then closes its files as a crash would, without a checkpoint.
+// committed writes the upserts to the database first, as if the crash came
+// before the log was emptied.
+func crash(t *testing.T, path string, committed bool) {
+	t.Helper()
+	s, err := Open(Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Init(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	for i, ch := range crashChunks {
+		vector := make([]float64, 2)
+		vector[i] = 1
+		if err := s.Upsert([]domain.Chunk{ch}, [][]float64{vector}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if committed {
+		if err := s.commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// chunkIDs returns the IDs of the chunks s holds.
+func chunkIDs(t *testing.T, s *Storage) []string {
+	t.Helper()
+	chunks, _, err := s.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, ch := range chunks {
+		ids = append(ids, ch.ChunkID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestReplayAfterCommit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	crash(t, path, true)
+	want := []string{"paxos:0", "raft:0"}
+	s := open(t, Config{Path: path})
+	if got := chunkIDs(t, s); !slices.Equal(got, want) {
+		t.Fatalf("after replay the store holds %v, want %v", got, want)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s = open(t, Config{Path: path})
+	if got := chunkIDs(t, s); !slices.Equal(got, want) {
+		t.Errorf("after reopening the store holds %v, want %v", got, want)
+	}
+}
+
+func TestReplayTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	crash(t, path, false)
+	info, err := os.Stat(path + ".wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tear the last upsert.
+	if err := os.Truncate(path+".wal", info.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"raft:0"}
+	s := open(t, Config{Path: path})
+	if got := chunkIDs(t, s); !slices.Equal(got, want) {
+		t.Fatalf("after replay the store holds %v, want %v", got, want)
+	}
+	if err := s.Upsert(crashChunks[1:], [][]float64{{0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"paxos:0", "raft:0"}
+	s = open(t, Config{Path: path})
+	if got := chunkIDs(t, s); !slices.Equal(got, want) {
+		t.Errorf("after reopening the store holds %v, want %v", got, want)
+	}
+}
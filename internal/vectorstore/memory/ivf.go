@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"gonum.org/v1/gonum/floats"
+
+	"rag/internal/cluster"
+)
+
+// ivf is an IVF-Flat index: a k-means coarse quantizer whose centroids
+// partition the vectors into inverted lists. A search scores only the
+// vectors in the lists of the centroids nearest to the query, trading a
+// little recall for not scanning the whole corpus, with no memory beyond
+// the centroids and one list entry per vector.
+type ivf struct {
+	// lists is the configured number of lists; 0 picks √n at training.
+	lists  int
+	probes int
+	// centroids and members are nil until trained.
+	centroids [][]float64
+	members   [][]int
+	// trainedAt is the vector count the quantizer was trained on.
+	trainedAt int
+}
+
+const (
+	// ivfMinVectors is the corpus size below which searches stay exact: a
+	// full scan of a small corpus is cheap and k-means would be meaningless.
+	ivfMinVectors = 1024
+	// ivfSamplePerList bounds the k-means training sample, which need not
+	// cover the whole corpus to place good centroids.
+	ivfSamplePerList = 32
+	ivfIterations    = 10
+	ivfSeed          = 1
+)
+
+func newIVF(lists, probes int) *ivf {
+	if probes <= 0 {
+		probes = 8
+	}
+	return &ivf{lists: lists, probes: probes}
+}
+
+// stale reports whether the index should be (re)trained for n vectors:
+// once the corpus is big enough, and again whenever it has doubled since,
+// as vectors added after training may not fit the centroids well.
+func (x *ivf) stale(n int) bool {
+	return n >= ivfMinVectors && (x.centroids == nil || n >= 2*x.trainedAt)
+}
+
+// train runs k-means over a sample of vectors and fills the inverted lists.
+func (x *ivf) train(vectors [][]float64) {
+	k := x.lists
+	if k <= 0 {
+		k = int(math.Sqrt(float64(len(vectors))))
+	}
+	k = min(k, len(vectors))
+	sample := vectors
+	if n := k * ivfSamplePerList; n < len(vectors) {
+		rng := rand.New(rand.NewSource(ivfSeed))
+		sample = make([][]float64, n)
+		for i, j := range rng.Perm(len(vectors))[:n] {
+			sample[i] = vectors[j]
+		}
+	}
+	x.centroids = cluster.KMeans(sample, k, ivfIterations, ivfSeed).Centroids
+	x.members = make([][]int, len(x.centroids))
+	x.trainedAt = len(vectors)
+	x.add(0, vectors)
+}
+
+// add files vectors, stored from position first on, into their lists.
+func (x *ivf) add(first int, vectors [][]float64) {
+	if x.centroids == nil {
+		return
+	}
+	for i, v := range vectors {
+		c := x.nearestOne(v)
+		x.members[c] = append(x.members[c], first+i)
+	}
+}
+
+// candidates returns the positions of the vectors in the lists nearest to
+// query, or nil when the index is untrained and every vector must be scored.
+func (x *ivf) candidates(query []float64) []int {
+	if x.centroids == nil {
+		return nil
+	}
+	var out []int
+	for _, c := range x.nearest(query, x.probes) {
+		out = append(out, x.members[c]...)
+	}
+	return out
+}
+
+// nearest returns the n centroids with the highest dot product with v,
+// matching how the store scores vectors.
+func (x *ivf) nearest(v []float64, n int) []int {
+	scores := make([]float64, len(x.centroids))
+	order := make([]int, len(x.centroids))
+	for c, centroid := range x.centroids {
+		scores[c] = floats.Dot(centroid, v)
+		order[c] = c
+	}
+	sort.Slice(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+	return order[:min(n, len(order))]
+}
+
+// nearestOne is nearest(v, 1)[0] without sorting.
+func (x *ivf) nearestOne(v []float64) int {
+	best, bestScore := 0, math.Inf(-1)
+	for c, centroid := range x.centroids {
+		if score := floats.Dot(centroid, v); score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	return best
+}
+
+func (x *ivf) reset() {
+	x.centroids, x.members, x.trainedAt = nil, nil, 0
+}
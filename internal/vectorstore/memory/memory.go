@@ -1,23 +1,87 @@
 package memory
 
 import (
+	"container/heap"
 	"errors"
+	"fmt"
+	"slices"
 	"sync"
 
+	"gonum.org/v1/gonum/blas"
+	"gonum.org/v1/gonum/blas/blas64"
+	"gonum.org/v1/gonum/floats"
+
 	"rag/internal/domain"
+	"rag/internal/textlog"
+	"rag/internal/vectorstore"
 )
 
-// Storage is a simple in-memory vector store using brute-force cosine similarity.
+// Storage is a simple in-memory vector store using brute-force cosine (or dot
+// product) similarity.
 type Storage struct {
 	mu        sync.RWMutex
 	dimension int
-	vectors   [][]float64
-	chunks    []domain.Chunk
+	// matrix holds the vectors as the rows of a row-major matrix, so that a
+	// full scan is a single matrix-vector product.
+	matrix []float64
+	chunks []domain.Chunk
+	texts  *textlog.Log
+	refs   []textlog.Ref
+	ivf    *ivf
+	// deleted counts the soft-deleted chunks, which unfiltered searches
+	// must skip.
+	deleted int
+	// dotScores skips normalization (DistanceDot).
+	dotScores bool
+}
+
+// Config configures an in-memory vector store.
+type Config struct {
+	// TextLog, when set, keeps chunk texts on disk instead of RAM. The store
+	// owns the log's contents and resets it on Init and Clear.
+	TextLog *textlog.Log
+	// IVF searches through an IVF-Flat index instead of scanning every
+	// vector, once the store holds enough vectors for one to pay off.
+	IVF bool
+	// IVFLists is the number of k-means clusters (0 = √n).
+	IVFLists int
+	// IVFProbes is how many of the clusters nearest to a query are
+	// searched (0 = 8); more probes raise recall and cost.
+	IVFProbes int
+	// Distance is DistanceCosine (default) or DistanceDot.
+	Distance string
 }
 
+// Similarity measures.
+const (
+	// DistanceCosine normalizes vectors to unit length when they are stored
+	// and queries when they are searched, so that embedders returning
+	// vectors of varying length rank correctly.
+	DistanceCosine = "cosine"
+	// DistanceDot scores by the raw dot product, for embeddings whose
+	// length carries meaning.
+	DistanceDot = "dot"
+)
+
 // NewStorage creates a new empty in-memory vector store.
 func NewStorage() *Storage { return &Storage{} }
 
+// NewStorageWithConfig creates an in-memory vector store configured by cfg.
+func NewStorageWithConfig(cfg Config) (*Storage, error) {
+	s := &Storage{texts: cfg.TextLog}
+	switch cfg.Distance {
+	case DistanceCosine, "":
+	case DistanceDot:
+		s.dotScores = true
+	default:
+		return nil, fmt.Errorf("unknown distance %q", cfg.Distance)
+	}
+	if cfg.IVF {
+		s.ivf = newIVF(cfg.IVFLists, cfg.IVFProbes)
+	}
+	return s, nil
+}
+
 // Init sets the vector dimensionality and clears existing data.
 func (s *Storage) Init(dimension int) error {
 	if dimension <= 0 {
@@ -26,8 +90,24 @@ func (s *Storage) Init(dimension int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.dimension = dimension
-	s.vectors = nil
-	s.chunks = nil
+	return s.reset()
+}
+
+// CheckDimension reports an error if the store holds vectors of another
+// dimension than the given one; an empty store is initialized with it.
+func (s *Storage) CheckDimension(dimension int) error {
+	if dimension <= 0 {
+		return errors.New("invalid dimension")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dimension == 0 {
+		s.dimension = dimension
+		return s.reset()
+	}
+	if s.dimension != dimension {
+		return fmt.Errorf("store holds %d-dimensional vectors but the embedder produces %d", s.dimension, dimension)
+	}
 	return nil
 }
 
@@ -40,94 +120,285 @@ func (s *Storage) Upsert(chunks []domain.Chunk, vectors [][]float64) error {
 	defer s.mu.Unlock()
 	for _, v := range vectors {
 		if len(v) != s.dimension {
-			return errors.New("vector dimension mismatch")
+			return fmt.Errorf("vector dimension %d, store initialized with %d", len(v), s.dimension)
+		}
+	}
+	if s.texts != nil {
+		for _, ch := range chunks {
+			ref, err := s.texts.Append(ch.Text)
+			if err != nil {
+				return err
+			}
+			ch.Text = ""
+			s.chunks = append(s.chunks, ch)
+			s.refs = append(s.refs, ref)
 		}
+	} else {
+		s.chunks = append(s.chunks, chunks...)
+	}
+	for _, ch := range chunks {
+		if vectorstore.IsDeleted(ch) {
+			s.deleted++
+		}
+	}
+	// Copy the vectors into the matrix and normalize them there, without an
+	// intermediate copy per vector.
+	first := s.len()
+	s.matrix = slices.Grow(s.matrix, len(vectors)*s.dimension)
+	for _, v := range vectors {
+		s.matrix = append(s.matrix, v...)
+	}
+	added := s.rows()[first:]
+	if !s.dotScores {
+		for _, v := range added {
+			if n := floats.Norm(v, 2); n > 0 {
+				floats.Scale(1/n, v)
+			}
+		}
+	}
+	if s.ivf != nil {
+		s.ivf.add(first, added)
 	}
-	s.chunks = append(s.chunks, chunks...)
-	s.vectors = append(s.vectors, vectors...)
 	return nil
 }
 
-// Search returns the topK chunks by cosine similarity to the provided vector.
-func (s *Storage) Search(vector []float64, topK int) ([]domain.SearchResult, error) {
+// Reserve makes room for n more vectors, so that an ingest of known size
+// fills a single matrix instead of reallocating it as batches arrive.
+func (s *Storage) Reserve(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.matrix = slices.Grow(s.matrix, n*s.dimension)
+	s.chunks = slices.Grow(s.chunks, n)
+	if s.texts != nil {
+		s.refs = slices.Grow(s.refs, n)
+	}
+}
+
+// Search returns up to topK chunks matching filter by similarity to the
+// provided vector, skipping the first offset. With an IVF index only the
+// vectors of the clusters nearest to the query are considered.
+func (s *Storage) Search(vector []float64, offset, topK int, filter vectorstore.Filter) ([]domain.SearchResult, error) {
+	s.trainIndex()
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	if topK <= 0 {
 		topK = 5
 	}
-	// compute cosine similarity (vectors are assumed L2-normalized)
-	scores := make([]float64, len(s.vectors))
-	for i := range s.vectors {
-		scores[i] = dot(s.vectors[i], vector)
+	n := s.len()
+	if n > 0 && len(vector) != s.dimension {
+		return nil, fmt.Errorf("query vector dimension %d, store initialized with %d", len(vector), s.dimension)
+	}
+	if !s.dotScores {
+		vector = normalized([][]float64{vector})[0]
+	}
+	// compute the similarities of the vectors the IVF index proposes, or of
+	// all in one product
+	var ids []int
+	if s.ivf != nil {
+		ids = s.ivf.candidates(vector)
+	}
+	var scores []float64
+	if ids != nil {
+		scores = make([]float64, len(ids))
+		for k, i := range ids {
+			scores[k] = floats.Dot(s.row(i), vector)
+		}
+	} else {
+		ids = make([]int, n)
+		for i := range ids {
+			ids[i] = i
+		}
+		scores = s.scan(vector)
+	}
+	if offset < 0 {
+		offset = 0
 	}
-	// Get topK indexes
-	idxs := argsortDesc(scores)
-	if topK > len(idxs) {
-		topK = len(idxs)
+	// select the best offset+topK hits, then drop the first offset
+	var keep func(int) bool
+	if !filter.Empty() || s.deleted > 0 {
+		keep = func(k int) bool { return filter.Match(s.chunks[ids[k]]) }
 	}
-	results := make([]domain.SearchResult, 0, topK)
-	for i := 0; i < topK; i++ {
-		j := idxs[i]
-		results = append(results, domain.SearchResult{Chunk: s.chunks[j], Score: scores[j]})
+	idxs := topScores(scores, offset+topK, keep)
+	if offset > len(idxs) {
+		offset = len(idxs)
+	}
+	idxs = idxs[offset:]
+	results := make([]domain.SearchResult, 0, len(idxs))
+	for _, k := range idxs {
+		j := ids[k]
+		chunk := s.chunks[j]
+		if s.texts != nil {
+			text, err := s.texts.Read(s.refs[j])
+			if err != nil {
+				return nil, err
+			}
+			chunk.Text = text
+		}
+		results = append(results, domain.SearchResult{Chunk: chunk, Score: scores[k]})
 	}
 	return results, nil
 }
 
-// Clear removes all stored vectors and chunks.
-func (s *Storage) Clear() error {
+// trainIndex (re)trains the IVF index when the corpus has outgrown it.
+// Searches only check that under the read lock, and take the write lock,
+// checking again, when the index is stale.
+func (s *Storage) trainIndex() {
+	if s.ivf == nil {
+		return
+	}
+	s.mu.RLock()
+	stale := s.ivf.stale(s.len())
+	s.mu.RUnlock()
+	if !stale {
+		return
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.vectors = nil
-	s.chunks = nil
-	return nil
+	if s.ivf.stale(s.len()) {
+		s.ivf.train(s.rows())
+	}
 }
 
-func dot(a, b []float64) float64 {
-	n := len(a)
-	if len(b) < n {
-		n = len(b)
+// normalized returns copies of vectors scaled to unit length; zero vectors
+// stay zero.
+func normalized(vectors [][]float64) [][]float64 {
+	out := make([][]float64, len(vectors))
+	for i, v := range vectors {
+		out[i] = append([]float64(nil), v...)
+		if n := floats.Norm(v, 2); n > 0 {
+			floats.Scale(1/n, out[i])
+		}
 	}
-	sum := 0.0
-	for i := 0; i < n; i++ {
-		sum += a[i] * b[i]
+	return out
+}
+
+// scan returns the dot products of vector with every stored vector.
+func (s *Storage) scan(vector []float64) []float64 {
+	n := s.len()
+	scores := make([]float64, n)
+	if n == 0 {
+		return scores
 	}
-	return sum
+	a := blas64.General{Rows: n, Cols: s.dimension, Stride: s.dimension, Data: s.matrix}
+	blas64.Gemv(blas.NoTrans, 1, a, blas64.Vector{N: s.dimension, Inc: 1, Data: vector}, 0, blas64.Vector{N: n, Inc: 1, Data: scores})
+	return scores
 }
 
-func argsortDesc(vals []float64) []int {
-	idxs := make([]int, len(vals))
-	for i := range vals {
-		idxs[i] = i
+// len returns the number of stored vectors.
+func (s *Storage) len() int {
+	if s.dimension == 0 {
+		return 0
 	}
-	// Partial selection sort for small topK could be faster, but we keep it simple here
-	// Stable sort not required
-	quicksort(idxs, vals, 0, len(idxs)-1)
-	return idxs
+	return len(s.matrix) / s.dimension
 }
 
-func quicksort(idxs []int, vals []float64, lo, hi int) {
-	if lo >= hi {
-		return
+// row returns the i-th stored vector, sharing the matrix's memory.
+func (s *Storage) row(i int) []float64 {
+	return s.matrix[i*s.dimension : (i+1)*s.dimension : (i+1)*s.dimension]
+}
+
+// rows returns all stored vectors, sharing the matrix's memory.
+func (s *Storage) rows() [][]float64 {
+	out := make([][]float64, s.len())
+	for i := range out {
+		out[i] = s.row(i)
 	}
-	i, j := lo, hi
-	pivot := vals[idxs[(lo+hi)/2]]
-	for i <= j {
-		for vals[idxs[i]] > pivot { // desc order
-			i++
+	return out
+}
+
+// All returns every stored chunk with its vector.
+func (s *Storage) All() ([]domain.Chunk, [][]float64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	chunks := make([]domain.Chunk, len(s.chunks))
+	copy(chunks, s.chunks)
+	if s.texts != nil {
+		for i := range chunks {
+			text, err := s.texts.Read(s.refs[i])
+			if err != nil {
+				return nil, nil, err
+			}
+			chunks[i].Text = text
 		}
-		for vals[idxs[j]] < pivot {
-			j--
+	}
+	// One copy of the matrix backs all returned vectors.
+	data := slices.Clone(s.matrix)
+	vectors := make([][]float64, s.len())
+	for i := range vectors {
+		vectors[i] = data[i*s.dimension : (i+1)*s.dimension : (i+1)*s.dimension]
+	}
+	return chunks, vectors, nil
+}
+
+// Clear removes all stored vectors and chunks.
+func (s *Storage) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reset()
+}
+
+func (s *Storage) reset() error {
+	s.matrix = nil
+	s.chunks = nil
+	s.refs = nil
+	s.deleted = 0
+	if s.ivf != nil {
+		s.ivf.reset()
+	}
+	if s.texts != nil {
+		return s.texts.Reset()
+	}
+	return nil
+}
+
+// topScores returns the indexes of the k highest scores accepted by keep
+// (nil accepts all), best first. Ties keep index order, so that the pages
+// of a query agree. A heap of the k best found so far avoids sorting every
+// score for one page of results.
+func topScores(scores []float64, k int, keep func(int) bool) []int {
+	if k <= 0 {
+		return nil
+	}
+	h := &scoreHeap{scores: scores}
+	for i := range scores {
+		if keep != nil && !keep(i) {
+			continue
 		}
-		if i <= j {
-			idxs[i], idxs[j] = idxs[j], idxs[i]
-			i++
-			j--
+		if len(h.idxs) < k {
+			heap.Push(h, i)
+		} else if h.worse(h.idxs[0], i) {
+			h.idxs[0] = i
+			heap.Fix(h, 0)
 		}
 	}
-	if lo < j {
-		quicksort(idxs, vals, lo, j)
+	out := make([]int, len(h.idxs))
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(h).(int)
 	}
-	if i < hi {
-		quicksort(idxs, vals, i, hi)
+	return out
+}
+
+// scoreHeap is a min-heap of score indexes, the worst at the root.
+type scoreHeap struct {
+	scores []float64
+	idxs   []int
+}
+
+// worse reports whether the score at a ranks below the one at b.
+func (h *scoreHeap) worse(a, b int) bool {
+	if h.scores[a] != h.scores[b] {
+		return h.scores[a] < h.scores[b]
 	}
+	return a > b
+}
+
+func (h *scoreHeap) Len() int           { return len(h.idxs) }
+func (h *scoreHeap) Less(i, j int) bool { return h.worse(h.idxs[i], h.idxs[j]) }
+func (h *scoreHeap) Swap(i, j int)      { h.idxs[i], h.idxs[j] = h.idxs[j], h.idxs[i] }
+func (h *scoreHeap) Push(x any)         { h.idxs = append(h.idxs, x.(int)) }
+func (h *scoreHeap) Pop() any {
+	n := len(h.idxs) - 1
+	x := h.idxs[n]
+	h.idxs = h.idxs[:n]
+	return x
 }
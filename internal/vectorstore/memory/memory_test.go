@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+
+	"rag/internal/domain"
+	"rag/internal/vectorstore"
+	"rag/internal/vectorstore/storetest"
+)
+
+const benchDim = 128
+
+var benchSizes = []int{10_000, 100_000}
+
+func randomVectors(r *rand.Rand, n, dim int) [][]float64 {
+	vectors := make([][]float64, n)
+	for i := range vectors {
+		vectors[i] = make([]float64, dim)
+		for j := range vectors[i] {
+			vectors[i][j] = r.NormFloat64()
+		}
+	}
+	return vectors
+}
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, storetest.Store{
+		New: func(t *testing.T) vectorstore.Storage { return NewStorage() },
+	})
+}
+
+func TestTopScores(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	scores := make([]float64, 1000)
+	for i := range scores {
+		// Few distinct values, so that ties are common.
+		scores[i] = float64(r.Intn(50))
+	}
+	want := make([]int, len(scores))
+	for i := range want {
+		want[i] = i
+	}
+	sort.SliceStable(want, func(a, b int) bool { return scores[want[a]] > scores[want[b]] })
+	for _, k := range []int{0, 1, 10, 999, 1000, 2000} {
+		got := topScores(scores, k, nil)
+		if len(got) != min(k, len(scores)) {
+			t.Fatalf("k=%d: %d indexes", k, len(got))
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Fatalf("k=%d: index %d is %d, want %d", k, i, got[i], want[i])
+			}
+		}
+	}
+	even := topScores(scores, 10, func(i int) bool { return i%2 == 0 })
+	for i, j := range even {
+		if j%2 != 0 {
+			t.Fatalf("index %d is %d, which keep rejects", i, j)
+		}
+		if i > 0 && scores[j] > scores[even[i-1]] {
+			t.Fatalf("index %d scores above index %d", i, i-1)
+		}
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range benchSizes {
+		s := NewStorage()
+		if err := s.Init(benchDim); err != nil {
+			b.Fatal(err)
+		}
+		chunks := make([]domain.Chunk, n)
+		for i := range chunks {
+			chunks[i] = domain.Chunk{DocumentID: "doc", ChunkID: "doc:" + strconv.Itoa(i), Index: i}
+		}
+		if err := s.Upsert(chunks, randomVectors(r, n, benchDim)); err != nil {
+			b.Fatal(err)
+		}
+		query := randomVectors(r, 1, benchDim)[0]
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := s.Search(query, 0, 10, vectorstore.Filter{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkTopScores(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range benchSizes {
+		scores := make([]float64, n)
+		for i := range scores {
+			scores[i] = r.Float64()
+		}
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				topScores(scores, 10, nil)
+			}
+		})
+	}
+}
@@ -2,24 +2,32 @@ package qdrant
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha1"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"rag/internal/domain"
+	"rag/internal/textcodec"
+	"rag/internal/vectorstore"
 )
 
-// Storage is a minimal REST client to Qdrant.
-// It assumes cosine distance and creates the collection if missing.
 // Storage is a minimal REST client to Qdrant implementing the vector store.
+// It creates the collection if missing.
 type Storage struct {
 	url        string
 	apiKey     string
 	collection string
 	dimension  int
 	client     *http.Client
+	compress   bool
+	fields     map[string]string
+	readOnly   bool
+	distance   string
 }
 
 // Config holds connection parameters for Qdrant.
@@ -28,66 +36,193 @@ type Config struct {
 	APIKey     string
 	Collection string
 	Timeout    time.Duration
+	// CompressText stores chunk text zstd-compressed and base64-encoded
+	// under the "text_zstd" payload key instead of plain "text"; with text
+	// mapped to another key, under that key with "_zstd" appended.
+	CompressText bool
+	// PayloadMapping renames payload keys, mapping the chunk fields listed
+	// in Fields to the keys another ingestion pipeline used; a dotted key
+	// such as "metadata.source" names a nested value.
+	PayloadMapping map[string]string
+	// ReadOnly leaves the collection to the pipeline that populated it: Init
+	// only checks that it exists, Upsert fails and Clear does nothing.
+	ReadOnly bool
+	// Distance is "cosine" (default) or "dot"; Qdrant normalizes vectors
+	// of cosine collections itself.
+	Distance string
 }
 
+// Fields are the chunk fields stored in the payload, under their own names
+// unless Config.PayloadMapping says otherwise.
+var Fields = []string{"text", "document_id", "chunk_id", "index", "path", "start", "end", "start_line", "end_line", "time", "metadata", "keywords"}
+
 // NewStorage creates a new Qdrant-backed vector store client.
-func NewStorage(cfg Config) *Storage {
+func NewStorage(cfg Config) (*Storage, error) {
 	timeout := cfg.Timeout
 	if timeout == 0 {
 		timeout = 15 * time.Second
 	}
+	fields := make(map[string]string, len(Fields))
+	for _, f := range Fields {
+		fields[f] = f
+	}
+	distance := "Cosine"
+	switch strings.ToLower(cfg.Distance) {
+	case "cosine", "":
+	case "dot":
+		distance = "Dot"
+	default:
+		return nil, fmt.Errorf("unknown distance %q", cfg.Distance)
+	}
+	for field, key := range cfg.PayloadMapping {
+		if _, ok := fields[field]; !ok {
+			return nil, fmt.Errorf("payload_mapping: unknown field %q (known: %s)", field, strings.Join(Fields, ", "))
+		}
+		if key == "" {
+			return nil, fmt.Errorf("payload_mapping: empty key for %q", field)
+		}
+		fields[field] = key
+	}
+	// Compressed text follows the text wherever it is mapped.
+	fields["text_zstd"] = fields["text"] + "_zstd"
 	return &Storage{
 		url:        cfg.URL,
 		apiKey:     cfg.APIKey,
 		collection: cfg.Collection,
 		client:     &http.Client{Timeout: timeout},
-	}
+		compress:   cfg.CompressText,
+		fields:     fields,
+		readOnly:   cfg.ReadOnly,
+		distance:   distance,
+	}, nil
 }
 
 // Init creates (or validates) the Qdrant collection with the given dimension.
+// A read-only collection must already exist with vectors of that dimension.
 func (s *Storage) Init(dimension int) error {
 	if dimension <= 0 {
 		return errors.New("invalid dimension")
 	}
 	s.dimension = dimension
+	if s.readOnly {
+		return s.checkCollection(dimension)
+	}
 	// Create collection if not exists
 	body := map[string]any{
 		"vectors": map[string]any{
 			"size":     dimension,
-			"distance": "Cosine",
+			"distance": s.distance,
 		},
 	}
-	if err := s.putJSON(fmt.Sprintf("%s/collections/%s", s.url, s.collection), body); err != nil {
+	if err := s.putJSON(context.Background(), fmt.Sprintf("%s/collections/%s", s.url, s.collection), body); err != nil {
 		// Qdrant returns 200 OK if collection exists with same schema; if error, propagate
 		return err
 	}
 	return nil
 }
 
+// CheckDimension verifies that the collection exists, holds vectors of
+// the given dimension and has the point IDs Upsert writes, leaving its
+// points in place.
+func (s *Storage) CheckDimension(dimension int) error {
+	if dimension <= 0 {
+		return errors.New("invalid dimension")
+	}
+	s.dimension = dimension
+	if err := s.checkCollection(dimension); err != nil {
+		return err
+	}
+	return s.checkPointIDs()
+}
+
 // Upsert inserts or updates points in the Qdrant collection.
 func (s *Storage) Upsert(chunks []domain.Chunk, vectors [][]float64) error {
+	return s.UpsertContext(context.Background(), chunks, vectors)
+}
+
+// UpsertContext is Upsert with a context that cancels the request.
+func (s *Storage) UpsertContext(ctx context.Context, chunks []domain.Chunk, vectors [][]float64) error {
 	if len(chunks) != len(vectors) {
 		return errors.New("chunks and vectors length mismatch")
 	}
+	if s.readOnly {
+		return fmt.Errorf("qdrant collection %s is read-only", s.collection)
+	}
 	points := make([]map[string]any, len(chunks))
 	for i := range chunks {
+		payload := map[string]any{}
+		if len(chunks[i].Metadata) > 0 {
+			// Set first and as a generic object, so that fields mapped into
+			// it (path: metadata.source) are added rather than overwritten.
+			metadata := make(map[string]any, len(chunks[i].Metadata))
+			for key, value := range chunks[i].Metadata {
+				metadata[key] = value
+			}
+			s.set(payload, "metadata", metadata)
+			payload["meta_index"] = metadataIndex(chunks[i].Metadata)
+		}
+		s.set(payload, "document_id", chunks[i].DocumentID)
+		s.set(payload, "chunk_id", chunks[i].ChunkID)
+		s.set(payload, "index", chunks[i].Index)
+		s.set(payload, "path", chunks[i].Path)
+		s.set(payload, "start", chunks[i].Start)
+		s.set(payload, "end", chunks[i].End)
+		if chunks[i].StartLine > 0 {
+			s.set(payload, "start_line", chunks[i].StartLine)
+			s.set(payload, "end_line", chunks[i].EndLine)
+		}
+		if len(chunks[i].Keywords) > 0 {
+			s.set(payload, "keywords", chunks[i].Keywords)
+		}
+		if !chunks[i].Time.IsZero() {
+			// Unix seconds, so that range filters work on it.
+			s.set(payload, "time", chunks[i].Time.Unix())
+		}
+		if chunks[i].Embedded != "" {
+			payload["embedded"] = chunks[i].Embedded
+		}
+		switch {
+		case chunks[i].Text == "":
+			// Text is hydrated from the source file by the caller.
+		case s.compress:
+			enc, err := textcodec.CompressBase64(chunks[i].Text)
+			if err != nil {
+				return err
+			}
+			s.set(payload, "text_zstd", enc)
+		default:
+			s.set(payload, "text", chunks[i].Text)
+		}
 		points[i] = map[string]any{
-			"id":     fmt.Sprintf("%s:%d", chunks[i].DocumentID, chunks[i].Index),
-			"vector": vectors[i],
-			"payload": map[string]any{
-				"document_id": chunks[i].DocumentID,
-				"chunk_id":    chunks[i].ChunkID,
-				"index":       chunks[i].Index,
-				"text":        chunks[i].Text,
-			},
+			"id":      pointID(chunks[i]),
+			"vector":  vectors[i],
+			"payload": payload,
 		}
 	}
 	body := map[string]any{"points": points}
-	return s.putJSON(fmt.Sprintf("%s/collections/%s/points?wait=true", s.url, s.collection), body)
+	return s.putJSON(ctx, fmt.Sprintf("%s/collections/%s/points?wait=true", s.url, s.collection), body)
 }
 
-// Search queries the Qdrant collection for nearest neighbors.
-func (s *Storage) Search(vector []float64, topK int) ([]domain.SearchResult, error) {
+// pointNamespace is the namespace of the UUIDs of rag's points,
+// 69da2ceb-8175-4e37-96e6-07f972312723.
+var pointNamespace = [16]byte{0x69, 0xda, 0x2c, 0xeb, 0x81, 0x75, 0x4e, 0x37, 0x96, 0xe6, 0x07, 0xf9, 0x72, 0x31, 0x27, 0x23}
+
+// pointID returns the ID of chunk's point: Qdrant takes only unsigned
+// integers and UUIDs, so "<document>:<index>" is hashed into a version 5
+// UUID in pointNamespace (RFC 9562), the same on every ingest.
+func pointID(chunk domain.Chunk) string {
+	h := sha1.New()
+	h.Write(pointNamespace[:])
+	fmt.Fprintf(h, "%s:%d", chunk.DocumentID, chunk.Index)
+	u := h.Sum(nil)[:16]
+	u[6] = u[6]&0x0f | 0x50
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// Search queries the Qdrant collection for nearest neighbors matching
+// filter, skipping the first offset hits.
+func (s *Storage) Search(vector []float64, offset, topK int, filter vectorstore.Filter) ([]domain.SearchResult, error) {
 	if topK <= 0 {
 		topK = 5
 	}
@@ -96,8 +231,16 @@ func (s *Storage) Search(vector []float64, topK int) ([]domain.SearchResult, err
 		"limit":        topK,
 		"with_payload": true,
 	}
+	if offset > 0 {
+		req["offset"] = offset
+	}
+	// Only collections rag writes to hold soft-deleted points.
+	if !filter.Empty() || !s.readOnly {
+		req["filter"] = s.searchFilter(filter)
+	}
 	var resp struct {
 		Result []struct {
+			ID      any            `json:"id"`
 			Score   float64        `json:"score"`
 			Payload map[string]any `json:"payload"`
 		} `json:"result"`
@@ -107,26 +250,267 @@ func (s *Storage) Search(vector []float64, topK int) ([]domain.SearchResult, err
 	}
 	results := make([]domain.SearchResult, 0, len(resp.Result))
 	for _, r := range resp.Result {
-		chunk := domain.Chunk{}
-		if v, ok := r.Payload["document_id"].(string); ok {
-			chunk.DocumentID = v
+		chunk, err := s.chunkFromPayload(r.ID, r.Payload)
+		if err != nil {
+			return nil, err
 		}
-		if v, ok := r.Payload["chunk_id"].(string); ok {
-			chunk.ChunkID = v
+		results = append(results, domain.SearchResult{Chunk: chunk, Score: r.Score})
+	}
+	return results, nil
+}
+
+// All scrolls through the whole collection and returns every point.
+func (s *Storage) All() ([]domain.Chunk, [][]float64, error) {
+	var (
+		chunks  []domain.Chunk
+		vectors [][]float64
+		offset  any
+	)
+	for {
+		req := map[string]any{
+			"limit":        256,
+			"with_payload": true,
+			"with_vector":  true,
 		}
-		if v, ok := r.Payload["index"].(float64); ok {
-			chunk.Index = int(v)
+		if offset != nil {
+			req["offset"] = offset
 		}
-		if v, ok := r.Payload["text"].(string); ok {
-			chunk.Text = v
+		var resp struct {
+			Result struct {
+				Points []struct {
+					ID      any            `json:"id"`
+					Vector  []float64      `json:"vector"`
+					Payload map[string]any `json:"payload"`
+				} `json:"points"`
+				NextPageOffset any `json:"next_page_offset"`
+			} `json:"result"`
 		}
-		results = append(results, domain.SearchResult{Chunk: chunk, Score: r.Score})
+		if err := s.postJSON(fmt.Sprintf("%s/collections/%s/points/scroll", s.url, s.collection), req, &resp); err != nil {
+			return nil, nil, err
+		}
+		for _, p := range resp.Result.Points {
+			chunk, err := s.chunkFromPayload(p.ID, p.Payload)
+			if err != nil {
+				return nil, nil, err
+			}
+			chunks = append(chunks, chunk)
+			vectors = append(vectors, p.Vector)
+		}
+		if resp.Result.NextPageOffset == nil {
+			break
+		}
+		offset = resp.Result.NextPageOffset
 	}
-	return results, nil
+	return chunks, vectors, nil
+}
+
+// searchFilter translates filter into Qdrant conditions: a range on "time"
+// and exact matches on the normalized values in "meta_index". Undated points
+// lack the time key and therefore never match a date bound. Points written by
+// other pipelines lack meta_index, so metadata filters match none of them.
+// Soft-deleted points are those with a "deleted" entry in meta_index.
+func (s *Storage) searchFilter(filter vectorstore.Filter) map[string]any {
+	var must []map[string]any
+	if !filter.After.IsZero() || !filter.Before.IsZero() {
+		rng := map[string]any{}
+		if !filter.After.IsZero() {
+			rng["gte"] = filter.After.Unix()
+		}
+		if !filter.Before.IsZero() {
+			rng["lt"] = filter.Before.Unix()
+		}
+		must = append(must, map[string]any{"key": s.fields["time"], "range": rng})
+	}
+	for key, want := range filter.Metadata {
+		must = append(must, map[string]any{
+			"key":   "meta_index." + key,
+			"match": map[string]any{"value": vectorstore.NormalizeMetadata(want)},
+		})
+	}
+	deleted := map[string]any{"is_empty": map[string]any{"key": "meta_index." + vectorstore.DeletedKey}}
+	if filter.Deleted {
+		return map[string]any{"must": must, "must_not": []map[string]any{deleted}}
+	}
+	return map[string]any{"must": append(must, deleted)}
+}
+
+// metadataIndex expands metadata into arrays of normalized terms, which
+// Qdrant matches element-wise.
+func metadataIndex(metadata map[string]string) map[string][]string {
+	out := make(map[string][]string, len(metadata))
+	for key, values := range metadata {
+		out[key] = vectorstore.MetadataTerms(values)
+	}
+	return out
+}
+
+// chunkFromPayload builds a chunk from a point's payload. Points written by
+// other pipelines may lack our bookkeeping fields: the point ID then serves
+// as the chunk ID and the path as the document ID.
+func (s *Storage) chunkFromPayload(id any, payload map[string]any) (domain.Chunk, error) {
+	chunk := domain.Chunk{}
+	if v, ok := s.get(payload, "document_id").(string); ok {
+		chunk.DocumentID = v
+	}
+	if v, ok := s.get(payload, "chunk_id").(string); ok {
+		chunk.ChunkID = v
+	}
+	if v, ok := s.get(payload, "index").(float64); ok {
+		chunk.Index = int(v)
+	}
+	if v, ok := s.get(payload, "path").(string); ok {
+		chunk.Path = v
+	}
+	if v, ok := s.get(payload, "start").(float64); ok {
+		chunk.Start = int(v)
+	}
+	if v, ok := s.get(payload, "end").(float64); ok {
+		chunk.End = int(v)
+	}
+	if v, ok := s.get(payload, "start_line").(float64); ok {
+		chunk.StartLine = int(v)
+	}
+	if v, ok := s.get(payload, "end_line").(float64); ok {
+		chunk.EndLine = int(v)
+	}
+	if v, ok := s.get(payload, "time").(float64); ok {
+		chunk.Time = time.Unix(int64(v), 0).UTC()
+	}
+	if v, ok := s.get(payload, "metadata").(map[string]any); ok {
+		chunk.Metadata = make(map[string]string, len(v))
+		for key, val := range v {
+			switch val := val.(type) {
+			case string:
+				chunk.Metadata[key] = val
+			case float64, bool:
+				chunk.Metadata[key] = fmt.Sprint(val)
+			}
+		}
+	}
+	if v, ok := s.get(payload, "keywords").([]any); ok {
+		for _, k := range v {
+			if kw, ok := k.(string); ok {
+				chunk.Keywords = append(chunk.Keywords, kw)
+			}
+		}
+	}
+	if v, ok := s.get(payload, "text").(string); ok {
+		chunk.Text = v
+	} else if v, ok := s.get(payload, "text_zstd").(string); ok {
+		text, err := textcodec.DecompressBase64(v)
+		if err != nil {
+			return chunk, fmt.Errorf("decode compressed payload: %w", err)
+		}
+		chunk.Text = text
+	}
+	if v, ok := payload["embedded"].(string); ok {
+		chunk.Embedded = v
+	}
+	if chunk.ChunkID == "" && id != nil {
+		chunk.ChunkID = fmt.Sprint(id)
+	}
+	if chunk.DocumentID == "" {
+		chunk.DocumentID = chunk.Path
+	}
+	if chunk.DocumentID == "" {
+		chunk.DocumentID = chunk.ChunkID
+	}
+	return chunk, nil
 }
 
-// Clear attempts to drop the underlying Qdrant collection.
+// get returns the payload value of field, following the dots of a mapped
+// key into nested objects.
+func (s *Storage) get(payload map[string]any, field string) any {
+	key := s.fields[field]
+	var v any = payload
+	for _, part := range strings.Split(key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
+
+// set stores value under the payload key of field, creating the nested
+// objects a dotted key names.
+func (s *Storage) set(payload map[string]any, field string, value any) {
+	parts := strings.Split(s.fields[field], ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := payload[part].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			payload[part] = next
+		}
+		payload = next
+	}
+	payload[parts[len(parts)-1]] = value
+}
+
+// checkCollection verifies that the collection exists and, when it has a
+// single unnamed vector, that its size is dimension.
+func (s *Storage) checkCollection(dimension int) error {
+	var resp struct {
+		Result struct {
+			Config struct {
+				Params struct {
+					Vectors json.RawMessage `json:"vectors"`
+				} `json:"params"`
+			} `json:"config"`
+		} `json:"result"`
+	}
+	if err := s.getJSON(fmt.Sprintf("%s/collections/%s", s.url, s.collection), &resp); err != nil {
+		return err
+	}
+	var single struct {
+		Size int `json:"size"`
+	}
+	if err := json.Unmarshal(resp.Result.Config.Params.Vectors, &single); err == nil && single.Size > 0 && single.Size != dimension {
+		return fmt.Errorf("qdrant collection %s holds %d-dimensional vectors but the embedder produces %d", s.collection, single.Size, dimension)
+	}
+	return nil
+}
+
+// checkPointIDs reports an error if a point rag wrote to the collection has
+// another ID than pointID gives its chunk: collections indexed before point
+// IDs were UUIDs used "<document>:<index>", and upserting into them would
+// add every chunk a second time. An ingest re-creates the collection.
+func (s *Storage) checkPointIDs() error {
+	var resp struct {
+		Result struct {
+			Points []struct {
+				ID      any            `json:"id"`
+				Payload map[string]any `json:"payload"`
+			} `json:"points"`
+		} `json:"result"`
+	}
+	req := map[string]any{"limit": 1, "with_payload": true, "with_vector": false}
+	if err := s.postJSON(fmt.Sprintf("%s/collections/%s/points/scroll", s.url, s.collection), req, &resp); err != nil {
+		return err
+	}
+	for _, p := range resp.Result.Points {
+		if _, ok := s.get(p.Payload, "document_id").(string); !ok {
+			// A point of another pipeline.
+			continue
+		}
+		chunk, err := s.chunkFromPayload(p.ID, p.Payload)
+		if err != nil {
+			return err
+		}
+		if id := fmt.Sprint(p.ID); id != pointID(chunk) {
+			return fmt.Errorf("qdrant collection %s has point IDs of an older rag (%s); ingest the corpus again to re-create it", s.collection, id)
+		}
+	}
+	return nil
+}
+
+// Clear attempts to drop the underlying Qdrant collection; a read-only
+// collection is kept.
 func (s *Storage) Clear() error {
+	if s.readOnly {
+		return nil
+	}
 	// Best-effort: drop collection
 	req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s/collections/%s", s.url, s.collection), nil)
 	if s.apiKey != "" {
@@ -136,9 +520,9 @@ func (s *Storage) Clear() error {
 	return nil
 }
 
-func (s *Storage) putJSON(url string, body any) error {
+func (s *Storage) putJSON(ctx context.Context, url string, body any) error {
 	data, _ := json.Marshal(body)
-	req, _ := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(data))
 	req.Header.Set("Content-Type", "application/json")
 	if s.apiKey != "" {
 		req.Header.Set("api-key", s.apiKey)
@@ -154,6 +538,22 @@ func (s *Storage) putJSON(url string, body any) error {
 	return nil
 }
 
+func (s *Storage) getJSON(url string, out any) error {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if s.apiKey != "" {
+		req.Header.Set("api-key", s.apiKey)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("qdrant GET %s failed: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
 func (s *Storage) postJSON(url string, body any, out any) error {
 	data, _ := json.Marshal(body)
 	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
@@ -0,0 +1,231 @@
+package qdrant
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"rag/internal/domain"
+	"rag/internal/vectorstore"
+	"rag/internal/vectorstore/storetest"
+)
+
+// TestConformance runs the store suite against the Qdrant server at
+// $QDRANT_URL (with $QDRANT_API_KEY, if set), in collections it drops
+// afterwards; it is skipped without one.
+func TestConformance(t *testing.T) {
+	url := os.Getenv("QDRANT_URL")
+	if url == "" {
+		t.Skip("QDRANT_URL not set")
+	}
+	n := 0
+	storetest.Run(t, storetest.Store{
+		New: func(t *testing.T) vectorstore.Storage {
+			n++
+			s, err := NewStorage(Config{
+				URL:        url,
+				APIKey:     os.Getenv("QDRANT_API_KEY"),
+				Collection: fmt.Sprintf("rag_conformance_%d_%d", time.Now().UnixNano(), n),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { s.Clear() })
+			return s
+		},
+	})
+}
+
+var uuidRE = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestPointID(t *testing.T) {
+	a := pointID(domain.Chunk{DocumentID: "172f21735ea6b899", ChunkID: "172f21735ea6b899:0", Index: 0})
+	if !uuidRE.MatchString(a) {
+		t.Errorf("point ID %s is not a version 5 UUID", a)
+	}
+	if b := pointID(domain.Chunk{DocumentID: "172f21735ea6b899", Index: 0, Text: "changed"}); b != a {
+		t.Errorf("point ID of the same chunk changed from %s to %s", a, b)
+	}
+	if b := pointID(domain.Chunk{DocumentID: "172f21735ea6b899", Index: 1}); b == a {
+		t.Errorf("chunks 0 and 1 share point ID %s", a)
+	}
+	// uuid.NewSHA1(pointNamespace, []byte("raft:0")) in github.com/google/uuid.
+	if got, want := pointID(domain.Chunk{DocumentID: "raft", Index: 0}), "9b819bb1-ca4a-5251-b4a1-81a5d5d62214"; got != want {
+		t.Errorf("point ID of raft:0 is %s, want %s", got, want)
+	}
+}
+
+// request is a request the test server got.
+type request struct {
+	method, uri string
+	body        map[string]any
+}
+
+// newServer returns a Qdrant server that records the requests it gets and
+// answers searches with testdata/search_response.json and everything else
+// with an empty success.
+func newServer(t *testing.T) (*httptest.Server, *[]request) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		requests []request
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("api-key"); got != "secret" {
+			t.Errorf("api-key: %q", got)
+		}
+		var body map[string]any
+		if r.Body != nil && r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("request body: %v", err)
+			}
+		}
+		mu.Lock()
+		requests = append(requests, request{r.Method, r.URL.RequestURI(), body})
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/points/search") {
+			w.Write(readFile(t, "search_response.json"))
+			return
+		}
+		io.WriteString(w, `{"result":true,"status":"ok","time":0.001}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func readFile(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+var update = flag.Bool("update", false, "rewrite the request bodies in testdata")
+
+// checkBody compares a request body with the JSON of a testdata file, or
+// writes it there with -update.
+func checkBody(t *testing.T, got map[string]any, file string) {
+	t.Helper()
+	if *update {
+		data, err := json.MarshalIndent(got, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join("testdata", file), append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	var want map[string]any
+	if err := json.Unmarshal(readFile(t, file), &want); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		data, _ := json.MarshalIndent(got, "", "  ")
+		t.Errorf("request body differs from testdata/%s:\n%s", file, data)
+	}
+}
+
+func TestRequests(t *testing.T) {
+	srv, requests := newServer(t)
+	s, err := NewStorage(Config{URL: srv.URL, APIKey: "secret", Collection: "notes"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Init(3); err != nil {
+		t.Fatal(err)
+	}
+	chunks := []domain.Chunk{
+		{DocumentID: "raft", ChunkID: "raft:0", Index: 0, Path: "notes/raft.md", Text: "Raft elects a leader.", Start: 0, End: 21, StartLine: 1, EndLine: 1, Keywords: []string{"raft", "leader"}, Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Metadata: map[string]string{"tags": "Consensus, #raft"}},
+		{DocumentID: "bread", ChunkID: "bread:0", Index: 0, Path: "notes/bread.md", Text: "Feed the starter daily.", Start: 0, End: 23},
+	}
+	if err := s.Upsert(chunks, [][]float64{{1, 0, 0}, {0, 0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	results, err := s.Search([]float64{1, 0, 0}, 2, 5, vectorstore.Filter{
+		After:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Metadata: map[string]string{"tags": "Raft"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Search([]float64{1, 0, 0}, 0, 5, vectorstore.Filter{Deleted: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct{ method, uri, file string }{
+		{"PUT", "/collections/notes", "create_request.json"},
+		{"PUT", "/collections/notes/points?wait=true", "upsert_request.json"},
+		{"POST", "/collections/notes/points/search", "search_request.json"},
+		{"POST", "/collections/notes/points/search", "search_deleted_request.json"},
+	}
+	if len(*requests) != len(want) {
+		t.Fatalf("%d requests, want %d", len(*requests), len(want))
+	}
+	for i, w := range want {
+		r := (*requests)[i]
+		if r.method != w.method || r.uri != w.uri {
+			t.Errorf("request %d is %s %s, want %s %s", i, r.method, r.uri, w.method, w.uri)
+		}
+		checkBody(t, r.body, w.file)
+	}
+
+	// The response holds a point written by rag, one with compressed text
+	// and one of another pipeline, without rag's fields.
+	wantResults := []domain.SearchResult{
+		{Score: 0.97, Chunk: domain.Chunk{DocumentID: "raft", ChunkID: "raft:0", Index: 0, Path: "notes/raft.md", Text: "Raft elects a leader.", Start: 0, End: 21, StartLine: 1, EndLine: 1, Keywords: []string{"raft", "leader"}, Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Metadata: map[string]string{"tags": "Consensus, #raft"}}},
+		{Score: 0.81, Chunk: domain.Chunk{DocumentID: "raft", ChunkID: "raft:1", Index: 1, Path: "notes/raft.md", Text: "The leader replicates the log.", Start: 22, End: 52}},
+		{Score: 0.42, Chunk: domain.Chunk{DocumentID: "7", ChunkID: "7", Text: "A point of another pipeline."}},
+	}
+	if !reflect.DeepEqual(results, wantResults) {
+		t.Errorf("results\n%+v\nwant\n%+v", results, wantResults)
+	}
+}
+
+// TestCheckDimensionPointIDs checks that a collection whose points have the
+// IDs of an older rag is refused for retries, which would duplicate them.
+func TestCheckDimensionPointIDs(t *testing.T) {
+	chunk := domain.Chunk{DocumentID: "raft", ChunkID: "raft:0", Index: 0}
+	tests := []struct {
+		name    string
+		id      string
+		wantErr bool
+	}{
+		{"current", `"` + pointID(chunk) + `"`, false},
+		{"older", `"raft:0"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if strings.HasSuffix(r.URL.Path, "/points/scroll") {
+					fmt.Fprintf(w, `{"result":{"points":[{"id":%s,"payload":{"document_id":"raft","chunk_id":"raft:0","index":0}}]}}`, tt.id)
+					return
+				}
+				io.WriteString(w, `{"result":{"config":{"params":{"vectors":{"size":3,"distance":"Cosine"}}}}}`)
+			}))
+			defer srv.Close()
+			s, err := NewStorage(Config{URL: srv.URL, Collection: "notes"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := s.CheckDimension(3); (err != nil) != tt.wantErr {
+				t.Errorf("CheckDimension: %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,11 +1,163 @@
 package vectorstore
 
-import "rag/internal/domain"
+import (
+	"context"
+	"strings"
+	"time"
+
+	"rag/internal/domain"
+)
 
 // Storage persists vectors and supports similarity search.
 type Storage interface {
 	Init(dimension int) error
+	// Upsert stores chunks with their vectors. It must not keep the vector
+	// slices after it returns: the ingest reuses their backing array for the
+	// next batch.
 	Upsert(chunks []domain.Chunk, vectors [][]float64) error
-	Search(vector []float64, topK int) ([]domain.SearchResult, error)
+	// Search returns up to limit nearest chunks matching filter, skipping
+	// the first offset.
+	Search(vector []float64, offset, limit int, filter Filter) ([]domain.SearchResult, error)
 	Clear() error
 }
+
+// Filter restricts a search to chunks dated within [After, Before) whose
+// metadata has the given values. Zero bounds are open; undated chunks never
+// match a date bound. Soft-deleted chunks match only a filter for Deleted
+// chunks, which live ones never match.
+type Filter struct {
+	After    time.Time
+	Before   time.Time
+	Metadata map[string]string
+	Deleted  bool
+}
+
+// Empty reports whether the filter lets every live chunk through.
+func (f Filter) Empty() bool {
+	return f.After.IsZero() && f.Before.IsZero() && len(f.Metadata) == 0 && !f.Deleted
+}
+
+// Match reports whether chunk passes the filter.
+func (f Filter) Match(chunk domain.Chunk) bool {
+	if IsDeleted(chunk) != f.Deleted {
+		return false
+	}
+	for key, want := range f.Metadata {
+		if !MetadataContains(chunk.Metadata[key], want) {
+			return false
+		}
+	}
+	if f.After.IsZero() && f.Before.IsZero() {
+		return true
+	}
+	if chunk.Time.IsZero() {
+		return false
+	}
+	if !f.After.IsZero() && chunk.Time.Before(f.After) {
+		return false
+	}
+	if !f.Before.IsZero() && !chunk.Time.Before(f.Before) {
+		return false
+	}
+	return true
+}
+
+// DeletedKey is the metadata key that marks a soft-deleted chunk, one of a
+// document gone from the corpus that the store keeps until the document
+// returns or is purged. Its value is the time of deletion in RFC 3339.
+const DeletedKey = "deleted"
+
+// IsDeleted reports whether chunk is soft-deleted.
+func IsDeleted(chunk domain.Chunk) bool { return chunk.Metadata[DeletedKey] != "" }
+
+// Live returns the chunks that are not soft-deleted, with their vectors;
+// vectors may be nil.
+func Live(chunks []domain.Chunk, vectors [][]float64) ([]domain.Chunk, [][]float64) {
+	var outChunks []domain.Chunk
+	var outVectors [][]float64
+	for i, ch := range chunks {
+		if IsDeleted(ch) {
+			continue
+		}
+		outChunks = append(outChunks, ch)
+		if vectors != nil {
+			outVectors = append(outVectors, vectors[i])
+		}
+	}
+	return outChunks, outVectors
+}
+
+// MetadataContains reports whether one of the ", "-separated values, or a
+// single word of one, matches want, ignoring case and a leading '#' (as in
+// Slack channel names). So "alice" matches "Alice Smith, Bob".
+func MetadataContains(values, want string) bool {
+	want = NormalizeMetadata(want)
+	for _, v := range MetadataTerms(values) {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// MetadataTerms lists the normalized forms a metadata value matches: each
+// ", "-separated value and, for multi-word values, each of its words.
+func MetadataTerms(values string) []string {
+	var out []string
+	for _, v := range strings.Split(values, ", ") {
+		v = NormalizeMetadata(v)
+		out = append(out, v)
+		if words := strings.Fields(v); len(words) > 1 {
+			out = append(out, words...)
+		}
+	}
+	return out
+}
+
+// NormalizeMetadata is the form metadata values are compared in.
+func NormalizeMetadata(v string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(v), "#"))
+}
+
+// ContextUpserter is implemented by stores whose writes can be canceled
+// through a context, such as remote databases.
+type ContextUpserter interface {
+	UpsertContext(ctx context.Context, chunks []domain.Chunk, vectors [][]float64) error
+}
+
+// Reserver is implemented by stores that can allocate room for a known
+// number of vectors up front, rather than growing as batches arrive.
+type Reserver interface {
+	Reserve(n int)
+}
+
+// DimensionChecker is implemented by stores that can take more vectors
+// into what they already hold, as retrying failed chunks does, without the
+// reset Init makes. CheckDimension reports an error if the stored vectors
+// are not of dimension; an empty store takes it.
+type DimensionChecker interface {
+	CheckDimension(dimension int) error
+}
+
+// Scanner is implemented by stores that can enumerate all stored points,
+// which corpus-wide analyses such as topic clustering rely on.
+type Scanner interface {
+	All() ([]domain.Chunk, [][]float64, error)
+}
+
+// Stamp identifies what a persistent store holds: the embedder its vectors
+// come from and the chunks that were indexed with them, as digests the
+// ingest computes. Empty fields are unknown.
+type Stamp struct {
+	Embedder string
+	Contents string
+}
+
+// Persistent is implemented by stores whose contents outlive the process.
+// An ingest stamps what it indexed, so that the next one can tell whether
+// the stored vectors still fit its embedder and chunks and reuse them
+// instead of embedding every chunk again. Init and Clear reset the stamp.
+type Persistent interface {
+	Stamp() (Stamp, error)
+	SetStamp(Stamp) error
+}
@@ -0,0 +1,301 @@
+// Package storetest is the conformance suite of the vector stores: each
+// backend runs it from its own tests, so that they all agree on what
+// Upsert, Search, soft deletion, Clear and, for persistent stores, stamps
+// and reopening do.
+package storetest
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"rag/internal/domain"
+	"rag/internal/vectorstore"
+)
+
+// Store opens the store under test.
+type Store struct {
+	// New returns a store that the suite initializes before use.
+	New func(t *testing.T) vectorstore.Storage
+	// Reopen closes s and opens the same store again; nil for stores whose
+	// contents do not outlive them.
+	Reopen func(t *testing.T, s vectorstore.Storage) vectorstore.Storage
+}
+
+const dim = 4
+
+// fixture returns four chunks whose vectors score 1, 0.8, 0.6 and 0 against
+// query, in that order, with every chunk field set.
+func fixture() ([]domain.Chunk, [][]float64) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	chunks := []domain.Chunk{
+		{DocumentID: "raft", ChunkID: "raft:0", Index: 0, Path: "notes/raft.md", Text: "Raft elects a leader.", Start: 0, End: 21, StartLine: 1, EndLine: 1, Keywords: []string{"raft", "leader"}, Time: day, Metadata: map[string]string{"tags": "consensus, raft"}},
+		{DocumentID: "raft", ChunkID: "raft:1", Index: 1, Path: "notes/raft.md", Text: "The leader replicates the log.", Start: 22, End: 52, StartLine: 2, EndLine: 3, Time: day, Metadata: map[string]string{"tags": "consensus, raft"}},
+		{DocumentID: "paxos", ChunkID: "paxos:0", Index: 0, Path: "notes/paxos.md", Text: "Paxos agrees on a value.", Start: 0, End: 24, StartLine: 1, EndLine: 1, Time: day.AddDate(0, 1, 0), Metadata: map[string]string{"tags": "consensus"}},
+		{DocumentID: "bread", ChunkID: "bread:0", Index: 0, Path: "notes/bread.md", Text: "Feed the starter daily.", Start: 0, End: 23, StartLine: 1, EndLine: 1},
+	}
+	vectors := [][]float64{
+		{1, 0, 0, 0},
+		{0.8, 0.6, 0, 0},
+		{0.6, 0, 0.8, 0},
+		{0, 0, 0, 1},
+	}
+	return chunks, vectors
+}
+
+var query = []float64{1, 0, 0, 0}
+
+// Run runs the suite against the stores st opens.
+func Run(t *testing.T, st Store) {
+	t.Run("Init", func(t *testing.T) {
+		s := st.New(t)
+		if err := s.Init(0); err == nil {
+			t.Error("Init(0) succeeded")
+		}
+		if err := s.Init(dim); err != nil {
+			t.Fatal(err)
+		}
+		if got := search(t, s, query, 0, 10, vectorstore.Filter{}); len(got) != 0 {
+			t.Errorf("new store found %v", ids(got))
+		}
+	})
+	t.Run("Upsert", func(t *testing.T) {
+		s := st.New(t)
+		mustInit(t, s)
+		chunks, vectors := fixture()
+		if err := s.Upsert(chunks[:2], vectors[:1]); err == nil {
+			t.Error("Upsert of 2 chunks with 1 vector succeeded")
+		}
+		if err := s.Upsert(chunks[:1], [][]float64{{1, 0, 0}}); err == nil {
+			t.Error("Upsert of a vector of the wrong dimension succeeded")
+		}
+		// The store must not keep the caller's vectors.
+		batch := make([][]float64, len(vectors))
+		for i, v := range vectors {
+			batch[i] = append([]float64(nil), v...)
+		}
+		if err := s.Upsert(chunks, batch); err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range batch {
+			clear(v)
+		}
+		checkRanking(t, search(t, s, query, 0, 10, vectorstore.Filter{}), chunks)
+	})
+	t.Run("Search", func(t *testing.T) {
+		s := filled(t, st)
+		chunks, _ := fixture()
+		checkRanking(t, search(t, s, query, 0, 2, vectorstore.Filter{}), chunks[:2])
+		checkRanking(t, search(t, s, query, 1, 2, vectorstore.Filter{}), chunks[1:3])
+		if got := search(t, s, query, 10, 2, vectorstore.Filter{}); len(got) != 0 {
+			t.Errorf("offset past the end found %v", ids(got))
+		}
+		if got := search(t, s, []float64{0, 0, 0, 1}, 0, 1, vectorstore.Filter{}); !reflect.DeepEqual(ids(got), []string{"bread:0"}) {
+			t.Errorf("nearest to bread:0 is %v", ids(got))
+		}
+	})
+	t.Run("Filter", func(t *testing.T) {
+		s := filled(t, st)
+		tests := []struct {
+			name   string
+			filter vectorstore.Filter
+			want   []string
+		}{
+			{"metadata", vectorstore.Filter{Metadata: map[string]string{"tags": "raft"}}, []string{"raft:0", "raft:1"}},
+			{"metadata case", vectorstore.Filter{Metadata: map[string]string{"tags": "#Consensus"}}, []string{"raft:0", "raft:1", "paxos:0"}},
+			{"after", vectorstore.Filter{After: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)}, []string{"paxos:0"}},
+			{"before", vectorstore.Filter{Before: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)}, []string{"raft:0", "raft:1"}},
+		}
+		for _, tt := range tests {
+			if got := ids(search(t, s, query, 0, 10, tt.filter)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: found %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+	t.Run("Tombstone", func(t *testing.T) {
+		s := st.New(t)
+		mustInit(t, s)
+		chunks, vectors := fixture()
+		chunks[0].Metadata = map[string]string{"tags": "consensus, raft", vectorstore.DeletedKey: "2024-05-01T00:00:00Z"}
+		if err := s.Upsert(chunks, vectors); err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(search(t, s, query, 0, 10, vectorstore.Filter{})); !reflect.DeepEqual(got, []string{"raft:1", "paxos:0", "bread:0"}) {
+			t.Errorf("live chunks are %v", got)
+		}
+		deleted := search(t, s, query, 0, 10, vectorstore.Filter{Deleted: true})
+		if got := ids(deleted); !reflect.DeepEqual(got, []string{"raft:0"}) {
+			t.Fatalf("deleted chunks are %v", got)
+		}
+		if !vectorstore.IsDeleted(deleted[0].Chunk) {
+			t.Errorf("deleted chunk lost its mark: %v", deleted[0].Chunk.Metadata)
+		}
+		if got := ids(search(t, s, query, 0, 10, vectorstore.Filter{Deleted: true, Metadata: map[string]string{"tags": "paxos"}})); len(got) != 0 {
+			t.Errorf("deleted chunks tagged paxos are %v", got)
+		}
+		if scanner, ok := s.(vectorstore.Scanner); ok {
+			all, vectors, err := scanner.All()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != len(chunks) || len(vectors) != len(chunks) {
+				t.Errorf("All returned %d chunks and %d vectors, want %d", len(all), len(vectors), len(chunks))
+			}
+			live, _ := vectorstore.Live(all, vectors)
+			if len(live) != len(chunks)-1 {
+				t.Errorf("All returned %d live chunks, want %d", len(live), len(chunks)-1)
+			}
+		}
+	})
+	t.Run("Clear", func(t *testing.T) {
+		s := filled(t, st)
+		if err := s.Clear(); err != nil {
+			t.Fatal(err)
+		}
+		mustInit(t, s)
+		if got := search(t, s, query, 0, 10, vectorstore.Filter{}); len(got) != 0 {
+			t.Errorf("cleared store found %v", ids(got))
+		}
+	})
+	t.Run("Stamp", func(t *testing.T) {
+		s := filled(t, st)
+		p, ok := s.(vectorstore.Persistent)
+		if !ok {
+			t.Skip("store is not persistent")
+		}
+		want := vectorstore.Stamp{Embedder: "tfidf:1", Contents: "sha256:abc"}
+		if err := p.SetStamp(want); err != nil {
+			t.Fatal(err)
+		}
+		if got := stamp(t, p); got != want {
+			t.Errorf("Stamp() = %+v, want %+v", got, want)
+		}
+		if st.Reopen != nil {
+			s = st.Reopen(t, s)
+			p = s.(vectorstore.Persistent)
+			if got := stamp(t, p); got != want {
+				t.Errorf("Stamp() after reopening = %+v, want %+v", got, want)
+			}
+		}
+		mustInit(t, s)
+		if got := stamp(t, p); got != (vectorstore.Stamp{}) {
+			t.Errorf("Stamp() after Init = %+v", got)
+		}
+		if err := p.SetStamp(want); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Clear(); err != nil {
+			t.Fatal(err)
+		}
+		if got := stamp(t, p); got != (vectorstore.Stamp{}) {
+			t.Errorf("Stamp() after Clear = %+v", got)
+		}
+	})
+	t.Run("Reopen", func(t *testing.T) {
+		if st.Reopen == nil {
+			t.Skip("store is not persistent")
+		}
+		s := st.New(t)
+		mustInit(t, s)
+		chunks, vectors := fixture()
+		// One batch per chunk, so that the store holds changes it has
+		// logged but not yet written in bulk.
+		for i := range chunks {
+			if err := s.Upsert(chunks[i:i+1], vectors[i:i+1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		s = st.Reopen(t, s)
+		checkRanking(t, search(t, s, query, 0, 10, vectorstore.Filter{}), chunks)
+		if err := s.Clear(); err != nil {
+			t.Fatal(err)
+		}
+		s = st.Reopen(t, s)
+		mustInit(t, s)
+		if got := search(t, s, query, 0, 10, vectorstore.Filter{}); len(got) != 0 {
+			t.Errorf("store cleared before reopening found %v", ids(got))
+		}
+	})
+}
+
+// filled returns a new store holding the fixture.
+func filled(t *testing.T, st Store) vectorstore.Storage {
+	t.Helper()
+	s := st.New(t)
+	mustInit(t, s)
+	chunks, vectors := fixture()
+	if err := s.Upsert(chunks, vectors); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func mustInit(t *testing.T, s vectorstore.Storage) {
+	t.Helper()
+	if err := s.Init(dim); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func search(t *testing.T, s vectorstore.Storage, vector []float64, offset, limit int, filter vectorstore.Filter) []domain.SearchResult {
+	t.Helper()
+	results, err := s.Search(vector, offset, limit, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func stamp(t *testing.T, p vectorstore.Persistent) vectorstore.Stamp {
+	t.Helper()
+	got, err := p.Stamp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func ids(results []domain.SearchResult) []string {
+	out := make([]string, len(results))
+	for i, r := range results {
+		out[i] = r.Chunk.ChunkID
+	}
+	return out
+}
+
+// checkRanking checks that results are want in order, every field intact,
+// with the cosine similarities of the fixture.
+func checkRanking(t *testing.T, results []domain.SearchResult, want []domain.Chunk) {
+	t.Helper()
+	if len(results) != len(want) {
+		t.Fatalf("found %v, want %d chunks", ids(results), len(want))
+	}
+	scores := map[string]float64{"raft:0": 1, "raft:1": 0.8, "paxos:0": 0.6, "bread:0": 0}
+	for i, r := range results {
+		if !sameChunk(r.Chunk, want[i]) {
+			t.Errorf("result %d is %+v, want %+v", i, r.Chunk, want[i])
+		}
+		if math.Abs(r.Score-scores[want[i].ChunkID]) > 1e-6 {
+			t.Errorf("result %d (%s) scores %v, want %v", i, r.Chunk.ChunkID, r.Score, scores[want[i].ChunkID])
+		}
+	}
+}
+
+// sameChunk compares chunks field by field, empty and nil slices and maps
+// alike, and times by instant.
+func sameChunk(a, b domain.Chunk) bool {
+	if !a.Time.Equal(b.Time) || len(a.Keywords) != len(b.Keywords) || len(a.Metadata) != len(b.Metadata) {
+		return false
+	}
+	if len(a.Keywords) > 0 && !reflect.DeepEqual(a.Keywords, b.Keywords) {
+		return false
+	}
+	if len(a.Metadata) > 0 && !reflect.DeepEqual(a.Metadata, b.Metadata) {
+		return false
+	}
+	a.Time, b.Time = time.Time{}, time.Time{}
+	a.Keywords, b.Keywords = nil, nil
+	a.Metadata, b.Metadata = nil, nil
+	return reflect.DeepEqual(a, b)
+}
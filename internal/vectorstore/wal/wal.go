@@ -0,0 +1,186 @@
+// Package wal is a write-ahead log for on-disk vector stores. A store
+// appends every change to the log, which is synced before the change is
+// applied to the store's own files; on open the store replays the entries
+// logged since its last checkpoint. An entry is written whole or not at all:
+// a record torn by a crash fails its checksum and is cut off on replay, so an
+// interrupted ingest loses at most the batch being written and never leaves a
+// half-applied one behind.
+//
+// Each record is a 4-byte little-endian payload length, the payload's
+// CRC-32C and the gob-encoded Entry.
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"rag/internal/domain"
+)
+
+// Op is the kind of change an entry records.
+type Op uint8
+
+// Logged operations, mirroring the vectorstore.Storage methods that change
+// a store.
+const (
+	OpInit Op = iota + 1
+	OpUpsert
+	OpClear
+)
+
+// Entry is one logged change. Replay is at-least-once: a crash after a store
+// has durably applied its entries but before Checkpoint empties the log
+// delivers them again on the next Open, so a store either applies them
+// idempotently or records the Seq of the last entry it applied and skips up
+// to it.
+type Entry struct {
+	Op Op
+	// Seq numbers the entry. The log stores it but does not assign it; a
+	// store numbers its changes in the order it logs them.
+	Seq uint64
+	// Dimension is set for OpInit.
+	Dimension int
+	// Chunks and Vectors are set for OpUpsert.
+	Chunks  []domain.Chunk
+	Vectors [][]float64
+}
+
+// maxRecord bounds the payload length read back, so a corrupt length field
+// cannot make replay allocate an arbitrary amount of memory.
+const maxRecord = 1 << 30
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Log is an open write-ahead log file.
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open opens the log at path, creating it and its directory if needed, and
+// returns the entries it holds. A torn or corrupt tail, left by a crash
+// while appending, is truncated so new entries follow the last intact one.
+func Open(path string) (*Log, []Entry, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, end, err := replay(f)
+	if err == nil {
+		err = f.Truncate(end)
+	}
+	if err == nil {
+		_, err = f.Seek(end, io.SeekStart)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, err
+	}
+	return &Log{file: f}, entries, nil
+}
+
+// replay reads entries from the start of f up to the first record that is
+// incomplete or fails its checksum, returning them and the offset where
+// intact records end.
+func replay(f *os.File) ([]Entry, int64, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	r := bufio.NewReader(f)
+	var (
+		entries []Entry
+		end     int64
+		header  [8]byte
+	)
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return entries, end, nil
+		}
+		n := binary.LittleEndian.Uint32(header[:4])
+		sum := binary.LittleEndian.Uint32(header[4:])
+		if n > maxRecord {
+			return entries, end, nil
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return entries, end, nil
+		}
+		if crc32.Checksum(payload, castagnoli) != sum {
+			return entries, end, nil
+		}
+		var e Entry
+		if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&e); err != nil {
+			return entries, end, nil
+		}
+		entries = append(entries, e)
+		end += int64(len(header)) + int64(n)
+	}
+}
+
+// Append writes e to the log and syncs it to disk; once Append returns the
+// entry survives a crash.
+func (l *Log) Append(e Entry) error {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(e); err != nil {
+		return err
+	}
+	if payload.Len() > maxRecord {
+		return errors.New("wal entry too large")
+	}
+	record := make([]byte, 8, 8+payload.Len())
+	binary.LittleEndian.PutUint32(record[:4], uint32(payload.Len()))
+	binary.LittleEndian.PutUint32(record[4:], crc32.Checksum(payload.Bytes(), castagnoli))
+	record = append(record, payload.Bytes()...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return errors.New("wal closed")
+	}
+	if _, err := l.file.Write(record); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// Checkpoint empties the log. A store calls it after its own files durably
+// hold every logged change, so they need not be replayed again. A crash
+// before Checkpoint returns leaves the entries in the log, and the next Open
+// returns them even though the store already holds them.
+func (l *Log) Checkpoint() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return errors.New("wal closed")
+	}
+	if err := l.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := l.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// Close closes the log file, keeping its entries for the next Open.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
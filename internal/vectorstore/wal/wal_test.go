@@ -0,0 +1,134 @@
+package wal
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"rag/internal/domain"
+)
+
+var testEntries = []Entry{
+	{Op: OpInit, Seq: 1, Dimension: 2},
+	{Op: OpUpsert, Seq: 2, Chunks: []domain.Chunk{{DocumentID: "raft", ChunkID: "raft:0", Text: "Raft elects a leader."}}, Vectors: [][]float64{{1, 0}}},
+	{Op: OpUpsert, Seq: 3, Chunks: []domain.Chunk{{DocumentID: "paxos", ChunkID: "paxos:0", Text: "Paxos agrees on a value."}}, Vectors: [][]float64{{0, 1}}},
+}
+
+// write logs entries to a new log, closes it and returns its path and the
+// offsets where each record ends.
+func write(t *testing.T, entries []Entry) (string, []int64) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "index.db.wal")
+	l, got, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("new log holds %d entries", len(got))
+	}
+	var ends []int64
+	for _, e := range entries {
+		if err := l.Append(e); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ends = append(ends, info.Size())
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path, ends
+}
+
+// reopen opens the log at path, closing it when the test ends, and checks
+// that it returns want.
+func reopen(t *testing.T, path string, want []Entry) *Log {
+	t.Helper()
+	l, got, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("replayed %+v, want %+v", got, want)
+	}
+	return l
+}
+
+// truncated checks that the log at path was cut to size.
+func truncated(t *testing.T, path string, size int64) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != size {
+		t.Errorf("log is %d bytes, want %d", info.Size(), size)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	path, _ := write(t, testEntries)
+	l := reopen(t, path, testEntries)
+	if err := l.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopen(t, path, nil)
+}
+
+func TestTornTail(t *testing.T) {
+	path, ends := write(t, testEntries)
+	if err := os.Truncate(path, ends[2]-3); err != nil {
+		t.Fatal(err)
+	}
+	l := reopen(t, path, testEntries[:2])
+	truncated(t, path, ends[1])
+
+	// New entries follow the last intact one.
+	if err := l.Append(testEntries[2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopen(t, path, testEntries)
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	path, ends := write(t, testEntries)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip a payload byte of the second record: replay stops before it,
+	// dropping the intact third record too.
+	data[ends[0]+8] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reopen(t, path, testEntries[:1])
+	truncated(t, path, ends[0])
+}
+
+func TestRecordTooLarge(t *testing.T) {
+	path, ends := write(t, testEntries)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A length field past maxRecord is taken for corruption, not allocated.
+	binary.LittleEndian.PutUint32(data[ends[0]:], maxRecord+1)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reopen(t, path, testEntries[:1])
+	truncated(t, path, ends[0])
+}
@@ -0,0 +1,77 @@
+package watch
+
+import (
+	"os"
+	"time"
+
+	"rag/internal/loader"
+)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Poller detects changes to a set of files or glob patterns by comparing
+// modification times and sizes between polls. It needs no OS-specific
+// notification APIs, which keeps it portable.
+type Poller struct {
+	patterns []string
+	state    map[string]fileState
+}
+
+// NewPoller creates a poller over the given paths or glob patterns and
+// records their current state.
+func NewPoller(patterns []string) *Poller {
+	p := &Poller{patterns: patterns}
+	p.state = p.scan()
+	return p
+}
+
+// Changed reports whether any file was added, removed or modified since the
+// previous call (or since NewPoller).
+func (p *Poller) Changed() bool {
+	next := p.scan()
+	changed := len(next) != len(p.state)
+	if !changed {
+		for path, st := range next {
+			if prev, ok := p.state[path]; !ok || prev != st {
+				changed = true
+				break
+			}
+		}
+	}
+	p.state = next
+	return changed
+}
+
+// Run polls every interval and calls onChange after a change, until stop is
+// closed.
+func (p *Poller) Run(interval time.Duration, stop <-chan struct{}, onChange func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if p.Changed() {
+				onChange()
+			}
+		}
+	}
+}
+
+func (p *Poller) scan() map[string]fileState {
+	out := make(map[string]fileState)
+	for _, pat := range p.patterns {
+		for _, m := range loader.Expand(pat) {
+			info, err := os.Stat(m)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			out[m] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return out
+}